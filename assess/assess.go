@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -14,6 +15,7 @@ import (
 	"github.com/teilomillet/gollm"
 	"github.com/teilomillet/gollm/config"
 	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/replay"
 	"github.com/teilomillet/gollm/types"
 	"golang.org/x/time/rate"
 )
@@ -26,7 +28,7 @@ type BatchTestConfig struct {
 	BatchTimeout time.Duration
 }
 
-// BatchMetrics tracks batch execution metrics
+// BatchMetrics tracks batch execution metrics, keyed by provider/model
 type BatchMetrics struct {
 	BatchTiming struct {
 		StartTime       time.Time
@@ -57,15 +59,21 @@ type TestCase struct {
 	ExpectedSchema interface{}
 	Timeout        time.Duration
 	Validations    []ValidationFunc
-	options        map[string]interface{}
-	directives     []string
-	context        string
-	maxLength      int
-	examples       []string
-	tools          []gollm.Tool
-	toolChoice     string
-	messages       []gollm.PromptMessage
-	output         string
+	// Tags label the case in results and reports; see FilterTags.
+	Tags []string
+	// Samples is how many times the case runs against each provider; see WithSamples.
+	Samples         int
+	validationNames []string
+	options         map[string]interface{}
+	directives      []string
+	context         string
+	maxLength       int
+	examples        []string
+	tools           []gollm.Tool
+	toolChoice      string
+	messages        []gollm.PromptMessage
+	output          string
+	judgements      []judgement
 }
 
 // ValidationFunc is a function type for custom validations
@@ -73,7 +81,7 @@ type ValidationFunc func(response string) error
 
 // TestRunner manages test execution across providers
 type TestRunner struct {
-	t            *testing.T
+	t            Reporter
 	providers    []TestProvider
 	cases        []*TestCase
 	metrics      *TestMetrics
@@ -85,9 +93,22 @@ type TestRunner struct {
 	// usageObserver, when set, is installed on every per-provider client the runner builds, so a
 	// suite run against real providers can account for what it spent.
 	usageObserver types.UsageObserver
+
+	// replayDir, when set, holds a cassette per provider and case; see WithReplay.
+	replayDir  string
+	replayOpts []replay.Option
+
+	// judgeResults records every judgement made; see ExpectJudged.
+	judgeResults []JudgeResult
+	// results records the outcome of every case run; see Results.
+	results []CaseResult
+
+	// baseline, when set, is the run that cases are compared against; see WithBaseline.
+	baseline  *Report
+	tolerance Tolerance
 }
 
-// TestMetrics tracks test execution metrics
+// TestMetrics tracks test execution metrics, keyed by provider/model
 type TestMetrics struct {
 	ResponseTimes map[string][]time.Duration
 	CacheHits     map[string]int
@@ -97,6 +118,10 @@ type TestMetrics struct {
 }
 
 func NewTest(t *testing.T) *TestRunner {
+	return newRunner(t)
+}
+
+func newRunner(t Reporter) *TestRunner {
 	return &TestRunner{
 		t: t,
 		metrics: &TestMetrics{
@@ -118,6 +143,12 @@ func (tr *TestRunner) WithProvider(name, model string) *TestRunner {
 	return tr
 }
 
+// label names the provider and model in results, metrics and reports, so that two models of
+// one provider are told apart.
+func (p TestProvider) label() string {
+	return p.Name + "/" + p.Model
+}
+
 // providerAPIKeyEnv returns the environment variable name for a provider's API key.
 func (p TestProvider) providerAPIKeyEnv() string {
 	return fmt.Sprintf("%s_API_KEY", strings.ToUpper(p.Name))
@@ -133,17 +164,59 @@ func (p TestProvider) hasAPIKey() bool {
 	return p.apiKey() != ""
 }
 
-// HasAvailableProviders checks if any configured provider has an API key set.
+// HasAvailableProviders checks if any configured provider has an API key set, or if the suite
+// replays recorded responses and needs none.
 // Use this to skip tests early when no providers are available.
 func (tr *TestRunner) HasAvailableProviders() bool {
 	for _, provider := range tr.providers {
-		if provider.hasAPIKey() {
+		if tr.available(provider) {
 			return true
 		}
 	}
 	return false
 }
 
+// available reports whether the runner can run cases against provider.
+func (tr *TestRunner) available(provider TestProvider) bool {
+	return tr.replaying() || provider.hasAPIKey()
+}
+
+// replaying reports whether cases are answered from cassettes rather than by the providers.
+func (tr *TestRunner) replaying() bool {
+	return tr.replayDir != "" && replay.ModeOf(tr.replayOpts...) == replay.ModeReplay
+}
+
+// WithReplay runs the suite against recorded provider traffic, one cassette per provider and case
+// under dir (dir/<provider>/<model>/<case>.json). In replay mode, the default, providers need no API key
+// and no request leaves the process. With GOLLM_RECORD=1 set (or replay.WithMode(replay.ModeRecord)
+// among opts) the cases call the providers as usual and their traffic is recorded to the
+// cassettes, replacing what was there.
+func (tr *TestRunner) WithReplay(dir string, opts ...replay.Option) *TestRunner {
+	tr.replayDir = dir
+	tr.replayOpts = opts
+	return tr
+}
+
+// cassettePath is where the traffic of sample of tc against provider is recorded. The first
+// sample, the only one of most cases, has no suffix.
+func (tr *TestRunner) cassettePath(provider TestProvider, tc *TestCase, sample int) string {
+	clean := strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_")
+	name := clean.Replace(tc.Name)
+	if sample > 0 {
+		name = fmt.Sprintf("%s.sample%d", name, sample+1)
+	}
+	return filepath.Join(tr.replayDir, clean.Replace(provider.Name), clean.Replace(provider.Model), name+".json")
+}
+
+// replayAPIKey stands in for a provider's API key when its responses are replayed. It only has
+// to pass key validation; recorded authentication headers are redacted and never matched.
+func replayAPIKey(provider string) string {
+	if provider == "anthropic" {
+		return "sk-ant-REDACTED"
+	}
+	return "sk-replay-placeholder-key-0000"
+}
+
 func (tr *TestRunner) WithProviders(providers map[string]string) *TestRunner {
 	for name, model := range providers {
 		tr.WithProvider(name, model)
@@ -180,7 +253,12 @@ func (tc *TestCase) ExpectSchema(schema interface{}) *TestCase {
 }
 
 func (tc *TestCase) Validate(fn ValidationFunc) *TestCase {
-	tc.Validations = append(tc.Validations, fn)
+	return tc.validateNamed("", fn)
+}
+
+// WithTags labels the case in results and reports.
+func (tc *TestCase) WithTags(tags ...string) *TestCase {
+	tc.Tags = append(tc.Tags, tags...)
 	return tc
 }
 
@@ -270,7 +348,7 @@ func (tr *TestRunner) RunBatch(ctx context.Context) {
 	// Filter providers with available API keys
 	var availableProviders []TestProvider
 	for _, provider := range tr.providers {
-		if provider.hasAPIKey() {
+		if tr.available(provider) {
 			availableProviders = append(availableProviders, provider)
 		} else {
 			tr.t.Logf("Skipping provider %s: %s environment variable not set", provider.Name, provider.providerAPIKeyEnv())
@@ -295,13 +373,8 @@ func (tr *TestRunner) RunBatch(ctx context.Context) {
 	tr.batchMetrics.BatchTiming.StartTime = time.Now()
 	tr.t.Logf("Starting batch execution with %d providers and %d test cases", len(availableProviders), len(tr.cases))
 
-	// Create worker pool
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, tr.batchCfg.MaxParallel)
-	currentConcurrent := 0
-	maxConcurrent := 0
-
-	var concurrencyMu sync.Mutex
+	// Every sample takes a slot, so MaxParallel and the rate limit bound the requests in flight
+	slots := newBatchSlots(tr.batchCfg)
 
 	// Track latency sums and counts for computing true arithmetic mean
 	providerLatencySum := make(map[string]time.Duration)
@@ -317,98 +390,60 @@ func (tr *TestRunner) RunBatch(ctx context.Context) {
 	}
 	results := make(chan testResult, len(availableProviders)*len(tr.cases))
 
+	var wg sync.WaitGroup
 	for _, provider := range availableProviders {
-		tr.t.Logf("Starting test cases for provider: %s", provider.Name)
+		tr.t.Logf("Starting test cases for provider: %s", provider.label())
 
 		for _, tc := range tr.cases {
 			wg.Add(1)
 			go func(p TestProvider, testCase *TestCase) {
 				defer wg.Done()
 
-				// Acquire semaphore
-				semaphore <- struct{}{}
-				concurrencyMu.Lock()
-				currentConcurrent++
-				if currentConcurrent > maxConcurrent {
-					maxConcurrent = currentConcurrent
-				}
-				concurrencyMu.Unlock()
-
-				tr.t.Logf("Starting test case [%s] for provider [%s]", testCase.Name, p.Name)
-
-				// Apply rate limiting if configured
-				if tr.batchCfg.RateLimit != nil {
-					if err := tr.batchCfg.RateLimit.Wait(ctx); err != nil {
-						rateLimitErr := fmt.Errorf("rate limit wait failed: %w", err)
-						tr.t.Logf("Rate limit wait error for provider %s: %v", p.Name, err)
-
-						// Send failure result and clean up
-						results <- testResult{
-							provider: p.Name,
-							testCase: testCase.Name,
-							err:      rateLimitErr,
-						}
-						concurrencyMu.Lock()
-						currentConcurrent--
-						concurrencyMu.Unlock()
-						<-semaphore
-						return
-					}
-				}
-
-				// Create a new client for each test case to avoid race conditions
-				// when concurrent goroutines call SetOption on the same client
-				client, err := tr.setupClient(p)
-				if err != nil {
-					tr.t.Logf("Client setup error for provider %s: %v", p.Name, err)
-
-					// Send failure result and clean up
-					results <- testResult{
-						provider: p.Name,
-						testCase: testCase.Name,
-						err:      err,
-					}
-					concurrencyMu.Lock()
-					currentConcurrent--
-					concurrencyMu.Unlock()
-					<-semaphore
-					return
-				}
+				tr.t.Logf("Starting test case [%s] for provider [%s]", testCase.Name, p.label())
 
 				// Run the test case
 				start := time.Now()
+				var result CaseResult
 				var testErr error
-				var response string
 
 				// Create a sub-test for proper test organization
-				tr.t.Run(fmt.Sprintf("%s/%s", p.Name, testCase.Name), func(t *testing.T) {
-					response, testErr = tr.runBatchCase(ctx, t, client, p, testCase)
+				subtest(tr.t, fmt.Sprintf("%s/%s", p.label(), testCase.Name), func(t Reporter) {
+					samples := make([]CaseResult, testCase.sampleCount())
+					errs := make([]error, len(samples))
+					var samplesWG sync.WaitGroup
+					for i := range samples {
+						samplesWG.Add(1)
+						go func(i int) {
+							defer samplesWG.Done()
+							samples[i], errs[i] = tr.runBatchSample(ctx, sampleReporter(t, testCase), slots, p, testCase, i)
+						}(i)
+					}
+					samplesWG.Wait()
+
+					result = tr.finishCase(t, p, testCase, samples)
+					if result.Error != "" {
+						testErr = errs[0]
+					}
 				})
 
 				duration := time.Since(start)
 
 				// Send result through channel
 				results <- testResult{
-					provider: p.Name,
+					provider: p.label(),
 					testCase: testCase.Name,
 					duration: duration,
 					err:      testErr,
-					response: response,
+					response: result.Response,
 				}
 
 				// Accumulate latency for computing arithmetic mean later
 				tr.mu.Lock()
-				providerLatencySum[p.Name] += duration
-				providerLatencyCount[p.Name]++
+				providerLatencySum[p.label()] += duration
+				providerLatencyCount[p.label()]++
 				tr.mu.Unlock()
 
-				// Release semaphore
-				concurrencyMu.Lock()
-				currentConcurrent--
-				concurrencyMu.Unlock()
-				<-semaphore
-
-				tr.t.Logf("Completed test case [%s] for provider [%s] in %v", testCase.Name, p.Name, duration)
+				tr.t.Logf("Completed test case [%s] for provider [%s] in %v", testCase.Name, p.label(), duration)
 			}(provider, tc)
 		}
 	}
@@ -443,17 +478,36 @@ func (tr *TestRunner) RunBatch(ctx context.Context) {
 	// Record final metrics
 	tr.batchMetrics.BatchTiming.EndTime = time.Now()
 	tr.batchMetrics.BatchTiming.TotalDuration = tr.batchMetrics.BatchTiming.EndTime.Sub(tr.batchMetrics.BatchTiming.StartTime)
-	tr.batchMetrics.ConcurrencyStats.MaxConcurrent = maxConcurrent
+	tr.batchMetrics.ConcurrencyStats.MaxConcurrent = slots.max()
 
 	tr.t.Logf("Batch execution completed in %v", tr.batchMetrics.BatchTiming.TotalDuration)
-	tr.t.Logf("Maximum concurrent tests: %d", maxConcurrent)
+	tr.t.Logf("Maximum concurrent tests: %d", slots.max())
 
 	// Print error summary at the end
 	tr.printErrorSummary()
 }
 
+// runBatchSample takes a slot and runs one sample of a batch test case.
+func (tr *TestRunner) runBatchSample(ctx context.Context, t Reporter, slots *batchSlots, provider TestProvider, tc *TestCase, sample int) (CaseResult, error) {
+	release, err := slots.acquire(ctx)
+	if err != nil {
+		tr.t.Logf("Rate limit wait error for provider %s: %v", provider.Name, err)
+		return newCaseResult(provider, tc, "", types.TokenUsage{}, 0, err), err
+	}
+	defer release()
+
+	// Create a new client for each sample to avoid race conditions
+	// when concurrent goroutines call SetOption on the same client
+	client, err := tr.setupClient(provider, tc, sample)
+	if err != nil {
+		tr.t.Logf("Client setup error for provider %s: %v", provider.Name, err)
+		return newCaseResult(provider, tc, "", types.TokenUsage{}, 0, err), err
+	}
+	return tr.runBatchCase(ctx, t, client, provider, tc)
+}
+
 // Helper method to run a single batch test case
-func (tr *TestRunner) runBatchCase(ctx context.Context, t *testing.T, client llm.LLM, provider TestProvider, tc *TestCase) (string, error) {
+func (tr *TestRunner) runBatchCase(ctx context.Context, t Reporter, client llm.LLM, provider TestProvider, tc *TestCase) (CaseResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, tc.Timeout)
 	defer cancel()
 
@@ -469,33 +523,24 @@ func (tr *TestRunner) runBatchCase(ctx context.Context, t *testing.T, client llm
 		}
 	}
 
-	var response string
-	var err error
-
-	if tc.ExpectedSchema != nil {
-		// Use JSON schema validation for all providers
-		response, err = client.GenerateWithSchema(ctx, prompt, tc.ExpectedSchema)
-	} else {
-		response, err = client.Generate(ctx, prompt)
-	}
-
+	start := time.Now()
+	response, usage, err := generateCase(ctx, client, prompt, tc)
+	result := newCaseResult(provider, tc, response, usage, time.Since(start), err)
 	if err != nil {
-		return "", err
+		return result, err
 	}
 
-	// Run validations
-	for _, validate := range tc.Validations {
-		if err := validate(response); err != nil {
-			t.Errorf("Validation failed: %v", err)
-		}
-	}
-
-	return response, nil
+	// Run validations and judgements
+	tr.check(ctx, t, provider, tc, &result)
+	return result, nil
 }
 
-func (tr *TestRunner) setupClient(provider TestProvider) (llm.LLM, error) {
+func (tr *TestRunner) setupClient(provider TestProvider, tc *TestCase, sample int) (llm.LLM, error) {
 	// Get API key from environment (providers should be pre-filtered, but check as safety)
 	apiKey := provider.apiKey()
+	if apiKey == "" && tr.replaying() {
+		apiKey = replayAPIKey(provider.Name)
+	}
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key not set: %s environment variable is empty", provider.providerAPIKeyEnv())
 	}
@@ -534,6 +579,18 @@ func (tr *TestRunner) setupClient(provider TestProvider) (llm.LLM, error) {
 		opts = append(opts, gollm.WithUsageObserver(tr.usageObserver))
 	}
 
+	// Record or replay this case's traffic. A replayed response needs no wait before a retry.
+	if tr.replayDir != "" {
+		recorder, err := replay.New(tr.cassettePath(provider, tc, sample), tr.replayOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %w", err)
+		}
+		opts = append(opts, gollm.SetHTTPClient(recorder.Client()))
+		if recorder.Mode() == replay.ModeReplay {
+			opts = append(opts, gollm.SetRetryDelay(time.Millisecond))
+		}
+	}
+
 	// Create LLM client
 	client, err := gollm.NewLLM(opts...)
 	if err != nil {
@@ -546,7 +603,7 @@ func (tr *TestRunner) setupClient(provider TestProvider) (llm.LLM, error) {
 	return client, nil
 }
 
-func (tr *TestRunner) runCase(ctx context.Context, t *testing.T, client llm.LLM, provider TestProvider, tc *TestCase) {
+func (tr *TestRunner) runCase(ctx context.Context, t Reporter, client llm.LLM, provider TestProvider, tc *TestCase) CaseResult {
 	ctx, cancel := context.WithTimeout(ctx, tc.Timeout)
 	defer cancel()
 
@@ -603,36 +660,26 @@ func (tr *TestRunner) runCase(ctx context.Context, t *testing.T, client llm.LLM,
 	}
 
 	start := time.Now()
-	var response string
-	var err error
-
-	if tc.ExpectedSchema != nil {
-		response, err = client.GenerateWithSchema(ctx, prompt, tc.ExpectedSchema)
-	} else {
-		response, err = client.Generate(ctx, prompt)
-	}
-
+	response, usage, err := generateCase(ctx, client, prompt, tc)
 	duration := time.Since(start)
+	result := newCaseResult(provider, tc, response, usage, duration, err)
 
 	// Synchronize metrics access for future-proofing if Run() becomes concurrent
 	tr.mu.Lock()
-	tr.metrics.ResponseTimes[provider.Name] = append(tr.metrics.ResponseTimes[provider.Name], duration)
+	tr.metrics.ResponseTimes[provider.label()] = append(tr.metrics.ResponseTimes[provider.label()], duration)
 	if err != nil {
-		tr.metrics.Errors[provider.Name] = append(tr.metrics.Errors[provider.Name], err)
+		tr.metrics.Errors[provider.label()] = append(tr.metrics.Errors[provider.label()], err)
 	}
 	tr.mu.Unlock()
 
 	if err != nil {
-		t.Errorf("Generation failed: %v", err)
-		return
+		tr.fail(t, provider, tc, "Generation failed: %v", err)
+		return result
 	}
 
-	// Run validations
-	for _, validate := range tc.Validations {
-		if err := validate(response); err != nil {
-			t.Errorf("Validation failed: %v", err)
-		}
-	}
+	// Run validations and judgements
+	tr.check(ctx, t, provider, tc, &result)
+	return result
 }
 
 func getDefaultHeaders(provider string) map[string]string {
@@ -675,7 +722,7 @@ func (tr *TestRunner) Run(ctx context.Context) {
 	// Filter providers with available API keys (consistent with RunBatch)
 	var availableProviders []TestProvider
 	for _, provider := range tr.providers {
-		if provider.hasAPIKey() {
+		if tr.available(provider) {
 			availableProviders = append(availableProviders, provider)
 		} else {
 			tr.t.Logf("Skipping provider %s: %s environment variable not set", provider.Name, provider.providerAPIKeyEnv())
@@ -693,22 +740,32 @@ func (tr *TestRunner) Run(ctx context.Context) {
 	}
 
 	for _, provider := range availableProviders {
-		tr.t.Run(provider.Name, func(t *testing.T) {
+		subtest(tr.t, provider.label(), func(t Reporter) {
 			for _, tc := range tr.cases {
-				t.Run(tc.Name, func(t *testing.T) {
-					// Create a new client per test case to match RunBatch behavior
-					// and prevent issues if t.Parallel() is added later
-					client, err := tr.setupClient(provider)
-					if err != nil {
-						t.Fatalf("Failed to setup client for %s: %v", provider.Name, err)
+				subtest(t, tc.Name, func(t Reporter) {
+					samples := make([]CaseResult, tc.sampleCount())
+					for i := range samples {
+						samples[i] = tr.runSample(ctx, sampleReporter(t, tc), provider, tc, i)
 					}
-					tr.runCase(ctx, t, client, provider, tc)
+					tr.finishCase(t, provider, tc, samples)
 				})
 			}
 		})
 	}
 }
 
+// runSample runs one sample of a test case on a client of its own.
+func (tr *TestRunner) runSample(ctx context.Context, t Reporter, provider TestProvider, tc *TestCase, sample int) CaseResult {
+	// Create a new client per sample to match RunBatch behavior
+	// and prevent issues if t.Parallel() is added later
+	client, err := tr.setupClient(provider, tc, sample)
+	if err != nil {
+		t.Errorf("Failed to setup client for %s: %v", provider.Name, err)
+		return newCaseResult(provider, tc, "", types.TokenUsage{}, 0, err)
+	}
+	return tr.runCase(ctx, t, client, provider, tc)
+}
+
 func (tr *TestRunner) recordError(provider string, err error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
@@ -0,0 +1,308 @@
+package assess
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Tolerance bounds how much worse a case may do than in the baseline before it counts as a
+// regression. A negative bound turns its check off.
+type Tolerance struct {
+	// ScoreDrop is how far the mean judge score of a case may fall, on the 0 to 1 scale.
+	ScoreDrop float64
+	// LatencyIncrease is how much slower a case may be, as a fraction of its baseline latency
+	// (0.5 allows 50% slower).
+	LatencyIncrease float64
+	// TokenIncrease is how many more tokens a case may spend, as a fraction of its baseline
+	// usage.
+	TokenIncrease float64
+}
+
+// DefaultTolerance allows a 0.1 drop in judge score, twice the latency and 25% more tokens.
+// Latency varies from run to run, so its bound is loose.
+var DefaultTolerance = Tolerance{ScoreDrop: 0.1, LatencyIncrease: 1.0, TokenIncrease: 0.25}
+
+// RegressionKind is the way a case got worse.
+type RegressionKind string
+
+const (
+	// RegressionFailure is a case that passed in the baseline and fails now.
+	RegressionFailure RegressionKind = "new_failure"
+	// RegressionScore is a judge score that fell by more than the tolerance.
+	RegressionScore RegressionKind = "score_drop"
+	// RegressionLatency is a case that got slower by more than the tolerance.
+	RegressionLatency RegressionKind = "latency"
+	// RegressionTokens is a case that spent more tokens than the tolerance allows.
+	RegressionTokens RegressionKind = "tokens"
+)
+
+// Regression is one way one case did worse than in the baseline.
+type Regression struct {
+	Provider string         `json:"provider"`
+	Model    string         `json:"model"`
+	Case     string         `json:"case"`
+	Kind     RegressionKind `json:"kind"`
+	// Baseline and Current are the compared values: the judge score, the latency in
+	// milliseconds or the total tokens. Both are zero for a new failure.
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Message  string  `json:"message"`
+}
+
+// Comparison is the difference between a run and its baseline. Cases are named
+// "provider/model/case".
+type Comparison struct {
+	Regressions []Regression `json:"regressions"`
+	// Fixed are the cases that failed in the baseline and pass now.
+	Fixed []string `json:"fixed,omitempty"`
+	// KnownFailures are the cases that failed in the baseline and still fail.
+	KnownFailures []string `json:"known_failures,omitempty"`
+	// Added are the cases the baseline does not have.
+	Added []string `json:"added,omitempty"`
+	// Removed are the baseline's cases that did not run.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// HasRegressions reports whether any case did worse than in the baseline.
+func (c *Comparison) HasRegressions() bool {
+	return len(c.Regressions) > 0
+}
+
+// SaveBaseline writes the run's report to path, for later runs to compare against with
+// WithBaseline or Compare.
+func (tr *TestRunner) SaveBaseline(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create baseline directory: %w", err)
+		}
+	}
+	return writeReportFile(path, tr.Report().WriteJSON)
+}
+
+// LoadBaseline reads a report saved with SaveBaseline or WriteReports.
+func LoadBaseline(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// WithBaseline compares each case with its result in baseline as it runs. A case fails the test
+// only when it regresses: a failure the baseline already had is logged, not raised. Compare
+// returns the full comparison after the run.
+func (tr *TestRunner) WithBaseline(baseline *Report, tolerance Tolerance) *TestRunner {
+	tr.baseline = baseline
+	tr.tolerance = tolerance
+	return tr
+}
+
+// Compare returns how the cases run so far differ from the baseline set with WithBaseline, or
+// nil without one.
+func (tr *TestRunner) Compare() *Comparison {
+	if tr.baseline == nil {
+		return nil
+	}
+	return Compare(tr.baseline, tr.Report(), tr.tolerance)
+}
+
+// Compare returns how current differs from baseline. Cases are matched by provider, model and
+// name; when the baseline ran a case against a single model, the case also matches across
+// models, so a run that switched model or provider is compared too.
+func Compare(baseline, current *Report, tolerance Tolerance) *Comparison {
+	comparison := &Comparison{}
+	matched := make(map[*CaseResult]bool)
+	for _, result := range current.Results {
+		name := result.Label() + "/" + result.Case
+		base := baseline.find(result.Label(), result.Case)
+		if base == nil {
+			comparison.Added = append(comparison.Added, name)
+			continue
+		}
+		matched[base] = true
+		switch {
+		case !base.Passed && !result.Passed:
+			comparison.KnownFailures = append(comparison.KnownFailures, name)
+		case !base.Passed:
+			comparison.Fixed = append(comparison.Fixed, name)
+		}
+		comparison.Regressions = append(comparison.Regressions, caseRegressions(base, &result, tolerance)...)
+	}
+	for i := range baseline.Results {
+		base := &baseline.Results[i]
+		if !matched[base] {
+			comparison.Removed = append(comparison.Removed, base.Label()+"/"+base.Case)
+		}
+	}
+	return comparison
+}
+
+// find returns the result of the case against label, a provider/model, or the case's only
+// result when it ran against a single model, or nil.
+func (r *Report) find(label, name string) *CaseResult {
+	var only *CaseResult
+	count := 0
+	for i := range r.Results {
+		result := &r.Results[i]
+		if result.Case != name {
+			continue
+		}
+		if result.Label() == label {
+			return result
+		}
+		only = result
+		count++
+	}
+	if count == 1 {
+		return only
+	}
+	return nil
+}
+
+// caseRegressions returns the ways current did worse than base.
+func caseRegressions(base, current *CaseResult, tolerance Tolerance) []Regression {
+	regression := func(kind RegressionKind, baseline, now float64, format string, args ...interface{}) Regression {
+		return Regression{
+			Provider: current.Provider,
+			Model:    current.Model,
+			Case:     current.Case,
+			Kind:     kind,
+			Baseline: baseline,
+			Current:  now,
+			Message:  fmt.Sprintf(format, args...),
+		}
+	}
+
+	if base.Passed && !current.Passed {
+		return []Regression{regression(RegressionFailure, 0, 0, "passed in the baseline, fails now: %s", failureReason(current))}
+	}
+	// Latency and cost are only comparable between responses that were both generated.
+	if base.Error != "" || current.Error != "" {
+		return nil
+	}
+
+	var regressions []Regression
+	if tolerance.ScoreDrop >= 0 {
+		baseScore, baseOK := base.meanScore()
+		score, ok := current.meanScore()
+		if baseOK && ok && baseScore-score > tolerance.ScoreDrop {
+			regressions = append(regressions, regression(RegressionScore, baseScore, score,
+				"judge score fell from %.2f to %.2f", baseScore, score))
+		}
+	}
+	if tolerance.LatencyIncrease >= 0 && base.Latency > 0 &&
+		float64(current.Latency) > float64(base.Latency)*(1+tolerance.LatencyIncrease) {
+		regressions = append(regressions, regression(RegressionLatency, milliseconds(base.Latency), milliseconds(current.Latency),
+			"latency rose from %s to %s", base.Latency.Round(time.Millisecond), current.Latency.Round(time.Millisecond)))
+	}
+	if baseTokens, tokens := base.Usage.ComputedTotal(), current.Usage.ComputedTotal(); tolerance.TokenIncrease >= 0 && baseTokens > 0 &&
+		float64(tokens) > float64(baseTokens)*(1+tolerance.TokenIncrease) {
+		regressions = append(regressions, regression(RegressionTokens, float64(baseTokens), float64(tokens),
+			"tokens rose from %d to %d", baseTokens, tokens))
+	}
+	return regressions
+}
+
+// meanScore returns the mean of the result's judge scores, and false when it has none.
+func (r *CaseResult) meanScore() (float64, bool) {
+	var sum float64
+	count := 0
+	for _, v := range r.Validations {
+		if v.Score != nil {
+			sum += *v.Score
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// failureReason summarises why a result failed.
+func failureReason(r *CaseResult) string {
+	if r.Error != "" {
+		return r.Error
+	}
+	var reasons []string
+	for _, v := range r.Validations {
+		if !v.Passed {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", v.Name, v.Message))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// fail fails t for a case that did not pass, unless the baseline shows the case already failing,
+// in which case the failure is only logged.
+func (tr *TestRunner) fail(t Reporter, provider TestProvider, tc *TestCase, format string, args ...interface{}) {
+	t.Helper()
+	if tr.baseline != nil {
+		if base := tr.baseline.find(provider.label(), tc.Name); base != nil && !base.Passed {
+			t.Logf("Known failure: "+format, args...)
+			return
+		}
+	}
+	t.Errorf(format, args...)
+}
+
+// checkBaseline fails t for each way result did worse than its baseline, other than failing,
+// which fail has already raised.
+func (tr *TestRunner) checkBaseline(t Reporter, result CaseResult) {
+	t.Helper()
+	if tr.baseline == nil {
+		return
+	}
+	base := tr.baseline.find(result.Label(), result.Case)
+	if base == nil {
+		return
+	}
+	for _, r := range caseRegressions(base, &result, tr.tolerance) {
+		if r.Kind != RegressionFailure {
+			t.Errorf("Regression (%s): %s", r.Kind, r.Message)
+		}
+	}
+}
+
+// WriteMarkdown writes the comparison as a Markdown summary.
+func (c *Comparison) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Baseline comparison\n\n")
+	if !c.HasRegressions() {
+		b.WriteString("No regressions.\n")
+	} else {
+		fmt.Fprintf(&b, "%d regressions.\n\n", len(c.Regressions))
+		b.WriteString("| Case | Kind | Detail |\n|---|---|---|\n")
+		for _, r := range c.Regressions {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(r.Provider+"/"+r.Model+"/"+r.Case), r.Kind, markdownCell(r.Message))
+		}
+	}
+	for _, section := range []struct {
+		title string
+		cases []string
+	}{
+		{"Fixed", c.Fixed},
+		{"Known failures", c.KnownFailures},
+		{"Added", c.Added},
+		{"Removed", c.Removed},
+	} {
+		if len(section.cases) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", section.title)
+		for _, name := range section.cases {
+			fmt.Fprintf(&b, "- %s\n", markdownCell(name))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,134 @@
+package assess
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/replay"
+	"github.com/teilomillet/gollm/types"
+)
+
+func judged(score float64) []ValidationResult {
+	return []ValidationResult{{Name: "judge", Passed: true, Score: &score}}
+}
+
+func usage(total int) types.TokenUsage {
+	return types.TokenUsage{PromptTokens: total / 2, CompletionTokens: total - total/2, TotalTokens: total}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{Results: []CaseResult{
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "breaks", Passed: true},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Passed: true, Validations: judged(0.9)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "slow", Passed: true, Latency: time.Second},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "verbose", Passed: true, Usage: usage(100)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "steady", Passed: true, Latency: time.Second, Usage: usage(100), Validations: judged(0.8)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "flaky", Passed: false, Error: "timeout"},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "broken", Passed: false},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "retired", Passed: true},
+	}}
+	current := &Report{Results: []CaseResult{
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "breaks", Passed: false, Validations: []ValidationResult{{Name: "contains", Message: `expected response to contain "4"`}}},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Passed: true, Validations: judged(0.6)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "slow", Passed: true, Latency: 3 * time.Second},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "verbose", Passed: true, Usage: usage(200)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "steady", Passed: true, Latency: 1500 * time.Millisecond, Usage: usage(110), Validations: judged(0.75)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "flaky", Passed: true, Latency: time.Second},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "broken", Passed: false},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "new", Passed: false},
+	}}
+
+	comparison := Compare(baseline, current, DefaultTolerance)
+	require.True(t, comparison.HasRegressions())
+	kinds := make(map[string]RegressionKind)
+	for _, r := range comparison.Regressions {
+		kinds[r.Case] = r.Kind
+	}
+	assert.Equal(t, map[string]RegressionKind{
+		"breaks":  RegressionFailure,
+		"tone":    RegressionScore,
+		"slow":    RegressionLatency,
+		"verbose": RegressionTokens,
+	}, kinds)
+	assert.Contains(t, comparison.Regressions[0].Message, `expected response to contain "4"`)
+	assert.Equal(t, []string{"openai/gpt-4o-mini/flaky"}, comparison.Fixed)
+	assert.Equal(t, []string{"openai/gpt-4o-mini/broken"}, comparison.KnownFailures)
+	assert.Equal(t, []string{"openai/gpt-4o-mini/new"}, comparison.Added)
+	assert.Equal(t, []string{"openai/gpt-4o-mini/retired"}, comparison.Removed)
+
+	relaxed := Compare(baseline, current, Tolerance{ScoreDrop: -1, LatencyIncrease: -1, TokenIncrease: -1})
+	require.Len(t, relaxed.Regressions, 1, "a negative tolerance turns its check off")
+	assert.Equal(t, RegressionFailure, relaxed.Regressions[0].Kind)
+
+	var out strings.Builder
+	require.NoError(t, comparison.WriteMarkdown(&out))
+	assert.Contains(t, out.String(), "4 regressions.")
+	assert.Contains(t, out.String(), "| openai/gpt-4o-mini/tone | score_drop | judge score fell from 0.90 to 0.60 |")
+	assert.Contains(t, out.String(), "## Known failures\n\n- openai/gpt-4o-mini/broken")
+}
+
+func TestCompareAcrossProviders(t *testing.T) {
+	baseline := &Report{Results: []CaseResult{{Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Passed: true, Validations: judged(0.9)}}}
+	current := &Report{Results: []CaseResult{{Provider: "anthropic", Model: "claude-3-5-haiku-latest", Case: "tone", Passed: true, Validations: judged(0.5)}}}
+
+	comparison := Compare(baseline, current, DefaultTolerance)
+	require.Len(t, comparison.Regressions, 1)
+	assert.Equal(t, RegressionScore, comparison.Regressions[0].Kind)
+	assert.Equal(t, "anthropic", comparison.Regressions[0].Provider)
+	assert.Empty(t, comparison.Added)
+}
+
+func TestCompareModelsOfOneProvider(t *testing.T) {
+	baseline := &Report{Results: []CaseResult{
+		{Provider: "openai", Model: "gpt-4o", Case: "tone", Passed: true, Validations: judged(0.9)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Passed: true, Validations: judged(0.5)},
+	}}
+	current := &Report{Results: []CaseResult{
+		{Provider: "openai", Model: "gpt-4o", Case: "tone", Passed: true, Validations: judged(0.9)},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Passed: true, Validations: judged(0.5)},
+	}}
+
+	comparison := Compare(baseline, current, DefaultTolerance)
+	assert.False(t, comparison.HasRegressions(), "each model is compared with its own baseline")
+	assert.Empty(t, comparison.Added)
+	assert.Empty(t, comparison.Removed)
+}
+
+func TestBaselineRun(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+	// The baseline records "known bad" as failing, as an earlier run would have.
+	path := filepath.Join(t.TempDir(), "baselines", "openai.json")
+	recorded := &TestRunner{metrics: &TestMetrics{StartTime: time.Now()}, results: []CaseResult{
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "arithmetic", Passed: true, Latency: time.Minute},
+		{Provider: "openai", Model: "gpt-4o-mini", Case: "known bad", Passed: false,
+			Validations: []ValidationResult{{Name: "validation_1", Message: `expected response to contain "5"`}}},
+	}}
+	require.NoError(t, recorded.SaveBaseline(path))
+	baseline, err := LoadBaseline(path)
+	require.NoError(t, err)
+	require.Len(t, baseline.Results, 2)
+	assert.Equal(t, time.Minute, baseline.Results[0].Latency)
+
+	// "known bad" still fails, which is logged rather than failing this test.
+	runner := NewTest(t).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(t.TempDir(), replay.WithMode(replay.ModeRecord), replay.WithTransport(&arithmeticProvider{})).
+		WithBaseline(baseline, DefaultTolerance)
+	runner.AddCase("arithmetic", "What's 2+2?").Validate(ExpectContains("4"))
+	runner.AddCase("known bad", "What's 2+3?").Validate(ExpectContains("5"))
+	runner.Run(context.Background())
+
+	comparison := runner.Compare()
+	require.NotNil(t, comparison)
+	assert.False(t, comparison.HasRegressions())
+	assert.Equal(t, []string{"openai/gpt-4o-mini/known bad"}, comparison.KnownFailures)
+	results := runner.Results()
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed, "the failure is still recorded")
+}
@@ -0,0 +1,345 @@
+package assess
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// DatasetFile is the document read from a YAML dataset (see LoadDataset):
+//
+//	cases:
+//	  - name: capital_of_france
+//	    input: What is the capital of France?
+//	    system_prompt: Answer in one word.
+//	    expected: Paris
+//	    tags: [geography, smoke]
+//	  - name: arithmetic
+//	    input: What's 2+2?
+//	    validators:
+//	      - {name: contains, value: "4"}
+//	      - {name: max_length, value: "50"}
+//
+// A JSONL dataset has one case per line, with the same fields.
+type DatasetFile struct {
+	Cases []DatasetCase `json:"cases" yaml:"cases"`
+}
+
+// DatasetCase describes one case of a dataset. It is the declarative form of a case built with
+// AddCase.
+type DatasetCase struct {
+	// Name identifies the case in results and reports. It defaults to the case's position in the
+	// dataset ("case_3").
+	Name string `json:"name" yaml:"name"`
+
+	// Input is the prompt sent to the model.
+	Input string `json:"input" yaml:"input"`
+
+	SystemPrompt string `json:"system_prompt" yaml:"system_prompt"`
+
+	// Expected is the output the response must equal, ignoring surrounding whitespace.
+	Expected string `json:"expected" yaml:"expected"`
+
+	// Schema is a JSON schema the response must conform to; see TestCase.ExpectSchema.
+	Schema map[string]interface{} `json:"schema" yaml:"schema"`
+
+	// Validators are checks looked up by name among the registered validators; see
+	// RegisterValidator.
+	Validators []ValidatorSpec `json:"validators" yaml:"validators"`
+
+	// Tags label the case in results and reports, and select cases with FilterTags.
+	Tags []string `json:"tags" yaml:"tags"`
+
+	// Timeout is a duration such as "45s"; it defaults to AddCase's.
+	Timeout string `json:"timeout" yaml:"timeout"`
+
+	// Options are set on the client before the case runs; see TestCase.WithOption.
+	Options map[string]interface{} `json:"options" yaml:"options"`
+
+	// Samples is how many times the case runs against each provider; see TestCase.WithSamples.
+	Samples int `json:"samples" yaml:"samples"`
+}
+
+// ValidatorSpec names a registered validator and its argument.
+type ValidatorSpec struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// ValidatorFactory builds a validation from the argument given in a dataset. It returns an error
+// for an argument it cannot use.
+type ValidatorFactory func(value string) (ValidationFunc, error)
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFactory{
+		"contains": func(value string) (ValidationFunc, error) {
+			return ExpectContains(value), nil
+		},
+		"not_contains": func(value string) (ValidationFunc, error) {
+			return ExpectNotContains(value), nil
+		},
+		"matches": func(value string) (ValidationFunc, error) {
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			return ExpectMatches(value), nil
+		},
+		"equals": func(value string) (ValidationFunc, error) {
+			return ExpectEquals(value), nil
+		},
+		"json": func(string) (ValidationFunc, error) {
+			return ExpectJSON(), nil
+		},
+		"max_length": func(value string) (ValidationFunc, error) {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("max_length needs a positive number, got %q", value)
+			}
+			return ExpectMaxLength(n), nil
+		},
+	}
+)
+
+// RegisterValidator makes a validator available to datasets under name, replacing any validator
+// registered under that name. The built-in validators are contains, not_contains, matches,
+// equals, json and max_length.
+func RegisterValidator(name string, factory ValidatorFactory) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = factory
+}
+
+// lookupValidator builds the validation spec names.
+func lookupValidator(spec ValidatorSpec) (ValidationFunc, error) {
+	validatorsMu.RLock()
+	factory, ok := validators[spec.Name]
+	validatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown validator %q", spec.Name)
+	}
+	return factory(spec.Value)
+}
+
+// LoadDataset reads cases from a JSONL file, one case per line, when the name ends in ".jsonl",
+// and from a YAML file otherwise. Unknown fields, unknown validators and cases without input
+// are errors; all problems in the file are reported together.
+func LoadDataset(path string) ([]DatasetCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var cases []DatasetCase
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		cases, err = decodeJSONL(data)
+	} else {
+		var file DatasetFile
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+		cases = file.Cases
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dataset %s: %w", path, err)
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for i := range cases {
+		c := &cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case_%d", i+1)
+		}
+		label := fmt.Sprintf("cases[%d] (%s)", i, c.Name)
+		for _, p := range validateDatasetCase(c) {
+			problems = append(problems, label+": "+p)
+		}
+		if seen[c.Name] {
+			problems = append(problems, label+": duplicate name")
+		}
+		seen[c.Name] = true
+	}
+	if len(cases) == 0 {
+		problems = append(problems, "no cases defined")
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid dataset %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return cases, nil
+}
+
+// decodeJSONL decodes one case per non-blank line.
+func decodeJSONL(data []byte) ([]DatasetCase, error) {
+	var cases []DatasetCase
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var c DatasetCase
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cases = append(cases, c)
+	}
+	return cases, scanner.Err()
+}
+
+// validateDatasetCase returns the problems of a case.
+func validateDatasetCase(c *DatasetCase) []string {
+	var problems []string
+	if strings.TrimSpace(c.Input) == "" {
+		problems = append(problems, "input is required")
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			problems = append(problems, fmt.Sprintf("invalid timeout %q", c.Timeout))
+		}
+	}
+	if c.Samples < 0 {
+		problems = append(problems, fmt.Sprintf("samples must not be negative, got %d", c.Samples))
+	}
+	for _, spec := range c.Validators {
+		if _, err := lookupValidator(spec); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// AddDataset adds the cases of a dataset, as loaded by LoadDataset, to the runner.
+func (tr *TestRunner) AddDataset(cases []DatasetCase) error {
+	var problems []string
+	built := make([]*TestCase, 0, len(cases))
+	for i, c := range cases {
+		tc, err := newDatasetTestCase(c)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("cases[%d] (%s): %v", i, c.Name, err))
+			continue
+		}
+		built = append(built, tc)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid dataset:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	tr.cases = append(tr.cases, built...)
+	return nil
+}
+
+// newDatasetTestCase builds the TestCase a DatasetCase describes.
+func newDatasetTestCase(c DatasetCase) (*TestCase, error) {
+	if problems := validateDatasetCase(&c); len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	tc := &TestCase{
+		Name:         c.Name,
+		Input:        c.Input,
+		SystemPrompt: c.SystemPrompt,
+		Timeout:      30 * time.Second,
+		Tags:         c.Tags,
+		Samples:      c.Samples,
+		options:      make(map[string]interface{}),
+	}
+	if c.Timeout != "" {
+		tc.Timeout, _ = time.ParseDuration(c.Timeout)
+	}
+	if c.Schema != nil {
+		tc.ExpectedSchema = c.Schema
+	}
+	if c.Expected != "" {
+		tc.validateNamed("equals", ExpectEquals(c.Expected))
+	}
+	for _, spec := range c.Validators {
+		fn, _ := lookupValidator(spec)
+		tc.validateNamed(spec.Name, fn)
+	}
+	// Options are applied in a stable order, so runs of the same dataset set them alike.
+	keys := make([]string, 0, len(c.Options))
+	for key := range c.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tc.WithOption(key, c.Options[key])
+	}
+	return tc, nil
+}
+
+// FilterTags keeps only the cases carrying at least one of tags. With no tags, every case is kept.
+func (tr *TestRunner) FilterTags(tags ...string) *TestRunner {
+	if len(tags) == 0 {
+		return tr
+	}
+	want := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		want[tag] = true
+	}
+	kept := tr.cases[:0]
+	for _, tc := range tr.cases {
+		for _, tag := range tc.Tags {
+			if want[tag] {
+				kept = append(kept, tc)
+				break
+			}
+		}
+	}
+	tr.cases = kept
+	return tr
+}
+
+// ExpectNotContains fails when the response contains substr.
+func ExpectNotContains(substr string) ValidationFunc {
+	return func(response string) error {
+		if strings.Contains(response, substr) {
+			return fmt.Errorf("expected response not to contain %q", substr)
+		}
+		return nil
+	}
+}
+
+// ExpectEquals fails unless the response equals expected, ignoring surrounding whitespace.
+func ExpectEquals(expected string) ValidationFunc {
+	return func(response string) error {
+		if strings.TrimSpace(response) != strings.TrimSpace(expected) {
+			return fmt.Errorf("expected response to equal %q", expected)
+		}
+		return nil
+	}
+}
+
+// ExpectJSON fails unless the response is valid JSON.
+func ExpectJSON() ValidationFunc {
+	return func(response string) error {
+		if !json.Valid([]byte(strings.TrimSpace(response))) {
+			return fmt.Errorf("expected response to be valid JSON")
+		}
+		return nil
+	}
+}
+
+// ExpectMaxLength fails when the response is longer than n characters.
+func ExpectMaxLength(n int) ValidationFunc {
+	return func(response string) error {
+		if length := utf8.RuneCountInString(response); length > n {
+			return fmt.Errorf("expected at most %d characters, got %d", n, length)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,235 @@
+package assess
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/replay"
+	"github.com/teilomillet/gollm/types"
+)
+
+func writeDataset(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDataset(t *testing.T) {
+	yamlPath := writeDataset(t, "cases.yaml", `
+cases:
+  - name: arithmetic
+    input: What's 2+2?
+    system_prompt: Answer briefly.
+    tags: [math, smoke]
+    timeout: 10s
+    validators:
+      - {name: contains, value: "4"}
+      - {name: max_length, value: "50"}
+  - input: Reply with the JSON object {"ok":true}.
+    schema:
+      type: object
+      properties:
+        ok: {type: boolean}
+    validators:
+      - {name: json}
+`)
+	jsonlPath := writeDataset(t, "cases.jsonl", `{"name":"arithmetic","input":"What's 2+2?","tags":["math","smoke"],"timeout":"10s","system_prompt":"Answer briefly.","validators":[{"name":"contains","value":"4"},{"name":"max_length","value":"50"}]}
+
+{"input":"Reply with the JSON object {\"ok\":true}.","schema":{"type":"object","properties":{"ok":{"type":"boolean"}}},"validators":[{"name":"json"}]}
+`)
+
+	for _, path := range []string{yamlPath, jsonlPath} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			cases, err := LoadDataset(path)
+			require.NoError(t, err)
+			require.Len(t, cases, 2)
+			assert.Equal(t, "arithmetic", cases[0].Name)
+			assert.Equal(t, []string{"math", "smoke"}, cases[0].Tags)
+			assert.Equal(t, "case_2", cases[1].Name, "unnamed cases are named by position")
+			assert.NotNil(t, cases[1].Schema)
+
+			runner := NewTest(t)
+			require.NoError(t, runner.AddDataset(cases))
+			require.Len(t, runner.cases, 2)
+			tc := runner.cases[0]
+			assert.Equal(t, 10*time.Second, tc.Timeout)
+			assert.Equal(t, "Answer briefly.", tc.SystemPrompt)
+			require.Len(t, tc.Validations, 2)
+			assert.Equal(t, "contains", tc.validationName(0))
+			assert.NoError(t, tc.Validations[0]("2+2 is 4"))
+			assert.Error(t, tc.Validations[1](strings.Repeat("4", 51)))
+			assert.Equal(t, 30*time.Second, runner.cases[1].Timeout)
+
+			runner.FilterTags("smoke")
+			assert.Len(t, runner.cases, 1)
+		})
+	}
+}
+
+func TestLoadDatasetReportsEveryProblem(t *testing.T) {
+	path := writeDataset(t, "broken.yaml", `
+cases:
+  - name: a
+    input: ""
+  - name: a
+    input: hi
+    timeout: soon
+    validators:
+      - {name: sounds_right}
+      - {name: matches, value: "("}
+`)
+	_, err := LoadDataset(path)
+	require.Error(t, err)
+	for _, want := range []string{"input is required", "duplicate name", `invalid timeout "soon"`, `unknown validator "sounds_right"`, "invalid pattern"} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	_, err = LoadDataset(writeDataset(t, "typo.jsonl", `{"input":"hi","expect":"x"}`))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("starts_with", func(value string) (ValidationFunc, error) {
+		return func(response string) error {
+			if !strings.HasPrefix(response, value) {
+				return assert.AnError
+			}
+			return nil
+		}, nil
+	})
+	runner := NewTest(t)
+	require.NoError(t, runner.AddDataset([]DatasetCase{{
+		Name:       "greeting",
+		Input:      "Say hello",
+		Expected:   "Hello!",
+		Validators: []ValidatorSpec{{Name: "starts_with", Value: "Hello"}},
+	}}))
+	tc := runner.cases[0]
+	assert.Equal(t, "equals", tc.validationName(0))
+	assert.Equal(t, "starts_with", tc.validationName(1))
+	assert.NoError(t, tc.Validations[0]("  Hello!\n"))
+	assert.NoError(t, tc.Validations[1]("Hello!"))
+}
+
+func TestDatasetRunWritesReports(t *testing.T) {
+	path := writeDataset(t, "cases.jsonl", `{"name":"arithmetic","input":"What's 2+2?","tags":["math"],"validators":[{"name":"contains","value":"4"}]}
+{"name":"again","input":"And 2+2 once more?","validators":[{"name":"not_contains","value":"5"}]}
+`)
+	cases, err := LoadDataset(path)
+	require.NoError(t, err)
+
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+	runner := NewTest(t).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(t.TempDir(), replay.WithMode(replay.ModeRecord), replay.WithTransport(&arithmeticProvider{}))
+	require.NoError(t, runner.AddDataset(cases))
+	runner.Run(context.Background())
+
+	results := runner.Results()
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, []string{"math"}, results[0].Tags)
+	assert.Equal(t, "gpt-4o-mini", results[0].Model)
+	assert.Equal(t, []ValidationResult{{Name: "contains", Passed: true}}, results[0].Validations)
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, runner.WriteReports(dir))
+	for _, name := range []string{"report.json", "junit.xml", "summary.md"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	require.NoError(t, err)
+	var report struct {
+		Summary struct {
+			Total  int `json:"total"`
+			Passed int `json:"passed"`
+		} `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 2, report.Summary.Total)
+	assert.Equal(t, 2, report.Summary.Passed)
+}
+
+func TestReportFormats(t *testing.T) {
+	score := 0.4
+	report := &Report{StartTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Providers: map[string]ReportSummary{}}
+	report.Results = []CaseResult{
+		{
+			Provider: "openai", Model: "gpt-4o-mini", Case: "arithmetic", Passed: true, Response: "4",
+			Latency: 1500 * time.Millisecond, Usage: types.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+			Validations: []ValidationResult{{Name: "contains", Passed: true}},
+		},
+		{
+			Provider: "openai", Model: "gpt-4o-mini", Case: "tone", Tags: []string{"style"}, Response: "whatever",
+			Latency: 500 * time.Millisecond, JudgeUsage: types.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+			Validations: []ValidationResult{{Name: "judge", Message: "Rude | curt.", Score: &score}},
+		},
+		{Provider: "anthropic", Model: "claude-3-5-haiku-latest", Case: "arithmetic", Error: "rate limited"},
+	}
+	for _, r := range report.Results {
+		report.Summary.add(r)
+		s := report.Providers[r.Label()]
+		s.add(r)
+		report.Providers[r.Label()] = s
+	}
+	assert.Equal(t, ReportSummary{Total: 3, Passed: 1, Failed: 2, Errors: 1, Samples: 3, SamplesPassed: 1, PassedAtK: 1, Latency: 2 * time.Second,
+		Usage:      types.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		JudgeUsage: types.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}, report.Summary)
+
+	var out strings.Builder
+	require.NoError(t, report.WriteJSON(&out))
+	assert.Contains(t, out.String(), `"latency_ms": 1500`)
+	assert.Contains(t, out.String(), `"mean_latency_ms": 1000`)
+
+	out.Reset()
+	require.NoError(t, report.WriteJUnit(&out))
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Errors   int `xml:"errors,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Time    string `xml:"time,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				Error *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"error"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(out.String()), &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Errors)
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, "anthropic/claude-3-5-haiku-latest", suites.Suites[0].Name)
+	assert.Equal(t, "rate limited", suites.Suites[0].Cases[0].Error.Message)
+	openai := suites.Suites[1]
+	assert.Equal(t, "1.500", openai.Cases[0].Time)
+	assert.Nil(t, openai.Cases[0].Failure)
+	assert.Equal(t, "judge: Rude | curt.", openai.Cases[1].Failure.Message)
+
+	out.Reset()
+	require.NoError(t, report.WriteMarkdown(&out))
+	markdown := out.String()
+	assert.Contains(t, markdown, "1 of 3 cases passed, 1 could not be generated")
+	assert.Contains(t, markdown, "| openai/gpt-4o-mini | 1 | 1 | 0 | 1s | 12 | 120 |")
+	assert.Contains(t, markdown, "- **openai/gpt-4o-mini / tone** (style)")
+	assert.Contains(t, markdown, `judge: Rude \| curt.`)
+	assert.Contains(t, markdown, "error: rate limited")
+}
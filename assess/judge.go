@@ -0,0 +1,164 @@
+package assess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+// judgeSystemPrompt tells the judge model how to grade.
+const judgeSystemPrompt = `You grade a response to a task against a rubric. ` +
+	`Score how well the response meets the rubric, from 0 (not at all) to 1 (fully), ` +
+	`and explain the score in one or two sentences. Judge only what the rubric asks about.`
+
+// judgeSchema is the shape of a judge's verdict.
+var judgeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"score":     map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+		"rationale": map[string]interface{}{"type": "string"},
+	},
+	"required":             []interface{}{"score", "rationale"},
+	"additionalProperties": false,
+}
+
+// Judge grades responses with a model of its own, for what substring and pattern checks cannot
+// tell: whether an answer is correct, faithful to its context or on-tone. Attach it to cases with
+// TestCase.ExpectJudged. One Judge can serve many cases and runners, concurrently.
+type Judge struct {
+	client llm.LLM
+}
+
+// JudgeVerdict is a judge's structured answer.
+type JudgeVerdict struct {
+	// Score is how well the response meets the rubric, from 0 to 1.
+	Score float64 `json:"score"`
+	// Rationale explains the score.
+	Rationale string `json:"rationale"`
+}
+
+// JudgeResult records one judgement of a case's response.
+type JudgeResult struct {
+	Provider  string
+	Case      string
+	Rubric    string
+	Threshold float64
+	// Verdict is the judge's score and rationale; zero when Err is set.
+	Verdict JudgeVerdict
+	// Passed reports whether the score reached the threshold.
+	Passed bool
+	// Usage is what the judge spent, kept apart from the usage of the model under test.
+	Usage types.TokenUsage
+	// Err is set when the judge could not be asked or gave no valid verdict.
+	Err error
+}
+
+// judgement is a judge attached to a case.
+type judgement struct {
+	judge     *Judge
+	rubric    string
+	threshold float64
+}
+
+// NewJudge builds a judge from the options of its client, which is separate from the clients of
+// the providers under test:
+//
+//	judge, err := assess.NewJudge(
+//		gollm.SetProvider("openai"),
+//		gollm.SetModel("gpt-4o"),
+//		gollm.SetAPIKey(os.Getenv("OPENAI_API_KEY")),
+//	)
+func NewJudge(opts ...gollm.ConfigOption) (*Judge, error) {
+	client, err := gollm.NewLLM(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create judge client: %w", err)
+	}
+	return &Judge{client: client}, nil
+}
+
+// Evaluate asks the judge to score response, given as an answer to input, against rubric.
+func (j *Judge) Evaluate(ctx context.Context, input, response, rubric string) (JudgeVerdict, types.TokenUsage, error) {
+	prompt := j.client.NewPrompt(judgePrompt(input, response, rubric))
+	prompt.SystemPrompt = judgeSystemPrompt
+
+	text, details, err := j.client.GenerateWithSchemaAndUsage(ctx, prompt, judgeSchema)
+	var usage types.TokenUsage
+	if details != nil {
+		usage = details.TokenUsage
+	}
+	if err != nil {
+		return JudgeVerdict{}, usage, fmt.Errorf("judge failed: %w", err)
+	}
+	var verdict JudgeVerdict
+	if err := json.Unmarshal([]byte(text), &verdict); err != nil {
+		return JudgeVerdict{}, usage, fmt.Errorf("judge returned an invalid verdict: %w", err)
+	}
+	return verdict, usage, nil
+}
+
+// judgePrompt lays out what the judge grades, each part delimited so the response cannot pass
+// itself off as the rubric.
+func judgePrompt(input, response, rubric string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<rubric>\n%s\n</rubric>\n\n", rubric)
+	fmt.Fprintf(&b, "<task>\n%s\n</task>\n\n", input)
+	fmt.Fprintf(&b, "<response>\n%s\n</response>", response)
+	return b.String()
+}
+
+// ExpectJudged has judge score the case's response against rubric, and fails the case when the
+// score is below threshold (from 0 to 1). The judge sees the case input along with the response.
+func (tc *TestCase) ExpectJudged(judge *Judge, rubric string, threshold float64) *TestCase {
+	tc.judgements = append(tc.judgements, judgement{judge: judge, rubric: rubric, threshold: threshold})
+	return tc
+}
+
+// JudgeResults returns the judgements made so far, in the order they were made.
+func (tr *TestRunner) JudgeResults() []JudgeResult {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]JudgeResult{}, tr.judgeResults...)
+}
+
+// runJudgements has the case's judges grade the response, records their results, adds them to
+// the case's result and fails t for every judgement that does not pass.
+func (tr *TestRunner) runJudgements(ctx context.Context, t Reporter, provider TestProvider, tc *TestCase, caseResult *CaseResult) {
+	for _, j := range tc.judgements {
+		verdict, usage, err := j.judge.Evaluate(ctx, tc.Input, caseResult.Response, j.rubric)
+		result := JudgeResult{
+			Provider:  provider.Name,
+			Case:      tc.Name,
+			Rubric:    j.rubric,
+			Threshold: j.threshold,
+			Verdict:   verdict,
+			Passed:    err == nil && verdict.Score >= j.threshold,
+			Usage:     usage,
+			Err:       err,
+		}
+		tr.mu.Lock()
+		tr.judgeResults = append(tr.judgeResults, result)
+		tr.mu.Unlock()
+
+		outcome := ValidationResult{Name: "judge", Passed: result.Passed, Message: verdict.Rationale}
+		caseResult.JudgeUsage = caseResult.JudgeUsage.Add(usage)
+		switch {
+		case err != nil:
+			tr.fail(t, provider, tc, "Judgement failed: %v", err)
+			outcome.Message = err.Error()
+		case !result.Passed:
+			tr.fail(t, provider, tc, "Judge scored %.2f, below %.2f: %s", verdict.Score, j.threshold, verdict.Rationale)
+		default:
+			t.Logf("Judge scored %.2f: %s", verdict.Score, verdict.Rationale)
+		}
+		if err == nil {
+			score := verdict.Score
+			outcome.Score = &score
+		}
+		caseResult.Validations = append(caseResult.Validations, outcome)
+	}
+}
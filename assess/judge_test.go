@@ -0,0 +1,86 @@
+package assess
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/gollmtest"
+	"github.com/teilomillet/gollm/replay"
+	"github.com/teilomillet/gollm/types"
+)
+
+func newTestJudge(t *testing.T, srv *gollmtest.Server) *Judge {
+	t.Helper()
+	judge, err := NewJudge(
+		gollm.SetProvider("anthropic"),
+		gollm.SetModel("judge-model"),
+		gollm.SetAPIKey("sk-ant-REDACTED"),
+		gollm.SetBaseURL(srv.URL()),
+		gollm.SetMaxRetries(0),
+		gollm.SetLogLevel(gollm.LogLevelOff),
+	)
+	require.NoError(t, err)
+	return judge
+}
+
+func TestJudgedCase(t *testing.T) {
+	srv := gollmtest.NewServer(gollmtest.ServeResponses(
+		gollmtest.JSON(JudgeVerdict{Score: 0.9, Rationale: "Correct and concise."}).WithUsage(120, 15),
+	))
+	defer srv.Close()
+	judge := newTestJudge(t, srv)
+
+	var mu sync.Mutex
+	var observed []string
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+	runner := NewTest(t).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(t.TempDir(), replay.WithMode(replay.ModeRecord), replay.WithTransport(&arithmeticProvider{})).
+		WithUsageObserver(func(_ context.Context, event types.UsageEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed = append(observed, event.Model)
+		})
+	runner.AddCase("basic math", "What's 2+2?").
+		ExpectJudged(judge, "The answer states that 2+2 is 4.", 0.7)
+	runner.Run(context.Background())
+
+	results := runner.JudgeResults()
+	require.Len(t, results, 1)
+	result := results[0]
+	assert.Equal(t, "openai", result.Provider)
+	assert.Equal(t, "basic math", result.Case)
+	assert.True(t, result.Passed)
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 0.9, result.Verdict.Score)
+	assert.Equal(t, "Correct and concise.", result.Verdict.Rationale)
+	assert.Equal(t, 135, result.Usage.TotalTokens)
+	assert.Equal(t, []string{"gpt-4o-mini"}, observed, "the judge's usage is not the suite's")
+
+	request, ok := srv.LastRequest()
+	require.True(t, ok)
+	assert.Contains(t, request.Prompt, "What's 2+2?")
+	assert.Contains(t, request.Prompt, "2+2 is 4")
+	assert.Contains(t, request.Prompt, "The answer states that 2+2 is 4.")
+}
+
+func TestJudgeEvaluate(t *testing.T) {
+	srv := gollmtest.NewServer(gollmtest.ServeResponses(
+		gollmtest.JSON(JudgeVerdict{Score: 0.2, Rationale: "Off-topic."}),
+		gollmtest.Text("I think it is fine."),
+	))
+	defer srv.Close()
+	judge := newTestJudge(t, srv)
+
+	verdict, _, err := judge.Evaluate(context.Background(), "Summarise the report.", "Cats are great.", "Faithful to the report.")
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, verdict.Score)
+
+	_, _, err = judge.Evaluate(context.Background(), "Summarise the report.", "Cats are great.", "Faithful to the report.")
+	assert.Error(t, err, "a verdict that is not JSON is an error, not a pass")
+}
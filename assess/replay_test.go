@@ -0,0 +1,60 @@
+package assess
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/replay"
+)
+
+// arithmeticProvider answers every chat completion with "4".
+type arithmeticProvider struct {
+	calls atomic.Int32
+}
+
+func (a *arithmeticProvider) RoundTrip(*http.Request) (*http.Response, error) {
+	a.calls.Add(1)
+	rec := httptest.NewRecorder()
+	_, _ = rec.WriteString(`{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"2+2 is 4"}}]}`)
+	return rec.Result(), nil
+}
+
+func TestRunnerReplay(t *testing.T) {
+	dir := t.TempDir()
+	upstream := &arithmeticProvider{}
+
+	// Record once, as a developer with an API key would.
+	t.Setenv("OPENAI_API_KEY", "sk-recording-key-0123456789abcdef")
+	recording := NewTest(t).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(dir, replay.WithMode(replay.ModeRecord), replay.WithTransport(upstream))
+	recording.AddCase("basic math", "What's 2+2?").Validate(ExpectContains("4"))
+	recording.Run(context.Background())
+	require.EqualValues(t, 1, upstream.calls.Load())
+
+	cassette := filepath.Join(dir, "openai", "gpt-4o-mini", "basic_math.json")
+	data, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-recording-key-0123456789abcdef")
+
+	// Replay, as CI does, without an API key.
+	t.Setenv("OPENAI_API_KEY", "")
+	replaying := NewTest(t).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(dir, replay.WithMode(replay.ModeReplay))
+	require.True(t, replaying.HasAvailableProviders(), "replay needs no API key")
+	replaying.AddCase("basic math", "What's 2+2?").Validate(ExpectContains("4"))
+	replaying.Run(context.Background())
+
+	assert.EqualValues(t, 1, upstream.calls.Load(), "the provider is not called again")
+	assert.Len(t, replaying.metrics.ResponseTimes["openai/gpt-4o-mini"], 1)
+	assert.Empty(t, replaying.metrics.Errors["openai/gpt-4o-mini"])
+}
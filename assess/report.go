@@ -0,0 +1,488 @@
+package assess
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/teilomillet/gollm/types"
+)
+
+// Report is a machine-readable account of a run, for archiving and reading in CI. Build it with
+// TestRunner.Report and write it with WriteJSON, WriteJUnit or WriteMarkdown, or all three with
+// WriteReports.
+type Report struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Summary totals the results overall, and Providers per provider and model, keyed by
+	// CaseResult.Label.
+	Summary   ReportSummary            `json:"summary"`
+	Providers map[string]ReportSummary `json:"providers"`
+	Results   []CaseResult             `json:"results"`
+}
+
+// ReportSummary totals a set of case results.
+type ReportSummary struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// Errors counts the cases whose response could not be generated; they are also Failed.
+	Errors int `json:"errors"`
+	// Samples counts the responses sampled, SamplesPassed those that passed, PassedAtK the cases
+	// with at least one passing sample and Inconsistent the cases whose samples gave different
+	// answers. A case run once is one sample; see TestCase.WithSamples.
+	Samples       int `json:"samples"`
+	SamplesPassed int `json:"samples_passed"`
+	PassedAtK     int `json:"passed_at_k"`
+	Inconsistent  int `json:"inconsistent"`
+	// Latency is the sum of the cases' latencies.
+	Latency    time.Duration    `json:"-"`
+	Usage      types.TokenUsage `json:"usage"`
+	JudgeUsage types.TokenUsage `json:"judge_usage"`
+}
+
+// MarshalJSON writes the latency in milliseconds, as latency_ms, with the mean as
+// mean_latency_ms.
+func (s ReportSummary) MarshalJSON() ([]byte, error) {
+	type plain ReportSummary
+	return json.Marshal(struct {
+		plain
+		LatencyMS     float64 `json:"latency_ms"`
+		MeanLatencyMS float64 `json:"mean_latency_ms"`
+	}{plain(s), milliseconds(s.Latency), milliseconds(s.MeanLatency())})
+}
+
+// UnmarshalJSON reads a summary written by MarshalJSON.
+func (s *ReportSummary) UnmarshalJSON(data []byte) error {
+	type plain ReportSummary
+	var decoded struct {
+		plain
+		LatencyMS float64 `json:"latency_ms"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*s = ReportSummary(decoded.plain)
+	s.Latency = time.Duration(decoded.LatencyMS * float64(time.Millisecond))
+	return nil
+}
+
+// MeanLatency returns the mean latency of the cases, zero when there are none.
+func (s ReportSummary) MeanLatency() time.Duration {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Total)
+}
+
+// PassRate returns the fraction of the cases that passed, zero when there are none.
+func (s ReportSummary) PassRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Total)
+}
+
+// PassAt1 returns the fraction of samples that passed, across the cases: the chance that a case
+// passes when run once.
+func (s ReportSummary) PassAt1() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.SamplesPassed) / float64(s.Samples)
+}
+
+// PassAtK returns the fraction of cases with at least one passing sample.
+func (s ReportSummary) PassAtK() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.PassedAtK) / float64(s.Total)
+}
+
+func (s *ReportSummary) add(r CaseResult) {
+	s.Total++
+	if r.Passed {
+		s.Passed++
+	} else {
+		s.Failed++
+	}
+	if r.Sampling != nil {
+		s.Samples += r.Sampling.Samples
+		s.SamplesPassed += r.Sampling.Passed
+		if r.Sampling.PassAtK {
+			s.PassedAtK++
+		}
+		if r.Sampling.Distinct > 1 {
+			s.Inconsistent++
+		}
+	} else {
+		s.Samples++
+		if r.Passed {
+			s.SamplesPassed++
+			s.PassedAtK++
+		}
+	}
+	if r.Error != "" {
+		s.Errors++
+	}
+	s.Latency += r.Latency
+	s.Usage = s.Usage.Add(r.Usage)
+	s.JudgeUsage = s.JudgeUsage.Add(r.JudgeUsage)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Report returns a report of the cases run so far.
+func (tr *TestRunner) Report() *Report {
+	results := tr.Results()
+	report := &Report{
+		StartTime: tr.metrics.StartTime,
+		EndTime:   time.Now(),
+		Providers: make(map[string]ReportSummary),
+		Results:   results,
+	}
+	for _, r := range results {
+		report.Summary.add(r)
+		summary := report.Providers[r.Label()]
+		summary.add(r)
+		report.Providers[r.Label()] = summary
+	}
+	return report
+}
+
+// providerNames returns the provider/model labels of the report in order.
+func (r *Report) providerNames() []string {
+	names := make([]string, 0, len(r.Providers))
+	for name := range r.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sampled reports whether any case of the report ran more than once.
+func (r *Report) sampled() bool {
+	for _, result := range r.Results {
+		if result.Sampling != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report as JUnit XML, one test suite per provider and model. A case whose response
+// could not be generated is an error; one that failed a validation or judgement is a failure.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{
+		Tests:    r.Summary.Total,
+		Failures: r.Summary.Failed - r.Summary.Errors,
+		Errors:   r.Summary.Errors,
+		Time:     seconds(r.Summary.Latency),
+	}
+	for _, provider := range r.providerNames() {
+		summary := r.Providers[provider]
+		suite := junitTestSuite{
+			Name:      provider,
+			Tests:     summary.Total,
+			Failures:  summary.Failed - summary.Errors,
+			Errors:    summary.Errors,
+			Time:      seconds(summary.Latency),
+			Timestamp: r.StartTime.UTC().Format("2006-01-02T15:04:05"),
+		}
+		for _, result := range r.Results {
+			if result.Label() != provider {
+				continue
+			}
+			suite.Cases = append(suite.Cases, junitCase(result))
+		}
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitCase(result CaseResult) junitTestCase {
+	tc := junitTestCase{
+		Name:      result.Case,
+		Classname: result.Provider + "." + result.Model,
+		Time:      seconds(result.Latency),
+		SystemOut: result.Response,
+		Properties: []junitProperty{
+			{Name: "prompt_tokens", Value: fmt.Sprint(result.Usage.PromptTokens)},
+			{Name: "completion_tokens", Value: fmt.Sprint(result.Usage.CompletionTokens)},
+			{Name: "total_tokens", Value: fmt.Sprint(result.Usage.ComputedTotal())},
+		},
+	}
+	if len(result.Tags) > 0 {
+		tc.Properties = append(tc.Properties, junitProperty{Name: "tags", Value: strings.Join(result.Tags, ",")})
+	}
+	if !result.JudgeUsage.IsZero() {
+		tc.Properties = append(tc.Properties, junitProperty{Name: "judge_total_tokens", Value: fmt.Sprint(result.JudgeUsage.ComputedTotal())})
+	}
+	for _, v := range result.Validations {
+		if v.Score != nil {
+			tc.Properties = append(tc.Properties, junitProperty{Name: v.Name + "_score", Value: fmt.Sprintf("%.2f", *v.Score)})
+		}
+	}
+	if st := result.Sampling; st != nil {
+		tc.Properties = append(tc.Properties,
+			junitProperty{Name: "samples", Value: fmt.Sprint(st.Samples)},
+			junitProperty{Name: "samples_passed", Value: fmt.Sprint(st.Passed)},
+			junitProperty{Name: "pass_at_1", Value: fmt.Sprintf("%.2f", st.PassAt1)},
+			junitProperty{Name: "distinct_answers", Value: fmt.Sprint(st.Distinct)},
+		)
+	}
+
+	if result.Error != "" {
+		tc.Error = &junitMessage{Message: result.Error, Type: "generation", Text: result.Error}
+		return tc
+	}
+	var failed []string
+	for _, v := range result.Validations {
+		if !v.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", v.Name, v.Message))
+		}
+	}
+	if len(failed) > 0 {
+		tc.Failure = &junitMessage{Message: failed[0], Type: "validation", Text: strings.Join(failed, "\n")}
+	}
+	return tc
+}
+
+// WriteMarkdown writes a Markdown summary of the report: totals per provider and model, then the cases
+// that failed and why.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Evaluation report\n\n")
+	fmt.Fprintf(&b, "%d of %d cases passed", r.Summary.Passed, r.Summary.Total)
+	if r.Summary.Errors > 0 {
+		fmt.Fprintf(&b, ", %d could not be generated", r.Summary.Errors)
+	}
+	fmt.Fprintf(&b, ". Run started %s.\n\n", r.StartTime.UTC().Format(time.RFC3339))
+
+	b.WriteString("| Provider | Passed | Failed | Errors | Mean latency | Tokens | Judge tokens |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, provider := range r.providerNames() {
+		s := r.Providers[provider]
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %s | %d | %d |\n", markdownCell(provider), s.Passed, s.Failed, s.Errors,
+			s.MeanLatency().Round(time.Millisecond), s.Usage.ComputedTotal(), s.JudgeUsage.ComputedTotal())
+	}
+
+	if r.sampled() {
+		b.WriteString("\n## Sampling\n\n")
+		b.WriteString("| Provider | Samples | pass@1 | pass@k | Majority | Inconsistent cases |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+		for _, provider := range r.providerNames() {
+			s := r.Providers[provider]
+			fmt.Fprintf(&b, "| %s | %d | %.1f%% | %.1f%% | %.1f%% | %d |\n", markdownCell(provider), s.Samples,
+				s.PassAt1()*100, s.PassAtK()*100, s.PassRate()*100, s.Inconsistent)
+		}
+	}
+
+	var failures []CaseResult
+	for _, result := range r.Results {
+		if !result.Passed {
+			failures = append(failures, result)
+		}
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n## Failures\n\n")
+		for _, result := range failures {
+			fmt.Fprintf(&b, "- **%s / %s**", markdownCell(result.Label()), markdownCell(result.Case))
+			if len(result.Tags) > 0 {
+				fmt.Fprintf(&b, " (%s)", markdownCell(strings.Join(result.Tags, ", ")))
+			}
+			b.WriteString("\n")
+			if result.Error != "" {
+				fmt.Fprintf(&b, "  - error: %s\n", markdownCell(result.Error))
+			}
+			if st := result.Sampling; st != nil {
+				fmt.Fprintf(&b, "  - %d of %d samples passed, %d distinct answers\n", st.Passed, st.Samples, st.Distinct)
+			}
+			for _, v := range result.Validations {
+				if !v.Passed {
+					fmt.Fprintf(&b, "  - %s: %s\n", markdownCell(v.Name), markdownCell(v.Message))
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTable writes the report as a plain-text table for a terminal: a row per case with a
+// column per provider and model, then each one's pass rate, mean latency and tokens. When cases
+// were sampled, it adds pass@1, pass@k and the number of cases whose samples disagreed.
+func (r *Report) WriteTable(w io.Writer) error {
+	providers := r.providerNames()
+	outcomes := make(map[string]map[string]string)
+	var cases []string
+	for _, result := range r.Results {
+		if outcomes[result.Case] == nil {
+			outcomes[result.Case] = make(map[string]string)
+			cases = append(cases, result.Case)
+		}
+		var outcome string
+		switch {
+		case result.Error != "":
+			outcome = "ERROR"
+		case !result.Passed:
+			outcome = "FAIL"
+		default:
+			outcome = "pass"
+		}
+		if st := result.Sampling; st != nil {
+			outcome += fmt.Sprintf(" (%d/%d)", st.Passed, st.Samples)
+		}
+		outcomes[result.Case][result.Label()] = outcome
+	}
+	sort.Strings(cases)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(label string, cell func(provider string) string) {
+		fmt.Fprint(tw, label)
+		for _, provider := range providers {
+			fmt.Fprint(tw, "\t"+cell(provider))
+		}
+		fmt.Fprintln(tw)
+	}
+	row("CASE", func(provider string) string { return provider })
+	for _, name := range cases {
+		row(name, func(provider string) string {
+			if outcome, ok := outcomes[name][provider]; ok {
+				return outcome
+			}
+			return "-"
+		})
+	}
+	row("pass rate", func(provider string) string {
+		s := r.Providers[provider]
+		return fmt.Sprintf("%.1f%% (%d/%d)", s.PassRate()*100, s.Passed, s.Total)
+	})
+	if r.sampled() {
+		row("pass@1", func(provider string) string {
+			return fmt.Sprintf("%.1f%%", r.Providers[provider].PassAt1()*100)
+		})
+		row("pass@k", func(provider string) string {
+			return fmt.Sprintf("%.1f%%", r.Providers[provider].PassAtK()*100)
+		})
+		row("inconsistent", func(provider string) string {
+			return fmt.Sprint(r.Providers[provider].Inconsistent)
+		})
+	}
+	row("mean latency", func(provider string) string {
+		return r.Providers[provider].MeanLatency().Round(time.Millisecond).String()
+	})
+	row("tokens", func(provider string) string {
+		return fmt.Sprint(r.Providers[provider].Usage.ComputedTotal())
+	})
+	return tw.Flush()
+}
+
+// markdownCell keeps text on one line and out of the table's column separators.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// WriteReports writes the run's report to dir as report.json, junit.xml and summary.md, creating
+// dir if needed.
+func (tr *TestRunner) WriteReports(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	report := tr.Report()
+	for name, write := range map[string]func(io.Writer) error{
+		"report.json": report.WriteJSON,
+		"junit.xml":   report.WriteJUnit,
+		"summary.md":  report.WriteMarkdown,
+	} {
+		if err := writeReportFile(filepath.Join(dir, name), write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReportFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
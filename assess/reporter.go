@@ -0,0 +1,92 @@
+package assess
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Reporter receives a run's log lines and failures. *testing.T is a Reporter; NewRunner runs
+// without one, for evaluations outside go test.
+type Reporter interface {
+	Helper()
+	Log(args ...interface{})
+	Logf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// NewRunner returns a runner that needs no *testing.T. Log lines and failures go to reporter,
+// which NewWriterReporter can provide; a nil reporter discards them. Whether the cases passed is
+// in Results and Report.
+func NewRunner(reporter Reporter) *TestRunner {
+	if reporter == nil {
+		reporter = NewWriterReporter(io.Discard, false)
+	}
+	return newRunner(reporter)
+}
+
+// NewWriterReporter returns a Reporter that writes failures to w, one per line and prefixed with
+// the provider and case. Log lines are written too when verbose is set. It is safe for the
+// concurrent cases of RunBatch.
+func NewWriterReporter(w io.Writer, verbose bool) Reporter {
+	return &writerReporter{mu: &sync.Mutex{}, w: w, verbose: verbose}
+}
+
+type writerReporter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	verbose bool
+	name    string
+}
+
+func (r *writerReporter) Helper() {}
+
+func (r *writerReporter) Log(args ...interface{}) {
+	if r.verbose {
+		r.write("", strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	}
+}
+
+func (r *writerReporter) Logf(format string, args ...interface{}) {
+	if r.verbose {
+		r.write("", fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *writerReporter) Errorf(format string, args ...interface{}) {
+	r.write("FAIL ", fmt.Sprintf(format, args...))
+}
+
+func (r *writerReporter) write(level, msg string) {
+	if r.name != "" {
+		msg = r.name + ": " + msg
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintln(r.w, level+msg)
+}
+
+// sub returns a reporter for the named part of the run, sharing r's writer.
+func (r *writerReporter) sub(name string) *writerReporter {
+	child := *r
+	if child.name != "" {
+		name = child.name + "/" + name
+	}
+	child.name = name
+	return &child
+}
+
+// subtest runs fn as the named part of the run: a subtest under go test, otherwise under a
+// reporter that names it.
+func subtest(t Reporter, name string, fn func(t Reporter)) {
+	switch parent := t.(type) {
+	case *testing.T:
+		parent.Run(name, func(t *testing.T) { fn(t) })
+	case *writerReporter:
+		fn(parent.sub(name))
+	default:
+		fn(t)
+	}
+}
@@ -0,0 +1,47 @@
+package assess
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/replay"
+)
+
+func TestRunnerWithoutTesting(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+	var out strings.Builder
+	runner := NewRunner(NewWriterReporter(&out, false)).
+		WithProvider("openai", "gpt-4o-mini").
+		WithReplay(t.TempDir(), replay.WithMode(replay.ModeRecord), replay.WithTransport(&arithmeticProvider{})).
+		WithBatchConfig(BatchTestConfig{MaxParallel: 2})
+	runner.AddCase("arithmetic", "What's 2+2?").Validate(ExpectContains("4"))
+	runner.AddCase("wrong", "What's 2+3?").Validate(ExpectContains("5"))
+	runner.RunBatch(context.Background())
+
+	report := runner.Report()
+	assert.Equal(t, 2, report.Summary.Total)
+	assert.Equal(t, 1, report.Summary.Passed)
+	assert.InDelta(t, 0.5, report.Providers["openai/gpt-4o-mini"].PassRate(), 1e-9)
+	assert.Equal(t, "FAIL openai/gpt-4o-mini/wrong: Validation failed: expected response to contain \"5\"\n", out.String(),
+		"only failures are written when not verbose")
+
+	var table strings.Builder
+	require.NoError(t, report.WriteTable(&table))
+	assert.Regexp(t, `CASE\s+openai/gpt-4o-mini`, table.String())
+	assert.Regexp(t, `arithmetic\s+pass`, table.String())
+	assert.Regexp(t, `wrong\s+FAIL`, table.String())
+	assert.Regexp(t, `pass rate\s+50.0% \(1/2\)`, table.String())
+}
+
+func TestWriterReporterVerbose(t *testing.T) {
+	var out strings.Builder
+	runner := NewRunner(NewWriterReporter(&out, true))
+	runner.Run(context.Background())
+	assert.Contains(t, out.String(), "No providers available")
+
+	assert.NotPanics(t, func() { NewRunner(nil).Run(context.Background()) })
+}
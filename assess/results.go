@@ -0,0 +1,164 @@
+package assess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+// CaseResult is the outcome of one case run against one provider.
+type CaseResult struct {
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	Case     string   `json:"case"`
+	Tags     []string `json:"tags,omitempty"`
+	// Passed reports whether the response was generated and passed every validation and
+	// judgement.
+	Passed bool `json:"passed"`
+	// Error is why the response could not be generated; empty when it was.
+	Error    string `json:"error,omitempty"`
+	Response string `json:"response"`
+	// Latency is how long the response took to generate, retries included.
+	Latency time.Duration `json:"-"`
+	// Usage is what the model under test spent on the case.
+	Usage types.TokenUsage `json:"usage"`
+	// JudgeUsage is what the case's judges spent grading the response.
+	JudgeUsage  types.TokenUsage   `json:"judge_usage"`
+	Validations []ValidationResult `json:"validations,omitempty"`
+	// Sampling summarises the samples of a case run more than once, and Samples holds each
+	// sample's own result; see WithSamples. Both are empty for a case run once.
+	Sampling *SampleStats `json:"sampling,omitempty"`
+	Samples  []CaseResult `json:"samples,omitempty"`
+}
+
+// MarshalJSON writes the latency in milliseconds, as latency_ms.
+func (r CaseResult) MarshalJSON() ([]byte, error) {
+	type plain CaseResult
+	return json.Marshal(struct {
+		plain
+		LatencyMS float64 `json:"latency_ms"`
+	}{plain(r), float64(r.Latency) / float64(time.Millisecond)})
+}
+
+// UnmarshalJSON reads a result written by MarshalJSON.
+func (r *CaseResult) UnmarshalJSON(data []byte) error {
+	type plain CaseResult
+	var decoded struct {
+		plain
+		LatencyMS float64 `json:"latency_ms"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = CaseResult(decoded.plain)
+	r.Latency = time.Duration(decoded.LatencyMS * float64(time.Millisecond))
+	return nil
+}
+
+// Label names the result's provider and model, as "provider/model"; reports group results by it.
+func (r CaseResult) Label() string {
+	return r.Provider + "/" + r.Model
+}
+
+// ValidationResult is the outcome of one validation or judgement of a response.
+type ValidationResult struct {
+	// Name is the validator's name, "judge" for a judgement.
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Message is why the validation failed, or the judge's rationale.
+	Message string `json:"message,omitempty"`
+	// Score is the judge's score; nil for other validations.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// Results returns the outcome of every case run so far, in the order they finished.
+func (tr *TestRunner) Results() []CaseResult {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]CaseResult{}, tr.results...)
+}
+
+func (tr *TestRunner) recordResult(result CaseResult) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.results = append(tr.results, result)
+}
+
+// generateCase sends the case's prompt, with its schema when it has one, and returns the
+// response with its usage.
+func generateCase(ctx context.Context, client llm.LLM, prompt *llm.Prompt, tc *TestCase) (string, types.TokenUsage, error) {
+	var response string
+	var details *types.ResponseDetails
+	var err error
+	if tc.ExpectedSchema != nil {
+		response, details, err = client.GenerateWithSchemaAndUsage(ctx, prompt, tc.ExpectedSchema)
+	} else {
+		response, details, err = client.GenerateWithUsage(ctx, prompt)
+	}
+	var usage types.TokenUsage
+	if details != nil {
+		usage = details.TokenUsage
+	}
+	return response, usage, err
+}
+
+// newCaseResult starts the result of tc against provider from its generation.
+func newCaseResult(provider TestProvider, tc *TestCase, response string, usage types.TokenUsage, latency time.Duration, err error) CaseResult {
+	result := CaseResult{
+		Provider: provider.Name,
+		Model:    provider.Model,
+		Case:     tc.Name,
+		Tags:     tc.Tags,
+		Passed:   err == nil,
+		Response: response,
+		Latency:  latency,
+		Usage:    usage,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// check runs the case's validations and judgements on the response, adds their outcomes to
+// result and fails t for each that does not pass, unless the baseline knows the case fails.
+func (tr *TestRunner) check(ctx context.Context, t Reporter, provider TestProvider, tc *TestCase, result *CaseResult) {
+	for i, validate := range tc.Validations {
+		outcome := ValidationResult{Name: tc.validationName(i), Passed: true}
+		if err := validate(result.Response); err != nil {
+			tr.fail(t, provider, tc, "Validation failed: %v", err)
+			outcome.Passed = false
+			outcome.Message = err.Error()
+		}
+		result.Validations = append(result.Validations, outcome)
+	}
+	tr.runJudgements(ctx, t, provider, tc, result)
+
+	for _, outcome := range result.Validations {
+		if !outcome.Passed {
+			result.Passed = false
+		}
+	}
+}
+
+// validationName returns the name of the validation at index i, for results and reports.
+func (tc *TestCase) validationName(i int) string {
+	if i < len(tc.validationNames) && tc.validationNames[i] != "" {
+		return tc.validationNames[i]
+	}
+	return fmt.Sprintf("validation_%d", i+1)
+}
+
+// validateNamed adds a validation reported under name.
+func (tc *TestCase) validateNamed(name string, fn ValidationFunc) *TestCase {
+	for len(tc.validationNames) < len(tc.Validations) {
+		tc.validationNames = append(tc.validationNames, "")
+	}
+	tc.Validations = append(tc.Validations, fn)
+	tc.validationNames = append(tc.validationNames, name)
+	return tc
+}
@@ -0,0 +1,184 @@
+package assess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SampleStats summarises the samples of a case run more than once; see WithSamples.
+type SampleStats struct {
+	Samples int `json:"samples"`
+	// Passed counts the samples that were generated and passed every validation and judgement.
+	Passed int `json:"passed"`
+	// PassAt1 is the fraction of samples that passed: the chance that a single sample passes.
+	PassAt1 float64 `json:"pass_at_1"`
+	// PassAtK reports whether at least one of the samples passed.
+	PassAtK bool `json:"pass_at_k"`
+	// Majority is the most common answer, normalised (see normalizeAnswer), and MajorityPassed
+	// whether it passed. A tie goes to the answer given first.
+	Majority       string `json:"majority"`
+	MajorityPassed bool   `json:"majority_passed"`
+	// Distinct is how many different normalised answers the samples gave; 1 means they agreed.
+	Distinct int `json:"distinct"`
+}
+
+// WithSamples runs the case n times against each provider. Every sample is validated and judged.
+// The case passes when its majority answer does; its result carries the majority sample's
+// response and validations, the mean latency, the total usage and SampleStats.
+func (tc *TestCase) WithSamples(n int) *TestCase {
+	tc.Samples = n
+	return tc
+}
+
+// sampleCount returns how many times the case runs against each provider.
+func (tc *TestCase) sampleCount() int {
+	if tc.Samples < 1 {
+		return 1
+	}
+	return tc.Samples
+}
+
+// sampleReporter returns where a sample of tc reports: t when the case runs once, otherwise
+// nowhere, as finishCase reports on the samples together.
+func sampleReporter(t Reporter, tc *TestCase) Reporter {
+	if tc.sampleCount() == 1 {
+		return t
+	}
+	return NewWriterReporter(io.Discard, false)
+}
+
+// normalizeAnswer is the form in which answers are compared for the majority vote: lower case,
+// with runs of white space collapsed and trailing full stops dropped.
+func normalizeAnswer(response string) string {
+	answer := strings.Join(strings.Fields(strings.ToLower(response)), " ")
+	return strings.TrimRight(answer, ".")
+}
+
+// aggregateSamples combines the samples of one case into its result.
+func aggregateSamples(samples []CaseResult) CaseResult {
+	stats := &SampleStats{Samples: len(samples)}
+	counts := make(map[string]int)
+	var order []string
+	first := make(map[string]int)
+	var latency time.Duration
+	generated := 0
+	for i, s := range samples {
+		if s.Passed {
+			stats.Passed++
+		}
+		if s.Error != "" {
+			continue
+		}
+		generated++
+		latency += s.Latency
+		answer := normalizeAnswer(s.Response)
+		if _, seen := counts[answer]; !seen {
+			order = append(order, answer)
+			first[answer] = i
+		}
+		counts[answer]++
+	}
+	stats.PassAt1 = float64(stats.Passed) / float64(len(samples))
+	stats.PassAtK = stats.Passed > 0
+	stats.Distinct = len(order)
+
+	// Without a generated sample, the case's result is the first sample's error.
+	result := samples[0]
+	best := 0
+	for _, answer := range order {
+		if counts[answer] > best {
+			best = counts[answer]
+			stats.Majority = answer
+		}
+	}
+	if generated > 0 {
+		result = samples[first[stats.Majority]]
+		stats.MajorityPassed = result.Passed
+		result.Latency = latency / time.Duration(generated)
+	}
+
+	result.Usage = samples[0].Usage
+	result.JudgeUsage = samples[0].JudgeUsage
+	for _, s := range samples[1:] {
+		result.Usage = result.Usage.Add(s.Usage)
+		result.JudgeUsage = result.JudgeUsage.Add(s.JudgeUsage)
+	}
+	result.Passed = stats.MajorityPassed
+	result.Samples = samples
+	result.Sampling = stats
+	return result
+}
+
+// finishCase reports the case's samples to t, compares the case with the baseline and records
+// its result.
+func (tr *TestRunner) finishCase(t Reporter, provider TestProvider, tc *TestCase, samples []CaseResult) CaseResult {
+	result := samples[0]
+	if len(samples) > 1 {
+		result = aggregateSamples(samples)
+		s := result.Sampling
+		t.Logf("%d of %d samples passed, %d distinct answers", s.Passed, s.Samples, s.Distinct)
+		switch {
+		case result.Error != "":
+			tr.fail(t, provider, tc, "No sample could be generated: %s", result.Error)
+		case !result.Passed:
+			tr.fail(t, provider, tc, "Majority answer failed (%d of %d samples passed): %s", s.Passed, s.Samples, failureReason(&result))
+		}
+	}
+	tr.checkBaseline(t, result)
+	tr.recordResult(result)
+	return result
+}
+
+// batchSlots bounds how many requests a batch run has in flight and how fast they start. Every
+// sample takes a slot, so a case with samples shares MaxParallel and the rate limit with the rest.
+type batchSlots struct {
+	semaphore chan struct{}
+	limiter   *rate.Limiter
+
+	mu            sync.Mutex
+	current       int
+	maxConcurrent int
+}
+
+func newBatchSlots(cfg *BatchTestConfig) *batchSlots {
+	return &batchSlots{semaphore: make(chan struct{}, cfg.MaxParallel), limiter: cfg.RateLimit}
+}
+
+// acquire waits for a slot, then for the rate limiter, and returns the function that frees the
+// slot.
+func (s *batchSlots) acquire(ctx context.Context) (func(), error) {
+	s.semaphore <- struct{}{}
+	s.mu.Lock()
+	s.current++
+	if s.current > s.maxConcurrent {
+		s.maxConcurrent = s.current
+	}
+	s.mu.Unlock()
+
+	release := func() {
+		s.mu.Lock()
+		s.current--
+		s.mu.Unlock()
+		<-s.semaphore
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			release()
+			return nil, fmt.Errorf("rate limit wait failed: %w", err)
+		}
+	}
+	return release, nil
+}
+
+// max returns the most slots held at once.
+func (s *batchSlots) max() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxConcurrent
+}
@@ -0,0 +1,109 @@
+package assess
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/gollmtest"
+)
+
+func TestAggregateSamples(t *testing.T) {
+	samples := []CaseResult{
+		{Case: "capital", Response: "Lyon", Latency: 3 * time.Second, Usage: usage(10)},
+		{Case: "capital", Passed: true, Response: "Paris", Latency: time.Second, Usage: usage(10)},
+		{Case: "capital", Error: "timeout", Usage: usage(0)},
+		{Case: "capital", Passed: true, Response: " paris. ", Latency: 2 * time.Second, Usage: usage(10)},
+	}
+
+	result := aggregateSamples(samples)
+	require.NotNil(t, result.Sampling)
+	assert.Equal(t, SampleStats{
+		Samples:        4,
+		Passed:         2,
+		PassAt1:        0.5,
+		PassAtK:        true,
+		Majority:       "paris",
+		MajorityPassed: true,
+		Distinct:       2,
+	}, *result.Sampling)
+	assert.True(t, result.Passed)
+	assert.Equal(t, "Paris", result.Response, "the result carries the first majority sample")
+	assert.Equal(t, 2*time.Second, result.Latency, "latency is the mean of the generated samples")
+	assert.Equal(t, 30, result.Usage.TotalTokens)
+	assert.Len(t, result.Samples, 4)
+
+	failed := aggregateSamples([]CaseResult{{Error: "timeout"}, {Error: "refused"}})
+	assert.False(t, failed.Passed)
+	assert.Equal(t, "timeout", failed.Error)
+	assert.Zero(t, failed.Sampling.Distinct)
+}
+
+func TestSampledBatchRun(t *testing.T) {
+	var inFlight, maxInFlight, calls atomic.Int32
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.Prompt == "Say 4." {
+			return gollmtest.Text("4")
+		}
+		// Every third answer to the sampled case is wrong.
+		if calls.Add(1)%3 == 0 {
+			return gollmtest.Text("2+2 is 5")
+		}
+		return gollmtest.Text("2+2 is 4")
+	}))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	runner := NewRunner(nil).
+		WithProvider("openai", "gpt-4o-mini").
+		WithBatchConfig(BatchTestConfig{MaxParallel: 2})
+	runner.AddCase("arithmetic", "What's 2+2?").WithSamples(6).Validate(ExpectContains("4"))
+	runner.AddCase("once", "Say 4.").Validate(ExpectContains("4"))
+	runner.RunBatch(context.Background())
+
+	assert.EqualValues(t, 6, calls.Load(), "every sample is generated")
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2), "samples share MaxParallel")
+
+	report := runner.Report()
+	var sampled CaseResult
+	for _, r := range report.Results {
+		if r.Case == "arithmetic" {
+			sampled = r
+		}
+	}
+	require.NotNil(t, sampled.Sampling)
+	assert.Equal(t, 6, sampled.Sampling.Samples)
+	assert.Equal(t, 2, sampled.Sampling.Distinct)
+	assert.True(t, sampled.Passed, "the majority answer passes")
+	assert.Len(t, sampled.Samples, 6)
+	for _, s := range sampled.Samples {
+		assert.Len(t, s.Validations, 1, "every sample is validated")
+	}
+
+	summary := report.Providers["openai/gpt-4o-mini"]
+	assert.Equal(t, 7, summary.Samples)
+	assert.Equal(t, 1, summary.Inconsistent)
+	assert.Equal(t, 1.0, summary.PassAtK())
+
+	var table, markdown strings.Builder
+	require.NoError(t, report.WriteTable(&table))
+	assert.Regexp(t, `arithmetic\s+pass \(\d/6\)`, table.String())
+	assert.Regexp(t, `inconsistent\s+1`, table.String())
+	require.NoError(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "## Sampling")
+}
@@ -0,0 +1,246 @@
+// Package audio provides speech-to-text (Transcribe) and text-to-speech (Speak) over the
+// OpenAI-compatible /audio/transcriptions and /audio/speech endpoints. Besides OpenAI itself,
+// local servers such as whisper.cpp's server, faster-whisper-server and LocalAI implement the
+// same endpoints, so one Client covers hosted and self-hosted audio models.
+//
+// Chat models that take audio input or answer with speech are handled by the regular LLM
+// client instead: see llm.WithInputAudio and config.SetAudioOutput.
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Default models and voice used when a request leaves them empty.
+const (
+	DefaultTranscriptionModel = "whisper-1"
+	DefaultSpeechModel        = "gpt-4o-mini-tts"
+	DefaultVoice              = "alloy"
+)
+
+// Client calls an OpenAI-compatible audio API.
+type Client struct {
+	// BaseURL is the API base including any version prefix, e.g. "https://api.openai.com/v1"
+	// or "http://localhost:8080/v1".
+	BaseURL string
+	// APIKey is sent as a bearer token. An empty key sends no auth header, which suits local servers.
+	APIKey string
+	// Headers are added to every request.
+	Headers map[string]string
+	// TranscriptionsPath is appended to BaseURL for Transcribe. Defaults to "/audio/transcriptions";
+	// whisper.cpp's server uses "/inference" unless started with --inference-path.
+	TranscriptionsPath string
+	// SpeechPath is appended to BaseURL for Speak. Defaults to "/audio/speech".
+	SpeechPath string
+	// HTTPClient sends the requests. Defaults to a client with a 120 second timeout, since
+	// transcribing long recordings is slow.
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client for the audio API at baseURL.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{BaseURL: baseURL, APIKey: apiKey}
+}
+
+// NewOpenAIClient creates a client for OpenAI's audio API.
+func NewOpenAIClient(apiKey string) *Client {
+	return NewClient("https://api.openai.com/v1", apiKey)
+}
+
+// TranscriptionRequest describes audio to transcribe.
+type TranscriptionRequest struct {
+	// Audio is the encoded audio file (wav, mp3, m4a, webm, …).
+	Audio []byte
+	// Filename tells the server the container format by its extension. Defaults to "audio.wav".
+	Filename string
+	// Model defaults to DefaultTranscriptionModel. Local servers generally ignore it.
+	Model string
+	// Language is an optional ISO-639-1 hint such as "en", which improves accuracy and latency.
+	Language string
+	// Prompt is optional text to guide style or spell out uncommon words.
+	Prompt string
+	// Temperature is the sampling temperature; zero leaves the server default.
+	Temperature float64
+	// ResponseFormat is "json" (default), "verbose_json" for segments and duration, or "text",
+	// "srt" and "vtt" for plain-text bodies returned verbatim in Transcription.Text.
+	ResponseFormat string
+}
+
+// Transcription is the result of Transcribe.
+type Transcription struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language,omitempty"` // Detected language (verbose_json only)
+	Duration float64   `json:"duration,omitempty"` // Audio duration in seconds (verbose_json only)
+	Segments []Segment `json:"segments,omitempty"` // Timed segments (verbose_json only)
+}
+
+// Segment is a timed span of a verbose transcription.
+type Segment struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"` // Start time in seconds
+	End   float64 `json:"end"`   // End time in seconds
+	Text  string  `json:"text"`
+}
+
+// SpeechRequest describes text to synthesize.
+type SpeechRequest struct {
+	// Input is the text to speak.
+	Input string
+	// Model defaults to DefaultSpeechModel.
+	Model string
+	// Voice defaults to DefaultVoice.
+	Voice string
+	// Format is the audio format: "mp3" (default), "opus", "aac", "flac", "wav", or "pcm".
+	Format string
+	// Speed is the playback speed from 0.25 to 4.0; zero leaves the server default.
+	Speed float64
+	// Instructions steer tone and delivery on models that support it (gpt-4o-mini-tts).
+	Instructions string
+}
+
+// Transcribe converts speech to text.
+func (c *Client) Transcribe(ctx context.Context, req TranscriptionRequest) (*Transcription, error) {
+	if len(req.Audio) == 0 {
+		return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, "audio is required", nil)
+	}
+	filename := req.Filename
+	if filename == "" {
+		filename = "audio.wav"
+	}
+	model := req.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fields := [][2]string{
+		{"model", model},
+		{"language", req.Language},
+		{"prompt", req.Prompt},
+		{"response_format", req.ResponseFormat},
+	}
+	if req.Temperature != 0 {
+		fields = append(fields, [2]string{"temperature", strconv.FormatFloat(req.Temperature, 'f', -1, 64)})
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build transcription request", err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build transcription request", err)
+	}
+	if _, err := fw.Write(req.Audio); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build transcription request", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build transcription request", err)
+	}
+
+	respBody, err := c.do(ctx, c.path(c.TranscriptionsPath, "/audio/transcriptions"), form.Bytes(), mw.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.ResponseFormat {
+	case "text", "srt", "vtt":
+		return &Transcription{Text: string(respBody)}, nil
+	}
+	var t Transcription
+	if err := json.Unmarshal(respBody, &t); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse transcription response", err)
+	}
+	t.Text = strings.TrimSpace(t.Text)
+	return &t, nil
+}
+
+// Speak converts text to speech and returns the encoded audio.
+func (c *Client) Speak(ctx context.Context, req SpeechRequest) ([]byte, error) {
+	if req.Input == "" {
+		return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, "input text is required", nil)
+	}
+	body := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+		"voice": req.Voice,
+	}
+	if req.Model == "" {
+		body["model"] = DefaultSpeechModel
+	}
+	if req.Voice == "" {
+		body["voice"] = DefaultVoice
+	}
+	if req.Format != "" {
+		body["response_format"] = req.Format
+	}
+	if req.Speed != 0 {
+		body["speed"] = req.Speed
+	}
+	if req.Instructions != "" {
+		body["instructions"] = req.Instructions
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to encode speech request", err)
+	}
+	return c.do(ctx, c.path(c.SpeechPath, "/audio/speech"), encoded, "application/json")
+}
+
+// path joins BaseURL with the configured path or its default.
+func (c *Client) path(configured, fallback string) string {
+	if configured == "" {
+		configured = fallback
+	}
+	return strings.TrimSuffix(c.BaseURL, "/") + "/" + strings.TrimPrefix(configured, "/")
+}
+
+// do posts body to url and returns the response body, turning a non-200 status into the
+// classified LLMError the rest of the library returns.
+func (c *Client) do(ctx context.Context, url string, body []byte, contentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to create audio request", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 120 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, fmt.Sprintf("failed to send audio request to %s", url), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to read audio response", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, llm.NewHTTPStatusError(resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,115 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+func TestTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "fr", r.FormValue("language"))
+		assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+		assert.Empty(t, r.FormValue("temperature"))
+
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "memo.mp3", header.Filename)
+		assert.Equal(t, "ID3", string(data))
+
+		_, _ = w.Write([]byte(`{"text":" Bonjour. ","language":"french","duration":1.5,
+			"segments":[{"id":0,"start":0,"end":1.5,"text":" Bonjour."}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/v1/", "test-key")
+	got, err := c.Transcribe(context.Background(), TranscriptionRequest{
+		Audio:          []byte("ID3"),
+		Filename:       "memo.mp3",
+		Language:       "fr",
+		ResponseFormat: "verbose_json",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour.", got.Text)
+	assert.Equal(t, "french", got.Language)
+	assert.Equal(t, 1.5, got.Duration)
+	require.Len(t, got.Segments, 1)
+	assert.Equal(t, 1.5, got.Segments[0].End)
+}
+
+// TestTranscribeWhisperCpp covers a local whisper.cpp server: no key, its own inference path,
+// and a plain-text response format.
+func TestTranscribeWhisperCpp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/inference", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("hello world\n"))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "")
+	c.TranscriptionsPath = "/inference"
+	got, err := c.Transcribe(context.Background(), TranscriptionRequest{Audio: []byte("RIFF"), ResponseFormat: "text"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\n", got.Text)
+}
+
+func TestSpeak(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/audio/speech", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = w.Write([]byte("RIFFdata"))
+	}))
+	defer server.Close()
+
+	audio, err := NewClient(server.URL+"/v1", "k").Speak(context.Background(), SpeechRequest{
+		Input:  "Hello there",
+		Format: "wav",
+		Speed:  1.25,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFFdata"), audio)
+	assert.Equal(t, map[string]interface{}{
+		"model":           DefaultSpeechModel,
+		"input":           "Hello there",
+		"voice":           DefaultVoice,
+		"response_format": "wav",
+		"speed":           1.25,
+	}, gotBody)
+}
+
+func TestAudioErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "bad")
+	var llmErr *llm.LLMError
+
+	_, err := c.Speak(context.Background(), SpeechRequest{Input: "hi"})
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, llm.ErrorTypeAuthentication, llmErr.Type)
+
+	_, err = c.Transcribe(context.Background(), TranscriptionRequest{})
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, llm.ErrorTypeInvalidInput, llmErr.Type)
+}
@@ -0,0 +1,172 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// anthropicBackend implements Anthropic Message Batches: the request params are posted inline,
+// and once processing ends the results are streamed as JSONL from the batch's results_url.
+type anthropicBackend struct{}
+
+type anthropicBatch struct {
+	ID               string `json:"id"`
+	ProcessingStatus string `json:"processing_status"`
+	ResultsURL       string `json:"results_url"`
+	CancelInitiated  string `json:"cancel_initiated_at"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+type anthropicResultLine struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string          `json:"type"` // succeeded, errored, canceled, expired
+		Message json.RawMessage `json:"message"`
+		Error   *struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error"`
+	} `json:"result"`
+}
+
+func (anthropicBackend) submit(ctx context.Context, c *Client, customIDs []string, bodies [][]byte) (*Job, error) {
+	requests := make([]map[string]interface{}, len(bodies))
+	for i, body := range bodies {
+		requests[i] = map[string]interface{}{
+			"custom_id": customIDs[i],
+			"params":    json.RawMessage(body),
+		}
+	}
+	createBody, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to encode batch", err)
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, c.baseURL()+"/v1/messages/batches", createBody, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	if err := applyAnthropicBatch(job, respBody); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (anthropicBackend) refresh(ctx context.Context, c *Client, job *Job) error {
+	respBody, err := c.do(ctx, http.MethodGet, c.baseURL()+"/v1/messages/batches/"+job.ID, nil, "")
+	if err != nil {
+		return err
+	}
+	return applyAnthropicBatch(job, respBody)
+}
+
+func (anthropicBackend) cancel(ctx context.Context, c *Client, job *Job) error {
+	respBody, err := c.do(ctx, http.MethodPost, c.baseURL()+"/v1/messages/batches/"+job.ID+"/cancel", nil, "")
+	if err != nil {
+		return err
+	}
+	return applyAnthropicBatch(job, respBody)
+}
+
+func (anthropicBackend) results(ctx context.Context, c *Client, job *Job) ([]rawResult, error) {
+	target := job.outputRef
+	if target == "" {
+		target = c.baseURL() + "/v1/messages/batches/" + job.ID + "/results"
+	}
+	content, err := c.do(ctx, http.MethodGet, target, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []rawResult
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var l anthropicResultLine
+		if err := json.Unmarshal(line, &l); err != nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse batch result line", err)
+		}
+		r := rawResult{customID: l.CustomID}
+		switch l.Result.Type {
+		case "succeeded":
+			r.statusCode = http.StatusOK
+			r.body = append([]byte(nil), l.Result.Message...)
+		case "errored":
+			errType, msg := "api_error", "request errored"
+			if l.Result.Error != nil {
+				errType, msg = l.Result.Error.Error.Type, l.Result.Error.Error.Message
+			}
+			r.err = llm.NewLLMError(anthropicErrorType(errType), fmt.Sprintf("%s: %s", errType, msg), nil)
+		default: // canceled, expired
+			r.err = llm.NewLLMError(llm.ErrorTypeAPI, fmt.Sprintf("request %s", l.Result.Type), nil)
+		}
+		results = append(results, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to read batch results", err)
+	}
+	return results, nil
+}
+
+// applyAnthropicBatch copies a message batch object onto job. Anthropic reports only
+// in_progress, canceling and ended, so the terminal status is inferred from the counts.
+func applyAnthropicBatch(job *Job, body []byte) error {
+	var b anthropicBatch
+	if err := json.Unmarshal(body, &b); err != nil {
+		return llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse batch", err)
+	}
+	counts := b.RequestCounts
+	job.ID = b.ID
+	job.ProviderStatus = b.ProcessingStatus
+	job.outputRef = b.ResultsURL
+	job.Counts = Counts{
+		Total:     counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired,
+		Succeeded: counts.Succeeded,
+		Failed:    counts.Errored + counts.Canceled + counts.Expired,
+	}
+
+	switch {
+	case b.ProcessingStatus != "ended":
+		job.Status = StatusInProgress
+	case b.CancelInitiated != "" || counts.Canceled > 0:
+		job.Status = StatusCancelled
+	case counts.Expired > 0:
+		job.Status = StatusExpired
+	default:
+		job.Status = StatusCompleted
+	}
+	return nil
+}
+
+// anthropicErrorType maps an Anthropic error type to the closest ErrorType.
+func anthropicErrorType(errType string) llm.ErrorType {
+	switch errType {
+	case "invalid_request_error", "request_too_large":
+		return llm.ErrorTypeInvalidInput
+	case "authentication_error", "permission_error":
+		return llm.ErrorTypeAuthentication
+	case "rate_limit_error":
+		return llm.ErrorTypeRateLimit
+	default:
+		return llm.ErrorTypeAPI
+	}
+}
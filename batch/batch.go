@@ -0,0 +1,505 @@
+// Package batch submits prompts through the providers' asynchronous batch APIs — OpenAI Batch
+// (a JSONL file of /v1/chat/completions or /v1/responses requests) and Anthropic Message Batches.
+// Batched requests are billed at roughly half the synchronous price in exchange for a completion
+// window of up to 24 hours, which suits offline jobs such as evaluations, backfills and bulk
+// extraction.
+//
+// Request bodies are built by the same provider Prepare* builders the synchronous client uses, and
+// results are parsed by the same ParseResponseWithUsage parsers, so a prompt produces the same
+// request and the same ResponseDetails whichever way it is sent. Every billed item is reported to
+// the configured UsageObserver with ServiceTier "batch".
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// ServiceTier is the tier reported on every UsageEvent the package emits. Providers do not always
+// echo it per item, but every item in a batch is billed at batch rates.
+const ServiceTier = "batch"
+
+// Status is the provider-independent state of a batch job.
+type Status string
+
+const (
+	// StatusInProgress covers validation, processing and finalization.
+	StatusInProgress Status = "in_progress"
+	// StatusCompleted means every request has a result (successful or not).
+	StatusCompleted Status = "completed"
+	// StatusFailed means the batch was rejected as a whole, e.g. a malformed input file.
+	StatusFailed Status = "failed"
+	// StatusExpired means the completion window elapsed. Finished requests still have results.
+	StatusExpired Status = "expired"
+	// StatusCancelled means the batch was cancelled. Finished requests still have results.
+	StatusCancelled Status = "cancelled"
+)
+
+// Done reports whether the status is terminal.
+func (s Status) Done() bool {
+	return s != StatusInProgress && s != ""
+}
+
+// Request is one prompt in a batch.
+type Request struct {
+	// CustomID identifies the request in the results. It must be unique within the batch; an
+	// empty ID is replaced with "request-<index>".
+	CustomID string
+	// Prompt is converted with the provider's Prepare* builders, exactly as Generate would.
+	Prompt *llm.Prompt
+	// Schema, when set, requests structured output and validates each result against it.
+	Schema interface{}
+	// Options are per-request provider options (temperature, max_tokens, …) layered over the
+	// configuration defaults.
+	Options map[string]interface{}
+}
+
+// Counts tallies the requests of a job as reported by the provider.
+type Counts struct {
+	Total     int
+	Succeeded int
+	Failed    int
+}
+
+// Job is a submitted batch. It can be persisted by ID and recovered later with Client.Retrieve,
+// though a recovered job no longer knows the schemas its requests asked for and returns results
+// unvalidated.
+type Job struct {
+	// ID is the provider's batch ID.
+	ID string
+	// Provider is the provider name the job was submitted through.
+	Provider string
+	// Status is the normalized job state; ProviderStatus is the provider's own value.
+	Status         Status
+	ProviderStatus string
+	// Counts are the provider's request counts at the last poll.
+	Counts Counts
+	// Error describes why a failed job failed, when the provider says.
+	Error string
+
+	// outputRef and errorRef locate the results: OpenAI output and error file IDs, or the
+	// Anthropic results URL (errorRef unused).
+	outputRef string
+	errorRef  string
+	// order and schemas come from Submit; both are empty on a job recovered with Retrieve.
+	order   []string
+	schemas map[string]interface{}
+}
+
+// Result is the outcome of one request.
+type Result struct {
+	CustomID string
+	// Text is the parsed response text, as Generate would return it.
+	Text string
+	// Details carries token usage, tool calls and the rest of the parsed response.
+	Details *types.ResponseDetails
+	// Err is set when the request failed, its response could not be parsed, or it did not match
+	// the request's schema. It is an *llm.LLMError.
+	Err error
+}
+
+// rawResult is a backend's view of one result before provider parsing.
+type rawResult struct {
+	customID   string
+	statusCode int
+	body       []byte
+	err        error
+}
+
+// backend is one provider's batch API.
+type backend interface {
+	submit(ctx context.Context, c *Client, customIDs []string, bodies [][]byte) (*Job, error)
+	refresh(ctx context.Context, c *Client, job *Job) error
+	results(ctx context.Context, c *Client, job *Job) ([]rawResult, error)
+	cancel(ctx context.Context, c *Client, job *Job) error
+}
+
+// Client submits and collects batches for one provider and model.
+type Client struct {
+	// PollInterval is the delay before the first status check in Wait. It doubles after every
+	// check up to MaxPollInterval. Defaults to 10 seconds and 5 minutes.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// BaseURL overrides the API root (scheme and host) the batch endpoints are resolved against.
+	// It defaults to the root of the provider's own endpoint.
+	BaseURL string
+	// CompletionWindow is the OpenAI completion window. Defaults to "24h", the only value the
+	// API currently accepts.
+	CompletionWindow string
+
+	cfg        *config.Config
+	provider   providers.Provider
+	backend    backend
+	httpClient *http.Client
+	logger     utils.Logger
+}
+
+// NewClient builds a batch client from the same configuration options as gollm.NewLLM. Only the
+// "openai", "openai-responses" and "anthropic" providers have batch APIs; any other provider
+// returns an ErrorTypeUnsupported error.
+func NewClient(opts ...config.ConfigOption) (*Client, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	config.ApplyOptions(cfg, opts...)
+
+	if cfg.APIKeys == nil {
+		cfg.APIKeys = make(map[string]string)
+	}
+	if cfg.Provider == "openai-responses" && cfg.APIKeys["openai-responses"] == "" {
+		cfg.APIKeys["openai-responses"] = cfg.APIKeys["openai"]
+	}
+
+	var b backend
+	switch cfg.Provider {
+	case "openai", "openai-responses":
+		b = openAIBackend{}
+	case "anthropic":
+		b = anthropicBackend{}
+	default:
+		return nil, llm.NewLLMError(llm.ErrorTypeUnsupported, fmt.Sprintf("provider %q has no batch API", cfg.Provider), nil)
+	}
+
+	apiKey := cfg.APIKeys[cfg.Provider]
+	if apiKey == "" {
+		return nil, llm.NewLLMError(llm.ErrorTypeAuthentication, "empty API key", nil)
+	}
+
+	provider, err := providers.GetDefaultRegistry().Get(cfg.Provider, apiKey, cfg.Model, cfg.ExtraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	provider.SetDefaultOptions(cfg)
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = utils.NewLogger(cfg.LogLevel)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &Client{
+		PollInterval:     10 * time.Second,
+		MaxPollInterval:  5 * time.Minute,
+		CompletionWindow: "24h",
+		cfg:              cfg,
+		provider:         provider,
+		backend:          b,
+		httpClient:       httpClient,
+		logger:           logger,
+	}, nil
+}
+
+// Provider returns the provider whose request builders and parsers the client uses.
+func (c *Client) Provider() providers.Provider {
+	return c.provider
+}
+
+// Submit builds one request body per Request and creates the batch. Nothing is billed until the
+// provider processes the batch, so a Submit error costs nothing.
+func (c *Client) Submit(ctx context.Context, requests []Request) (*Job, error) {
+	if len(requests) == 0 {
+		return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, "batch has no requests", nil)
+	}
+
+	customIDs := make([]string, len(requests))
+	bodies := make([][]byte, len(requests))
+	schemas := make(map[string]interface{})
+	seen := make(map[string]bool, len(requests))
+	for i, req := range requests {
+		id := req.CustomID
+		if id == "" {
+			id = fmt.Sprintf("request-%d", i)
+		}
+		if seen[id] {
+			return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, fmt.Sprintf("duplicate custom ID %q", id), nil)
+		}
+		seen[id] = true
+		if req.Prompt == nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, fmt.Sprintf("request %q has no prompt", id), nil)
+		}
+
+		body, err := llm.PrepareRequestBody(c.provider, req.Prompt, req.Options, req.Schema)
+		if err != nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeRequest, fmt.Sprintf("failed to prepare request %q", id), err)
+		}
+		customIDs[i] = id
+		bodies[i] = body
+		if req.Schema != nil {
+			schemas[id] = req.Schema
+		}
+	}
+
+	job, err := c.backend.submit(ctx, c, customIDs, bodies)
+	if err != nil {
+		return nil, err
+	}
+	job.Provider = c.provider.Name()
+	job.order = customIDs
+	job.schemas = schemas
+	c.logger.Info("Batch submitted", "provider", job.Provider, "batch_id", job.ID, "requests", len(requests))
+	return job, nil
+}
+
+// Retrieve fetches the current state of a batch by ID, e.g. one submitted by an earlier process.
+func (c *Client) Retrieve(ctx context.Context, id string) (*Job, error) {
+	job := &Job{ID: id, Provider: c.provider.Name()}
+	if err := c.backend.refresh(ctx, c, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Refresh updates job with the provider's current state.
+func (c *Client) Refresh(ctx context.Context, job *Job) error {
+	return c.backend.refresh(ctx, c, job)
+}
+
+// Wait polls the job with exponential backoff until it reaches a terminal status or ctx is done.
+// A failed job is returned as an error; expired and cancelled jobs are not, since their finished
+// requests still have results.
+func (c *Client) Wait(ctx context.Context, job *Job) error {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		if err := c.backend.refresh(ctx, c, job); err != nil {
+			return err
+		}
+		c.logger.Debug("Batch status", "batch_id", job.ID, "status", job.ProviderStatus,
+			"succeeded", job.Counts.Succeeded, "failed", job.Counts.Failed, "total", job.Counts.Total)
+		if job.Status.Done() {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if c.MaxPollInterval > 0 && interval > c.MaxPollInterval {
+			interval = c.MaxPollInterval
+		}
+	}
+
+	if job.Status == StatusFailed {
+		msg := "batch failed"
+		if job.Error != "" {
+			msg = "batch failed: " + job.Error
+		}
+		return llm.NewLLMError(llm.ErrorTypeAPI, msg, nil)
+	}
+	return nil
+}
+
+// Cancel asks the provider to stop processing the job. Requests already finished are still billed
+// and still have results.
+func (c *Client) Cancel(ctx context.Context, job *Job) error {
+	return c.backend.cancel(ctx, c, job)
+}
+
+// Results downloads and parses the results of a finished job, reporting one UsageEvent per billed
+// item. Results come back in submission order when the job was created by this client's Submit,
+// with a Result carrying an error for any request the provider returned nothing for; otherwise
+// they come back in the provider's order.
+func (c *Client) Results(ctx context.Context, job *Job) ([]Result, error) {
+	if !job.Status.Done() {
+		return nil, llm.NewLLMError(llm.ErrorTypeInvalidInput, fmt.Sprintf("batch %s is still %s", job.ID, job.ProviderStatus), nil)
+	}
+
+	raw, err := c.backend.results(ctx, c, job)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Result, len(raw))
+	var providerOrder []string
+	for _, r := range raw {
+		if _, dup := byID[r.customID]; !dup {
+			providerOrder = append(providerOrder, r.customID)
+		}
+		byID[r.customID] = c.parseResult(ctx, job, r)
+	}
+
+	order := job.order
+	if len(order) == 0 {
+		order = providerOrder
+	}
+	results := make([]Result, 0, len(order))
+	for _, id := range order {
+		result, ok := byID[id]
+		if !ok {
+			result = Result{CustomID: id, Err: llm.NewLLMError(llm.ErrorTypeResponse, fmt.Sprintf("no result for request %q (batch %s)", id, job.Status), nil)}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Run submits requests, waits for the batch to finish and returns its results.
+func (c *Client) Run(ctx context.Context, requests []Request) ([]Result, error) {
+	job, err := c.Submit(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Wait(ctx, job); err != nil {
+		return nil, err
+	}
+	return c.Results(ctx, job)
+}
+
+// parseResult runs one raw result through the provider's parser and schema validation, reporting
+// usage for every item the provider billed.
+func (c *Client) parseResult(ctx context.Context, job *Job, r rawResult) Result {
+	result := Result{CustomID: r.customID}
+	if r.err != nil {
+		result.Err = r.err
+		return result
+	}
+	if r.statusCode != http.StatusOK {
+		result.Err = llm.NewHTTPStatusError(r.statusCode, r.body)
+		return result
+	}
+
+	text, details, err := c.provider.ParseResponseWithUsage(r.body)
+	if err != nil {
+		// Billed even though it would not parse, as on the synchronous path.
+		c.reportUsage(ctx, llm.UsageOutcomeParseFail, nil, r.body)
+		result.Err = llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse response", err)
+		return result
+	}
+	result.Text = text
+	result.Details = details
+
+	if schema, ok := job.schemas[r.customID]; ok {
+		if err := llm.ValidateAgainstSchema(text, schema); err != nil {
+			c.reportUsage(ctx, llm.UsageOutcomeSchemaFail, details, r.body)
+			result.Err = llm.NewLLMError(llm.ErrorTypeResponse, "response does not match schema", err)
+			return result
+		}
+	}
+	c.reportUsage(ctx, llm.UsageOutcomeSuccess, details, r.body)
+	return result
+}
+
+// reportUsage delivers one UsageEvent per billed item. It mirrors the synchronous client's
+// accounting, except that the tier is always "batch": that is what every item is billed at,
+// whether or not the provider echoes it.
+func (c *Client) reportUsage(ctx context.Context, outcome llm.UsageOutcome, details *types.ResponseDetails, body []byte) {
+	observer := c.cfg.UsageObserver
+	if observer == nil {
+		return
+	}
+
+	usage := types.TokenUsage{}
+	model := ""
+	if details != nil {
+		usage = details.TokenUsage
+		model = details.Model
+	}
+	if usage.IsZero() && len(body) > 0 {
+		usage, _, _ = llm.ExtractUsageAndTier(body)
+	}
+	if model == "" {
+		model = c.cfg.Model
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("Usage observer panicked", "panic", r)
+		}
+	}()
+	observer(ctx, types.UsageEvent{
+		Provider:    c.provider.Name(),
+		Model:       model,
+		Outcome:     outcome,
+		Usage:       usage,
+		ServiceTier: ServiceTier,
+		Details:     details,
+	})
+}
+
+// baseURL returns the API root the batch endpoints hang off.
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return trimSlash(c.BaseURL)
+	}
+	u, err := url.Parse(c.provider.Endpoint())
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// endpointPath is the path of the provider's synchronous endpoint, which is what an OpenAI batch
+// line names as its url.
+func (c *Client) endpointPath() string {
+	u, err := url.Parse(c.provider.Endpoint())
+	if err != nil {
+		return "/v1/chat/completions"
+	}
+	return u.Path
+}
+
+// do sends one request to the batch API with the provider's headers, returning the body of a 2xx
+// response and an LLMError for anything else.
+func (c *Client) do(ctx context.Context, method, target string, body []byte, contentType string) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to create request", err)
+	}
+	for k, v := range c.provider.Headers() {
+		req.Header.Set(k, v)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	} else if body == nil {
+		req.Header.Del("Content-Type")
+	}
+
+	c.logger.Wire("Batch API request", "method", method, "url", target, "headers", utils.RedactHTTPHeaders(req.Header))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to send request", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to read response body", err)
+	}
+	c.logger.Wire("Batch API response", "status", resp.StatusCode, "bytes", len(respBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, llm.NewHTTPStatusError(resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
+
+func trimSlash(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '/' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
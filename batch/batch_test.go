@@ -0,0 +1,320 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+// usageRecorder collects UsageEvents for assertions.
+type usageRecorder struct {
+	mu     sync.Mutex
+	events []types.UsageEvent
+}
+
+func (r *usageRecorder) observe(_ context.Context, e types.UsageEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func newTestClient(t *testing.T, provider, model, baseURL string, rec *usageRecorder) *Client {
+	t.Helper()
+	c, err := NewClient(
+		config.SetProvider(provider),
+		config.SetModel(model),
+		config.SetAPIKey("test-key"),
+		config.SetMaxTokens(100),
+		config.SetLogLevel(0),
+		config.WithUsageObserver(rec.observe),
+	)
+	require.NoError(t, err)
+	c.BaseURL = baseURL
+	c.PollInterval = time.Millisecond
+	c.MaxPollInterval = 2 * time.Millisecond
+	return c
+}
+
+func openAIChatBody(content string, prompt, completion int) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"id":    "chatcmpl-1",
+		"model": "gpt-4o-mini-2024-07-18",
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]interface{}{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]interface{}{"prompt_tokens": prompt, "completion_tokens": completion, "total_tokens": prompt + completion},
+	})
+	return string(b)
+}
+
+func TestOpenAIBatch(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		inputFile string
+		polls     int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "batch", r.FormValue("purpose"))
+			f, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, _ := io.ReadAll(f)
+			inputFile = string(data)
+			_, _ = w.Write([]byte(`{"id":"file-in","purpose":"batch"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "file-in", body["input_file_id"])
+			assert.Equal(t, "/v1/chat/completions", body["endpoint"])
+			assert.Equal(t, "24h", body["completion_window"])
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"validating","request_counts":{"total":0}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/batches/batch_1":
+			polls++
+			if polls < 3 {
+				_, _ = w.Write([]byte(`{"id":"batch_1","status":"in_progress","request_counts":{"total":4,"completed":1}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file-out","error_file_id":"file-err",
+				"request_counts":{"total":4,"completed":3,"failed":1}}`))
+		case r.URL.Path == "/v1/files/file-out/content":
+			lines := []string{
+				`{"id":"r2","custom_id":"b","response":{"status_code":200,"body":` + openAIChatBody("second", 12, 3) + `}}`,
+				`{"id":"r1","custom_id":"a","response":{"status_code":200,"body":` + openAIChatBody("first", 10, 2) + `}}`,
+				`{"id":"r3","custom_id":"json","response":{"status_code":200,"body":` + openAIChatBody(`{"name":42}`, 20, 5) + `}}`,
+			}
+			_, _ = w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		case r.URL.Path == "/v1/files/file-err/content":
+			_, _ = w.Write([]byte(`{"id":"r4","custom_id":"bad","response":{"status_code":400,"body":{"error":{"message":"bad model"}}}}` + "\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	rec := &usageRecorder{}
+	c := newTestClient(t, "openai", "gpt-4o-mini", server.URL, rec)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		"required":   []string{"name"},
+	}
+	results, err := c.Run(context.Background(), []Request{
+		{CustomID: "a", Prompt: llm.NewPrompt("one", llm.WithSystemPrompt("be brief", llm.CacheTypeEphemeral))},
+		{CustomID: "b", Prompt: llm.NewPrompt("two"), Options: map[string]interface{}{"temperature": 0.1}},
+		{CustomID: "json", Prompt: llm.NewPrompt("three"), Schema: schema},
+		{CustomID: "bad", Prompt: llm.NewPrompt("four")},
+	})
+	require.NoError(t, err)
+
+	// The input file holds one line per request built by the provider's own builders.
+	lines := strings.Split(strings.TrimSpace(inputFile), "\n")
+	require.Len(t, lines, 4)
+	var first struct {
+		CustomID string                 `json:"custom_id"`
+		Method   string                 `json:"method"`
+		URL      string                 `json:"url"`
+		Body     map[string]interface{} `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "a", first.CustomID)
+	assert.Equal(t, "POST", first.Method)
+	assert.Equal(t, "/v1/chat/completions", first.URL)
+	assert.Equal(t, "gpt-4o-mini", first.Body["model"])
+	assert.Contains(t, lines[0], "be brief")
+	assert.Contains(t, lines[1], `"temperature":0.1`)
+	assert.Contains(t, lines[2], "json_schema")
+
+	// Results come back in submission order, not file order.
+	require.Len(t, results, 4)
+	assert.Equal(t, "a", results[0].CustomID)
+	assert.Equal(t, "first", results[0].Text)
+	assert.Equal(t, 10, results[0].Details.TokenUsage.PromptTokens)
+	assert.Equal(t, "second", results[1].Text)
+
+	var llmErr *llm.LLMError
+	require.True(t, errors.As(results[2].Err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeResponse, llmErr.Type)
+	require.True(t, errors.As(results[3].Err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeInvalidInput, llmErr.Type)
+
+	// One event per billed item, all at the batch tier; the 400 was not billed.
+	require.Len(t, rec.events, 3)
+	outcomes := map[types.UsageOutcome]int{}
+	for _, e := range rec.events {
+		assert.Equal(t, "batch", e.ServiceTier)
+		assert.Equal(t, "openai", e.Provider)
+		assert.Equal(t, "gpt-4o-mini-2024-07-18", e.Model)
+		outcomes[e.Outcome]++
+	}
+	assert.Equal(t, 2, outcomes[types.UsageOutcomeSuccess])
+	assert.Equal(t, 1, outcomes[types.UsageOutcomeSchemaFail])
+}
+
+func TestOpenAIBatchFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/files":
+			_, _ = w.Write([]byte(`{"id":"file-in"}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"failed","errors":{"data":[{"code":"invalid_json","message":"bad line","line":1}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, "openai", "gpt-4o-mini", server.URL, &usageRecorder{})
+	_, err := c.Run(context.Background(), []Request{{Prompt: llm.NewPrompt("x")}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: bad line")
+}
+
+func TestAnthropicBatch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		created  map[string]interface{}
+		polls    int
+		finished bool
+	)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.NotEmpty(t, r.Header.Get("anthropic-version"))
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			_, _ = w.Write([]byte(`{"id":"msgbatch_1","type":"message_batch","processing_status":"in_progress","request_counts":{"processing":3}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			polls++
+			if polls < 2 {
+				_, _ = w.Write([]byte(`{"id":"msgbatch_1","processing_status":"in_progress","request_counts":{"processing":3}}`))
+				return
+			}
+			finished = true
+			_, _ = w.Write([]byte(`{"id":"msgbatch_1","processing_status":"ended","results_url":"` + server.URL + `/results/msgbatch_1",
+				"request_counts":{"succeeded":1,"errored":1,"expired":1}}`))
+		case r.URL.Path == "/results/msgbatch_1":
+			assert.True(t, finished)
+			lines := []string{
+				`{"custom_id":"ok","result":{"type":"succeeded","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-haiku-20241022",
+					"content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","usage":{"input_tokens":11,"output_tokens":4}}}}`,
+				`{"custom_id":"err","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens too large"}}}}`,
+				`{"custom_id":"late","result":{"type":"expired"}}`,
+			}
+			for _, l := range lines {
+				_, _ = w.Write([]byte(strings.Join(strings.Fields(l), " ") + "\n"))
+			}
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	rec := &usageRecorder{}
+	c := newTestClient(t, "anthropic", "claude-3-5-haiku-latest", server.URL, rec)
+	job, err := c.Submit(context.Background(), []Request{
+		{CustomID: "ok", Prompt: llm.NewPrompt("hi")},
+		{CustomID: "err", Prompt: llm.NewPrompt("hi")},
+		{CustomID: "late", Prompt: llm.NewPrompt("hi")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, job.Status)
+
+	requests := created["requests"].([]interface{})
+	require.Len(t, requests, 3)
+	params := requests[0].(map[string]interface{})["params"].(map[string]interface{})
+	assert.Equal(t, "claude-3-5-haiku-latest", params["model"])
+	assert.NotContains(t, params, "stream")
+
+	_, err = c.Results(context.Background(), job)
+	require.Error(t, err, "results of an unfinished batch must be refused")
+
+	require.NoError(t, c.Wait(context.Background(), job))
+	assert.Equal(t, StatusExpired, job.Status)
+	assert.Equal(t, Counts{Total: 3, Succeeded: 1, Failed: 2}, job.Counts)
+
+	results, err := c.Results(context.Background(), job)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "hello", results[0].Text)
+	assert.Equal(t, 11, results[0].Details.TokenUsage.PromptTokens)
+
+	var llmErr *llm.LLMError
+	require.True(t, errors.As(results[1].Err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeInvalidInput, llmErr.Type)
+	require.Error(t, results[2].Err)
+
+	require.Len(t, rec.events, 1)
+	assert.Equal(t, "batch", rec.events[0].ServiceTier)
+	assert.Equal(t, "claude-3-5-haiku-20241022", rec.events[0].Model)
+	assert.Equal(t, 11, rec.events[0].Usage.PromptTokens)
+}
+
+func TestWaitHonorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"msgbatch_1","processing_status":"in_progress"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, "anthropic", "claude-3-5-haiku-latest", server.URL, &usageRecorder{})
+	c.PollInterval = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := c.Wait(ctx, &Job{ID: "msgbatch_1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSubmitValidation(t *testing.T) {
+	c := newTestClient(t, "openai", "gpt-4o-mini", "http://127.0.0.1:0", &usageRecorder{})
+
+	_, err := c.Submit(context.Background(), nil)
+	require.Error(t, err)
+
+	_, err = c.Submit(context.Background(), []Request{
+		{CustomID: "x", Prompt: llm.NewPrompt("a")},
+		{CustomID: "x", Prompt: llm.NewPrompt("b")},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate")
+
+	_, err = c.Submit(context.Background(), []Request{{CustomID: "x"}})
+	require.Error(t, err)
+}
+
+func TestNewClientUnsupportedProvider(t *testing.T) {
+	_, err := NewClient(config.SetProvider("groq"), config.SetModel("llama"), config.SetAPIKey("k"))
+	var llmErr *llm.LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestResponsesEndpointPath(t *testing.T) {
+	c, err := NewClient(config.SetProvider("openai-responses"), config.SetModel("gpt-5"), config.SetAPIKey("k"))
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/responses", c.endpointPath())
+	assert.Equal(t, "https://api.openai.com", c.baseURL())
+}
@@ -0,0 +1,228 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// openAIBackend implements the OpenAI Batch API: the requests are uploaded as a JSONL file with
+// purpose "batch", a batch is created over that file, and the results come back as output and
+// error files keyed by custom_id.
+type openAIBackend struct{}
+
+type openAIBatchLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type openAIBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+	Errors *struct {
+		Data []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Line    *int   `json:"line"`
+		} `json:"data"`
+	} `json:"errors"`
+}
+
+type openAIResultLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (openAIBackend) submit(ctx context.Context, c *Client, customIDs []string, bodies [][]byte) (*Job, error) {
+	path := c.endpointPath()
+	var input bytes.Buffer
+	for i, body := range bodies {
+		line, err := json.Marshal(openAIBatchLine{CustomID: customIDs[i], Method: http.MethodPost, URL: path, Body: body})
+		if err != nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to encode batch line", err)
+		}
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	// Upload the JSONL input as a multipart file.
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	if err := mw.WriteField("purpose", "batch"); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build upload", err)
+	}
+	fw, err := mw.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build upload", err)
+	}
+	if _, err := fw.Write(input.Bytes()); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build upload", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to build upload", err)
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, c.baseURL()+"/v1/files", form.Bytes(), mw.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &file); err != nil || file.ID == "" {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse file upload response", err)
+	}
+
+	window := c.CompletionWindow
+	if window == "" {
+		window = "24h"
+	}
+	createBody, err := json.Marshal(map[string]interface{}{
+		"input_file_id":     file.ID,
+		"endpoint":          path,
+		"completion_window": window,
+	})
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to encode batch", err)
+	}
+	respBody, err = c.do(ctx, http.MethodPost, c.baseURL()+"/v1/batches", createBody, "application/json")
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := applyOpenAIBatch(job, respBody); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (openAIBackend) refresh(ctx context.Context, c *Client, job *Job) error {
+	respBody, err := c.do(ctx, http.MethodGet, c.baseURL()+"/v1/batches/"+job.ID, nil, "")
+	if err != nil {
+		return err
+	}
+	return applyOpenAIBatch(job, respBody)
+}
+
+func (openAIBackend) cancel(ctx context.Context, c *Client, job *Job) error {
+	respBody, err := c.do(ctx, http.MethodPost, c.baseURL()+"/v1/batches/"+job.ID+"/cancel", nil, "")
+	if err != nil {
+		return err
+	}
+	return applyOpenAIBatch(job, respBody)
+}
+
+func (openAIBackend) results(ctx context.Context, c *Client, job *Job) ([]rawResult, error) {
+	var results []rawResult
+	for _, fileID := range []string{job.outputRef, job.errorRef} {
+		if fileID == "" {
+			continue
+		}
+		content, err := c.do(ctx, http.MethodGet, c.baseURL()+"/v1/files/"+fileID+"/content", nil, "")
+		if err != nil {
+			return nil, err
+		}
+		lines, err := parseOpenAIResults(content)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, lines...)
+	}
+	return results, nil
+}
+
+// applyOpenAIBatch copies a batch object onto job.
+func applyOpenAIBatch(job *Job, body []byte) error {
+	var b openAIBatch
+	if err := json.Unmarshal(body, &b); err != nil {
+		return llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse batch", err)
+	}
+	job.ID = b.ID
+	job.ProviderStatus = b.Status
+	job.outputRef = b.OutputFileID
+	job.errorRef = b.ErrorFileID
+	job.Counts = Counts{Total: b.RequestCounts.Total, Succeeded: b.RequestCounts.Completed, Failed: b.RequestCounts.Failed}
+
+	switch b.Status {
+	case "completed":
+		job.Status = StatusCompleted
+	case "failed":
+		job.Status = StatusFailed
+	case "expired":
+		job.Status = StatusExpired
+	case "cancelled":
+		job.Status = StatusCancelled
+	default: // validating, in_progress, finalizing, cancelling
+		job.Status = StatusInProgress
+	}
+
+	if b.Errors != nil && len(b.Errors.Data) > 0 {
+		msgs := make([]string, 0, len(b.Errors.Data))
+		for _, e := range b.Errors.Data {
+			msg := e.Message
+			if e.Line != nil {
+				msg = fmt.Sprintf("line %d: %s", *e.Line, msg)
+			}
+			msgs = append(msgs, msg)
+		}
+		job.Error = strings.Join(msgs, "; ")
+	}
+	return nil
+}
+
+// parseOpenAIResults reads an output or error file. Each line carries either the HTTP response the
+// request would have received synchronously or a batch-level error.
+func parseOpenAIResults(content []byte) ([]rawResult, error) {
+	var results []rawResult
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var l openAIResultLine
+		if err := json.Unmarshal(line, &l); err != nil {
+			return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse batch result line", err)
+		}
+		r := rawResult{customID: l.CustomID}
+		switch {
+		case l.Response != nil:
+			r.statusCode = l.Response.StatusCode
+			r.body = append([]byte(nil), l.Response.Body...)
+		case l.Error != nil:
+			r.err = llm.NewLLMError(llm.ErrorTypeAPI, fmt.Sprintf("%s: %s", l.Error.Code, l.Error.Message), nil)
+		default:
+			r.err = llm.NewLLMError(llm.ErrorTypeResponse, "batch result has neither response nor error", nil)
+		}
+		results = append(results, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to read batch results", err)
+	}
+	return results, nil
+}
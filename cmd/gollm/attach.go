@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/types"
+)
+
+var errNotImage = errors.New("not an image")
+
+// readImage reads the image at path as a content part. The error wraps errNotImage when the
+// file is not an image.
+func readImage(path string) (types.ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return types.ContentPart{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	mediaType := http.DetectContentType(data)
+	if !strings.HasPrefix(mediaType, "image/") {
+		return types.ContentPart{}, fmt.Errorf("%s is %w (%s)", path, errNotImage, mediaType)
+	}
+	return types.NewImageBase64Content(base64.StdEncoding.EncodeToString(data), mediaType), nil
+}
+
+// attachment returns the prompt option attaching the file at path: as an image when it is one,
+// otherwise as a document.
+func attachment(path string) (gollm.PromptOption, error) {
+	image, err := readImage(path)
+	if err == nil {
+		return gollm.WithImages([]types.ContentPart{image}), nil
+	}
+	if !errors.Is(err, errNotImage) {
+		return nil, err
+	}
+	return gollm.WithDocumentFile(path), nil
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+	"golang.org/x/time/rate"
+)
+
+// batchRequest is one line of a gollm batch input file. A request needs a prompt, messages or
+// both; the prompt then follows the messages as the last user message.
+type batchRequest struct {
+	// ID identifies the request in the results. It defaults to "line-<n>", so a file whose
+	// requests have no IDs must not be edited between a run and its resumption.
+	ID       string                `json:"id"`
+	Prompt   string                `json:"prompt"`
+	Messages []gollm.PromptMessage `json:"messages"`
+	System   string                `json:"system"`
+	// Schema, when set, asks for JSON matching it; a reply that does not match is an error.
+	Schema map[string]interface{} `json:"schema"`
+	// Options are provider options, such as temperature or max_tokens, for this request alone.
+	Options map[string]interface{} `json:"options"`
+}
+
+// batchResult is one line of a gollm batch output file.
+type batchResult struct {
+	ID       string            `json:"id"`
+	Response string            `json:"response,omitempty"`
+	Usage    *gollm.TokenUsage `json:"usage,omitempty"`
+	// Error and ErrorType describe a failed request. ErrorType is the llm.LLMError type, such
+	// as "RateLimitError", when the failure has one.
+	Error     string `json:"error,omitempty"`
+	ErrorType string `json:"error_type,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// loadBatchRequests reads a JSONL file of requests. It reports every invalid line at once.
+func loadBatchRequests(path string) ([]batchRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open requests: %w", err)
+	}
+	defer f.Close()
+
+	var requests []batchRequest
+	var problems []string
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var req batchRequest
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if req.ID == "" {
+			req.ID = fmt.Sprintf("line-%d", line)
+		}
+		if first, ok := seen[req.ID]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: id %q is already used on line %d", line, req.ID, first))
+			continue
+		}
+		seen[req.ID] = line
+		if req.Prompt == "" && len(req.Messages) == 0 {
+			problems = append(problems, fmt.Sprintf("line %d: a request needs a prompt or messages", line))
+			continue
+		}
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read requests: %w", err)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid requests in %s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	return requests, nil
+}
+
+// prompt builds the prompt for the request.
+func (r batchRequest) prompt() *gollm.Prompt {
+	var opts []gollm.PromptOption
+	if r.System != "" {
+		opts = append(opts, gollm.WithSystemPrompt(r.System, gollm.CacheTypeEphemeral))
+	}
+	messages := r.Messages
+	if r.Prompt != "" {
+		messages = append(append([]gollm.PromptMessage{}, messages...), gollm.PromptMessage{Role: "user", Content: r.Prompt})
+	}
+	// A lone user message does not take the conversation path, so it is sent as the input.
+	if len(messages) == 1 && messages[0].Role == "user" && len(messages[0].MultiContent) == 0 {
+		return gollm.NewPrompt(messages[0].Content, opts...)
+	}
+	return gollm.NewPrompt(messages[len(messages)-1].Content, append(opts, gollm.WithMessages(messages))...)
+}
+
+// resumeBatch keeps the successful results already in the output file, so they are not paid
+// for again, and rewrites the file without the failed ones, which are run again. It returns the
+// IDs of the kept results.
+func resumeBatch(path string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var kept strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		var result batchResult
+		// A line cut short by an interrupted write does not parse, and its request runs again.
+		if json.Unmarshal([]byte(line), &result) != nil || result.ID == "" || result.Error != "" || done[result.ID] {
+			continue
+		}
+		done[result.ID] = true
+		kept.WriteString(line)
+		kept.WriteString("\n")
+	}
+	if err := utils.WriteFileAtomic(path, []byte(kept.String()), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to rewrite results: %w", err)
+	}
+	return done, nil
+}
+
+// batchWriter appends results to the output file as they complete, one whole line per write.
+type batchWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (w *batchWriter) write(result batchResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result %s: %w", result.ID, err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write result %s: %w", result.ID, err)
+	}
+	return nil
+}
+
+// runBatch runs the batch subcommand and returns the exit code: 0 when every request succeeded,
+// 1 when one failed, 2 when the run could not start and 130 when it was interrupted.
+func runBatch(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	profile := flags.String("profile", "", "Configuration profile, replacing LLM_PROFILE")
+	provider := flags.String("provider", "", "LLM provider")
+	model := flags.String("model", "", "LLM model")
+	apiKey := flags.String("api-key", "", "API key for the specified provider")
+	temperature := flags.Float64("temperature", -1, "LLM temperature")
+	maxTokens := flags.Int("max-tokens", 0, "LLM max tokens")
+	timeout := flags.Duration("timeout", 0, "LLM timeout for each request")
+	maxRetries := flags.Int("max-retries", 3, "Maximum number of retries for each request")
+	retryDelay := flags.Duration("retry-delay", time.Second*2, "Delay between retries")
+	debugLevel := flags.String("debug-level", "warn", "Debug level (debug, info, warn, error)")
+	parallel := flags.Int("parallel", 5, "Maximum number of requests run at once")
+	requestRate := flags.Float64("rate", 0, "Maximum requests per second (0 for no limit)")
+	output := flags.String("output", "", "Results file; defaults to the input file with .results.jsonl")
+	inputPrice := flags.Float64("input-price", -1, "Dollars per million prompt tokens, replacing the built-in price")
+	outputPrice := flags.Float64("output-price", -1, "Dollars per million completion tokens, replacing the built-in price")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gollm batch [flags] <requests.jsonl>\n\n")
+		fmt.Fprintf(stderr, "Runs every request of a JSONL file and appends a result line for each to the results file.\n")
+		fmt.Fprintf(stderr, "Rerunning resumes: requests that already succeeded are skipped, failed ones run again.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *parallel < 1 {
+		fmt.Fprintf(stderr, "Error: -parallel must be at least 1, got %d\n", *parallel)
+		return exitUsage
+	}
+	input := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, ".jsonl") + ".results.jsonl"
+	}
+
+	requests, err := loadBatchRequests(input)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	done, err := resumeBatch(*output)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitError
+	}
+	var pending []batchRequest
+	for _, req := range requests {
+		if !done[req.ID] {
+			pending = append(pending, req)
+		}
+	}
+
+	configOpts, err := profileOptions(*profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	configOpts = append(configOpts, prepareConfigOptions(provider, model, temperature, maxTokens, timeout, apiKey,
+		explicit(flags, "max-retries", maxRetries), explicit(flags, "retry-delay", retryDelay), explicit(flags, "debug-level", debugLevel))...)
+	client, err := gollm.NewLLM(configOpts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error creating LLM client: %v\n", err)
+		return exitCode(err)
+	}
+	// Options apply to a whole client, so a request with its own options gets its own client.
+	clientFor := func(req batchRequest) (gollm.LLM, error) {
+		if len(req.Options) == 0 {
+			return client, nil
+		}
+		c, err := gollm.NewLLM(configOpts...)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range req.Options {
+			c.SetOption(key, value)
+		}
+		return c, nil
+	}
+
+	f, err := os.OpenFile(*output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to open results: %v\n", err)
+		return exitError
+	}
+	defer f.Close()
+	writer := &batchWriter{f: f}
+
+	fmt.Fprintf(stderr, "Running %d of %d requests with %s/%s (%d already done).\n", len(pending), len(requests), client.GetProvider(), client.GetModel(), len(requests)-len(pending))
+
+	// Ctrl-C stops the run; requests cut short are not recorded and run on the next resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var limiter *rate.Limiter
+	if *requestRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(*requestRate), 1)
+	}
+
+	var mu sync.Mutex
+	var succeeded, failed int
+	var usage gollm.TokenUsage
+	var writeErr error
+	queue := make(chan batchRequest)
+	var wg sync.WaitGroup
+	for i := 0; i < min(*parallel, len(pending)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range queue {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					continue
+				}
+				result := runBatchRequest(ctx, clientFor, req)
+				if interrupted(ctx, result) {
+					continue
+				}
+				err := writer.write(result)
+				mu.Lock()
+				if result.Usage != nil {
+					usage = usage.Add(*result.Usage)
+				}
+				if result.Error != "" {
+					failed++
+					fmt.Fprintf(stderr, "FAIL %s: %s\n", result.ID, result.Error)
+				} else {
+					succeeded++
+				}
+				if err != nil && writeErr == nil {
+					writeErr = err
+					stop()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, req := range pending {
+		select {
+		case queue <- req:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	fmt.Fprintf(stdout, "%d succeeded, %d failed, %d skipped; %d prompt + %d completion tokens",
+		succeeded, failed, len(requests)-len(pending), usage.PromptTokens, usage.CompletionTokens)
+	price, priced := priceOf(client.GetModel())
+	if *inputPrice >= 0 || *outputPrice >= 0 {
+		price, priced = modelPrice{Input: max(*inputPrice, 0), Output: max(*outputPrice, 0)}, true
+	}
+	if priced {
+		fmt.Fprintf(stdout, ", $%.4f", price.cost(usage))
+	}
+	fmt.Fprintf(stdout, "\nResults: %s\n", *output)
+
+	switch {
+	case writeErr != nil:
+		fmt.Fprintf(stderr, "Error: %v\n", writeErr)
+		return exitError
+	case ctx.Err() != nil:
+		fmt.Fprintf(stderr, "Interrupted; run the same command again to resume.\n")
+		return exitInterrupted
+	case failed > 0:
+		return exitError
+	}
+	return exitOK
+}
+
+// interrupted reports whether result failed because the run was stopped. Such a result is not
+// recorded, so its request runs again on resume; one that succeeded anyway is kept and not paid
+// for twice.
+func interrupted(ctx context.Context, result batchResult) bool {
+	return result.Error != "" && ctx.Err() != nil
+}
+
+// runBatchRequest sends one request and returns its result.
+func runBatchRequest(ctx context.Context, clientFor func(batchRequest) (gollm.LLM, error), req batchRequest) batchResult {
+	result := batchResult{ID: req.ID}
+	client, err := clientFor(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create client: %v", err)
+		return result
+	}
+
+	start := time.Now()
+	var response string
+	var details *types.ResponseDetails
+	if req.Schema != nil {
+		response, details, err = client.GenerateWithSchemaAndUsage(ctx, req.prompt(), req.Schema)
+	} else {
+		response, details, err = client.GenerateWithUsage(ctx, req.prompt())
+	}
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if details != nil && !details.TokenUsage.IsZero() {
+		result.Usage = &details.TokenUsage
+	}
+	if err != nil {
+		result.Error = err.Error()
+		var llmErr *llm.LLMError
+		if errors.As(err, &llmErr) {
+			result.ErrorType = llmErr.TypeString()
+		}
+		return result
+	}
+	result.Response = response
+	return result
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/gollmtest"
+	"github.com/teilomillet/gollm/llm"
+)
+
+func readBatchResults(t *testing.T, path string) map[string]batchResult {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	results := make(map[string]batchResult)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var result batchResult
+		require.NoError(t, json.Unmarshal([]byte(line), &result), line)
+		results[result.ID] = result
+	}
+	return results
+}
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	var inFlight, maxInFlight atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		// A flat prompt renders its messages after the input, so the question is the first line.
+		question, _, _ := strings.Cut(r.Prompt, "\n")
+		mu.Lock()
+		bodies[question] = string(r.Body)
+		mu.Unlock()
+		switch {
+		case question == "Flaky?" && failing.Load():
+			return gollmtest.Error(llm.ErrorTypeRateLimit, "slow down")
+		case strings.Contains(question, "JSON"):
+			return gollmtest.JSON(map[string]string{"city": "Paris"}).WithUsage(10, 5)
+		}
+		return gollmtest.Text("Answer to "+question).WithUsage(10, 2)
+	}))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "requests.jsonl")
+	require.NoError(t, os.WriteFile(input, []byte(`{"id": "plain", "prompt": "Hello?"}
+{"id": "chat", "system": "Be terse.", "messages": [{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello"}], "prompt": "How are you?"}
+
+{"id": "schema", "prompt": "City as JSON?", "schema": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}}
+{"prompt": "Flaky?", "options": {"temperature": 0.25}}
+`), 0o600))
+	output := filepath.Join(dir, "requests.results.jsonl")
+	args := append(openAIFlags, "-parallel", "2", input)
+
+	var stdout, stderr strings.Builder
+	code := runBatch(args, &stdout, &stderr)
+	assert.Equal(t, exitError, code, "a request failed")
+	assert.Contains(t, stdout.String(), "3 succeeded, 1 failed, 0 skipped; 30 prompt + 9 completion tokens, $0.0000")
+	assert.Contains(t, stderr.String(), "FAIL line-5:")
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+
+	results := readBatchResults(t, output)
+	require.Len(t, results, 4)
+	assert.Equal(t, "Answer to Hello?", results["plain"].Response)
+	assert.Equal(t, 12, results["plain"].Usage.TotalTokens)
+	assert.Equal(t, "Answer to How are you?", results["chat"].Response)
+	assert.JSONEq(t, `{"city": "Paris"}`, results["schema"].Response)
+	assert.Equal(t, "RateLimitError", results["line-5"].ErrorType)
+	assert.Empty(t, results["line-5"].Response)
+
+	assert.Contains(t, bodies["How are you?"], "Be terse.")
+	assert.Contains(t, bodies["How are you?"], `"Hello"`, "the messages come before the prompt")
+	assert.Contains(t, bodies["Flaky?"], `"temperature":0.25`)
+	assert.NotContains(t, bodies["Hello?"], `"temperature":0.25`, "options apply to their own request")
+
+	// A result cut short by an interrupted write is dropped, and its request runs again along
+	// with the failed one. The successful results are not paid for again.
+	f, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id": "plain", "respo`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	failing.Store(false)
+	mu.Lock()
+	clear(bodies)
+	mu.Unlock()
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runBatch(args, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Contains(t, stdout.String(), "1 succeeded, 0 failed, 3 skipped")
+	assert.Equal(t, []string{"Flaky?"}, mapKeys(bodies))
+
+	results = readBatchResults(t, output)
+	require.Len(t, results, 4)
+	assert.Equal(t, "Answer to Flaky?", results["line-5"].Response)
+	assert.Empty(t, results["line-5"].Error)
+}
+
+func TestBatchInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.False(t, interrupted(ctx, batchResult{ID: "a", Error: "rate limited"}), "a failure before Ctrl-C is recorded")
+	cancel()
+	assert.True(t, interrupted(ctx, batchResult{ID: "a", Error: "context canceled"}))
+	assert.False(t, interrupted(ctx, batchResult{ID: "a", Response: "done"}), "a result that arrived anyway is kept")
+}
+
+func mapKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestLoadBatchRequestsProblems(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "requests.jsonl")
+	require.NoError(t, os.WriteFile(input, []byte(`{"id": "a", "prompt": "Hi"}
+{"id": "a", "prompt": "Hi again"}
+{"id": "b"}
+not json
+`), 0o600))
+
+	_, err := loadBatchRequests(input)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `line 2: id "a" is already used on line 1`)
+	assert.Contains(t, err.Error(), "line 3: a request needs a prompt or messages")
+	assert.Contains(t, err.Error(), "line 4:")
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, exitUsage, runBatch([]string{input}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, runBatch(nil, &stdout, &stderr))
+}
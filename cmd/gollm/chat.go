@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+const chatHelp = `Commands:
+  /system [prompt]         show or set the system prompt
+  /model [provider/]model  show or switch the model, keeping the conversation
+  /clear                   forget the conversation; the session's usage is kept
+  /save [session]          save the session, by name or path, and keep saving there
+  /load <session>          load a saved session, by name or path
+  /usage                   show the tokens and cost of the session
+  /image <path>            attach an image to the next message
+  /help                    show this help
+  /exit                    leave; the session is saved after every turn`
+
+// chatSession is a conversation of gollm chat. It is saved after every turn, so a conversation
+// can be picked up again with -session or /load.
+type chatSession struct {
+	Provider     string                `json:"provider"`
+	Model        string                `json:"model"`
+	SystemPrompt string                `json:"system_prompt,omitempty"`
+	Messages     []gollm.PromptMessage `json:"messages"`
+	// Usage and Cost total every turn of the session, including those /clear forgot. Cost
+	// counts only turns whose model had a price.
+	Usage     gollm.TokenUsage `json:"usage"`
+	Cost      float64          `json:"cost"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// sessionPath returns where the session named name is saved: name itself when it is a path,
+// otherwise a file under the user's configuration directory.
+func sessionPath(name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || strings.HasSuffix(name, ".json") {
+		return name, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "gollm", "sessions", name+".json"), nil
+}
+
+// loadSession reads the session saved at path. The error wraps fs.ErrNotExist when there is
+// none.
+func loadSession(path string) (*chatSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	var session chatSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", path, err)
+	}
+	return &session, nil
+}
+
+// save writes the session to path, replacing the file whole so an interrupted write cannot
+// leave half a session behind.
+func (s *chatSession) save(path string) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// chat is a running gollm chat.
+type chat struct {
+	session *chatSession
+	path    string
+	client  gollm.LLM
+	// newClient builds the client for a provider and model; /model and /load switch with it.
+	newClient func(provider, model string) (gollm.LLM, error)
+	// price, when set, replaces the list price of every model.
+	price *modelPrice
+	// images are attached with /image and sent with the next message.
+	images []types.ContentPart
+	out    io.Writer
+	errOut io.Writer
+}
+
+// runChat runs the chat subcommand and returns the exit code.
+func runChat(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("chat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	profile := flags.String("profile", "", "Configuration profile, replacing LLM_PROFILE")
+	provider := flags.String("provider", "", "LLM provider; defaults to the session's, then to LLM_PROVIDER")
+	model := flags.String("model", "", "LLM model; defaults to the session's, then to LLM_MODEL")
+	apiKey := flags.String("api-key", "", "API key for the provider given with -provider")
+	system := flags.String("system", "", "System prompt, replacing the session's")
+	maxTokens := flags.Int("max-tokens", 1024, "Maximum tokens in a reply")
+	sessionName := flags.String("session", "default", "Session to resume and save to, by name or path")
+	inputPrice := flags.Float64("input-price", -1, "Dollars per million prompt tokens, replacing the built-in price")
+	outputPrice := flags.Float64("output-price", -1, "Dollars per million completion tokens, replacing the built-in price")
+	debugLevel := flags.String("debug-level", "warn", "Debug level (debug, info, warn, error)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gollm chat [flags]\n\n")
+		fmt.Fprintf(stderr, "Holds a conversation with a model, streaming its replies.\n\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\n%s\n", chatHelp)
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	profileOpts, err := profileOptions(*profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	path, err := sessionPath(*sessionName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	session, err := loadSession(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		session, err = &chatSession{}, nil
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *provider != "" && *provider != session.Provider {
+		session.Provider, session.Model = *provider, ""
+	}
+	if *model != "" {
+		session.Model = *model
+	}
+	if *system != "" {
+		session.SystemPrompt = *system
+	}
+
+	c := &chat{
+		session: session,
+		path:    path,
+		out:     stdout,
+		errOut:  stderr,
+		newClient: func(p, m string) (gollm.LLM, error) {
+			// The default reply length gives way to a profile; -max-tokens does not.
+			var opts []gollm.ConfigOption
+			if !given(flags, "max-tokens") {
+				opts = append(opts, gollm.SetMaxTokens(*maxTokens))
+			}
+			opts = append(opts, profileOpts...)
+			if given(flags, "max-tokens") {
+				opts = append(opts, gollm.SetMaxTokens(*maxTokens))
+			}
+			if given(flags, "debug-level") {
+				opts = append(opts, gollm.SetLogLevel(getLogLevel(*debugLevel)))
+			}
+			if p != "" {
+				opts = append(opts, gollm.SetProvider(p))
+			}
+			if m != "" {
+				opts = append(opts, gollm.SetModel(m))
+			}
+			if *apiKey != "" && p == *provider {
+				opts = append(opts, gollm.SetAPIKey(*apiKey))
+			}
+			return gollm.NewLLM(opts...)
+		},
+	}
+	if *inputPrice >= 0 || *outputPrice >= 0 {
+		c.price = &modelPrice{Input: max(*inputPrice, 0), Output: max(*outputPrice, 0)}
+	}
+	if err := c.switchModel(session.Provider, session.Model); err != nil {
+		fmt.Fprintf(stderr, "Error creating LLM client: %v\n", err)
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(stdout, "Chatting with %s/%s. Type /help for commands.\n", session.Provider, session.Model)
+	if len(session.Messages) > 0 {
+		fmt.Fprintf(stdout, "Resumed %s with %d messages.\n", path, len(session.Messages))
+	}
+	return c.loop(stdin)
+}
+
+// loop reads messages and commands until /exit or the end of the input.
+func (c *chat) loop(in io.Reader) int {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(c.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(c.out)
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "/"):
+			if c.command(line) {
+				return 0
+			}
+		default:
+			if err := c.turn(line); err != nil {
+				fmt.Fprintf(c.errOut, "Error: %v\n", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(c.errOut, "Error reading input: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// command runs a slash command and reports whether the chat should end.
+func (c *chat) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	var err error
+	switch name {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		fmt.Fprintln(c.out, chatHelp)
+	case "/system":
+		if arg == "" {
+			if c.session.SystemPrompt == "" {
+				fmt.Fprintln(c.out, "No system prompt.")
+			} else {
+				fmt.Fprintln(c.out, c.session.SystemPrompt)
+			}
+			break
+		}
+		c.session.SystemPrompt = arg
+		if err = c.session.save(c.path); err == nil {
+			fmt.Fprintln(c.out, "System prompt set.")
+		}
+	case "/model":
+		if arg == "" {
+			fmt.Fprintf(c.out, "%s/%s\n", c.session.Provider, c.session.Model)
+			break
+		}
+		provider, model := c.session.Provider, arg
+		if prefix, rest, ok := strings.Cut(arg, "/"); ok && isProvider(prefix) {
+			provider, model = prefix, rest
+		}
+		if err = c.switchModel(provider, model); err == nil {
+			fmt.Fprintf(c.out, "Switched to %s/%s.\n", c.session.Provider, c.session.Model)
+			err = c.session.save(c.path)
+		}
+	case "/clear":
+		c.session.Messages = nil
+		c.images = nil
+		if err = c.session.save(c.path); err == nil {
+			fmt.Fprintln(c.out, "Conversation cleared.")
+		}
+	case "/save":
+		path := c.path
+		if arg != "" {
+			if path, err = sessionPath(arg); err != nil {
+				break
+			}
+		}
+		if err = c.session.save(path); err == nil {
+			c.path = path
+			fmt.Fprintf(c.out, "Saved to %s.\n", path)
+		}
+	case "/load":
+		err = c.load(arg)
+	case "/usage":
+		c.printUsage()
+	case "/image":
+		err = c.attachImage(arg)
+	default:
+		fmt.Fprintf(c.out, "Unknown command %s. Type /help for commands.\n", name)
+	}
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Error: %v\n", err)
+	}
+	return false
+}
+
+// isProvider reports whether name is a registered provider.
+func isProvider(name string) bool {
+	_, err := providers.GetDefaultRegistry().Get(name, "", "", nil)
+	return err == nil
+}
+
+// switchModel replaces the client with one for provider and model, keeping the conversation.
+// Empty names take the configured defaults.
+func (c *chat) switchModel(provider, model string) error {
+	client, err := c.newClient(provider, model)
+	if err != nil {
+		return err
+	}
+	c.client = client
+	c.session.Provider, c.session.Model = client.GetProvider(), client.GetModel()
+	return nil
+}
+
+// load replaces the session with the one saved under name, and saves there from then on.
+func (c *chat) load(name string) error {
+	if name == "" {
+		return errors.New("/load needs a session name or path")
+	}
+	path, err := sessionPath(name)
+	if err != nil {
+		return err
+	}
+	session, err := loadSession(path)
+	if err != nil {
+		return err
+	}
+	if session.Provider != c.session.Provider || session.Model != c.session.Model {
+		previous := c.session
+		c.session = session
+		if err := c.switchModel(session.Provider, session.Model); err != nil {
+			c.session = previous
+			return err
+		}
+	}
+	c.session, c.path, c.images = session, path, nil
+	fmt.Fprintf(c.out, "Loaded %s with %d messages, chatting with %s/%s.\n", path, len(session.Messages), session.Provider, session.Model)
+	return nil
+}
+
+// attachImage reads the image at path for the next message.
+func (c *chat) attachImage(path string) error {
+	if path == "" {
+		return errors.New("/image needs a file path")
+	}
+	image, err := readImage(path)
+	if err != nil {
+		return err
+	}
+	c.images = append(c.images, image)
+	fmt.Fprintf(c.out, "Attached %s to your next message.\n", filepath.Base(path))
+	return nil
+}
+
+// turn sends a message, streams the reply and saves the session.
+func (c *chat) turn(input string) error {
+	message := gollm.PromptMessage{Role: "user", Content: input}
+	if len(c.images) > 0 {
+		message.MultiContent = append(append([]types.ContentPart{}, c.images...), types.NewTextContent(input))
+	}
+	messages := append(append([]gollm.PromptMessage{}, c.session.Messages...), message)
+
+	// Ctrl-C stops the reply rather than the chat.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	reply, usage, err := c.send(ctx, c.prompt(input, messages))
+	fmt.Fprintln(c.out)
+
+	// A failed or interrupted reply may still have been billed.
+	cost, priced := c.cost(usage)
+	c.session.Usage = c.session.Usage.Add(usage)
+	c.session.Cost += cost
+	if err != nil {
+		return err
+	}
+
+	c.images = nil
+	c.session.Messages = append(messages, gollm.PromptMessage{Role: "assistant", Content: reply})
+	line := fmt.Sprintf("[%d prompt + %d completion tokens", usage.PromptTokens, usage.CompletionTokens)
+	if priced {
+		line += fmt.Sprintf(", $%.6f", cost)
+	}
+	fmt.Fprintf(c.out, "%s; session %d tokens, $%.6f]\n", line, c.session.Usage.ComputedTotal(), c.session.Cost)
+	return c.session.save(c.path)
+}
+
+// prompt builds the request for the conversation. The first message is sent as the prompt's
+// input, with its images, since a lone user message does not take the conversation path.
+func (c *chat) prompt(input string, messages []gollm.PromptMessage) *gollm.Prompt {
+	var opts []gollm.PromptOption
+	if c.session.SystemPrompt != "" {
+		opts = append(opts, gollm.WithSystemPrompt(c.session.SystemPrompt, gollm.CacheTypeEphemeral))
+	}
+	if len(messages) == 1 {
+		if len(c.images) > 0 {
+			opts = append(opts, gollm.WithImages(c.images))
+		}
+		return gollm.NewPrompt(input, opts...)
+	}
+	return gollm.NewPrompt(input, append(opts, gollm.WithMessages(messages))...)
+}
+
+// send streams the reply to the output as it arrives, or prints it whole when the provider
+// cannot stream, and returns it with its usage.
+func (c *chat) send(ctx context.Context, prompt *gollm.Prompt) (string, gollm.TokenUsage, error) {
+	if !c.client.SupportsStreaming() {
+		reply, details, err := c.client.GenerateWithUsage(ctx, prompt)
+		fmt.Fprint(c.out, reply)
+		var usage gollm.TokenUsage
+		if details != nil {
+			usage = details.TokenUsage
+		}
+		return reply, usage, err
+	}
+
+	stream, err := c.client.Stream(ctx, prompt)
+	if err != nil {
+		return "", gollm.TokenUsage{}, err
+	}
+	defer stream.Close()
+	var reply strings.Builder
+	for {
+		token, err := stream.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			usage, _ := gollm.StreamUsage(stream)
+			return reply.String(), usage, err
+		}
+		fmt.Fprint(c.out, token.Text)
+		reply.WriteString(token.Text)
+	}
+	usage, _ := gollm.StreamUsage(stream)
+	return reply.String(), usage, nil
+}
+
+// cost estimates what usage cost on the current model, and reports whether the model has a
+// price.
+func (c *chat) cost(usage gollm.TokenUsage) (float64, bool) {
+	price, ok := priceOf(c.session.Model)
+	if c.price != nil {
+		price, ok = *c.price, true
+	}
+	if !ok {
+		return 0, false
+	}
+	return price.cost(usage), true
+}
+
+func (c *chat) printUsage() {
+	u := c.session.Usage
+	fmt.Fprintf(c.out, "Session: %d prompt + %d completion = %d tokens", u.PromptTokens, u.CompletionTokens, u.ComputedTotal())
+	if u.CacheReadInputTokens > 0 || u.CachedPromptTokens > 0 {
+		fmt.Fprintf(c.out, " (%d read from cache)", u.CacheReadInputTokens+u.CachedPromptTokens)
+	}
+	fmt.Fprintf(c.out, ", $%.6f\n", c.session.Cost)
+	if _, ok := c.cost(gollm.TokenUsage{}); !ok {
+		fmt.Fprintf(c.out, "No price is known for %s; set -input-price and -output-price to count its cost.\n", c.session.Model)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/gollmtest"
+)
+
+func TestChat(t *testing.T) {
+	var mu sync.Mutex
+	var requests []gollmtest.Request
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		if r.Prompt == "And Italy?" {
+			return gollmtest.Text("Rome").WithUsage(40, 1)
+		}
+		return gollmtest.Text("Paris").WithUsage(20, 1)
+	}))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	path := filepath.Join(t.TempDir(), "geo.json")
+	var stdout, stderr strings.Builder
+	stdin := strings.NewReader("/system Answer in one word.\nWhat is the capital of France?\n/usage\n/exit\n")
+	code := runChat([]string{"-provider", "openai", "-model", "gpt-4o-mini", "-session", path}, stdin, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Empty(t, stderr.String())
+	assert.Contains(t, stdout.String(), "Chatting with openai/gpt-4o-mini.")
+	assert.Contains(t, stdout.String(), "Paris\n")
+	assert.Contains(t, stdout.String(), "[20 prompt + 1 completion tokens, $0.000004; session 21 tokens, $0.000004]")
+	assert.Contains(t, stdout.String(), "Session: 20 prompt + 1 completion = 21 tokens, $0.000004")
+
+	session, err := loadSession(path)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", session.Provider)
+	assert.Equal(t, "gpt-4o-mini", session.Model)
+	assert.Equal(t, "Answer in one word.", session.SystemPrompt)
+	require.Len(t, session.Messages, 2)
+	assert.Equal(t, "Paris", session.Messages[1].Content)
+	assert.Equal(t, 21, session.Usage.ComputedTotal())
+
+	// A second run resumes the session and sends the conversation so far.
+	stdout.Reset()
+	stdin = strings.NewReader("And Italy?\n/clear\n")
+	code = runChat([]string{"-session", path, "-input-price", "1", "-output-price", "2"}, stdin, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Resumed "+path+" with 2 messages.")
+	assert.Contains(t, stdout.String(), "Rome\n")
+	assert.Contains(t, stdout.String(), "[40 prompt + 1 completion tokens, $0.000042; session 62 tokens")
+	assert.Contains(t, stdout.String(), "Conversation cleared.")
+
+	require.Len(t, requests, 2)
+	assert.True(t, requests[1].Stream, "replies are streamed")
+	body := string(requests[1].Body)
+	assert.Contains(t, body, "What is the capital of France?")
+	assert.Contains(t, body, "Paris")
+	assert.Contains(t, body, "Answer in one word.")
+
+	session, err = loadSession(path)
+	require.NoError(t, err)
+	assert.Empty(t, session.Messages, "/clear forgets the conversation")
+	assert.Equal(t, 62, session.Usage.ComputedTotal(), "/clear keeps the session's usage")
+}
+
+func TestChatCommands(t *testing.T) {
+	server := gollmtest.NewServer(gollmtest.ServeDefault(gollmtest.Text("ok")))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.json")
+	saved := filepath.Join(dir, "b.json")
+	var stdout, stderr strings.Builder
+	stdin := strings.NewReader(strings.Join([]string{
+		"/model openai/gpt-4o",
+		"/save " + saved,
+		"/model gpt-4.1-mini",
+		"/load " + path,
+		"/image " + filepath.Join(dir, "missing.png"),
+		"/image chat.go",
+		"/unknown",
+	}, "\n"))
+	code := runChat([]string{"-provider", "openai", "-model", "gpt-4o-mini", "-session", path}, stdin, &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Switched to openai/gpt-4o.")
+	assert.Contains(t, stdout.String(), "Saved to "+saved+".")
+	assert.Contains(t, stdout.String(), "Switched to openai/gpt-4.1-mini.")
+	assert.Contains(t, stdout.String(), "chatting with openai/gpt-4o.", "/save switched saving to b.json, so a.json kept gpt-4o")
+	assert.Contains(t, stdout.String(), "Unknown command /unknown.")
+	assert.Contains(t, stderr.String(), "failed to read "+filepath.Join(dir, "missing.png"))
+	assert.Contains(t, stderr.String(), "chat.go is not an image")
+}
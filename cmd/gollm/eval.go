@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/assess"
+	"golang.org/x/time/rate"
+)
+
+// runEval runs the eval subcommand and returns the exit code: 0 when every model reaches the pass
+// rate, 1 when one does not, 2 when the run could not start.
+func runEval(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	parallel := fs.Int("parallel", 5, "Maximum number of cases run at once")
+	requestRate := fs.Float64("rate", 0, "Maximum requests per second across all providers (0 for no limit)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Time limit for the whole run")
+	minPassRate := fs.Float64("min-pass-rate", 1, "Pass rate, from 0 to 1, that every model must reach")
+	reportDir := fs.String("report-dir", "eval-report", "Directory to write report.json, junit.xml and summary.md to")
+	tags := fs.String("tags", "", "Comma-separated tags; only cases with one of them run")
+	verbose := fs.Bool("verbose", false, "Log each case as it runs")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gollm eval [flags] <dataset> <provider/model>...\n\n")
+		fmt.Fprintf(stderr, "Runs the cases of a YAML or JSONL dataset against each model and compares them.\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+	if *minPassRate < 0 || *minPassRate > 1 {
+		fmt.Fprintf(stderr, "Error: -min-pass-rate must be between 0 and 1, got %v\n", *minPassRate)
+		return 2
+	}
+
+	cases, err := assess.LoadDataset(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	runner := assess.NewRunner(assess.NewWriterReporter(stderr, *verbose))
+	var pairs []string
+	for _, pair := range fs.Args()[1:] {
+		provider, model, ok := strings.Cut(pair, "/")
+		if !ok || provider == "" || model == "" {
+			fmt.Fprintf(stderr, "Error: expected provider/model, got %q\n", pair)
+			return 2
+		}
+		for _, seen := range pairs {
+			if seen == pair {
+				fmt.Fprintf(stderr, "Error: %s is listed twice\n", pair)
+				return 2
+			}
+		}
+		pairs = append(pairs, pair)
+		runner.WithProvider(provider, model)
+	}
+
+	if err := runner.AddDataset(cases); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *tags != "" {
+		runner.FilterTags(strings.Split(*tags, ",")...)
+	}
+
+	batchCfg := assess.BatchTestConfig{
+		EnableBatch:  true,
+		MaxParallel:  *parallel,
+		BatchTimeout: *timeout,
+	}
+	if *requestRate > 0 {
+		batchCfg.RateLimit = rate.NewLimiter(rate.Limit(*requestRate), 1)
+	}
+	runner.WithBatchConfig(batchCfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	runner.RunBatch(ctx)
+
+	report := runner.Report()
+	if err := report.WriteTable(stdout); err != nil {
+		fmt.Fprintf(stderr, "Error writing table: %v\n", err)
+		return 2
+	}
+	if err := runner.WriteReports(*reportDir); err != nil {
+		fmt.Fprintf(stderr, "Error writing reports: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(stdout, "\nReports written to %s\n", *reportDir)
+
+	code := 0
+	for _, pair := range pairs {
+		summary, ok := report.Providers[pair]
+		if !ok {
+			provider, _, _ := strings.Cut(pair, "/")
+			fmt.Fprintf(stderr, "%s: no cases ran; is %s_API_KEY set?\n", pair, strings.ToUpper(provider))
+			code = 1
+			continue
+		}
+		if summary.PassRate() < *minPassRate {
+			fmt.Fprintf(stderr, "%s: pass rate %.1f%% is below %.1f%%\n", pair, summary.PassRate()*100, *minPassRate*100)
+			code = 1
+		}
+	}
+	return code
+}
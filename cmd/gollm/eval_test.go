@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/gollmtest"
+)
+
+const evalDataset = `cases:
+  - name: arithmetic
+    input: What's 2+2?
+    validators:
+      - {name: contains, value: "4"}
+  - name: capital
+    input: What is the capital of France?
+    expected: Paris
+`
+
+func TestEval(t *testing.T) {
+	server := gollmtest.NewServer(gollmtest.ServeDefault(gollmtest.Text("2+2 is 4")))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "eval.yaml")
+	require.NoError(t, os.WriteFile(dataset, []byte(evalDataset), 0o644))
+	reports := filepath.Join(dir, "reports")
+
+	var stdout, stderr strings.Builder
+	code := runEval([]string{"-report-dir", reports, "-min-pass-rate", "0.5", dataset, "openai/gpt-4o-mini"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Regexp(t, `arithmetic\s+pass`, stdout.String())
+	assert.Regexp(t, `capital\s+FAIL`, stdout.String())
+	assert.Contains(t, stderr.String(), "openai/gpt-4o-mini/capital")
+	for _, name := range []string{"report.json", "junit.xml", "summary.md"} {
+		assert.FileExists(t, filepath.Join(reports, name))
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runEval([]string{"-report-dir", reports, dataset, "openai/gpt-4o-mini"}, &stdout, &stderr)
+	assert.Equal(t, 1, code, "the default threshold needs every case to pass")
+	assert.Contains(t, stderr.String(), "openai/gpt-4o-mini: pass rate 50.0% is below 100.0%")
+}
+
+func TestEvalModelsOfOneProvider(t *testing.T) {
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		switch {
+		case !strings.Contains(r.Prompt, "capital"):
+			return gollmtest.Text("2+2 is 4")
+		case r.Model == "gpt-4o":
+			return gollmtest.Text("Paris")
+		default:
+			return gollmtest.Text("Lyon")
+		}
+	}))
+	defer server.Close()
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "eval.yaml")
+	require.NoError(t, os.WriteFile(dataset, []byte(evalDataset), 0o644))
+
+	var stdout, stderr strings.Builder
+	code := runEval([]string{"-report-dir", filepath.Join(dir, "reports"), dataset, "openai/gpt-4o", "openai/gpt-4o-mini"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Regexp(t, `CASE\s+openai/gpt-4o\s+openai/gpt-4o-mini`, stdout.String())
+	assert.Regexp(t, `capital\s+pass\s+FAIL`, stdout.String())
+	assert.Contains(t, stderr.String(), "openai/gpt-4o-mini: pass rate 50.0% is below 100.0%")
+	assert.NotContains(t, stderr.String(), "openai/gpt-4o: pass rate")
+}
+
+func TestEvalUsage(t *testing.T) {
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 2, runEval([]string{"eval.yaml"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: gollm eval")
+
+	dataset := filepath.Join(t.TempDir(), "eval.yaml")
+	require.NoError(t, os.WriteFile(dataset, []byte(evalDataset), 0o644))
+	stderr.Reset()
+	assert.Equal(t, 2, runEval([]string{dataset, "gpt-4o-mini"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `expected provider/model, got "gpt-4o-mini"`)
+
+	stderr.Reset()
+	assert.Equal(t, 2, runEval([]string{dataset, "openai/gpt-4o", "openai/gpt-4o"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "openai/gpt-4o is listed twice")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Exit codes of the prompt command. Failures reported by the provider map to their
+// llm.ErrorType, so scripts can, for example, back off on a rate limit and stop on bad
+// credentials.
+const (
+	exitOK           = 0
+	exitError        = 1 // any failure not listed below
+	exitUsage        = 2 // invalid flags or arguments
+	exitInvalidInput = 3 // the provider rejected the request as invalid
+	exitAuth         = 4 // the provider rejected the credentials
+	exitRateLimit    = 5 // the provider's rate limit was hit
+	exitAPI          = 6 // the provider failed to answer
+	exitRequest      = 7 // the request could not be sent
+	exitResponse     = 8 // the reply could not be read, was not JSON or did not match -schema
+	exitUnsupported  = 9 // the provider cannot do what was asked, such as streaming
+	exitInterrupted  = 130
+)
+
+// exitCode returns the exit code for an error returned by a generation call.
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	var llmErr *llm.LLMError
+	if !errors.As(err, &llmErr) {
+		return exitError
+	}
+	switch llmErr.Type {
+	case llm.ErrorTypeInvalidInput:
+		return exitInvalidInput
+	case llm.ErrorTypeAuthentication:
+		return exitAuth
+	case llm.ErrorTypeRateLimit:
+		return exitRateLimit
+	case llm.ErrorTypeAPI, llm.ErrorTypeProvider:
+		return exitAPI
+	case llm.ErrorTypeRequest:
+		return exitRequest
+	case llm.ErrorTypeResponse:
+		return exitResponse
+	case llm.ErrorTypeUnsupported:
+		return exitUnsupported
+	default:
+		return exitError
+	}
+}
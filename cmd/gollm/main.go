@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -16,56 +20,156 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "eval":
+			os.Exit(runEval(os.Args[2:], os.Stdout, os.Stderr))
+		case "chat":
+			os.Exit(runChat(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "batch":
+			os.Exit(runBatch(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// run sends one prompt and prints the reply. It returns one of the exit codes in exitcode.go.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gollm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
 	// Existing flags
-	promptType := flag.String("type", "raw", "Prompt type (raw, qa, cot, summarize, optimize)")
-	verbose := flag.Bool("verbose", false, "Display verbose output including full prompt")
-	provider := flag.String("provider", "", "LLM provider (anthropic, openai, groq, mistral, ollama, cohere)")
-	model := flag.String("model", "", "LLM model")
-	temperature := flag.Float64("temperature", -1, "LLM temperature")
-	maxTokens := flag.Int("max-tokens", 0, "LLM max tokens")
-	timeout := flag.Duration("timeout", 0, "LLM timeout")
-	apiKey := flag.String("api-key", "", "API key for the specified provider")
-	maxRetries := flag.Int("max-retries", 3, "Maximum number of retries for API calls")
-	retryDelay := flag.Duration("retry-delay", time.Second*2, "Delay between retries")
-	debugLevel := flag.String("debug-level", "warn", "Debug level (debug, info, warn, error)")
-	outputFormat := flag.String("output-format", "", "Output format for structured responses (json)")
+	profile := flags.String("profile", "", "Configuration profile, replacing LLM_PROFILE")
+	promptType := flags.String("type", "raw", "Prompt type (raw, qa, cot, summarize, optimize)")
+	verbose := flags.Bool("verbose", false, "Display verbose output including full prompt")
+	provider := flags.String("provider", "", "LLM provider (anthropic, openai, groq, mistral, ollama, cohere)")
+	model := flags.String("model", "", "LLM model")
+	temperature := flags.Float64("temperature", -1, "LLM temperature")
+	maxTokens := flags.Int("max-tokens", 0, "LLM max tokens")
+	timeout := flags.Duration("timeout", 0, "LLM timeout")
+	apiKey := flags.String("api-key", "", "API key for the specified provider")
+	maxRetries := flags.Int("max-retries", 3, "Maximum number of retries for API calls")
+	retryDelay := flags.Duration("retry-delay", time.Second*2, "Delay between retries")
+	debugLevel := flags.String("debug-level", "warn", "Debug level (debug, info, warn, error)")
+	outputFormat := flags.String("output-format", "", "Output format (json); the reply must be JSON and is printed indented")
 
 	// New flags for prompt optimization
-	optimizeGoal := flag.String("optimize-goal", "Improve the prompt's clarity and effectiveness", "Optimization goal")
-	optimizeIterations := flag.Int("optimize-iterations", 5, "Number of optimization iterations")
-	optimizeMemory := flag.Int("optimize-memory", 2, "Number of previous iterations to remember")
+	optimizeGoal := flags.String("optimize-goal", "Improve the prompt's clarity and effectiveness", "Optimization goal")
+	optimizeIterations := flags.Int("optimize-iterations", 5, "Number of optimization iterations")
+	optimizeMemory := flags.Int("optimize-memory", 2, "Number of previous iterations to remember")
+
+	// Input and output
+	fromStdin := flags.Bool("stdin", false, "Read the prompt from standard input, after any prompt arguments")
+	system := flags.String("system", "", "System prompt")
+	var attachments stringList
+	flags.Var(&attachments, "attach", "Attach an image or document by path (repeatable)")
+	stream := flags.Bool("stream", false, "Print the reply as it arrives (raw prompts only)")
+	schemaPath := flags.String("schema", "", "JSON schema file; the reply is validated against it and printed as JSON (raw prompts only)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gollm [flags] <prompt>\n")
+		fmt.Fprintf(stderr, "       gollm chat [flags]\n")
+		fmt.Fprintf(stderr, "       gollm batch [flags] <requests.jsonl>\n")
+		fmt.Fprintf(stderr, "       gollm eval [flags] <dataset> <provider/model>...\n\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExit codes: 1 error, 2 usage, 3 invalid input, 4 authentication, 5 rate limit,\n")
+		fmt.Fprintf(stderr, "6 provider API error, 7 request failed, 8 invalid response, 9 unsupported, 130 interrupted.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	rawPrompt := strings.Join(flags.Args(), " ")
+	if *fromStdin {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading standard input: %v\n", err)
+			return exitError
+		}
+		rawPrompt = strings.TrimSpace(strings.Join([]string{rawPrompt, strings.TrimSpace(string(input))}, "\n\n"))
+	}
+	if rawPrompt == "" {
+		flags.Usage()
+		return exitUsage
+	}
+	if *promptType != "raw" && (*stream || *schemaPath != "") {
+		fmt.Fprintf(stderr, "Error: -stream and -schema only apply to raw prompts\n")
+		return exitUsage
+	}
+	if *stream && (*schemaPath != "" || *outputFormat == "json") {
+		fmt.Fprintf(stderr, "Error: -stream cannot be combined with -schema or -output-format json\n")
+		return exitUsage
+	}
 
-	flag.Parse()
+	var schema map[string]interface{}
+	if *schemaPath != "" {
+		data, err := os.ReadFile(*schemaPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading schema: %v\n", err)
+			return exitUsage
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			fmt.Fprintf(stderr, "Error parsing schema %s: %v\n", *schemaPath, err)
+			return exitUsage
+		}
+	}
+
+	var promptOpts []gollm.PromptOption
+	if *system != "" {
+		promptOpts = append(promptOpts, gollm.WithSystemPrompt(*system, gollm.CacheTypeEphemeral))
+	}
+	for _, path := range attachments {
+		opt, err := attachment(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		promptOpts = append(promptOpts, opt)
+	}
 
 	// Prepare configuration options
-	configOpts := prepareConfigOptions(provider, model, temperature, maxTokens, timeout, apiKey, maxRetries, retryDelay, debugLevel)
+	configOpts, err := profileOptions(*profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	configOpts = append(configOpts, prepareConfigOptions(provider, model, temperature, maxTokens, timeout, apiKey,
+		explicit(flags, "max-retries", maxRetries), explicit(flags, "retry-delay", retryDelay), explicit(flags, "debug-level", debugLevel))...)
 
 	// Create LLM client with the specified options
 	llmClient, err := gollm.NewLLM(configOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating LLM client: %v\n", err)
-		os.Exit(1)
-	}
-
-	if len(flag.Args()) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <prompt>\n", os.Args[0])
-		flag.PrintDefaults()
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Error creating LLM client: %v\n", err)
+		return exitCode(err)
 	}
 
-	rawPrompt := strings.Join(flag.Args(), " ")
-	ctx := context.Background()
+	// Ctrl-C cancels the request rather than killing the process mid-write.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	var response string
 	var fullPrompt string
 
 	switch *promptType {
 	case "qa":
-		response, err = presets.QuestionAnswer(ctx, llmClient, rawPrompt)
+		response, err = presets.QuestionAnswer(ctx, llmClient, rawPrompt, promptOpts...)
 	case "cot":
-		response, err = presets.ChainOfThought(ctx, llmClient, rawPrompt)
+		response, err = presets.ChainOfThought(ctx, llmClient, rawPrompt, promptOpts...)
 	case "summarize":
-		response, err = presets.Summarize(ctx, llmClient, rawPrompt)
+		response, err = presets.Summarize(ctx, llmClient, rawPrompt, promptOpts...)
 	case "optimize":
 		optimizer := optimizer.NewPromptOptimizer(
 			llmClient,
@@ -83,22 +187,101 @@ func main() {
 			fullPrompt = fmt.Sprintf("Initial Prompt: %s\nOptimization Goal: %s\nMemory Size: %d", rawPrompt, *optimizeGoal, *optimizeMemory)
 		}
 	default:
-		prompt := gollm.NewPrompt(rawPrompt)
+		prompt := gollm.NewPrompt(rawPrompt, promptOpts...)
 		if *outputFormat == "json" {
 			prompt.Apply(gollm.WithOutput("Please provide your response in JSON format."))
 		}
-		response, err = llmClient.Generate(ctx, prompt, gollm.WithJSONSchemaValidation())
 		fullPrompt = prompt.String()
+		switch {
+		case *stream:
+			if *verbose {
+				printPromptHeader(stdout, *promptType, fullPrompt)
+			}
+			if err := streamResponse(ctx, llmClient, prompt, stdout); err != nil {
+				fmt.Fprintf(stderr, "Error generating response: %v\n", err)
+				return exitCode(err)
+			}
+			return exitOK
+		case schema != nil:
+			response, err = llmClient.GenerateWithSchema(ctx, prompt, schema)
+		default:
+			response, err = llmClient.Generate(ctx, prompt, gollm.WithJSONSchemaValidation())
+		}
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating response: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Error generating response: %v\n", err)
+		return exitCode(err)
+	}
+
+	format := *outputFormat
+	if schema != nil {
+		format = "json"
+	}
+	if err := printResponse(stdout, *verbose, *promptType, fullPrompt, rawPrompt, response, format); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitResponse
+	}
+	return exitOK
+}
+
+// streamResponse prints the reply to the prompt as it arrives.
+func streamResponse(ctx context.Context, client gollm.LLM, prompt *gollm.Prompt, w io.Writer) error {
+	stream, err := client.Stream(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for {
+		token, err := stream.Next(ctx)
+		if err == io.EOF {
+			fmt.Fprintln(w)
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(w)
+			return err
+		}
+		fmt.Fprint(w, token.Text)
+	}
+}
+
+// profileOptions returns the option that applies the named profile, or none when name is
+// empty. The profile replaces the one LLM_PROFILE names.
+func profileOptions(name string) ([]gollm.ConfigOption, error) {
+	if name == "" {
+		return nil, nil
+	}
+	opt, err := gollm.LoadProfile(name)
+	if err != nil {
+		return nil, err
 	}
+	os.Unsetenv("LLM_PROFILE")
+	return []gollm.ConfigOption{opt}, nil
+}
 
-	printResponse(*verbose, *promptType, fullPrompt, rawPrompt, response, *outputFormat)
+// given reports whether the named flag was set on the command line.
+func given(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// explicit returns value when the named flag was set on the command line and nil otherwise, so
+// that the flag's default does not override the environment or a profile.
+func explicit[T any](flags *flag.FlagSet, name string, value *T) *T {
+	if !given(flags, name) {
+		return nil
+	}
+	return value
 }
 
+// prepareConfigOptions returns the options for the flags that were set. The retry and debug
+// flags are nil when they were not.
 func prepareConfigOptions(provider, model *string, temperature *float64, maxTokens *int, timeout *time.Duration, apiKey *string, maxRetries *int, retryDelay *time.Duration, debugLevel *string) []gollm.ConfigOption {
 	var configOpts []gollm.ConfigOption
 
@@ -120,34 +303,56 @@ func prepareConfigOptions(provider, model *string, temperature *float64, maxToke
 	if *apiKey != "" {
 		configOpts = append(configOpts, gollm.SetAPIKey(*apiKey))
 	}
-	configOpts = append(configOpts, gollm.SetMaxRetries(*maxRetries))
-	configOpts = append(configOpts, gollm.SetRetryDelay(*retryDelay))
-	configOpts = append(configOpts, gollm.SetLogLevel(gollm.LogLevel(getLogLevel(*debugLevel))))
+	if maxRetries != nil {
+		configOpts = append(configOpts, gollm.SetMaxRetries(*maxRetries))
+	}
+	if retryDelay != nil {
+		configOpts = append(configOpts, gollm.SetRetryDelay(*retryDelay))
+	}
+	if debugLevel != nil {
+		configOpts = append(configOpts, gollm.SetLogLevel(gollm.LogLevel(getLogLevel(*debugLevel))))
+	}
 
 	return configOpts
 }
 
-func printResponse(verbose bool, promptType, fullPrompt, rawPrompt, response, outputFormat string) {
+func printPromptHeader(w io.Writer, promptType, fullPrompt string) {
+	fmt.Fprintf(w, "Prompt Type: %s\nFull Prompt:\n%s\n\nResponse:\n---------\n", promptType, fullPrompt)
+}
+
+// printResponse prints the response. With the json output format, the response must be JSON,
+// optionally fenced as a Markdown code block, and is printed indented.
+func printResponse(w io.Writer, verbose bool, promptType, fullPrompt, rawPrompt, response, outputFormat string) error {
 	if verbose {
 		if fullPrompt == "" {
 			fullPrompt = rawPrompt // For qa, cot, and summarize, we don't have access to the full prompt
 		}
-		fmt.Printf("Prompt Type: %s\nFull Prompt:\n%s\n\nResponse:\n---------\n", promptType, fullPrompt)
+		printPromptHeader(w, promptType, fullPrompt)
 	}
 
-	if outputFormat == "json" {
-		var jsonResponse interface{}
-		err := json.Unmarshal([]byte(response), &jsonResponse)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing JSON response: %v\n", err)
-			fmt.Println(response) // Print raw response if JSON parsing fails
-		} else {
-			jsonPretty, _ := json.MarshalIndent(jsonResponse, "", "  ")
-			fmt.Println(string(jsonPretty))
-		}
-	} else {
-		fmt.Println(response)
+	if outputFormat != "json" {
+		fmt.Fprintln(w, response)
+		return nil
+	}
+	var jsonPretty bytes.Buffer
+	if err := json.Indent(&jsonPretty, []byte(trimCodeFence(response)), "", "  "); err != nil {
+		return fmt.Errorf("response is not valid JSON: %w\n%s", err, response)
+	}
+	fmt.Fprintln(w, jsonPretty.String())
+	return nil
+}
+
+// trimCodeFence removes a Markdown code fence around s, as models often wrap JSON in one.
+func trimCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if _, body, ok := strings.Cut(s, "\n"); ok {
+		return strings.TrimSpace(body)
 	}
+	return ""
 }
 
 func getLogLevel(level string) gollm.LogLevel {
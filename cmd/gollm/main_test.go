@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/gollmtest"
+	"github.com/teilomillet/gollm/llm"
+)
+
+// serve starts an emulated provider answering every request with response, and returns the
+// requests it received.
+func serve(t *testing.T, response gollmtest.Response) func() []gollmtest.Request {
+	var mu sync.Mutex
+	var requests []gollmtest.Request
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		return response
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+	return func() []gollmtest.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]gollmtest.Request(nil), requests...)
+	}
+}
+
+var openAIFlags = []string{"-provider", "openai", "-model", "gpt-4o-mini", "-max-retries", "0"}
+
+func TestRunStdinStream(t *testing.T) {
+	requests := serve(t, gollmtest.Text("Paris"))
+
+	var stdout, stderr strings.Builder
+	args := append(openAIFlags, "-stdin", "-stream", "-system", "Answer in one word.", "Context:")
+	code := run(args, strings.NewReader("What is the capital of France?\n"), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "Paris\n", stdout.String())
+
+	require.Len(t, requests(), 1)
+	request := requests()[0]
+	assert.True(t, request.Stream)
+	assert.Contains(t, request.Prompt, "Context:\n\nWhat is the capital of France?")
+	assert.Contains(t, string(request.Body), "Answer in one word.")
+}
+
+func TestRunAttachments(t *testing.T) {
+	requests := serve(t, gollmtest.Text("A cat and a contract."))
+	dir := t.TempDir()
+	image := filepath.Join(dir, "cat.png")
+	require.NoError(t, os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n0000"), 0o600))
+	document := filepath.Join(dir, "contract.pdf")
+	require.NoError(t, os.WriteFile(document, []byte("%PDF-1.4 contract"), 0o600))
+
+	var stdout, stderr strings.Builder
+	args := append(openAIFlags, "-attach", image, "-attach", document, "Describe these.")
+	code := run(args, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "A cat and a contract.\n", stdout.String())
+
+	require.Len(t, requests(), 1)
+	body := string(requests()[0].Body)
+	assert.Contains(t, body, "data:image/png;base64,"+base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n0000")))
+	assert.Contains(t, body, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4 contract")))
+	assert.Contains(t, body, "contract.pdf")
+
+	stderr.Reset()
+	code = run(append(openAIFlags, "-attach", filepath.Join(dir, "missing.png"), "Describe it."), strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr.String(), "failed to read")
+}
+
+func TestRunSchema(t *testing.T) {
+	schema := "testdata/city.schema.json"
+
+	serve(t, gollmtest.JSON(map[string]string{"city": "Paris"}))
+	var stdout, stderr strings.Builder
+	code := run(append(openAIFlags, "-schema", schema, "Which city?"), strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "{\n  \"city\": \"Paris\"\n}\n", stdout.String())
+
+	serve(t, gollmtest.JSON(map[string]int{"town": 1}))
+	stdout.Reset()
+	code = run(append(openAIFlags, "-schema", schema, "Which city?"), strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, exitResponse, code)
+	assert.Empty(t, stdout.String(), "only validated JSON is printed")
+}
+
+func TestRunOutputFormatJSON(t *testing.T) {
+	serve(t, gollmtest.Text("```json\n{\"ok\": true}\n```"))
+	var stdout, stderr strings.Builder
+	code := run(append(openAIFlags, "-output-format", "json", "Are you ok?"), strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "{\n  \"ok\": true\n}\n", stdout.String())
+
+	serve(t, gollmtest.Text("I am fine."))
+	stdout.Reset()
+	code = run(append(openAIFlags, "-output-format", "json", "Are you ok?"), strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, exitResponse, code)
+	assert.Contains(t, stderr.String(), "response is not valid JSON")
+}
+
+func TestRunExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		response gollmtest.Response
+		args     []string
+		want     int
+	}{
+		{"rate limit", gollmtest.Error(llm.ErrorTypeRateLimit, "slow down"), nil, exitRateLimit},
+		{"authentication", gollmtest.Error(llm.ErrorTypeAuthentication, "bad key"), nil, exitAuth},
+		{"invalid input", gollmtest.HTTPError(400, "prompt too long"), nil, exitInvalidInput},
+		{"server error", gollmtest.HTTPError(500, "oops"), nil, exitAPI},
+		{"streamed rate limit", gollmtest.Error(llm.ErrorTypeRateLimit, "slow down"), []string{"-stream"}, exitRateLimit},
+		{"schema rate limit", gollmtest.Error(llm.ErrorTypeRateLimit, "slow down"), []string{"-schema", "testdata/city.schema.json"}, exitRateLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serve(t, tt.response)
+			var stdout, stderr strings.Builder
+			args := append(append(append([]string{}, openAIFlags...), tt.args...), "Hello")
+			assert.Equal(t, tt.want, run(args, strings.NewReader(""), &stdout, &stderr), stderr.String())
+		})
+	}
+
+	t.Run("no API key", func(t *testing.T) {
+		serve(t, gollmtest.Text("unused"))
+		t.Setenv("OPENAI_API_KEY", "")
+		var stdout, stderr strings.Builder
+		args := append(append([]string{}, openAIFlags...), "Hello")
+		assert.Equal(t, exitAuth, run(args, strings.NewReader(""), &stdout, &stderr), stderr.String())
+	})
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, exitUsage, run(nil, strings.NewReader(""), &stdout, &stderr), "a prompt is required")
+	assert.Equal(t, exitUsage, run([]string{"-stream", "-schema", "x.json", "Hi"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, exitUsage, run([]string{"-type", "qa", "-stream", "Hi"}, strings.NewReader(""), &stdout, &stderr))
+}
+
+func TestRunProfile(t *testing.T) {
+	requests := serve(t, gollmtest.Text("Paris"))
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  work:
+    provider: openai
+    model: gpt-4o-mini
+    max_tokens: 77
+    max_retries: 0
+`), 0o600))
+	t.Setenv("LLM_CONFIG_FILE", path)
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"-profile", "work", "-model", "gpt-4.1-mini", "Capital of France?"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "Paris\n", stdout.String())
+
+	require.Len(t, requests(), 1)
+	// The flag given overrides the profile; the rest of the profile applies.
+	assert.Equal(t, "gpt-4.1-mini", requests()[0].Model)
+	assert.Contains(t, string(requests()[0].Body), `"max_completion_tokens":77`)
+
+	stderr.Reset()
+	assert.Equal(t, exitUsage, run([]string{"-profile", "home", "Hi"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `profile "home" not found`)
+}
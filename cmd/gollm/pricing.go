@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/teilomillet/gollm"
+)
+
+// modelPrice is what a model charges, in US dollars per million tokens.
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// knownPrices are the list prices of common models, matched by model name prefix. A longer
+// prefix comes before a shorter one it extends. Prices change; -input-price and -output-price
+// override them.
+var knownPrices = []struct {
+	prefix string
+	price  modelPrice
+}{
+	{"gpt-4o-mini", modelPrice{0.15, 0.60}},
+	{"gpt-4o", modelPrice{2.50, 10.00}},
+	{"gpt-4.1-nano", modelPrice{0.10, 0.40}},
+	{"gpt-4.1-mini", modelPrice{0.40, 1.60}},
+	{"gpt-4.1", modelPrice{2.00, 8.00}},
+	{"o3-mini", modelPrice{1.10, 4.40}},
+	{"o4-mini", modelPrice{1.10, 4.40}},
+	{"claude-3-5-haiku", modelPrice{0.80, 4.00}},
+	{"claude-3-haiku", modelPrice{0.25, 1.25}},
+	{"claude-3-5-sonnet", modelPrice{3.00, 15.00}},
+	{"claude-3-7-sonnet", modelPrice{3.00, 15.00}},
+	{"claude-sonnet-4", modelPrice{3.00, 15.00}},
+	{"claude-3-opus", modelPrice{15.00, 75.00}},
+	{"claude-opus-4", modelPrice{15.00, 75.00}},
+}
+
+// priceOf returns the list price of model, and false when it is not known.
+func priceOf(model string) (modelPrice, bool) {
+	for _, known := range knownPrices {
+		if strings.HasPrefix(model, known.prefix) {
+			return known.price, true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// cost estimates what usage cost at price. Cached and reasoning tokens are charged as ordinary
+// prompt and completion tokens, so the estimate errs high for cached prompts.
+func (p modelPrice) cost(usage gollm.TokenUsage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
@@ -62,6 +62,17 @@ var (
 	//   }
 	LoadConfig = config.LoadConfig
 
+	// LoadProfile returns the option that applies a named profile from the configuration file.
+	// Environment variables take precedence over the profile, and options after it override both.
+	//
+	// Example usage:
+	//   profile, err := LoadProfile("work")
+	//   if err != nil {
+	//       log.Fatal(err)
+	//   }
+	//   llm, err := NewLLM(profile, SetMaxTokens(500))
+	LoadProfile = config.LoadProfile
+
 	// ApplyOptions applies a series of ConfigOption functions to a Config instance.
 	// This enables fluent configuration updates using the builder pattern.
 	//
@@ -81,6 +92,10 @@ var (
 	SetOllamaEndpoint = config.SetOllamaEndpoint // Sets the endpoint URL for Ollama local deployment
 	SetVLLMEndpoint   = config.SetVLLMEndpoint   // Sets the endpoint URL for vLLM local deployment
 	SetAPIKey         = config.SetAPIKey         // Sets the API key for the current provider
+	SetBaseURL        = config.SetBaseURL        // Points the provider's endpoints at another server
+
+	SetVertex                = config.SetVertex                // Sets the Google Cloud project and location for Vertex AI
+	SetGoogleCredentialsFile = config.SetGoogleCredentialsFile // Sets the service account key file for Vertex AI
 
 	// Generation parameters
 	SetTemperature      = config.SetTemperature      // Controls randomness in generation (0.0-1.0)
@@ -115,9 +130,17 @@ var (
 	// RoundTrippers (transport-level usage capture, logging, proxies, TLS).
 	SetHTTPClient = config.SetHTTPClient
 
+	// SetAPIKeyPool rotates a provider's requests through a shared pool of API keys.
+	SetAPIKeyPool = config.SetAPIKeyPool
+
+	// SetCredentialSource authenticates a provider with refreshable credentials (OAuth2 tokens,
+	// secret files, helper commands) instead of a static key.
+	SetCredentialSource = config.SetCredentialSource
+
 	// Feature toggles
 	SetEnableCaching = config.SetEnableCaching // Enables/disables response caching
 	SetMemory        = config.SetMemory        // Configures conversation memory
+	SetAudioOutput   = config.SetAudioOutput   // Requests spoken output from audio-capable OpenAI models
 
 	// Validation configuration
 	SetCustomValidator = config.SetCustomValidator // Sets a custom validation function to override default validation
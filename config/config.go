@@ -4,12 +4,14 @@
 package config
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/teilomillet/gollm/keypool"
 	"github.com/teilomillet/gollm/types"
 	"github.com/teilomillet/gollm/utils"
 )
@@ -31,6 +33,10 @@ type MemoryOption struct {
 //   - LLM_PROVIDER: LLM provider name (default: "anthropic")
 //   - LLM_MODEL: Model name (default: "claude-3-opus-20240229")
 //   - OLLAMA_ENDPOINT: Ollama API endpoint (default: "http://localhost:11434")
+//   - LLM_BASE_URL: Scheme, host and path prefix that replace those of the provider's endpoints
+//   - VERTEX_PROJECT: Google Cloud project for the vertex provider
+//   - VERTEX_LOCATION: Vertex AI region, or "global" (default: "us-central1")
+//   - GOOGLE_APPLICATION_CREDENTIALS: Service account key file the vertex provider signs in with
 //   - LLM_TEMPERATURE: Generation temperature (default: 0.7)
 //   - LLM_MAX_TOKENS: Maximum tokens to generate (default: 100)
 //   - LLM_TOP_P: Top-p sampling parameter (default: 0.9)
@@ -43,6 +49,9 @@ type MemoryOption struct {
 //   - LLM_SEED: Random seed for reproducible generation
 //   - LLM_ENABLE_CACHING: Enable response caching (default: false)
 //   - LLM_ENABLE_STREAMING: Enable streaming responses (default: false)
+//   - LLM_PROVIDERS_FILE: YAML or JSON file of custom providers to register
+//   - LLM_PROFILE: Profile to apply from the configuration file (see Profile)
+//   - LLM_CONFIG_FILE: Configuration file of profiles (default: gollm/config.yaml in the user's configuration directory)
 //
 // Advanced Parameters:
 //   - LLM_MIN_P: Minimum token probability threshold
@@ -57,6 +66,10 @@ type Config struct {
 	Model                 string            `env:"LLM_MODEL" envDefault:"claude-3-5-haiku-latest" validate:"required"`
 	OllamaEndpoint        string            `env:"OLLAMA_ENDPOINT" envDefault:"http://localhost:11434"`
 	VLLMEndpoint          string            `env:"VLLM_ENDPOINT" envDefault:"http://localhost:8000"`
+	BaseURL               string            `env:"LLM_BASE_URL"`
+	VertexProject         string            `env:"VERTEX_PROJECT" validate:"required_if=Provider vertex"`
+	VertexLocation        string            `env:"VERTEX_LOCATION" envDefault:"us-central1"`
+	GoogleCredentialsFile string            `env:"GOOGLE_APPLICATION_CREDENTIALS"`
 	Temperature           float64           `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
 	MaxTokens             int               `env:"LLM_MAX_TOKENS" envDefault:"100"`
 	TopP                  float64           `env:"LLM_TOP_P" envDefault:"0.9" validate:"gte=0,lte=1"`
@@ -79,8 +92,9 @@ type Config struct {
 	SystemPrompt          string
 	SystemPromptCacheType string
 	ExtraHeaders          map[string]string
-	EnableCaching         bool `env:"LLM_ENABLE_CACHING" envDefault:"false"`
-	EnableStreaming       bool `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
+	EnableCaching         bool   `env:"LLM_ENABLE_CACHING" envDefault:"false"`
+	EnableStreaming       bool   `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
+	ProvidersFile         string `env:"LLM_PROVIDERS_FILE"`
 	MemoryOption          *MemoryOption
 	CustomValidator       func(interface{}) error // Custom validation function to override default validation
 
@@ -96,11 +110,26 @@ type Config struct {
 	// reach of body parsing), request logging, proxies, or custom TLS. When nil a client is built
 	// with the configured Timeout; when set, its own Timeout is respected as-is.
 	HTTPClient *http.Client
+
+	// AudioOutput, when set, asks audio-capable OpenAI models to answer with speech as well as
+	// text. The audio and its transcript are returned on ResponseDetails.Audio.
+	AudioOutput *types.AudioOutputConfig
+
+	// APIKeyPools holds, per provider name, a pool of keys that requests rotate through instead
+	// of the single key in APIKeys. The pools are pointers, so every client built from this config
+	// (or from copies of it) shares their rotation, benches and accounting.
+	APIKeyPools map[string]*keypool.Pool
+
+	// CredentialSources holds, per provider name, a source asked for the credential before every
+	// request, for short-lived tokens and keys kept outside the environment. A provider with a
+	// source needs no entry in APIKeys, and the source takes precedence over a key pool.
+	CredentialSources map[string]CredentialSource
 }
 
 // LoadConfig creates a new Config instance, loading values from environment
-// variables and automatically detecting API keys. It returns an error if
-// environment variable parsing fails.
+// variables and automatically detecting API keys. When LLM_PROFILE is set, the
+// named profile fills in what the environment does not set. It returns an error if
+// environment variable parsing fails or the profile cannot be loaded.
 //
 // Example usage:
 //
@@ -121,9 +150,52 @@ func LoadConfig() (*Config, error) {
 	}
 
 	loadAPIKeys(cfg)
+	if name := os.Getenv("LLM_PROFILE"); name != "" {
+		profile, err := findProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		profile.Apply(cfg)
+	}
+	if cfg.ProvidersFile != "" {
+		if err := loadProvidersFile(cfg); err != nil {
+			return nil, err
+		}
+	}
 	return cfg, nil
 }
 
+// ProvidersFileLoader registers the providers defined in a file and returns the API keys the
+// file supplies, keyed by provider name.
+type ProvidersFileLoader func(path string) (map[string]string, error)
+
+var providersFileLoader ProvidersFileLoader
+
+// SetProvidersFileLoader installs the loader LoadConfig runs when LLM_PROVIDERS_FILE is set.
+// The providers package installs providers.RegisterProvidersFile when it is imported; config
+// cannot call it directly because providers depends on config.
+func SetProvidersFileLoader(loader ProvidersFileLoader) {
+	providersFileLoader = loader
+}
+
+// loadProvidersFile registers the providers in cfg.ProvidersFile. A key the file supplies is
+// used only when the environment did not already provide one for that provider.
+func loadProvidersFile(cfg *Config) error {
+	if providersFileLoader == nil {
+		return fmt.Errorf("LLM_PROVIDERS_FILE is set but no loader is installed; import github.com/teilomillet/gollm/providers")
+	}
+	keys, err := providersFileLoader(cfg.ProvidersFile)
+	if err != nil {
+		return err
+	}
+	for name, key := range keys {
+		if cfg.APIKeys[name] == "" {
+			cfg.APIKeys[name] = key
+		}
+	}
+	return nil
+}
+
 // loadAPIKeys automatically detects and loads API keys from environment variables
 // matching the pattern *_API_KEY. It ensures the default provider has an API key
 // available.
@@ -210,6 +282,33 @@ func SetVLLMEndpoint(endpoint string) ConfigOption {
 	}
 }
 
+// SetBaseURL points the provider at another server, such as a proxy, a self-hosted gateway or
+// the gollmtest emulator. The scheme and host of the provider's endpoints are replaced by those
+// of baseURL, and its path is prefixed to theirs, so "http://127.0.0.1:8080" sends OpenAI chat
+// completions to "http://127.0.0.1:8080/v1/chat/completions".
+func SetBaseURL(baseURL string) ConfigOption {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+// SetVertex sets the Google Cloud project and location (a region such as "europe-west4", or
+// "global") whose Vertex AI endpoints the vertex provider calls.
+func SetVertex(project, location string) ConfigOption {
+	return func(c *Config) {
+		c.VertexProject = project
+		c.VertexLocation = location
+	}
+}
+
+// SetGoogleCredentialsFile sets the service account key file the vertex provider exchanges for
+// access tokens when it has no API key or credential source.
+func SetGoogleCredentialsFile(path string) ConfigOption {
+	return func(c *Config) {
+		c.GoogleCredentialsFile = path
+	}
+}
+
 // SetTemperature sets the generation temperature.
 func SetTemperature(temperature float64) ConfigOption {
 	return func(c *Config) {
@@ -295,6 +394,39 @@ func WithUsageObserver(observer types.UsageObserver) ConfigOption {
 	}
 }
 
+// SetAPIKeyPool makes requests to provider rotate through the keys in pool. Each request takes a
+// key from the pool, a key answered with 401 or 429 is benched for the pool's cool-down, and the
+// key's ID is reported on UsageEvent.KeyID. When APIKeys has no key for the provider, the pool's
+// first key is stored there so that validation and client construction succeed.
+//
+// Pass the same pool to every client that should share it.
+func SetAPIKeyPool(provider string, pool *keypool.Pool) ConfigOption {
+	return func(c *Config) {
+		if c.APIKeyPools == nil {
+			c.APIKeyPools = make(map[string]*keypool.Pool)
+		}
+		c.APIKeyPools[provider] = pool
+		if c.APIKeys == nil {
+			c.APIKeys = make(map[string]string)
+		}
+		if c.APIKeys[provider] == "" {
+			c.APIKeys[provider] = pool.First().Value
+		}
+	}
+}
+
+// SetCredentialSource makes requests to provider authenticate with the credential source
+// supplies rather than a static API key: an Entra ID or Google OAuth2 token, a mounted secret, or
+// the output of a helper command. See CredentialSource and the built-in sources.
+func SetCredentialSource(provider string, source CredentialSource) ConfigOption {
+	return func(c *Config) {
+		if c.CredentialSources == nil {
+			c.CredentialSources = make(map[string]CredentialSource)
+		}
+		c.CredentialSources[provider] = source
+	}
+}
+
 // SetHTTPClient overrides the HTTP client used for provider requests, letting a caller install a
 // custom RoundTripper. That is the seam for transport-level concerns the provider layer cannot
 // reach: usage reported in response headers (Bedrock's token counts arrive that way), request
@@ -334,6 +466,14 @@ func WithStream(enableStreaming bool) ConfigOption {
 	}
 }
 
+// SetAudioOutput requests spoken responses in the given voice and format ("wav", "mp3", …)
+// from audio-capable OpenAI models such as gpt-4o-audio-preview.
+func SetAudioOutput(voice, format string) ConfigOption {
+	return func(c *Config) {
+		c.AudioOutput = &types.AudioOutputConfig{Voice: voice, Format: format}
+	}
+}
+
 // SetTopP sets the top-p sampling parameter.
 func SetTopP(topP float64) ConfigOption {
 	return func(c *Config) {
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credential is the secret a request authenticates with.
+type Credential struct {
+	// Value is the key or token.
+	Value string
+
+	// ExpiresAt is when Value stops being valid. The zero time means it does not expire.
+	ExpiresAt time.Time
+
+	// Header and Prefix, when Header is set, replace the provider's own authentication header
+	// with "Header: Prefix+Value". OAuth2 tokens use this to send "Authorization: Bearer ..."
+	// to providers whose keys travel in another header, such as Azure OpenAI's api-key. When
+	// Header is empty, Value takes the place of the API key in the provider's own
+	// authentication header.
+	Header string
+	Prefix string
+}
+
+// CredentialSource supplies the credential for a provider's requests. It is called before
+// every request, so a source that is slow to produce a credential should cache it; the sources
+// in this package that need to do so already do. A source may be shared by many clients and
+// must be safe for concurrent use.
+type CredentialSource interface {
+	Credential(ctx context.Context) (Credential, error)
+}
+
+// CredentialInvalidator is implemented by sources that cache. The client calls Invalidate when
+// the provider rejects a credential (401), so the next request fetches a fresh one rather than
+// reusing the rejected credential until it expires.
+type CredentialInvalidator interface {
+	Invalidate()
+}
+
+// CredentialSourceFunc adapts a function to CredentialSource.
+type CredentialSourceFunc func(ctx context.Context) (Credential, error)
+
+// Credential calls f.
+func (f CredentialSourceFunc) Credential(ctx context.Context) (Credential, error) {
+	return f(ctx)
+}
+
+// DefaultRefreshMargin is how long before its expiry a cached credential is replaced, so that a
+// request never sets out with a credential that expires in flight. A credential that lives for
+// less than twice the margin is replaced halfway through its life instead.
+const DefaultRefreshMargin = time.Minute
+
+// CachedCredentialSource caches the credential of another source until shortly before it
+// expires. Concurrent callers share a single refresh.
+type CachedCredentialSource struct {
+	source CredentialSource
+	margin time.Duration
+
+	mu      sync.Mutex
+	cached  *Credential
+	fetched time.Time // when cached was fetched
+}
+
+// CacheCredential wraps source so that its credential is fetched once and reused until
+// DefaultRefreshMargin before it expires, or until half its life has passed when that comes
+// later. A credential without an expiry is kept until Invalidate is called.
+func CacheCredential(source CredentialSource) *CachedCredentialSource {
+	return &CachedCredentialSource{source: source, margin: DefaultRefreshMargin}
+}
+
+// Credential returns the cached credential, fetching a new one when there is none or it is
+// about to expire.
+func (c *CachedCredentialSource) Credential(ctx context.Context) (Credential, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached != nil && (c.cached.ExpiresAt.IsZero() || time.Until(c.cached.ExpiresAt) > c.refreshMargin()) {
+		return *c.cached, nil
+	}
+	fetched := time.Now()
+	cred, err := c.source.Credential(ctx)
+	if err != nil {
+		return Credential{}, err
+	}
+	c.cached, c.fetched = &cred, fetched
+	return cred, nil
+}
+
+// refreshMargin is how long before its expiry the cached credential is replaced: the margin, but
+// no more than half the credential's lifetime, so that a short-lived one is not fetched again on
+// every request.
+func (c *CachedCredentialSource) refreshMargin() time.Duration {
+	return max(min(c.margin, c.cached.ExpiresAt.Sub(c.fetched)/2), 0)
+}
+
+// Invalidate drops the cached credential.
+func (c *CachedCredentialSource) Invalidate() {
+	c.mu.Lock()
+	c.cached = nil
+	c.mu.Unlock()
+}
+
+// EnvCredential reads the credential from an environment variable on every request, so a
+// process that updates its own environment is picked up immediately.
+func EnvCredential(name string) CredentialSource {
+	return CredentialSourceFunc(func(context.Context) (Credential, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return Credential{}, fmt.Errorf("credential: environment variable %s is not set", name)
+		}
+		return Credential{Value: value}, nil
+	})
+}
+
+// FileCredential reads the credential from a file, such as a mounted secret, trimming
+// surrounding whitespace. The file is read again once ttl has passed; a ttl of zero reads it
+// once.
+func FileCredential(path string, ttl time.Duration) *CachedCredentialSource {
+	return CacheCredential(CredentialSourceFunc(func(context.Context) (Credential, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Credential{}, fmt.Errorf("credential: %w", err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return Credential{}, fmt.Errorf("credential: %s is empty", path)
+		}
+		return Credential{Value: value, ExpiresAt: expiryAfter(ttl)}, nil
+	}))
+}
+
+// CommandCredential runs a helper command and uses what it prints. Plain output is the
+// credential itself, and is reused for ttl (zero: until invalidated). JSON output is read as a
+// token response: the credential is its "access_token", "accessToken" or "token" field, and its
+// expiry comes from "expires_in" (seconds) or "expires_at"/"expiresOn" (RFC 3339), falling back
+// to ttl.
+func CommandCredential(ttl time.Duration, name string, args ...string) *CachedCredentialSource {
+	return CacheCredential(CredentialSourceFunc(func(ctx context.Context) (Credential, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return Credential{}, fmt.Errorf("credential: %s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		cred, err := parseCommandOutput(out, ttl)
+		if err != nil {
+			return Credential{}, fmt.Errorf("credential: %s: %w", name, err)
+		}
+		return cred, nil
+	}))
+}
+
+func parseCommandOutput(out []byte, ttl time.Duration) (Credential, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return Credential{}, fmt.Errorf("no output")
+	}
+	if out[0] != '{' {
+		return Credential{Value: string(out), ExpiresAt: expiryAfter(ttl)}, nil
+	}
+
+	var resp struct {
+		AccessToken      string          `json:"access_token"`
+		AccessTokenCamel string          `json:"accessToken"`
+		Token            string          `json:"token"`
+		ExpiresIn        json.Number     `json:"expires_in"`
+		ExpiresAt        string          `json:"expires_at"`
+		ExpiresOn        json.RawMessage `json:"expiresOn"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return Credential{}, fmt.Errorf("parsing output: %w", err)
+	}
+	cred := Credential{Value: firstNonEmpty(resp.AccessToken, resp.AccessTokenCamel, resp.Token), ExpiresAt: expiryAfter(ttl)}
+	if cred.Value == "" {
+		return Credential{}, fmt.Errorf("output has no access_token, accessToken or token field")
+	}
+	var expiresOn string
+	_ = json.Unmarshal(resp.ExpiresOn, &expiresOn)
+	if seconds, err := resp.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		cred.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if at, err := time.Parse(time.RFC3339, firstNonEmpty(resp.ExpiresAt, expiresOn)); err == nil {
+		cred.ExpiresAt = at
+	}
+	return cred, nil
+}
+
+func expiryAfter(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AzureCognitiveServicesScope is the scope of Entra ID tokens for Azure OpenAI.
+const AzureCognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+// OAuth2ClientCredentialsConfig configures OAuth2ClientCredentials.
+type OAuth2ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient makes the token requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// OAuth2ClientCredentials exchanges a client ID and secret for access tokens with the OAuth2
+// client-credentials grant, caching each token until shortly before it expires. Tokens are sent
+// as "Authorization: Bearer <token>".
+func OAuth2ClientCredentials(cfg OAuth2ClientCredentialsConfig) *CachedCredentialSource {
+	return CacheCredential(CredentialSourceFunc(func(ctx context.Context) (Credential, error) {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {cfg.ClientID},
+			"client_secret": {cfg.ClientSecret},
+		}
+		if len(cfg.Scopes) > 0 {
+			form.Set("scope", strings.Join(cfg.Scopes, " "))
+		}
+		return requestToken(ctx, cfg.HTTPClient, cfg.TokenURL, form)
+	}))
+}
+
+// AzureEntraCredential obtains Entra ID (Azure AD) tokens for Azure OpenAI with a service
+// principal's client secret. With it, AzureOpenAIProvider authenticates with a bearer token
+// instead of an api-key.
+func AzureEntraCredential(tenantID, clientID, clientSecret string) *CachedCredentialSource {
+	return OAuth2ClientCredentials(OAuth2ClientCredentialsConfig{
+		TokenURL:     "https://login.microsoftonline.com/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{AzureCognitiveServicesScope},
+	})
+}
+
+// OAuth2JWTBearerConfig configures OAuth2JWTBearer.
+type OAuth2JWTBearerConfig struct {
+	TokenURL string
+	// Issuer is the assertion's "iss" claim, e.g. a service account's email address.
+	Issuer string
+	// Subject is the optional "sub" claim, for acting on behalf of a user.
+	Subject string
+	// Audience is the "aud" claim; the token URL when empty.
+	Audience string
+	Scopes   []string
+	// PrivateKeyPEM is the RSA key that signs the assertion, PKCS#8 or PKCS#1.
+	PrivateKeyPEM []byte
+	// KeyID is the optional "kid" header naming the key.
+	KeyID string
+	// HTTPClient makes the token requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// OAuth2JWTBearer obtains access tokens with the JWT-bearer grant (RFC 7523): it signs a
+// short-lived RS256 assertion and exchanges it at the token URL. Tokens are cached until
+// shortly before they expire and sent as "Authorization: Bearer <token>".
+func OAuth2JWTBearer(cfg OAuth2JWTBearerConfig) (*CachedCredentialSource, error) {
+	key, err := parseRSAPrivateKey(cfg.PrivateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	audience := cfg.Audience
+	if audience == "" {
+		audience = cfg.TokenURL
+	}
+	return CacheCredential(CredentialSourceFunc(func(ctx context.Context) (Credential, error) {
+		now := time.Now()
+		claims := map[string]interface{}{
+			"iss": cfg.Issuer,
+			"aud": audience,
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if cfg.Subject != "" {
+			claims["sub"] = cfg.Subject
+		}
+		if len(cfg.Scopes) > 0 {
+			claims["scope"] = strings.Join(cfg.Scopes, " ")
+		}
+		assertion, err := signJWT(key, cfg.KeyID, claims)
+		if err != nil {
+			return Credential{}, err
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		return requestToken(ctx, cfg.HTTPClient, cfg.TokenURL, form)
+	})), nil
+}
+
+// GoogleServiceAccountCredential obtains Google OAuth2 access tokens from a service account's
+// JSON key, for the Gemini and Vertex AI APIs. Scopes defaults to cloud-platform.
+func GoogleServiceAccountCredential(jsonKey []byte, scopes ...string) (*CachedCredentialSource, error) {
+	var key struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(jsonKey, &key); err != nil {
+		return nil, fmt.Errorf("credential: parsing service account key: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("credential: not a service account key (need type service_account, client_email and private_key)")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	if len(scopes) == 0 {
+		scopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+	}
+	return OAuth2JWTBearer(OAuth2JWTBearerConfig{
+		TokenURL:      key.TokenURI,
+		Issuer:        key.ClientEmail,
+		Scopes:        scopes,
+		PrivateKeyPEM: []byte(key.PrivateKey),
+		KeyID:         key.PrivateKeyID,
+	})
+}
+
+// requestToken posts a token request and reads the standard OAuth2 token response.
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values) (Credential, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Credential{}, fmt.Errorf("credential: creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Credential{}, fmt.Errorf("credential: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Credential{}, fmt.Errorf("credential: reading token response: %w", err)
+	}
+
+	var token struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &token)
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		detail := strings.TrimSpace(token.Error + ": " + token.ErrorDescription)
+		if token.Error == "" {
+			detail = strings.TrimSpace(string(body))
+		}
+		return Credential{}, fmt.Errorf("credential: token endpoint returned status %d: %s", resp.StatusCode, detail)
+	}
+
+	cred := Credential{Value: token.AccessToken, Header: "Authorization", Prefix: "Bearer "}
+	if seconds, err := token.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		cred.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return cred, nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("credential: private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("credential: parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("credential: private key is %T, not RSA", parsed)
+	}
+	return key, nil
+}
+
+// signJWT returns a compact RS256-signed JWT.
+func signJWT(key *rsa.PrivateKey, keyID string, claims map[string]interface{}) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(headerJSON) + "." + enc.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("credential: signing assertion: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(signature), nil
+}
@@ -0,0 +1,217 @@
+package config_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestCacheCredential(t *testing.T) {
+	var calls atomic.Int32
+	lifetime := time.Hour
+	source := config.CacheCredential(config.CredentialSourceFunc(func(context.Context) (config.Credential, error) {
+		n := calls.Add(1)
+		return config.Credential{Value: fmt.Sprintf("token-%d", n), ExpiresAt: time.Now().Add(lifetime)}, nil
+	}))
+
+	for i := 0; i < 3; i++ {
+		cred, err := source.Credential(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", cred.Value)
+	}
+	assert.EqualValues(t, 1, calls.Load(), "a valid credential is reused")
+
+	source.Invalidate()
+	cred, err := source.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", cred.Value, "an invalidated credential is fetched again")
+
+	// A credential shorter-lived than the refresh margin is reused for half its life, then
+	// counts as expired.
+	lifetime = 200 * time.Millisecond
+	source.Invalidate()
+	_, _ = source.Credential(context.Background())
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "token-3", cred.Value)
+	time.Sleep(lifetime * 3 / 4)
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "token-4", cred.Value)
+
+	// One that has already expired is never reused.
+	lifetime = -time.Second
+	source.Invalidate()
+	_, _ = source.Credential(context.Background())
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "token-6", cred.Value)
+}
+
+func TestEnvCredential(t *testing.T) {
+	source := config.EnvCredential("GOLLM_TEST_CREDENTIAL")
+	_, err := source.Credential(context.Background())
+	assert.ErrorContains(t, err, "GOLLM_TEST_CREDENTIAL is not set")
+
+	t.Setenv("GOLLM_TEST_CREDENTIAL", "from-env")
+	cred, err := source.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, config.Credential{Value: "from-env"}, cred)
+}
+
+func TestFileCredential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("  first-secret\n"), 0o600))
+
+	source := config.FileCredential(path, 0)
+	cred, err := source.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first-secret", cred.Value)
+
+	require.NoError(t, os.WriteFile(path, []byte("rotated-secret"), 0o600))
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "first-secret", cred.Value, "without a ttl the file is read once")
+	source.Invalidate()
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "rotated-secret", cred.Value)
+
+	// A ttl under the refresh margin still spares the file a read on every request.
+	source = config.FileCredential(path, 30*time.Second)
+	_, _ = source.Credential(context.Background())
+	require.NoError(t, os.WriteFile(path, []byte("third-secret"), 0o600))
+	cred, _ = source.Credential(context.Background())
+	assert.Equal(t, "rotated-secret", cred.Value)
+
+	_, err = config.FileCredential(filepath.Join(t.TempDir(), "absent"), 0).Credential(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCommandCredential(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	cred, err := config.CommandCredential(time.Minute, "sh", "-c", "echo plain-token").Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "plain-token", cred.Value)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), cred.ExpiresAt, 5*time.Second)
+
+	cred, err = config.CommandCredential(0, "sh", "-c", `echo '{"accessToken":"az-token","expiresOn":"2030-01-02T03:04:05Z"}'`).Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "az-token", cred.Value)
+	assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), cred.ExpiresAt.UTC())
+
+	cred, err = config.CommandCredential(0, "sh", "-c", `echo '{"access_token":"tok","expires_in":600}'`).Credential(context.Background())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), cred.ExpiresAt, 5*time.Second)
+
+	_, err = config.CommandCredential(0, "sh", "-c", "echo denied >&2; exit 3").Credential(context.Background())
+	assert.ErrorContains(t, err, "denied")
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "app", r.PostForm.Get("client_id"))
+		assert.Equal(t, config.AzureCognitiveServicesScope, r.PostForm.Get("scope"))
+		if r.PostForm.Get("client_secret") != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad secret"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"entra-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	cfg := config.OAuth2ClientCredentialsConfig{
+		TokenURL: server.URL, ClientID: "app", ClientSecret: "s3cret",
+		Scopes: []string{config.AzureCognitiveServicesScope},
+	}
+	source := config.OAuth2ClientCredentials(cfg)
+	for i := 0; i < 2; i++ {
+		cred, err := source.Credential(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "entra-token", cred.Value)
+		assert.Equal(t, "Authorization", cred.Header)
+		assert.Equal(t, "Bearer ", cred.Prefix)
+	}
+	assert.EqualValues(t, 1, requests.Load(), "the token is cached until it expires")
+
+	cfg.ClientSecret = "wrong"
+	_, err := config.OAuth2ClientCredentials(cfg).Credential(context.Background())
+	assert.ErrorContains(t, err, "invalid_client: bad secret")
+}
+
+func TestGoogleServiceAccountCredential(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature), "assertion is signed with the key")
+
+		var header, claims map[string]interface{}
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, json.Unmarshal(headerJSON, &header))
+		require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+		assert.Equal(t, "RS256", header["alg"])
+		assert.Equal(t, "key-1", header["kid"])
+		assert.Equal(t, "bot@project.iam.gserviceaccount.com", claims["iss"])
+		assert.Equal(t, "https://www.googleapis.com/auth/cloud-platform", claims["scope"])
+		assert.Equal(t, "http://"+r.Host+"/token", claims["aud"])
+
+		_, _ = w.Write([]byte(`{"access_token":"google-token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	jsonKey, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "bot@project.iam.gserviceaccount.com",
+		"private_key":    string(keyPEM),
+		"private_key_id": "key-1",
+		"token_uri":      server.URL + "/token",
+	})
+	source, err := config.GoogleServiceAccountCredential(jsonKey)
+	require.NoError(t, err)
+	cred, err := source.Credential(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "google-token", cred.Value)
+	assert.WithinDuration(t, time.Now().Add(3599*time.Second), cred.ExpiresAt, 5*time.Second)
+
+	_, err = config.GoogleServiceAccountCredential([]byte(`{"type":"authorized_user"}`))
+	assert.Error(t, err)
+	_, err = config.OAuth2JWTBearer(config.OAuth2JWTBearerConfig{PrivateKeyPEM: []byte("not pem")})
+	assert.Error(t, err)
+}
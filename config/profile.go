@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// ProfilesFile is the document read from a configuration file (see LoadProfiles):
+//
+//	profiles:
+//	  work:
+//	    provider: openai
+//	    model: gpt-4o-mini
+//	    temperature: 0.2
+//	    max_tokens: 1024
+//	    timeout: 45s
+//	    api_keys:
+//	      openai: ${WORK_OPENAI_KEY}
+//	  local:
+//	    provider: ollama
+//	    model: llama3.1
+//	    ollama_endpoint: http://gpu-box:11434
+type ProfilesFile struct {
+	Profiles map[string]Profile `json:"profiles" yaml:"profiles"`
+}
+
+// Profile is a named set of configuration values. A field that is not set leaves the
+// configuration as it is. The fields that Config reads from an environment variable are named
+// after it, in lower case and without the LLM_ prefix, and the variable takes precedence over
+// the profile when it is set and not empty.
+//
+// Loggers, validators, usage observers, HTTP clients, key pools and credential sources cannot
+// be written in a file; set them with their options.
+type Profile struct {
+	Provider                     *string         `json:"provider" yaml:"provider"`
+	Model                        *string         `json:"model" yaml:"model"`
+	OllamaEndpoint               *string         `json:"ollama_endpoint" yaml:"ollama_endpoint"`
+	VLLMEndpoint                 *string         `json:"vllm_endpoint" yaml:"vllm_endpoint"`
+	BaseURL                      *string         `json:"base_url" yaml:"base_url"`
+	VertexProject                *string         `json:"vertex_project" yaml:"vertex_project"`
+	VertexLocation               *string         `json:"vertex_location" yaml:"vertex_location"`
+	GoogleApplicationCredentials *string         `json:"google_application_credentials" yaml:"google_application_credentials"`
+	Temperature                  *float64        `json:"temperature" yaml:"temperature"`
+	MaxTokens                    *int            `json:"max_tokens" yaml:"max_tokens"`
+	TopP                         *float64        `json:"top_p" yaml:"top_p"`
+	FrequencyPenalty             *float64        `json:"frequency_penalty" yaml:"frequency_penalty"`
+	PresencePenalty              *float64        `json:"presence_penalty" yaml:"presence_penalty"`
+	Timeout                      *time.Duration  `json:"timeout" yaml:"timeout"`
+	MaxRetries                   *int            `json:"max_retries" yaml:"max_retries"`
+	RetryDelay                   *time.Duration  `json:"retry_delay" yaml:"retry_delay"`
+	LogLevel                     *utils.LogLevel `json:"log_level" yaml:"log_level"`
+	Seed                         *int            `json:"seed" yaml:"seed"`
+	MinP                         *float64        `json:"min_p" yaml:"min_p"`
+	RepeatPenalty                *float64        `json:"repeat_penalty" yaml:"repeat_penalty"`
+	RepeatLastN                  *int            `json:"repeat_last_n" yaml:"repeat_last_n"`
+	Mirostat                     *int            `json:"mirostat" yaml:"mirostat"`
+	MirostatEta                  *float64        `json:"mirostat_eta" yaml:"mirostat_eta"`
+	MirostatTau                  *float64        `json:"mirostat_tau" yaml:"mirostat_tau"`
+	TfsZ                         *float64        `json:"tfs_z" yaml:"tfs_z"`
+	EnableCaching                *bool           `json:"enable_caching" yaml:"enable_caching"`
+	EnableStreaming              *bool           `json:"enable_streaming" yaml:"enable_streaming"`
+	// ProvidersFile registers custom providers, with their endpoints and headers, as
+	// LLM_PROVIDERS_FILE does.
+	ProvidersFile *string `json:"providers_file" yaml:"providers_file"`
+
+	// APIKeys are used for the providers the environment supplies no <NAME>_API_KEY for. Write
+	// them as references such as ${WORK_OPENAI_KEY} rather than literal secrets.
+	APIKeys map[string]string `json:"api_keys" yaml:"api_keys"`
+	// Headers are sent with every request, as with SetExtraHeaders. Environment variables are
+	// expanded in their values, as in APIKeys.
+	Headers               map[string]string `json:"headers" yaml:"headers"`
+	SystemPrompt          *string           `json:"system_prompt" yaml:"system_prompt"`
+	SystemPromptCacheType *string           `json:"system_prompt_cache_type" yaml:"system_prompt_cache_type"`
+	// Memory enables conversation memory of that many tokens, as SetMemory does.
+	Memory      *int                     `json:"memory" yaml:"memory"`
+	AudioOutput *types.AudioOutputConfig `json:"audio_output" yaml:"audio_output"`
+}
+
+// ProfilesPath returns the configuration file profiles are read from: LLM_CONFIG_FILE when it
+// is set, otherwise gollm/config.yaml in the user's configuration directory (~/.config on
+// Linux).
+func ProfilesPath() (string, error) {
+	if path := os.Getenv("LLM_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "gollm", "config.yaml"), nil
+}
+
+// LoadProfiles reads the profiles of a configuration file, which is YAML or JSON. Environment
+// variables written as $VAR or ${VAR} are expanded in API keys and headers ("$$" is a literal
+// "$"). Unknown fields and unset variables are errors; all problems in the file are reported
+// together.
+func LoadProfiles(path string) (map[string]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	// JSON is YAML, so one decoder reads both.
+	var file ProfilesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		profile := file.Profiles[name]
+		for _, p := range profile.expand() {
+			problems = append(problems, fmt.Sprintf("profiles.%s: %s", name, p))
+		}
+		file.Profiles[name] = profile
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration file %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return file.Profiles, nil
+}
+
+// LoadProfile reads the named profile from the configuration file at ProfilesPath and returns
+// the option that applies it. Like any option, it overrides the options before it, so pass it
+// first:
+//
+//	profile, err := config.LoadProfile("work")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	llm, err := gollm.NewLLM(profile, gollm.SetMaxTokens(500))
+//
+// Environment variables still take precedence over the profile. LoadConfig applies the profile
+// named by LLM_PROFILE by itself.
+func LoadProfile(name string) (ConfigOption, error) {
+	profile, err := findProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	// LoadConfig registers the providers file before any option runs, so a profile applied as
+	// an option registers its own now, where the error can still be returned.
+	if profile.ProvidersFile != nil && os.Getenv("LLM_PROVIDERS_FILE") == "" {
+		cfg := &Config{ProvidersFile: *profile.ProvidersFile, APIKeys: make(map[string]string)}
+		if err := loadProvidersFile(cfg); err != nil {
+			return nil, err
+		}
+		for provider, key := range profile.APIKeys {
+			cfg.APIKeys[provider] = key
+		}
+		profile.APIKeys = cfg.APIKeys
+	}
+	return profile.Apply, nil
+}
+
+// findProfile reads the named profile from the configuration file at ProfilesPath.
+func findProfile(name string) (Profile, error) {
+	path, err := ProfilesPath()
+	if err != nil {
+		return Profile{}, err
+	}
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		return Profile{}, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	return profile, nil
+}
+
+// Apply sets the profile's values on c, except those whose environment variable is set, and
+// API keys the configuration already has.
+func (p Profile) Apply(c *Config) {
+	fromProfile(&c.Provider, p.Provider, "LLM_PROVIDER")
+	fromProfile(&c.Model, p.Model, "LLM_MODEL")
+	fromProfile(&c.OllamaEndpoint, p.OllamaEndpoint, "OLLAMA_ENDPOINT")
+	fromProfile(&c.VLLMEndpoint, p.VLLMEndpoint, "VLLM_ENDPOINT")
+	fromProfile(&c.BaseURL, p.BaseURL, "LLM_BASE_URL")
+	fromProfile(&c.VertexProject, p.VertexProject, "VERTEX_PROJECT")
+	fromProfile(&c.VertexLocation, p.VertexLocation, "VERTEX_LOCATION")
+	fromProfile(&c.GoogleCredentialsFile, p.GoogleApplicationCredentials, "GOOGLE_APPLICATION_CREDENTIALS")
+	fromProfile(&c.Temperature, p.Temperature, "LLM_TEMPERATURE")
+	fromProfile(&c.MaxTokens, p.MaxTokens, "LLM_MAX_TOKENS")
+	fromProfile(&c.TopP, p.TopP, "LLM_TOP_P")
+	fromProfile(&c.FrequencyPenalty, p.FrequencyPenalty, "LLM_FREQUENCY_PENALTY")
+	fromProfile(&c.PresencePenalty, p.PresencePenalty, "LLM_PRESENCE_PENALTY")
+	fromProfile(&c.Timeout, p.Timeout, "LLM_TIMEOUT")
+	fromProfile(&c.MaxRetries, p.MaxRetries, "LLM_MAX_RETRIES")
+	fromProfile(&c.RetryDelay, p.RetryDelay, "LLM_RETRY_DELAY")
+	fromProfile(&c.LogLevel, p.LogLevel, "LLM_LOG_LEVEL")
+	fromProfilePointer(&c.Seed, p.Seed, "LLM_SEED")
+	fromProfilePointer(&c.MinP, p.MinP, "LLM_MIN_P")
+	fromProfilePointer(&c.RepeatPenalty, p.RepeatPenalty, "LLM_REPEAT_PENALTY")
+	fromProfilePointer(&c.RepeatLastN, p.RepeatLastN, "LLM_REPEAT_LAST_N")
+	fromProfilePointer(&c.Mirostat, p.Mirostat, "LLM_MIROSTAT")
+	fromProfilePointer(&c.MirostatEta, p.MirostatEta, "LLM_MIROSTAT_ETA")
+	fromProfilePointer(&c.MirostatTau, p.MirostatTau, "LLM_MIROSTAT_TAU")
+	fromProfilePointer(&c.TfsZ, p.TfsZ, "LLM_TFS_Z")
+	fromProfile(&c.EnableCaching, p.EnableCaching, "LLM_ENABLE_CACHING")
+	fromProfile(&c.EnableStreaming, p.EnableStreaming, "LLM_ENABLE_STREAMING")
+	fromProfile(&c.ProvidersFile, p.ProvidersFile, "LLM_PROVIDERS_FILE")
+
+	for name, key := range p.APIKeys {
+		if c.APIKeys == nil {
+			c.APIKeys = make(map[string]string)
+		}
+		if c.APIKeys[name] == "" {
+			c.APIKeys[name] = key
+		}
+	}
+	if len(p.Headers) > 0 {
+		SetExtraHeaders(p.Headers)(c)
+	}
+	if p.SystemPrompt != nil {
+		c.SystemPrompt = *p.SystemPrompt
+	}
+	if p.SystemPromptCacheType != nil {
+		c.SystemPromptCacheType = *p.SystemPromptCacheType
+	}
+	if p.Memory != nil {
+		SetMemory(*p.Memory)(c)
+	}
+	if p.AudioOutput != nil {
+		audio := *p.AudioOutput
+		c.AudioOutput = &audio
+	}
+}
+
+// fromProfile sets *dst to *value when the profile sets the value and envVar is empty, which
+// LoadConfig treats as unset too.
+func fromProfile[T any](dst *T, value *T, envVar string) {
+	if value == nil {
+		return
+	}
+	if os.Getenv(envVar) != "" {
+		return
+	}
+	*dst = *value
+}
+
+// fromProfilePointer is fromProfile for the optional fields of Config.
+func fromProfilePointer[T any](dst **T, value *T, envVar string) {
+	if value == nil {
+		return
+	}
+	if os.Getenv(envVar) != "" {
+		return
+	}
+	v := *value
+	*dst = &v
+}
+
+// expand expands environment variables in the profile's API keys and headers in place and
+// returns a problem for each unset variable.
+func (p *Profile) expand() []string {
+	missing := make(map[string]bool)
+	expand := func(values map[string]string) map[string]string {
+		expanded := make(map[string]string, len(values))
+		for k, v := range values {
+			expanded[k] = ExpandEnv(v, missing)
+		}
+		return expanded
+	}
+	if p.APIKeys != nil {
+		p.APIKeys = expand(p.APIKeys)
+	}
+	if p.Headers != nil {
+		p.Headers = expand(p.Headers)
+	}
+	return MissingEnvProblems(missing)
+}
+
+// ExpandEnv replaces $VAR and ${VAR} in s with the value of the environment variable, and $$
+// with a literal $. A variable that is not set expands to nothing and is added to missing.
+func ExpandEnv(s string, missing map[string]bool) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing[name] = true
+		}
+		return value
+	})
+}
+
+// MissingEnvProblems returns a problem for each variable that ExpandEnv found unset, sorted by
+// name.
+func MissingEnvProblems(missing map[string]bool) []string {
+	var problems []string
+	for name := range missing {
+		problems = append(problems, fmt.Sprintf("environment variable %s is not set", name))
+	}
+	sort.Strings(problems)
+	return problems
+}
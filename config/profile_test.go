@@ -0,0 +1,198 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/utils"
+)
+
+const profilesYAML = `
+profiles:
+  work:
+    provider: openai
+    model: gpt-4o-mini
+    temperature: 0.2
+    max_tokens: 1024
+    timeout: 45s
+    max_retries: 1
+    log_level: debug
+    seed: 7
+    api_keys:
+      openai: ${WORK_OPENAI_KEY}
+      anthropic: profile-anthropic-key
+    headers:
+      X-Team: ${WORK_TEAM}
+    system_prompt: Be brief.
+    memory: 2000
+  local:
+    provider: ollama
+    model: llama3.1
+    ollama_endpoint: http://gpu-box:11434
+`
+
+// clearConfigEnv unsets every variable LoadConfig reads, restoring them when the test ends.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	names := []string{"LLM_PROFILE", "LLM_CONFIG_FILE", "OPENAI_API_KEY", "ANTHROPIC_API_KEY"}
+	configType := reflect.TypeOf(config.Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := configType.Field(i).Tag.Get("env"); name != "" {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// writeProfiles writes a configuration file and points LLM_CONFIG_FILE at it.
+func writeProfiles(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("LLM_CONFIG_FILE", path)
+	return path
+}
+
+func TestLoadConfigAppliesProfile(t *testing.T) {
+	clearConfigEnv(t)
+	writeProfiles(t, profilesYAML)
+	t.Setenv("WORK_OPENAI_KEY", "sk-work")
+	t.Setenv("WORK_TEAM", "search")
+	t.Setenv("LLM_PROFILE", "work")
+	t.Setenv("LLM_TEMPERATURE", "0.9")
+	t.Setenv("ANTHROPIC_API_KEY", "env-anthropic-key")
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "openai", cfg.Provider)
+	assert.Equal(t, "gpt-4o-mini", cfg.Model)
+	assert.Equal(t, 1024, cfg.MaxTokens)
+	assert.Equal(t, 45*time.Second, cfg.Timeout)
+	assert.Equal(t, 1, cfg.MaxRetries)
+	assert.Equal(t, utils.LogLevelDebug, cfg.LogLevel)
+	require.NotNil(t, cfg.Seed)
+	assert.Equal(t, 7, *cfg.Seed)
+	assert.Equal(t, "Be brief.", cfg.SystemPrompt)
+	assert.Equal(t, map[string]string{"X-Team": "search"}, cfg.ExtraHeaders)
+	require.NotNil(t, cfg.MemoryOption)
+	assert.Equal(t, 2000, cfg.MemoryOption.MaxTokens)
+	assert.Equal(t, "sk-work", cfg.APIKeys["openai"])
+
+	// The environment takes precedence over the profile.
+	assert.Equal(t, 0.9, cfg.Temperature)
+	assert.Equal(t, "env-anthropic-key", cfg.APIKeys["anthropic"])
+	// What neither sets keeps its default.
+	assert.Equal(t, 2*time.Second, cfg.RetryDelay)
+}
+
+func TestLoadProfileOption(t *testing.T) {
+	clearConfigEnv(t)
+	writeProfiles(t, profilesYAML)
+	t.Setenv("WORK_OPENAI_KEY", "sk-work")
+	t.Setenv("WORK_TEAM", "search")
+	t.Setenv("OLLAMA_ENDPOINT", "http://localhost:11434")
+
+	profile, err := config.LoadProfile("local")
+	require.NoError(t, err)
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	config.ApplyOptions(cfg, profile, config.SetModel("qwen2.5"))
+
+	assert.Equal(t, "ollama", cfg.Provider)
+	// Options after the profile override it, and the environment is not overridden.
+	assert.Equal(t, "qwen2.5", cfg.Model)
+	assert.Equal(t, "http://localhost:11434", cfg.OllamaEndpoint)
+}
+
+func TestLoadProfileErrors(t *testing.T) {
+	clearConfigEnv(t)
+
+	t.Run("missing profile", func(t *testing.T) {
+		path := writeProfiles(t, "profiles:\n  local:\n    provider: ollama\n")
+		_, err := config.LoadProfile("work")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `profile "work" not found in `+path)
+
+		t.Setenv("LLM_PROFILE", "work")
+		_, err = config.LoadConfig()
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		writeProfiles(t, "profiles:\n  local:\n    provder: ollama\n")
+		_, err := config.LoadProfile("local")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "provder")
+	})
+
+	t.Run("unset variables", func(t *testing.T) {
+		path := writeProfiles(t, profilesYAML)
+		_, err := config.LoadProfiles(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "profiles.work: environment variable WORK_OPENAI_KEY is not set")
+		assert.Contains(t, err.Error(), "profiles.work: environment variable WORK_TEAM is not set")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		path := writeProfiles(t, `{"profiles": {"local": {"provider": "ollama", "max_tokens": 50}}}`)
+		profiles, err := config.LoadProfiles(path)
+		require.NoError(t, err)
+		require.NotNil(t, profiles["local"].MaxTokens)
+		assert.Equal(t, 50, *profiles["local"].MaxTokens)
+	})
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GOLLM_TEST_TEAM", "search")
+	missing := make(map[string]bool)
+	assert.Equal(t, "search/search/$5/", config.ExpandEnv("$GOLLM_TEST_TEAM/${GOLLM_TEST_TEAM}/$$5/$GOLLM_TEST_UNSET", missing))
+	assert.Equal(t, []string{"environment variable GOLLM_TEST_UNSET is not set"}, config.MissingEnvProblems(missing))
+}
+
+// TestProfileCoversConfig checks that a profile can set every Config field read from the
+// environment, under the variable's name, and every other field that can be written in a file.
+func TestProfileCoversConfig(t *testing.T) {
+	notInFiles := map[string]bool{
+		"Logger": true, "CustomValidator": true, "UsageObserver": true, "HTTPClient": true,
+		"APIKeyPools": true, "CredentialSources": true,
+	}
+	byName := map[string]string{
+		"APIKeys": "api_keys", "ExtraHeaders": "headers", "SystemPrompt": "system_prompt",
+		"SystemPromptCacheType": "system_prompt_cache_type", "MemoryOption": "memory",
+		"AudioOutput": "audio_output",
+	}
+
+	keys := make(map[string]bool)
+	profileType := reflect.TypeOf(config.Profile{})
+	for i := 0; i < profileType.NumField(); i++ {
+		keys[profileType.Field(i).Tag.Get("yaml")] = true
+	}
+
+	configType := reflect.TypeOf(config.Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if notInFiles[field.Name] {
+			continue
+		}
+		key, ok := byName[field.Name]
+		if name := field.Tag.Get("env"); name != "" {
+			key, ok = strings.ToLower(strings.TrimPrefix(name, "LLM_")), true
+		}
+		if assert.True(t, ok, "Config.%s is neither in Profile nor excluded from it", field.Name) {
+			assert.True(t, keys[key], "Profile has no %q for Config.%s", key, field.Name)
+		}
+	}
+}
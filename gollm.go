@@ -5,8 +5,11 @@ package gollm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/go-playground/validator/v10"
+
 	"github.com/teilomillet/gollm/config"
 	"github.com/teilomillet/gollm/llm"
 	"github.com/teilomillet/gollm/providers"
@@ -310,6 +313,8 @@ func (l *llmImpl) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.
 // - Configuration loading fails
 // - Provider initialization fails
 // - Memory setup fails (if memory option is enabled)
+//
+// A missing or malformed API key is reported as an LLMError of type ErrorTypeAuthentication.
 func NewLLM(opts ...ConfigOption) (LLM, error) {
 	cfg, err := LoadConfig()
 	if err != nil {
@@ -335,6 +340,10 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 
 	// Validate config (with custom validator if provided)
 	if err := llm.ValidateWithCustomValidator(cfg, cfg.CustomValidator); err != nil {
+		if failedAPIKey(err) {
+			// A missing or malformed key is an authentication failure, as it is past validation.
+			return nil, llm.NewLLMError(llm.ErrorTypeAuthentication, "invalid configuration", err)
+		}
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
@@ -386,6 +395,20 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 	return llmInstance, nil
 }
 
+// failedAPIKey reports whether err is a validation failure of the provider's API key.
+func failedAPIKey(err error) bool {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, fieldErr := range errs {
+		if fieldErr.Field() == "APIKeys" {
+			return true
+		}
+	}
+	return false
+}
+
 // ensureResponsesAPIKey copies the "openai" API key to the "openai-responses"
 // slot when the latter is empty, since both providers share the same key.
 func ensureResponsesAPIKey(cfg *config.Config) {
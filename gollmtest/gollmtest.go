@@ -0,0 +1,491 @@
+// Package gollmtest provides FakeLLM, a scriptable stand-in for gollm.LLM in the unit tests of
+// code that depends on it.
+//
+// A FakeLLM answers each call with the next scripted Response: text, tool calls, JSON for
+// schema calls, an error of any llm.ErrorType, or a stream with chosen chunks, timing and usage.
+// It records every call, prompt, options and all, so a test can assert on what the code under
+// test sent, and it reports usage to an observer the way the real client does:
+//
+//	fake := gollmtest.New(gollmtest.WithResponses(
+//		gollmtest.Text("Paris").WithUsage(12, 1),
+//		gollmtest.Error(llm.ErrorTypeRateLimit, "slow down"),
+//	))
+//	svc := NewService(fake)
+//	...
+//	assert.Equal(t, "What is the capital of France?", fake.Calls()[0].Prompt.Input)
+//
+// To exercise the real client instead, down to its HTTP, SSE and parsing code, start a Server,
+// an in-process emulator of the providers' APIs, and point the client at it with
+// gollm.SetBaseURL. It answers with the same scripted Responses, in each provider's wire format.
+package gollmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+var (
+	_ gollm.LLM           = (*FakeLLM)(nil)
+	_ llm.MemoryCapable   = (*FakeLLM)(nil)
+	_ llm.UsageObservable = (*FakeLLM)(nil)
+)
+
+// ErrNoResponse is returned (wrapped) by a call that finds the script exhausted and no default
+// response set.
+var ErrNoResponse = errors.New("gollmtest: no scripted response left")
+
+// Call is one recorded call to a FakeLLM.
+type Call struct {
+	// Method is the name of the method called: "Generate", "GenerateWithSchema",
+	// "GenerateWithUsage", "GenerateWithSchemaAndUsage" or "Stream".
+	Method string
+	// Prompt is the prompt as received.
+	Prompt *llm.Prompt
+	// Schema is the schema passed to the schema methods; nil otherwise.
+	Schema interface{}
+	// Generate holds the generate options applied, for the Generate methods.
+	Generate llm.GenerateConfig
+	// Stream holds the stream options applied, for Stream.
+	Stream llm.StreamConfig
+	// Options is a copy of the options set with SetOption at the time of the call.
+	Options map[string]interface{}
+	// Memory is a copy of the conversation history at the time of the call, the new user
+	// message included, when memory is enabled.
+	Memory []types.MemoryMessage
+}
+
+// FakeLLM implements gollm.LLM and llm.MemoryCapable with scripted responses. It is safe for
+// concurrent use; concurrent calls take responses from the script in the order they arrive.
+type FakeLLM struct {
+	mu sync.Mutex
+
+	provider  string
+	model     string
+	streaming bool
+	logger    utils.Logger
+	logLevel  utils.LogLevel
+	endpoint  string
+
+	script   []Response
+	fallback *Response
+	handler  func(Call) Response
+
+	calls    []Call
+	options  map[string]interface{}
+	observer llm.UsageObserver
+
+	memoryEnabled bool
+	memory        []types.MemoryMessage
+	structured    bool
+}
+
+// Option configures a FakeLLM.
+type Option func(*FakeLLM)
+
+// WithProvider sets the provider and model names the fake reports, in GetProvider, GetModel,
+// ResponseDetails and usage events. They are "fake" and "fake-model" by default.
+func WithProvider(provider, model string) Option {
+	return func(f *FakeLLM) {
+		f.provider = provider
+		f.model = model
+	}
+}
+
+// WithMemory enables conversation memory, as config.SetMemory does for a real client: the
+// Generate methods add the prompt and reply to the history, and the memory methods take effect.
+func WithMemory() Option {
+	return func(f *FakeLLM) {
+		f.memoryEnabled = true
+	}
+}
+
+// WithoutStreaming makes the fake report that it cannot stream, so that Stream fails with
+// ErrorTypeUnsupported.
+func WithoutStreaming() Option {
+	return func(f *FakeLLM) {
+		f.streaming = false
+	}
+}
+
+// WithUsageObserver installs a usage observer, as config.WithUsageObserver does.
+func WithUsageObserver(observer llm.UsageObserver) Option {
+	return func(f *FakeLLM) {
+		f.observer = observer
+	}
+}
+
+// WithResponses scripts the replies to the first calls, one response per call, in order.
+func WithResponses(responses ...Response) Option {
+	return func(f *FakeLLM) {
+		f.script = append(f.script, responses...)
+	}
+}
+
+// WithDefault sets the response given once the script is exhausted.
+func WithDefault(response Response) Option {
+	return func(f *FakeLLM) {
+		f.fallback = &response
+	}
+}
+
+// WithHandler answers every call whose script is exhausted by calling handler with the call, for
+// replies that depend on the prompt. It takes precedence over WithDefault.
+func WithHandler(handler func(Call) Response) Option {
+	return func(f *FakeLLM) {
+		f.handler = handler
+	}
+}
+
+// New creates a FakeLLM. Script its replies with WithResponses or Enqueue.
+func New(opts ...Option) *FakeLLM {
+	f := &FakeLLM{
+		provider:   "fake",
+		model:      "fake-model",
+		streaming:  true,
+		logLevel:   utils.LogLevelOff,
+		options:    make(map[string]interface{}),
+		structured: true,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.logger = utils.NewLogger(f.logLevel)
+	return f
+}
+
+// Enqueue appends responses to the script.
+func (f *FakeLLM) Enqueue(responses ...Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script = append(f.script, responses...)
+}
+
+// Remaining returns the number of scripted responses not yet used.
+func (f *FakeLLM) Remaining() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.script)
+}
+
+// Calls returns the calls received so far, in order.
+func (f *FakeLLM) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// LastCall returns the most recent call, and false when there has been none.
+func (f *FakeLLM) LastCall() (Call, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return Call{}, false
+	}
+	return f.calls[len(f.calls)-1], true
+}
+
+// Options returns a copy of the options set with SetOption.
+func (f *FakeLLM) Options() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return copyOptions(f.options)
+}
+
+// Endpoint returns the endpoint last set with SetEndpoint or SetOllamaEndpoint.
+func (f *FakeLLM) Endpoint() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoint
+}
+
+// Reset forgets the recorded calls and the remaining script.
+func (f *FakeLLM) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.script = nil
+}
+
+// next records call and returns the response that answers it.
+func (f *FakeLLM) next(call Call) (Response, error) {
+	f.mu.Lock()
+	call.Options = copyOptions(f.options)
+	if f.memoryEnabled {
+		call.Memory = append([]types.MemoryMessage(nil), f.memory...)
+	}
+	f.calls = append(f.calls, call)
+	n := len(f.calls)
+
+	if len(f.script) > 0 {
+		response := f.script[0]
+		f.script = f.script[1:]
+		f.mu.Unlock()
+		return response, nil
+	}
+	handler, fallback := f.handler, f.fallback
+	f.mu.Unlock()
+
+	switch {
+	case handler != nil:
+		return handler(call), nil
+	case fallback != nil:
+		return *fallback, nil
+	}
+	return Response{}, fmt.Errorf("%w for call %d (%s)", ErrNoResponse, n, call.Method)
+}
+
+func copyOptions(options map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+	return copied
+}
+
+// wait sleeps for d, or until ctx ends.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// render returns the text a provider would return for r: the text followed by one line per tool
+// call.
+func render(r Response) string {
+	parts := make([]string, 0, len(r.ToolCalls)+1)
+	if r.Text != "" {
+		parts = append(parts, r.Text)
+	}
+	for _, call := range r.ToolCalls {
+		var args interface{} = string(call.Function.Arguments)
+		formatted, err := utils.FormatFunctionCall(call.Function.Name, args)
+		if err != nil {
+			formatted = string(call.Function.Arguments)
+		}
+		parts = append(parts, formatted)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// generate answers the Generate methods, validating the reply against schema when there is one
+// and reporting usage as LLMImpl does: once per reply, with UsageOutcomeSchemaFail when the reply
+// does not match the schema, and not at all for a failed request.
+func (f *FakeLLM) generate(ctx context.Context, method string, prompt *llm.Prompt, schema interface{}, opts []llm.GenerateOption) (string, *types.ResponseDetails, error) {
+	config := llm.GenerateConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	f.remember("user", prompt.Input)
+	response, err := f.next(Call{Method: method, Prompt: prompt, Schema: schema, Generate: config})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := wait(ctx, response.Delay); err != nil {
+		return "", nil, err
+	}
+	if response.Err != nil {
+		return "", nil, response.Err
+	}
+
+	text := render(response)
+	details := response.details(f.model)
+	if schema != nil {
+		if err := llm.ValidateAgainstSchema(text, schema); err != nil {
+			f.reportUsage(ctx, llm.UsageOutcomeSchemaFail, details)
+			return "", nil, llm.NewLLMError(llm.ErrorTypeResponse, "response does not match schema", err)
+		}
+	}
+	f.reportUsage(ctx, llm.UsageOutcomeSuccess, details)
+	f.rememberReply(text, details)
+	return text, details, nil
+}
+
+// Generate returns the next scripted reply.
+func (f *FakeLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	text, _, err := f.generate(ctx, "Generate", prompt, nil, opts)
+	return text, err
+}
+
+// GenerateWithSchema returns the next scripted reply, failing with ErrorTypeResponse when it does
+// not match schema.
+func (f *FakeLLM) GenerateWithSchema(ctx context.Context, prompt *llm.Prompt, schema interface{}, opts ...llm.GenerateOption) (string, error) {
+	text, _, err := f.generate(ctx, "GenerateWithSchema", prompt, schema, opts)
+	return text, err
+}
+
+// GenerateWithUsage returns the next scripted reply and its details.
+func (f *FakeLLM) GenerateWithUsage(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, *types.ResponseDetails, error) {
+	return f.generate(ctx, "GenerateWithUsage", prompt, nil, opts)
+}
+
+// GenerateWithSchemaAndUsage returns the next scripted reply and its details, failing with
+// ErrorTypeResponse when the reply does not match schema.
+func (f *FakeLLM) GenerateWithSchemaAndUsage(ctx context.Context, prompt *llm.Prompt, schema interface{}, opts ...llm.GenerateOption) (string, *types.ResponseDetails, error) {
+	return f.generate(ctx, "GenerateWithSchemaAndUsage", prompt, schema, opts)
+}
+
+// Stream returns a stream of the next scripted reply. The stream reports usage when it ends or is
+// closed early, as the real client's streams do.
+func (f *FakeLLM) Stream(ctx context.Context, prompt *llm.Prompt, opts ...llm.StreamOption) (llm.TokenStream, error) {
+	if !f.SupportsStreaming() {
+		return nil, llm.NewLLMError(llm.ErrorTypeUnsupported, "streaming not supported by provider", nil)
+	}
+	config := llm.StreamConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	response, err := f.next(Call{Method: "Stream", Prompt: prompt, Stream: config})
+	if err != nil {
+		return nil, err
+	}
+	if err := wait(ctx, response.Delay); err != nil {
+		return nil, err
+	}
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return newStream(ctx, f, response), nil
+}
+
+// SupportsStreaming reports whether Stream works; true unless WithoutStreaming was given.
+func (f *FakeLLM) SupportsStreaming() bool {
+	return f.streaming
+}
+
+// SupportsJSONSchema reports true.
+func (f *FakeLLM) SupportsJSONSchema() bool {
+	return true
+}
+
+// SetUsageObserver installs observer, replacing any previous one, and reports true.
+func (f *FakeLLM) SetUsageObserver(observer llm.UsageObserver) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observer = observer
+	return true
+}
+
+// reportUsage fires the observer for a reply, with an event built the way LLMImpl builds it. The
+// fake makes a single attempt per call, so Attempt is always 0.
+func (f *FakeLLM) reportUsage(ctx context.Context, outcome llm.UsageOutcome, details *types.ResponseDetails) {
+	f.deliverUsage(ctx, llm.UsageEvent{
+		Outcome:     outcome,
+		Model:       details.Model,
+		Usage:       details.TokenUsage,
+		ServiceTier: details.ServiceTier,
+		Details:     details,
+	})
+}
+
+// deliverUsage fires the observer with event, containing a panicking observer as LLMImpl does.
+func (f *FakeLLM) deliverUsage(ctx context.Context, event llm.UsageEvent) {
+	f.mu.Lock()
+	observer := f.observer
+	f.mu.Unlock()
+	if observer == nil {
+		return
+	}
+	event.Provider = f.provider
+	defer func() {
+		if r := recover(); r != nil {
+			f.logger.Error("Usage observer panicked", "panic", r, "provider", event.Provider, "outcome", string(event.Outcome))
+		}
+	}()
+	observer(ctx, event)
+}
+
+// SetOption records an option; it shows up in Options and in later calls.
+func (f *FakeLLM) SetOption(key string, value interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options[key] = value
+}
+
+// SetLogLevel sets the log level.
+func (f *FakeLLM) SetLogLevel(level utils.LogLevel) {
+	f.UpdateLogLevel(level)
+}
+
+// UpdateLogLevel sets the log level.
+func (f *FakeLLM) UpdateLogLevel(level gollm.LogLevel) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logLevel = level
+	f.logger.SetLevel(level)
+}
+
+// GetLogLevel returns the log level, LogLevelOff unless set.
+func (f *FakeLLM) GetLogLevel() gollm.LogLevel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logLevel
+}
+
+// GetLogger returns the fake's logger.
+func (f *FakeLLM) GetLogger() utils.Logger {
+	return f.logger
+}
+
+// Debug logs at debug level.
+func (f *FakeLLM) Debug(msg string, keysAndValues ...interface{}) {
+	f.logger.Debug(msg, keysAndValues...)
+}
+
+// SetEndpoint records endpoint; see Endpoint.
+func (f *FakeLLM) SetEndpoint(endpoint string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.endpoint = endpoint
+}
+
+// SetOllamaEndpoint records endpoint when the fake's provider is "ollama" and fails otherwise, as
+// with the real client.
+func (f *FakeLLM) SetOllamaEndpoint(endpoint string) error {
+	if f.provider != "ollama" {
+		return fmt.Errorf("current provider does not support setting custom endpoint")
+	}
+	f.SetEndpoint(endpoint)
+	return nil
+}
+
+// SetSystemPrompt sets the "system_prompt" option as the real client does.
+func (f *FakeLLM) SetSystemPrompt(prompt string, cacheType gollm.CacheType) {
+	f.SetOption("system_prompt", llm.NewPrompt(prompt, llm.WithSystemPrompt(prompt, cacheType)))
+}
+
+// NewPrompt creates a prompt.
+func (f *FakeLLM) NewPrompt(input string) *llm.Prompt {
+	return llm.NewPrompt(input)
+}
+
+// GetPromptJSONSchema returns the JSON schema of Prompt.
+func (f *FakeLLM) GetPromptJSONSchema(opts ...gollm.SchemaOption) ([]byte, error) {
+	return (&llm.Prompt{}).GenerateJSONSchema(opts...)
+}
+
+// GetProvider returns the provider name.
+func (f *FakeLLM) GetProvider() string {
+	return f.provider
+}
+
+// GetModel returns the model name.
+func (f *FakeLLM) GetModel() string {
+	return f.model
+}
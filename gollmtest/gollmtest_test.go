@@ -0,0 +1,243 @@
+package gollmtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/gollmtest"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+// usageLog collects usage events.
+type usageLog struct {
+	mu     sync.Mutex
+	events []llm.UsageEvent
+}
+
+func (u *usageLog) observe(_ context.Context, event llm.UsageEvent) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.events = append(u.events, event)
+}
+
+func (u *usageLog) outcomes() []llm.UsageOutcome {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var outcomes []llm.UsageOutcome
+	for _, event := range u.events {
+		outcomes = append(outcomes, event.Outcome)
+	}
+	return outcomes
+}
+
+func TestScriptedGenerate(t *testing.T) {
+	usage := &usageLog{}
+	fake := gollmtest.New(
+		gollmtest.WithProvider("openai", "gpt-4o-mini"),
+		gollmtest.WithUsageObserver(usage.observe),
+		gollmtest.WithResponses(
+			gollmtest.Text("Paris").WithUsage(12, 1).WithServiceTier("flex"),
+			gollmtest.ToolCalls(gollmtest.ToolCall("call_1", "get_weather", map[string]string{"city": "Paris"})),
+		),
+	)
+	var client gollm.LLM = fake
+
+	client.SetOption("temperature", 0.2)
+	prompt := client.NewPrompt("What is the capital of France?")
+	text, err := client.Generate(context.Background(), prompt, llm.WithJSONSchemaValidation())
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", text)
+
+	text, details, err := client.GenerateWithUsage(context.Background(), client.NewPrompt("Weather?"))
+	require.NoError(t, err)
+	assert.Equal(t, `<function_call>{"arguments":{"city":"Paris"},"name":"get_weather"}</function_call>`, text)
+	require.Len(t, details.ToolCalls, 1)
+	assert.Equal(t, "call_1", details.ToolCalls[0].ID)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "Generate", calls[0].Method)
+	assert.Same(t, prompt, calls[0].Prompt)
+	assert.True(t, calls[0].Generate.UseJSONSchema)
+	assert.Equal(t, 0.2, calls[0].Options["temperature"])
+	assert.Equal(t, "GenerateWithUsage", calls[1].Method)
+
+	require.Len(t, usage.events, 2)
+	assert.Equal(t, llm.UsageEvent{
+		Provider:    "openai",
+		Model:       "gpt-4o-mini",
+		Outcome:     llm.UsageOutcomeSuccess,
+		Usage:       types.TokenUsage{PromptTokens: 12, CompletionTokens: 1, TotalTokens: 13},
+		ServiceTier: "flex",
+		Details:     usage.events[0].Details,
+	}, usage.events[0])
+	assert.NotNil(t, usage.events[0].Details)
+
+	_, err = client.Generate(context.Background(), client.NewPrompt("one more"))
+	assert.ErrorIs(t, err, gollmtest.ErrNoResponse)
+}
+
+func TestScriptedErrors(t *testing.T) {
+	usage := &usageLog{}
+	errorTypes := []llm.ErrorType{
+		llm.ErrorTypeUnknown, llm.ErrorTypeProvider, llm.ErrorTypeRequest, llm.ErrorTypeResponse,
+		llm.ErrorTypeAPI, llm.ErrorTypeRateLimit, llm.ErrorTypeAuthentication,
+		llm.ErrorTypeInvalidInput, llm.ErrorTypeUnsupported,
+	}
+	fake := gollmtest.New(gollmtest.WithUsageObserver(usage.observe))
+	for _, errType := range errorTypes {
+		fake.Enqueue(gollmtest.Error(errType, "scripted failure"))
+	}
+
+	for _, errType := range errorTypes {
+		_, err := fake.Generate(context.Background(), fake.NewPrompt("hi"))
+		var llmErr *llm.LLMError
+		require.True(t, errors.As(err, &llmErr))
+		assert.Equal(t, errType, llmErr.Type)
+	}
+	assert.Empty(t, usage.events, "a rejected request is not billed")
+	assert.Zero(t, fake.Remaining())
+}
+
+func TestSchemaOutput(t *testing.T) {
+	type answer struct {
+		City string `json:"city" validate:"required"`
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"city"},
+	}
+	usage := &usageLog{}
+	fake := gollmtest.New(
+		gollmtest.WithUsageObserver(usage.observe),
+		gollmtest.WithResponses(gollmtest.JSON(answer{City: "Paris"}), gollmtest.Text("not JSON")),
+	)
+
+	text, err := fake.GenerateWithSchema(context.Background(), fake.NewPrompt("Capital?"), schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"city":"Paris"}`, text)
+	assert.Equal(t, schema, fake.Calls()[0].Schema)
+
+	_, _, err = fake.GenerateWithSchemaAndUsage(context.Background(), fake.NewPrompt("Capital?"), schema)
+	var llmErr *llm.LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeResponse, llmErr.Type)
+	assert.Equal(t, []llm.UsageOutcome{llm.UsageOutcomeSuccess, llm.UsageOutcomeSchemaFail}, usage.outcomes())
+}
+
+func TestScriptedStream(t *testing.T) {
+	usage := &usageLog{}
+	fake := gollmtest.New(
+		gollmtest.WithUsageObserver(usage.observe),
+		gollmtest.WithResponses(
+			gollmtest.Text("").WithTokens("Hel", "lo").WithTokenInterval(5*time.Millisecond).WithUsage(3, 2),
+			gollmtest.Text("").WithTokens("never", "finished").WithUsage(3, 2),
+			gollmtest.Text("partial").WithStreamError(io.ErrUnexpectedEOF),
+		),
+	)
+
+	start := time.Now()
+	stream, err := fake.Stream(context.Background(), fake.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	var text strings.Builder
+	for {
+		token, err := stream.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		text.WriteString(token.Text)
+	}
+	require.NoError(t, stream.Close())
+	assert.Equal(t, "Hello", text.String())
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	streamed, ok := llm.StreamUsage(stream)
+	require.True(t, ok)
+	assert.Equal(t, 5, streamed.TotalTokens)
+
+	stream, err = fake.Stream(context.Background(), fake.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	_, err = stream.Next(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	stream, err = fake.Stream(context.Background(), fake.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	_, err = stream.Next(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Next(context.Background())
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, []llm.UsageOutcome{
+		llm.UsageOutcomeStream, llm.UsageOutcomeStreamAborted, llm.UsageOutcomeStreamAborted,
+	}, usage.outcomes(), "one event per stream, however it ends")
+	assert.Equal(t, 5, usage.events[0].Usage.TotalTokens)
+	assert.Nil(t, usage.events[0].Details)
+
+	noStreaming := gollmtest.New(gollmtest.WithoutStreaming())
+	_, err = noStreaming.Stream(context.Background(), noStreaming.NewPrompt("hi"))
+	var llmErr *llm.LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, llm.ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestDelayHonoursContext(t *testing.T) {
+	fake := gollmtest.New(gollmtest.WithDefault(gollmtest.Text("late").WithDelay(time.Minute)))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := fake.Generate(ctx, fake.NewPrompt("hi"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMemory(t *testing.T) {
+	fake := gollmtest.New(
+		gollmtest.WithMemory(),
+		gollmtest.WithHandler(func(call gollmtest.Call) gollmtest.Response {
+			return gollmtest.Text("echo: " + call.Prompt.Input)
+		}),
+	)
+	var client llm.MemoryCapable = fake
+
+	_, err := client.Generate(context.Background(), client.NewPrompt("first"))
+	require.NoError(t, err)
+	client.AddToolResult("call_1", "72F")
+	_, err = client.Generate(context.Background(), client.NewPrompt("second"))
+	require.NoError(t, err)
+
+	memory := client.GetMemory()
+	require.Len(t, memory, 5)
+	assert.Equal(t, "echo: first", memory[1].Content)
+	assert.Equal(t, "call_1", memory[2].ToolCallID)
+	assert.Len(t, fake.Calls()[1].Memory, 4, "the call sees the history up to its own prompt")
+
+	client.ClearMemory()
+	assert.Empty(t, client.GetMemory())
+
+	without := gollmtest.New(gollmtest.WithDefault(gollmtest.Text("ok")))
+	_, err = without.Generate(context.Background(), without.NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.False(t, without.HasMemory())
+	assert.Nil(t, without.GetMemory())
+}
+
+func TestObserverPanicIsContained(t *testing.T) {
+	fake := gollmtest.New(gollmtest.WithDefault(gollmtest.Text("ok")))
+	require.True(t, llm.AttachUsageObserver(fake, func(context.Context, llm.UsageEvent) {
+		panic("recorder bug")
+	}))
+	text, err := fake.Generate(context.Background(), fake.NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+}
@@ -0,0 +1,101 @@
+package gollmtest
+
+import (
+	"github.com/teilomillet/gollm/types"
+)
+
+// The memory methods follow llm.LLMWithMemory, and like gollm.LLM's they do nothing unless memory
+// was enabled with WithMemory.
+
+// HasMemory reports whether memory was enabled with WithMemory.
+func (f *FakeLLM) HasMemory() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.memoryEnabled
+}
+
+// ClearMemory empties the conversation history.
+func (f *FakeLLM) ClearMemory() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.memoryEnabled {
+		f.memory = nil
+	}
+}
+
+// GetMemory returns a copy of the conversation history, or nil when memory is not enabled.
+func (f *FakeLLM) GetMemory() []types.MemoryMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.memoryEnabled {
+		return nil
+	}
+	return append([]types.MemoryMessage{}, f.memory...)
+}
+
+// AddToMemory adds a message to the conversation history.
+func (f *FakeLLM) AddToMemory(role, content string) {
+	f.remember(role, content)
+}
+
+// AddStructuredMessage adds a message with cache control to the conversation history.
+func (f *FakeLLM) AddStructuredMessage(role, content, cacheControl string) {
+	f.addMessage(types.MemoryMessage{Role: role, Content: content, CacheControl: cacheControl})
+}
+
+// SetUseStructuredMessages records whether structured messages are used; see
+// UsesStructuredMessages.
+func (f *FakeLLM) SetUseStructuredMessages(use bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.memoryEnabled {
+		f.structured = use
+	}
+}
+
+// UsesStructuredMessages returns the last value given to SetUseStructuredMessages; true by
+// default, as with the real client.
+func (f *FakeLLM) UsesStructuredMessages() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.structured
+}
+
+// AddToolResult adds a tool result to the conversation history.
+func (f *FakeLLM) AddToolResult(toolCallID, result string) {
+	f.addMessage(types.MemoryMessage{Role: "tool", Content: result, ToolCallID: toolCallID})
+}
+
+// AddToolError adds a failed tool result to the conversation history.
+func (f *FakeLLM) AddToolError(toolCallID, errorMessage string) {
+	f.addMessage(types.MemoryMessage{
+		Role:       "tool",
+		Content:    "Error: " + errorMessage,
+		ToolCallID: toolCallID,
+		Metadata:   map[string]interface{}{"is_error": true},
+	})
+}
+
+// AddAssistantMessageWithToolCalls adds an assistant message with its tool calls to the
+// conversation history.
+func (f *FakeLLM) AddAssistantMessageWithToolCalls(content string, toolCalls []types.ToolCall) {
+	f.addMessage(types.MemoryMessage{Role: "assistant", Content: content, ToolCalls: toolCalls})
+}
+
+func (f *FakeLLM) remember(role, content string) {
+	f.addMessage(types.MemoryMessage{Role: role, Content: content})
+}
+
+// rememberReply adds a reply to the history, with its tool calls, as
+// LLMWithMemory.AddAssistantResponse does.
+func (f *FakeLLM) rememberReply(content string, details *types.ResponseDetails) {
+	f.addMessage(types.MemoryMessage{Role: "assistant", Content: content, ToolCalls: details.ToolCalls})
+}
+
+func (f *FakeLLM) addMessage(message types.MemoryMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.memoryEnabled {
+		f.memory = append(f.memory, message)
+	}
+}
@@ -0,0 +1,160 @@
+package gollmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+// Response is one scripted reply, for a FakeLLM or the emulator Server. Build it with Text,
+// ToolCalls, JSON, Error or HTTPError and refine it with the With* methods, which return a
+// modified copy.
+type Response struct {
+	// Text is what Generate returns and what Stream yields.
+	Text string
+	// ToolCalls are reported in ResponseDetails.ToolCalls and appended to Text the way
+	// providers render them, one utils.FormatFunctionCall line per call.
+	ToolCalls []types.ToolCall
+	// Usage is the token usage reported to the caller and to the usage observer.
+	Usage types.TokenUsage
+	// Model overrides the fake's model in ResponseDetails and usage events.
+	Model string
+	// ServiceTier is reported in ResponseDetails and usage events.
+	ServiceTier string
+	// Err, when set, is returned instead of a reply. Nothing is reported to the usage
+	// observer, as with a request the provider rejected.
+	Err error
+	// Delay is waited before the reply, or before the stream opens. A context that ends first
+	// returns its error.
+	Delay time.Duration
+	// Status is the HTTP status the emulator answers with; see HTTPError. When it is zero and
+	// Err is set, the emulator picks the status that Err's ErrorType is classified from.
+	Status int
+	// message is the error message the emulator puts in the provider's error body.
+	message string
+
+	// Tokens are the chunks Stream yields. When empty, Text is yielded as a single token.
+	Tokens []string
+	// TokenInterval is waited before each streamed token.
+	TokenInterval time.Duration
+	// StreamErr, when set, is returned by Next after the tokens instead of io.EOF, as a
+	// connection dropped mid-stream would be. The emulator drops the connection.
+	StreamErr error
+	// Stall makes the emulator stop sending after the tokens without ending the stream, until
+	// the client gives up or the server is closed.
+	Stall bool
+}
+
+// Text returns a plain text reply.
+func Text(text string) Response {
+	return Response{Text: text}
+}
+
+// ToolCalls returns a reply that calls the given tools. Use ToolCall to build them.
+func ToolCalls(calls ...types.ToolCall) Response {
+	return Response{ToolCalls: calls}
+}
+
+// ToolCall builds a tool call with arguments marshalled from args. It panics if args cannot be
+// marshalled, which is a mistake in the test.
+func ToolCall(id, name string, args interface{}) types.ToolCall {
+	data, err := json.Marshal(args)
+	if err != nil {
+		panic(fmt.Sprintf("gollmtest: marshalling arguments of %s: %v", name, err))
+	}
+	return types.NewToolCall(id, name, data)
+}
+
+// JSON returns a reply whose text is v marshalled to JSON, for GenerateWithSchema. It panics if
+// v cannot be marshalled.
+func JSON(v interface{}) Response {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("gollmtest: marshalling JSON response: %v", err))
+	}
+	return Response{Text: string(data)}
+}
+
+// Error returns a reply that fails with an *llm.LLMError of the given type.
+func Error(errType llm.ErrorType, message string) Response {
+	return Response{Err: llm.NewLLMError(errType, message, nil)}
+}
+
+// HTTPError returns a reply that the emulator answers with the given status and the provider's
+// error body carrying message. FakeLLM returns it as the client would, an *llm.LLMError
+// classified from the status.
+func HTTPError(status int, message string) Response {
+	return Response{Status: status, message: message, Err: llm.NewHTTPStatusError(status, []byte(message))}
+}
+
+// WithUsage sets the prompt and completion token counts, and their total.
+func (r Response) WithUsage(promptTokens, completionTokens int) Response {
+	r.Usage = types.TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	return r
+}
+
+// WithModel sets the model the reply reports.
+func (r Response) WithModel(model string) Response {
+	r.Model = model
+	return r
+}
+
+// WithServiceTier sets the service tier the reply reports.
+func (r Response) WithServiceTier(tier string) Response {
+	r.ServiceTier = tier
+	return r
+}
+
+// WithDelay sets how long the reply takes to arrive.
+func (r Response) WithDelay(delay time.Duration) Response {
+	r.Delay = delay
+	return r
+}
+
+// WithTokens sets the chunks a stream yields, and the text to their concatenation.
+func (r Response) WithTokens(tokens ...string) Response {
+	r.Tokens = tokens
+	r.Text = ""
+	for _, token := range tokens {
+		r.Text += token
+	}
+	return r
+}
+
+// WithTokenInterval sets the wait before each streamed token.
+func (r Response) WithTokenInterval(interval time.Duration) Response {
+	r.TokenInterval = interval
+	return r
+}
+
+// WithStreamError makes a stream fail with err after its tokens.
+func (r Response) WithStreamError(err error) Response {
+	r.StreamErr = err
+	return r
+}
+
+// WithStall makes a stream stall after its tokens; see Response.Stall.
+func (r Response) WithStall() Response {
+	r.Stall = true
+	return r
+}
+
+// details returns the ResponseDetails a provider would report for r.
+func (r Response) details(model string) *types.ResponseDetails {
+	if r.Model != "" {
+		model = r.Model
+	}
+	return &types.ResponseDetails{
+		TokenUsage:  r.Usage,
+		Model:       model,
+		ToolCalls:   r.ToolCalls,
+		ServiceTier: r.ServiceTier,
+	}
+}
@@ -0,0 +1,426 @@
+package gollmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Server is an in-process emulator of the providers' HTTP APIs. It speaks the OpenAI chat
+// completions, OpenAI Responses, Anthropic Messages, Ollama and Cohere v2 wire formats, picking
+// the format from the request path, so the real client's HTTP, SSE and parsing code can be
+// exercised without network access. Point a client at it with SetBaseURL:
+//
+//	srv := gollmtest.NewServer(gollmtest.ServeResponses(gollmtest.Text("Paris").WithUsage(12, 1)))
+//	defer srv.Close()
+//	client, err := gollm.NewLLM(
+//		gollm.SetProvider("anthropic"),
+//		gollm.SetAPIKey("test"),
+//		gollm.SetBaseURL(srv.URL()),
+//	)
+//
+// Each request is answered with the next scripted Response, then the default set with
+// ServeDefault, then the one returned by the ServeFunc handler. With none of these, the server
+// echoes the last user message back.
+type Server struct {
+	srv  *httptest.Server
+	done chan struct{}
+
+	mu       sync.Mutex
+	script   []Response
+	fallback *Response
+	handler  func(Request) Response
+	requests []Request
+	served   int
+}
+
+// Request is one request received by a Server.
+type Request struct {
+	// Format is the wire format the request was served in.
+	Format Format
+	Method string
+	Path   string
+	Header http.Header
+	// Body is the raw request body.
+	Body []byte
+	// Model is the model the request named.
+	Model string
+	// Stream is whether the request asked for a streamed response.
+	Stream bool
+	// Prompt is the text of the last user message, or of the prompt for Ollama's generate API.
+	Prompt string
+}
+
+// Decode unmarshals the request body into v.
+func (r Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// ServeResponses scripts the replies to the next requests, in order.
+func ServeResponses(responses ...Response) ServerOption {
+	return func(s *Server) {
+		s.script = append(s.script, responses...)
+	}
+}
+
+// ServeDefault sets the reply to requests that find the script exhausted.
+func ServeDefault(response Response) ServerOption {
+	return func(s *Server) {
+		s.fallback = &response
+	}
+}
+
+// ServeFunc answers requests that find the script exhausted and no default set with the reply
+// handler returns.
+func ServeFunc(handler func(Request) Response) ServerOption {
+	return func(s *Server) {
+		s.handler = handler
+	}
+}
+
+// Echo is a ServeFunc handler that replies with the request's prompt.
+func Echo(r Request) Response {
+	return Text(r.Prompt)
+}
+
+// NewServer starts an emulator. Close it when done.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL to pass to SetBaseURL.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Client returns an HTTP client for the server.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Enqueue appends replies to the script.
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append(s.script, responses...)
+}
+
+// Requests returns the requests received so far, in the order they arrived.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// LastRequest returns the last request received, and false when there has been none.
+func (s *Server) LastRequest() (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// Close releases stalled streams and shuts the server down.
+func (s *Server) Close() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.srv.Close()
+}
+
+// next records req and returns its reply.
+func (s *Server) next(req Request) Response {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.served++
+	if len(s.script) > 0 {
+		response := s.script[0]
+		s.script = s.script[1:]
+		s.mu.Unlock()
+		return response
+	}
+	fallback, handler := s.fallback, s.handler
+	s.mu.Unlock()
+
+	switch {
+	case fallback != nil:
+		return *fallback
+	case handler != nil:
+		return handler(req)
+	}
+	return Echo(req)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/api/tags") {
+		// Ollama's model list, which the client checks the server with.
+		writeJSON(w, http.StatusOK, map[string]interface{}{"models": []interface{}{}})
+		return
+	}
+	format, ok := formatFor(r.URL.Path)
+	if !ok || r.Method != http.MethodPost {
+		writeJSON(w, http.StatusNotFound, openAIErrorBody(http.StatusNotFound, fmt.Sprintf("gollmtest: no route for %s %s", r.Method, r.URL.Path)))
+		return
+	}
+	enc := encoderFor(format)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, enc.errorBody(http.StatusBadRequest, err.Error()))
+		return
+	}
+	var fields requestFields
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeJSON(w, http.StatusBadRequest, enc.errorBody(http.StatusBadRequest, "invalid JSON body: "+err.Error()))
+		return
+	}
+
+	req := Request{
+		Format: format,
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+		Model:  fields.Model,
+		Stream: fields.streams(format, r.URL.Path),
+		Prompt: fields.prompt(),
+	}
+	response := s.next(req)
+	if err := wait(r.Context(), response.Delay); err != nil {
+		return
+	}
+	if response.Err != nil || response.Status >= http.StatusBadRequest {
+		s.writeError(w, enc, response)
+		return
+	}
+
+	model := response.Model
+	if model == "" {
+		model = req.Model
+	}
+	s.mu.Lock()
+	id := fmt.Sprintf("gollmtest-%d", s.served)
+	s.mu.Unlock()
+	out := reply{
+		Response:     response,
+		id:           id,
+		model:        model,
+		created:      time.Now(),
+		includeUsage: fields.StreamOptions.IncludeUsage,
+		ollamaChat:   strings.HasSuffix(r.URL.Path, "/api/chat"),
+	}
+	if !req.Stream {
+		writeJSON(w, http.StatusOK, enc.body(out))
+		return
+	}
+	s.writeStream(w, r, enc, out)
+}
+
+// writeError answers with the reply's error status and the format's error body.
+func (s *Server) writeError(w http.ResponseWriter, enc encoder, response Response) {
+	status, message := response.Status, response.message
+	if message == "" && response.Err != nil {
+		message = response.Err.Error()
+		var llmErr *llm.LLMError
+		if errors.As(response.Err, &llmErr) {
+			message = llmErr.Message
+		}
+	}
+	if status == 0 {
+		status = statusFor(response.Err)
+	}
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeJSON(w, status, enc.errorBody(status, message))
+}
+
+// statusFor returns the status the client classifies as err's ErrorType.
+func statusFor(err error) int {
+	var llmErr *llm.LLMError
+	if !errors.As(err, &llmErr) {
+		return http.StatusInternalServerError
+	}
+	switch llmErr.Type {
+	case llm.ErrorTypeRateLimit:
+		return http.StatusTooManyRequests
+	case llm.ErrorTypeAuthentication:
+		return http.StatusUnauthorized
+	case llm.ErrorTypeInvalidInput, llm.ErrorTypeRequest:
+		return http.StatusBadRequest
+	case llm.ErrorTypeUnsupported:
+		return http.StatusNotFound
+	case llm.ErrorTypeProvider:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeStream sends the reply as SSE or NDJSON, flushing each event. A scripted stream error
+// drops the connection after the tokens; a stall holds it open until the client gives up or the
+// server is closed.
+func (s *Server) writeStream(w http.ResponseWriter, r *http.Request, enc encoder, out reply) {
+	flusher, _ := w.(http.Flusher)
+	if enc.ndjson() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event wireEvent) bool {
+		if event.token {
+			if err := wait(r.Context(), out.TokenInterval); err != nil {
+				return false
+			}
+		}
+		if _, err := w.Write(encodeEvent(event, enc.ndjson())); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	events, terminal := enc.stream(out)
+	for _, event := range events {
+		if !send(event) {
+			return
+		}
+	}
+	switch {
+	case out.StreamErr != nil:
+		panic(http.ErrAbortHandler)
+	case out.Stall:
+		select {
+		case <-r.Context().Done():
+		case <-s.done:
+		}
+		return
+	}
+	for _, event := range terminal {
+		if !send(event) {
+			return
+		}
+	}
+}
+
+// encodeEvent returns the bytes of one streamed event.
+func encodeEvent(event wireEvent, ndjson bool) []byte {
+	var data []byte
+	if text, ok := event.data.(string); ok {
+		data = []byte(text)
+	} else {
+		data, _ = json.Marshal(event.data)
+	}
+	if ndjson {
+		return append(data, '\n')
+	}
+	var buf bytes.Buffer
+	if event.name != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.name)
+	}
+	fmt.Fprintf(&buf, "data: %s\n\n", data)
+	return buf.Bytes()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// requestFields are the request fields the emulator reads, across the formats.
+type requestFields struct {
+	Model    string          `json:"model"`
+	Stream   *bool           `json:"stream"`
+	Prompt   string          `json:"prompt"`
+	Input    json.RawMessage `json:"input"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// streams reports whether the request asked for a streamed response. Ollama streams unless told
+// not to; Vertex asks for Anthropic streams by path.
+func (f requestFields) streams(format Format, path string) bool {
+	switch {
+	case format == FormatOllama:
+		return f.Stream == nil || *f.Stream
+	case strings.HasSuffix(path, ":streamRawPredict"):
+		return true
+	}
+	return f.Stream != nil && *f.Stream
+}
+
+// prompt returns the text of the last user message, of the Responses input, or of the prompt.
+func (f requestFields) prompt() string {
+	for i := len(f.Messages) - 1; i >= 0; i-- {
+		if f.Messages[i].Role == "user" {
+			return contentText(f.Messages[i].Content)
+		}
+	}
+	if len(f.Input) > 0 {
+		var input []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		}
+		if json.Unmarshal(f.Input, &input) == nil {
+			for i := len(input) - 1; i >= 0; i-- {
+				if input[i].Role == "user" {
+					return contentText(input[i].Content)
+				}
+			}
+		}
+		return contentText(f.Input)
+	}
+	return f.Prompt
+}
+
+// contentText returns the text of message content given as a string or as a list of parts.
+func contentText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &parts) != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
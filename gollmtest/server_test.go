@@ -0,0 +1,208 @@
+package gollmtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/gollmtest"
+	"github.com/teilomillet/gollm/llm"
+)
+
+// emulated are the built-in providers the emulator speaks for, with the format each is served in.
+var emulated = []struct {
+	provider string
+	format   gollmtest.Format
+}{
+	{"openai", gollmtest.FormatOpenAI},
+	{"openai-responses", gollmtest.FormatResponses},
+	{"anthropic", gollmtest.FormatAnthropic},
+	{"ollama", gollmtest.FormatOllama},
+	{"cohere", gollmtest.FormatCohere},
+}
+
+func newEmulatedClient(t *testing.T, srv *gollmtest.Server, provider string, opts ...gollm.ConfigOption) gollm.LLM {
+	t.Helper()
+	opts = append([]gollm.ConfigOption{
+		gollm.SetProvider(provider),
+		gollm.SetModel("emulated-model"),
+		gollm.SetAPIKey("sk-ant-emulated-0123456789"),
+		gollm.SetBaseURL(srv.URL()),
+		gollm.SetMaxRetries(0),
+		gollm.SetLogLevel(gollm.LogLevelOff),
+	}, opts...)
+	client, err := gollm.NewLLM(opts...)
+	require.NoError(t, err)
+	return client
+}
+
+func readAll(t *testing.T, stream llm.TokenStream) string {
+	t.Helper()
+	var text strings.Builder
+	for {
+		token, err := stream.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		text.WriteString(token.Text)
+	}
+	require.NoError(t, stream.Close())
+	return text.String()
+}
+
+func TestServerGenerate(t *testing.T) {
+	for _, tc := range emulated {
+		t.Run(tc.provider, func(t *testing.T) {
+			srv := gollmtest.NewServer(gollmtest.ServeResponses(gollmtest.Text("Paris").WithUsage(12, 1)))
+			defer srv.Close()
+			client := newEmulatedClient(t, srv, tc.provider)
+
+			text, details, err := client.GenerateWithUsage(context.Background(), client.NewPrompt("Capital of France?"))
+			require.NoError(t, err)
+			assert.Equal(t, "Paris", text)
+			assert.Equal(t, 12, details.TokenUsage.PromptTokens)
+			assert.Equal(t, 1, details.TokenUsage.CompletionTokens)
+
+			request, ok := srv.LastRequest()
+			require.True(t, ok)
+			assert.Equal(t, tc.format, request.Format)
+			assert.Equal(t, "emulated-model", request.Model)
+			assert.Equal(t, "Capital of France?", request.Prompt)
+
+			// With the script exhausted, the server echoes.
+			text, err = client.Generate(context.Background(), client.NewPrompt("echo me"))
+			require.NoError(t, err)
+			assert.Equal(t, "echo me", text)
+		})
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	for _, tc := range emulated {
+		t.Run(tc.provider, func(t *testing.T) {
+			srv := gollmtest.NewServer(gollmtest.ServeResponses(
+				gollmtest.Text("").WithTokens("Hel", "lo", "!").WithTokenInterval(2*time.Millisecond).WithUsage(5, 3),
+			))
+			defer srv.Close()
+			client := newEmulatedClient(t, srv, tc.provider)
+
+			stream, err := client.Stream(context.Background(), client.NewPrompt("Say hello"))
+			require.NoError(t, err)
+			assert.Equal(t, "Hello!", readAll(t, stream))
+			request, _ := srv.LastRequest()
+			assert.True(t, request.Stream)
+			if usage, ok := gollm.StreamUsage(stream); ok {
+				assert.Equal(t, 3, usage.CompletionTokens)
+			}
+		})
+	}
+}
+
+func TestServerToolCalls(t *testing.T) {
+	call := gollmtest.ToolCall("call_1", "get_weather", map[string]string{"city": "Paris"})
+	for _, tc := range emulated {
+		switch tc.provider {
+		case "ollama":
+			continue // the generate API carries no tool calls
+		case "cohere":
+			continue // like Cohere's, a tool-only reply has no content, which the client rejects
+		}
+		t.Run(tc.provider, func(t *testing.T) {
+			srv := gollmtest.NewServer(gollmtest.ServeResponses(gollmtest.ToolCalls(call).WithUsage(20, 8)))
+			defer srv.Close()
+			client := newEmulatedClient(t, srv, tc.provider)
+
+			text, details, err := client.GenerateWithUsage(context.Background(), client.NewPrompt("Weather in Paris?"))
+			require.NoError(t, err)
+			assert.Contains(t, text, `"name":"get_weather"`)
+			require.Len(t, details.ToolCalls, 1)
+			assert.Equal(t, "get_weather", details.ToolCalls[0].Function.Name)
+			assert.Contains(t, string(details.ToolCalls[0].Function.Arguments), "Paris")
+		})
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	cases := []struct {
+		response gollmtest.Response
+		want     llm.ErrorType
+	}{
+		{gollmtest.HTTPError(http.StatusTooManyRequests, "slow down"), llm.ErrorTypeRateLimit},
+		{gollmtest.HTTPError(http.StatusUnauthorized, "bad key"), llm.ErrorTypeAuthentication},
+		{gollmtest.HTTPError(http.StatusBadRequest, "context too long"), llm.ErrorTypeInvalidInput},
+		{gollmtest.Error(llm.ErrorTypeRateLimit, "scripted"), llm.ErrorTypeRateLimit},
+		{gollmtest.Error(llm.ErrorTypeProvider, "overloaded"), llm.ErrorTypeAPI},
+	}
+	for _, tc := range emulated {
+		t.Run(tc.provider, func(t *testing.T) {
+			srv := gollmtest.NewServer()
+			defer srv.Close()
+			client := newEmulatedClient(t, srv, tc.provider)
+
+			for _, c := range cases {
+				srv.Enqueue(c.response)
+				_, _, err := client.GenerateWithUsage(context.Background(), client.NewPrompt("hi"))
+				var llmErr *llm.LLMError
+				require.True(t, errors.As(err, &llmErr), "got %v", err)
+				assert.Equal(t, c.want, llmErr.Type, llmErr.Message)
+			}
+		})
+	}
+}
+
+func TestServerStalledStream(t *testing.T) {
+	srv := gollmtest.NewServer(gollmtest.ServeResponses(gollmtest.Text("").WithTokens("stuck").WithStall()))
+	defer srv.Close()
+	client := newEmulatedClient(t, srv, "anthropic")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stream, err := client.Stream(ctx, client.NewPrompt("hi"))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var text string
+	for {
+		token, err := stream.Next(ctx)
+		if err != nil {
+			assert.NotEqual(t, io.EOF, err, "a stalled stream does not end")
+			break
+		}
+		text += token.Text
+	}
+	assert.Equal(t, "stuck", text)
+	assert.Error(t, ctx.Err())
+}
+
+func TestServerDroppedStream(t *testing.T) {
+	srv := gollmtest.NewServer(gollmtest.ServeResponses(gollmtest.Text("partial").WithStreamError(io.ErrUnexpectedEOF)))
+	defer srv.Close()
+	client := newEmulatedClient(t, srv, "openai")
+
+	stream, err := client.Stream(context.Background(), client.NewPrompt("hi"))
+	require.NoError(t, err)
+	defer stream.Close()
+	token, err := stream.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "partial", token.Text)
+	_, err = stream.Next(context.Background())
+	assert.Error(t, err)
+}
+
+func TestServerUnknownRoute(t *testing.T) {
+	srv := gollmtest.NewServer()
+	defer srv.Close()
+	resp, err := srv.Client().Post(srv.URL()+"/v1/embeddings", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
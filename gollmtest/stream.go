@@ -0,0 +1,134 @@
+package gollmtest
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/types"
+)
+
+var _ llm.UsageReporter = (*stream)(nil)
+
+// stream yields a scripted reply token by token: its text chunks, then one tool_call_delta token
+// per tool call. Like the providers that send usage in their final event, it reports the reply's
+// usage once the last token has been read.
+type stream struct {
+	ctx      context.Context
+	fake     *FakeLLM
+	response Response
+	tokens   []*llm.StreamToken
+
+	mu         sync.Mutex
+	pos        int
+	usage      types.TokenUsage
+	reachedEnd bool
+	closed     bool
+	reported   sync.Once
+}
+
+func newStream(ctx context.Context, fake *FakeLLM, response Response) *stream {
+	chunks := response.Tokens
+	if len(chunks) == 0 && response.Text != "" {
+		chunks = []string{response.Text}
+	}
+	s := &stream{ctx: ctx, fake: fake, response: response}
+	for _, chunk := range chunks {
+		s.tokens = append(s.tokens, &llm.StreamToken{Text: chunk, Type: "text", Index: len(s.tokens)})
+	}
+	for i, call := range response.ToolCalls {
+		s.tokens = append(s.tokens, &llm.StreamToken{
+			Type:  "tool_call_delta",
+			Index: len(s.tokens),
+			ToolCallDelta: &types.ToolCallDelta{
+				Index:        i,
+				ID:           call.ID,
+				Name:         call.Function.Name,
+				ArgsFragment: string(call.Function.Arguments),
+			},
+		})
+	}
+	return s
+}
+
+// Next returns the next token after the scripted interval, then io.EOF, or the scripted stream
+// error. A stalled stream blocks after its tokens until ctx ends.
+func (s *stream) Next(ctx context.Context) (*llm.StreamToken, error) {
+	s.mu.Lock()
+	if s.closed || s.reachedEnd {
+		s.mu.Unlock()
+		return nil, io.EOF
+	}
+	pos := s.pos
+	s.mu.Unlock()
+
+	if pos == len(s.tokens) {
+		if s.response.Stall {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		if s.response.StreamErr != nil {
+			s.finish()
+			return nil, s.response.StreamErr
+		}
+		s.mu.Lock()
+		s.reachedEnd = true
+		s.mu.Unlock()
+		s.finish()
+		return nil, io.EOF
+	}
+
+	if err := wait(ctx, s.response.TokenInterval); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pos++
+	if s.pos == len(s.tokens) {
+		s.usage = s.response.Usage
+	}
+	return s.tokens[pos], nil
+}
+
+// Close ends the stream, reporting its usage as aborted when it was not read to the end.
+func (s *stream) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.finish()
+	return nil
+}
+
+// Usage returns the reply's usage once its last token has been read, and zero before.
+func (s *stream) Usage() types.TokenUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// ServiceTier returns the scripted service tier.
+func (s *stream) ServiceTier() string {
+	return s.response.ServiceTier
+}
+
+// finish reports the stream's usage exactly once, as UsageOutcomeStream when it reached its end
+// and UsageOutcomeStreamAborted otherwise.
+func (s *stream) finish() {
+	s.reported.Do(func() {
+		s.mu.Lock()
+		ended, usage := s.reachedEnd, s.usage
+		s.mu.Unlock()
+		outcome := llm.UsageOutcomeStreamAborted
+		if ended {
+			outcome = llm.UsageOutcomeStream
+		}
+		// Stream events carry no details, as the real client's don't.
+		s.fake.deliverUsage(s.ctx, llm.UsageEvent{
+			Outcome:     outcome,
+			Model:       s.response.details(s.fake.model).Model,
+			Usage:       usage,
+			ServiceTier: s.response.ServiceTier,
+		})
+	})
+}
@@ -0,0 +1,594 @@
+package gollmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/types"
+)
+
+// Format is a provider wire format the emulator speaks.
+type Format string
+
+// The wire formats the emulator speaks, and the paths it serves them on.
+const (
+	FormatOpenAI    Format = "openai"           // POST .../chat/completions
+	FormatResponses Format = "openai-responses" // POST .../responses
+	FormatAnthropic Format = "anthropic"        // POST .../messages, and Vertex's :rawPredict
+	FormatOllama    Format = "ollama"           // POST /api/generate and /api/chat
+	FormatCohere    Format = "cohere"           // POST /v2/chat
+)
+
+// formatFor returns the wire format served on path, and false for a path the emulator does not
+// serve.
+func formatFor(path string) (Format, bool) {
+	switch {
+	case strings.HasSuffix(path, "/chat/completions"):
+		return FormatOpenAI, true
+	case strings.HasSuffix(path, "/responses"):
+		return FormatResponses, true
+	case strings.HasSuffix(path, "/messages"), strings.HasSuffix(path, ":rawPredict"), strings.HasSuffix(path, ":streamRawPredict"):
+		return FormatAnthropic, true
+	case strings.HasSuffix(path, "/api/generate"), strings.HasSuffix(path, "/api/chat"):
+		return FormatOllama, true
+	case strings.HasSuffix(path, "/v2/chat"):
+		return FormatCohere, true
+	}
+	return "", false
+}
+
+// reply is a scripted Response as it goes out in answer to one request.
+type reply struct {
+	Response
+	id      string
+	model   string
+	created time.Time
+	// includeUsage is whether an OpenAI stream asked for its usage chunk.
+	includeUsage bool
+	// ollamaChat is whether an Ollama request came to /api/chat rather than /api/generate.
+	ollamaChat bool
+}
+
+// chunks returns the text as it is streamed.
+func (r reply) chunks() []string {
+	if len(r.Tokens) > 0 {
+		return r.Tokens
+	}
+	if r.Text != "" {
+		return []string{r.Text}
+	}
+	return nil
+}
+
+// arguments returns a tool call's arguments as JSON, "{}" when it has none.
+func arguments(call types.ToolCall) json.RawMessage {
+	if len(call.Function.Arguments) == 0 {
+		return json.RawMessage("{}")
+	}
+	return call.Function.Arguments
+}
+
+// wireEvent is one event of a streamed response: an SSE event, or a line of NDJSON.
+type wireEvent struct {
+	name  string      // SSE event name; empty for data-only events
+	data  interface{} // marshalled to JSON; a string is written as it is
+	token bool        // preceded by the reply's TokenInterval
+}
+
+// encoder writes one wire format.
+type encoder interface {
+	// body is the complete response to a request that did not stream.
+	body(r reply) interface{}
+	// stream returns a streamed response in two parts: the events that carry the reply, and the
+	// terminal events that end it, which a stalled or dropped stream never sends.
+	stream(r reply) (events, terminal []wireEvent)
+	// errorBody is the body the provider sends with an error status.
+	errorBody(status int, message string) interface{}
+	// ndjson is whether the format streams NDJSON rather than SSE.
+	ndjson() bool
+}
+
+func encoderFor(format Format) encoder {
+	switch format {
+	case FormatResponses:
+		return responsesEncoder{}
+	case FormatAnthropic:
+		return anthropicEncoder{}
+	case FormatOllama:
+		return ollamaEncoder{}
+	case FormatCohere:
+		return cohereEncoder{}
+	}
+	return openAIEncoder{}
+}
+
+// openAIErrorBody is the error body of OpenAI's APIs.
+func openAIErrorBody(status int, message string) interface{} {
+	errType, code := "invalid_request_error", interface{}(nil)
+	switch {
+	case status == http.StatusUnauthorized:
+		code = "invalid_api_key"
+	case status == http.StatusTooManyRequests:
+		errType, code = "requests", "rate_limit_exceeded"
+	case status >= 500:
+		errType = "server_error"
+	}
+	return map[string]interface{}{"error": map[string]interface{}{
+		"message": message,
+		"type":    errType,
+		"param":   nil,
+		"code":    code,
+	}}
+}
+
+type openAIEncoder struct{}
+
+func (openAIEncoder) ndjson() bool { return false }
+
+func (openAIEncoder) errorBody(status int, message string) interface{} {
+	return openAIErrorBody(status, message)
+}
+
+func (openAIEncoder) usage(u types.TokenUsage) map[string]interface{} {
+	return map[string]interface{}{
+		"prompt_tokens":             u.PromptTokens,
+		"completion_tokens":         u.CompletionTokens,
+		"total_tokens":              u.TotalTokens,
+		"prompt_tokens_details":     map[string]interface{}{"cached_tokens": u.CachedPromptTokens},
+		"completion_tokens_details": map[string]interface{}{"reasoning_tokens": u.ReasoningTokens},
+	}
+}
+
+func (openAIEncoder) toolCalls(r reply, streamed bool) []map[string]interface{} {
+	calls := make([]map[string]interface{}, 0, len(r.ToolCalls))
+	for i, call := range r.ToolCalls {
+		entry := map[string]interface{}{
+			"id":   call.ID,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      call.Function.Name,
+				"arguments": string(arguments(call)),
+			},
+		}
+		if streamed {
+			entry["index"] = i
+		}
+		calls = append(calls, entry)
+	}
+	return calls
+}
+
+func (openAIEncoder) finishReason(r reply) string {
+	if len(r.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// envelope returns the fields every OpenAI body and chunk carries.
+func (openAIEncoder) envelope(r reply, object string) map[string]interface{} {
+	envelope := map[string]interface{}{
+		"id":      r.id,
+		"object":  object,
+		"created": r.created.Unix(),
+		"model":   r.model,
+	}
+	if r.ServiceTier != "" {
+		envelope["service_tier"] = r.ServiceTier
+	}
+	return envelope
+}
+
+func (e openAIEncoder) body(r reply) interface{} {
+	message := map[string]interface{}{"role": "assistant", "content": nil}
+	if r.Text != "" {
+		message["content"] = r.Text
+	}
+	if len(r.ToolCalls) > 0 {
+		message["tool_calls"] = e.toolCalls(r, false)
+	}
+	body := e.envelope(r, "chat.completion")
+	body["choices"] = []interface{}{map[string]interface{}{
+		"index":         0,
+		"message":       message,
+		"finish_reason": e.finishReason(r),
+	}}
+	body["usage"] = e.usage(r.Usage)
+	return body
+}
+
+func (e openAIEncoder) stream(r reply) (events, terminal []wireEvent) {
+	chunk := func(delta map[string]interface{}, finishReason interface{}) map[string]interface{} {
+		c := e.envelope(r, "chat.completion.chunk")
+		c["choices"] = []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": finishReason}}
+		return c
+	}
+	events = append(events, wireEvent{data: chunk(map[string]interface{}{"role": "assistant", "content": ""}, nil)})
+	for _, text := range r.chunks() {
+		events = append(events, wireEvent{data: chunk(map[string]interface{}{"content": text}, nil), token: true})
+	}
+	for _, call := range e.toolCalls(r, true) {
+		events = append(events, wireEvent{data: chunk(map[string]interface{}{"tool_calls": []interface{}{call}}, nil), token: true})
+	}
+
+	terminal = append(terminal, wireEvent{data: chunk(map[string]interface{}{}, e.finishReason(r))})
+	if r.includeUsage {
+		usage := e.envelope(r, "chat.completion.chunk")
+		usage["choices"] = []interface{}{}
+		usage["usage"] = e.usage(r.Usage)
+		terminal = append(terminal, wireEvent{data: usage})
+	}
+	terminal = append(terminal, wireEvent{data: "[DONE]"})
+	return events, terminal
+}
+
+type responsesEncoder struct{}
+
+func (responsesEncoder) ndjson() bool { return false }
+
+func (responsesEncoder) errorBody(status int, message string) interface{} {
+	return openAIErrorBody(status, message)
+}
+
+// output returns the output items of the response.
+func (responsesEncoder) output(r reply) []interface{} {
+	var output []interface{}
+	if r.Text != "" {
+		output = append(output, map[string]interface{}{
+			"type":   "message",
+			"id":     "msg_" + r.id,
+			"status": "completed",
+			"role":   "assistant",
+			"content": []interface{}{map[string]interface{}{
+				"type":        "output_text",
+				"text":        r.Text,
+				"annotations": []interface{}{},
+			}},
+		})
+	}
+	for _, call := range r.ToolCalls {
+		output = append(output, map[string]interface{}{
+			"type":      "function_call",
+			"id":        "fc_" + call.ID,
+			"call_id":   call.ID,
+			"name":      call.Function.Name,
+			"arguments": string(arguments(call)),
+			"status":    "completed",
+		})
+	}
+	return output
+}
+
+func (e responsesEncoder) response(r reply, status string, output []interface{}) map[string]interface{} {
+	u := r.Usage
+	response := map[string]interface{}{
+		"id":         r.id,
+		"object":     "response",
+		"created_at": r.created.Unix(),
+		"status":     status,
+		"model":      r.model,
+		"output":     output,
+	}
+	if status == "completed" {
+		response["usage"] = map[string]interface{}{
+			"input_tokens":          u.PromptTokens,
+			"output_tokens":         u.CompletionTokens,
+			"total_tokens":          u.TotalTokens,
+			"input_tokens_details":  map[string]interface{}{"cached_tokens": u.CachedPromptTokens},
+			"output_tokens_details": map[string]interface{}{"reasoning_tokens": u.ReasoningTokens},
+		}
+	}
+	if r.ServiceTier != "" {
+		response["service_tier"] = r.ServiceTier
+	}
+	return response
+}
+
+func (e responsesEncoder) body(r reply) interface{} {
+	return e.response(r, "completed", e.output(r))
+}
+
+func (e responsesEncoder) stream(r reply) (events, terminal []wireEvent) {
+	event := func(eventType string, fields map[string]interface{}, token bool) wireEvent {
+		fields["type"] = eventType
+		return wireEvent{name: eventType, data: fields, token: token}
+	}
+	events = append(events, event("response.created", map[string]interface{}{"response": e.response(r, "in_progress", []interface{}{})}, false))
+
+	index := 0
+	if chunks := r.chunks(); len(chunks) > 0 {
+		events = append(events, event("response.output_item.added", map[string]interface{}{
+			"output_index": index,
+			"item":         map[string]interface{}{"type": "message", "id": "msg_" + r.id, "status": "in_progress", "role": "assistant", "content": []interface{}{}},
+		}, false))
+		for _, text := range chunks {
+			events = append(events, event("response.output_text.delta", map[string]interface{}{
+				"item_id": "msg_" + r.id, "output_index": index, "content_index": 0, "delta": text,
+			}, true))
+		}
+		index++
+	}
+	for _, call := range r.ToolCalls {
+		events = append(events, event("response.output_item.added", map[string]interface{}{
+			"output_index": index,
+			"item": map[string]interface{}{
+				"type": "function_call", "id": "fc_" + call.ID, "call_id": call.ID,
+				"name": call.Function.Name, "arguments": "", "status": "in_progress",
+			},
+		}, false))
+		events = append(events, event("response.function_call_arguments.delta", map[string]interface{}{
+			"item_id": "fc_" + call.ID, "output_index": index, "delta": string(arguments(call)),
+		}, true))
+		index++
+	}
+
+	terminal = append(terminal, event("response.completed", map[string]interface{}{"response": e.body(r)}, false))
+	return events, terminal
+}
+
+type anthropicEncoder struct{}
+
+func (anthropicEncoder) ndjson() bool { return false }
+
+func (anthropicEncoder) errorBody(status int, message string) interface{} {
+	errType := "api_error"
+	switch status {
+	case http.StatusBadRequest:
+		errType = "invalid_request_error"
+	case http.StatusUnauthorized:
+		errType = "authentication_error"
+	case http.StatusForbidden:
+		errType = "permission_error"
+	case http.StatusNotFound:
+		errType = "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		errType = "request_too_large"
+	case http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	case 529:
+		errType = "overloaded_error"
+	}
+	return map[string]interface{}{
+		"type":  "error",
+		"error": map[string]interface{}{"type": errType, "message": message},
+	}
+}
+
+func (anthropicEncoder) usage(r reply, outputTokens int) map[string]interface{} {
+	u := r.Usage
+	usage := map[string]interface{}{
+		"input_tokens":                u.PromptTokens,
+		"output_tokens":               outputTokens,
+		"cache_creation_input_tokens": u.CacheCreationInputTokens,
+		"cache_read_input_tokens":     u.CacheReadInputTokens,
+	}
+	if r.ServiceTier != "" {
+		usage["service_tier"] = r.ServiceTier
+	}
+	return usage
+}
+
+func (anthropicEncoder) stopReason(r reply) string {
+	if len(r.ToolCalls) > 0 {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+func (e anthropicEncoder) message(r reply, content []interface{}, stopReason interface{}, outputTokens int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            r.id,
+		"type":          "message",
+		"role":          "assistant",
+		"model":         r.model,
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage":         e.usage(r, outputTokens),
+	}
+}
+
+func (e anthropicEncoder) body(r reply) interface{} {
+	content := []interface{}{}
+	if r.Text != "" {
+		content = append(content, map[string]interface{}{"type": "text", "text": r.Text})
+	}
+	for _, call := range r.ToolCalls {
+		content = append(content, map[string]interface{}{
+			"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": arguments(call),
+		})
+	}
+	return e.message(r, content, e.stopReason(r), r.Usage.CompletionTokens)
+}
+
+func (e anthropicEncoder) stream(r reply) (events, terminal []wireEvent) {
+	event := func(eventType string, fields map[string]interface{}, token bool) wireEvent {
+		fields["type"] = eventType
+		return wireEvent{name: eventType, data: fields, token: token}
+	}
+	events = append(events, event("message_start", map[string]interface{}{"message": e.message(r, []interface{}{}, nil, 1)}, false))
+
+	index := 0
+	if chunks := r.chunks(); len(chunks) > 0 {
+		events = append(events, event("content_block_start", map[string]interface{}{
+			"index": index, "content_block": map[string]interface{}{"type": "text", "text": ""},
+		}, false))
+		for _, text := range chunks {
+			events = append(events, event("content_block_delta", map[string]interface{}{
+				"index": index, "delta": map[string]interface{}{"type": "text_delta", "text": text},
+			}, true))
+		}
+		events = append(events, event("content_block_stop", map[string]interface{}{"index": index}, false))
+		index++
+	}
+	for _, call := range r.ToolCalls {
+		events = append(events, event("content_block_start", map[string]interface{}{
+			"index": index,
+			"content_block": map[string]interface{}{
+				"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": map[string]interface{}{},
+			},
+		}, false))
+		events = append(events, event("content_block_delta", map[string]interface{}{
+			"index": index, "delta": map[string]interface{}{"type": "input_json_delta", "partial_json": string(arguments(call))},
+		}, true))
+		events = append(events, event("content_block_stop", map[string]interface{}{"index": index}, false))
+		index++
+	}
+
+	terminal = append(terminal,
+		event("message_delta", map[string]interface{}{
+			"delta": map[string]interface{}{"stop_reason": e.stopReason(r), "stop_sequence": nil},
+			"usage": map[string]interface{}{"output_tokens": r.Usage.CompletionTokens},
+		}, false),
+		event("message_stop", map[string]interface{}{}, false),
+	)
+	return events, terminal
+}
+
+type ollamaEncoder struct{}
+
+func (ollamaEncoder) ndjson() bool { return true }
+
+func (ollamaEncoder) errorBody(_ int, message string) interface{} {
+	return map[string]interface{}{"error": message}
+}
+
+// object returns one generate or chat object carrying text.
+func (ollamaEncoder) object(r reply, text string, calls []types.ToolCall, done bool) map[string]interface{} {
+	object := map[string]interface{}{
+		"model":      r.model,
+		"created_at": r.created.UTC().Format(time.RFC3339Nano),
+		"done":       done,
+	}
+	if r.ollamaChat {
+		message := map[string]interface{}{"role": "assistant", "content": text}
+		if len(calls) > 0 {
+			var toolCalls []interface{}
+			for _, call := range calls {
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"function": map[string]interface{}{"name": call.Function.Name, "arguments": arguments(call)},
+				})
+			}
+			message["tool_calls"] = toolCalls
+		}
+		object["message"] = message
+	} else {
+		object["response"] = text
+	}
+	if done {
+		object["done_reason"] = "stop"
+		object["prompt_eval_count"] = r.Usage.PromptTokens
+		object["eval_count"] = r.Usage.CompletionTokens
+	}
+	return object
+}
+
+func (e ollamaEncoder) body(r reply) interface{} {
+	return e.object(r, r.Text, r.ToolCalls, true)
+}
+
+func (e ollamaEncoder) stream(r reply) (events, terminal []wireEvent) {
+	for _, text := range r.chunks() {
+		events = append(events, wireEvent{data: e.object(r, text, nil, false), token: true})
+	}
+	if r.ollamaChat && len(r.ToolCalls) > 0 {
+		events = append(events, wireEvent{data: e.object(r, "", r.ToolCalls, false), token: true})
+	}
+	terminal = append(terminal, wireEvent{data: e.object(r, "", nil, true)})
+	return events, terminal
+}
+
+type cohereEncoder struct{}
+
+func (cohereEncoder) ndjson() bool { return false }
+
+func (cohereEncoder) errorBody(_ int, message string) interface{} {
+	return map[string]interface{}{"message": message}
+}
+
+func (cohereEncoder) usage(r reply) map[string]interface{} {
+	counts := map[string]interface{}{"input_tokens": r.Usage.PromptTokens, "output_tokens": r.Usage.CompletionTokens}
+	return map[string]interface{}{"billed_units": counts, "tokens": counts}
+}
+
+func (cohereEncoder) finishReason(r reply) string {
+	if len(r.ToolCalls) > 0 {
+		return "TOOL_CALL"
+	}
+	return "COMPLETE"
+}
+
+func (cohereEncoder) toolCall(call types.ToolCall) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   call.ID,
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":      call.Function.Name,
+			"arguments": string(arguments(call)),
+		},
+	}
+}
+
+func (e cohereEncoder) body(r reply) interface{} {
+	message := map[string]interface{}{"role": "assistant"}
+	// Like Cohere, a reply that only calls tools has no content.
+	if r.Text != "" {
+		message["content"] = []interface{}{map[string]interface{}{"type": "text", "text": r.Text}}
+	}
+	if len(r.ToolCalls) > 0 {
+		var calls []interface{}
+		for _, call := range r.ToolCalls {
+			calls = append(calls, e.toolCall(call))
+		}
+		message["tool_calls"] = calls
+	}
+	return map[string]interface{}{
+		"id":            r.id,
+		"finish_reason": e.finishReason(r),
+		"message":       message,
+		"usage":         e.usage(r),
+	}
+}
+
+func (e cohereEncoder) stream(r reply) (events, terminal []wireEvent) {
+	event := func(eventType string, fields map[string]interface{}, token bool) wireEvent {
+		fields["type"] = eventType
+		return wireEvent{name: eventType, data: fields, token: token}
+	}
+	message := func(content map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"message": content}
+	}
+	events = append(events, event("message-start", map[string]interface{}{
+		"id": r.id, "delta": message(map[string]interface{}{"role": "assistant"}),
+	}, false))
+
+	if chunks := r.chunks(); len(chunks) > 0 {
+		events = append(events, event("content-start", map[string]interface{}{
+			"index": 0, "delta": message(map[string]interface{}{"content": map[string]interface{}{"type": "text", "text": ""}}),
+		}, false))
+		for _, text := range chunks {
+			events = append(events, event("content-delta", map[string]interface{}{
+				"index": 0, "delta": message(map[string]interface{}{"content": map[string]interface{}{"text": text}}),
+			}, true))
+		}
+		events = append(events, event("content-end", map[string]interface{}{"index": 0}, false))
+	}
+	for i, call := range r.ToolCalls {
+		start := e.toolCall(call)
+		start["function"] = map[string]interface{}{"name": call.Function.Name, "arguments": ""}
+		events = append(events,
+			event("tool-call-start", map[string]interface{}{"index": i, "delta": message(map[string]interface{}{"tool_calls": start})}, false),
+			event("tool-call-delta", map[string]interface{}{"index": i, "delta": message(map[string]interface{}{
+				"tool_calls": map[string]interface{}{"function": map[string]interface{}{"arguments": string(arguments(call))}},
+			})}, true),
+			event("tool-call-end", map[string]interface{}{"index": i}, false),
+		)
+	}
+
+	terminal = append(terminal, event("message-end", map[string]interface{}{
+		"delta": map[string]interface{}{"finish_reason": e.finishReason(r), "usage": e.usage(r)},
+	}, false))
+	return events, terminal
+}
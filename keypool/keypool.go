@@ -0,0 +1,212 @@
+// Package keypool spreads requests for one provider across several API keys.
+//
+// A Pool hands out keys round-robin or least-used, benches a key for a cool-down after the
+// provider rejects it (401) or rate-limits it (429), and counts requests and failures per key.
+// Keys are identified by an ID that is safe to log and to attach to usage events; the key value
+// itself never leaves the pool except in the request's authentication header.
+//
+// Install a pool with config.SetAPIKeyPool. The same *Pool may be given to any number of
+// clients, which then share its rotation and its benches.
+package keypool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Strategy selects which available key serves the next request.
+type Strategy string
+
+const (
+	// RoundRobin cycles through the keys in order, skipping benched ones.
+	RoundRobin Strategy = "round_robin"
+	// LeastUsed picks the available key that has served the fewest requests, so a key that
+	// returns from the bench catches up with the others.
+	LeastUsed Strategy = "least_used"
+)
+
+// DefaultCooldown is how long a key is benched when no cool-down is configured.
+const DefaultCooldown = time.Minute
+
+// ErrNoKeyAvailable is returned (wrapped) by Acquire when every key is benched.
+var ErrNoKeyAvailable = errors.New("keypool: every key is benched")
+
+// Key is one API key. ID names it in logs, stats and usage events.
+type Key struct {
+	ID    string
+	Value string
+}
+
+// KeyStats is the accounting for one key.
+type KeyStats struct {
+	ID string
+	// Requests counts every request sent with the key.
+	Requests int64
+	// Failures counts the 401 and 429 responses that benched the key.
+	Failures int64
+	// BenchedUntil is when the key becomes available again; zero when it is available now.
+	BenchedUntil time.Time
+}
+
+type entry struct {
+	Key
+	requests     int64
+	failures     int64
+	benchedUntil time.Time
+}
+
+// Pool is a set of keys for one provider. It is safe for concurrent use.
+type Pool struct {
+	mu       sync.Mutex
+	keys     []*entry
+	next     int
+	strategy Strategy
+	cooldown time.Duration
+	now      func() time.Time
+}
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithStrategy sets the selection strategy. The default is RoundRobin.
+func WithStrategy(strategy Strategy) Option {
+	return func(p *Pool) {
+		p.strategy = strategy
+	}
+}
+
+// WithCooldown sets how long a rejected or rate-limited key is benched. The default is
+// DefaultCooldown.
+func WithCooldown(cooldown time.Duration) Option {
+	return func(p *Pool) {
+		p.cooldown = cooldown
+	}
+}
+
+// New creates a pool of keys. Keys without an ID get one from Fingerprint. It panics if keys is
+// empty or two keys share an ID, as both are programming errors.
+func New(keys []Key, opts ...Option) *Pool {
+	if len(keys) == 0 {
+		panic("keypool: New called with no keys")
+	}
+	p := &Pool{
+		strategy: RoundRobin,
+		cooldown: DefaultCooldown,
+		now:      time.Now,
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			k.ID = Fingerprint(k.Value)
+		}
+		if seen[k.ID] {
+			panic(fmt.Sprintf("keypool: duplicate key ID %q", k.ID))
+		}
+		seen[k.ID] = true
+		p.keys = append(p.keys, &entry{Key: k})
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// FromValues creates a pool from bare key values, identifying each by its Fingerprint.
+func FromValues(values []string, opts ...Option) *Pool {
+	keys := make([]Key, len(values))
+	for i, v := range values {
+		keys[i] = Key{Value: v}
+	}
+	return New(keys, opts...)
+}
+
+// Fingerprint returns a stable identifier for a key value that does not reveal it: "key-"
+// followed by the first 12 hex digits of its SHA-256.
+func Fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "key-" + hex.EncodeToString(sum[:6])
+}
+
+// First returns the first key, which clients are constructed with before any request picks one.
+func (p *Pool) First() Key {
+	return p.keys[0].Key
+}
+
+// Acquire picks the key for the next request and counts the request against it. When every key
+// is benched it returns an error wrapping ErrNoKeyAvailable that says when the first one returns.
+func (p *Pool) Acquire() (Key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var chosen *entry
+	switch p.strategy {
+	case LeastUsed:
+		for _, e := range p.keys {
+			if e.benchedUntil.After(now) {
+				continue
+			}
+			if chosen == nil || e.requests < chosen.requests {
+				chosen = e
+			}
+		}
+	default:
+		for i := range p.keys {
+			e := p.keys[(p.next+i)%len(p.keys)]
+			if !e.benchedUntil.After(now) {
+				chosen = e
+				p.next = (p.next + i + 1) % len(p.keys)
+				break
+			}
+		}
+	}
+
+	if chosen == nil {
+		soonest := p.keys[0].benchedUntil
+		for _, e := range p.keys[1:] {
+			if e.benchedUntil.Before(soonest) {
+				soonest = e.benchedUntil
+			}
+		}
+		return Key{}, fmt.Errorf("%w; the first returns in %s", ErrNoKeyAvailable, soonest.Sub(now).Round(time.Second))
+	}
+	chosen.requests++
+	return chosen.Key, nil
+}
+
+// Report records the HTTP status a request made with the key received. 401 and 429 bench the
+// key for the cool-down; other statuses, including 0 for a transport failure, change nothing.
+func (p *Pool) Report(id string, status int) {
+	if status != http.StatusUnauthorized && status != http.StatusTooManyRequests {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.keys {
+		if e.ID == id {
+			e.failures++
+			e.benchedUntil = p.now().Add(p.cooldown)
+			return
+		}
+	}
+}
+
+// Stats returns the accounting for every key, in the order the keys were given.
+func (p *Pool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	stats := make([]KeyStats, len(p.keys))
+	for i, e := range p.keys {
+		stats[i] = KeyStats{ID: e.ID, Requests: e.requests, Failures: e.failures}
+		if e.benchedUntil.After(now) {
+			stats[i].BenchedUntil = e.benchedUntil
+		}
+	}
+	return stats
+}
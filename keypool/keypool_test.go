@@ -0,0 +1,110 @@
+package keypool
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock lets the tests move past a cool-down without sleeping.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestPool(strategy Strategy) (*Pool, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := New([]Key{{ID: "a", Value: "va"}, {ID: "b", Value: "vb"}, {ID: "c", Value: "vc"}},
+		WithStrategy(strategy), WithCooldown(time.Minute))
+	p.now = clock.now
+	return p, clock
+}
+
+func acquireIDs(t *testing.T, p *Pool, n int) string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		key, err := p.Acquire()
+		require.NoError(t, err)
+		ids[i] = key.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRoundRobin(t *testing.T) {
+	p, clock := newTestPool(RoundRobin)
+	assert.Equal(t, "a,b,c,a", acquireIDs(t, p, 4))
+
+	p.Report("c", http.StatusTooManyRequests)
+	p.Report("a", http.StatusInternalServerError) // not a key problem
+	assert.Equal(t, "b,a,b", acquireIDs(t, p, 3), "the benched key is skipped")
+
+	clock.advance(time.Minute)
+	assert.Equal(t, "c,a", acquireIDs(t, p, 2), "the key returns after its cool-down")
+}
+
+func TestLeastUsed(t *testing.T) {
+	p, clock := newTestPool(LeastUsed)
+	assert.Equal(t, "a,b,c", acquireIDs(t, p, 3))
+
+	p.Report("a", http.StatusUnauthorized)
+	assert.Equal(t, "b,c,b,c", acquireIDs(t, p, 4))
+
+	clock.advance(2 * time.Minute)
+	assert.Equal(t, "a,a,a", acquireIDs(t, p, 3), "a returning key catches up")
+}
+
+func TestAllBenched(t *testing.T) {
+	p, clock := newTestPool(RoundRobin)
+	p.Report("a", http.StatusTooManyRequests)
+	clock.advance(20 * time.Second)
+	p.Report("b", http.StatusTooManyRequests)
+	p.Report("c", http.StatusUnauthorized)
+
+	_, err := p.Acquire()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoKeyAvailable))
+	assert.Contains(t, err.Error(), "40s")
+
+	stats := p.Stats()
+	assert.Equal(t, []string{"a", "b", "c"}, []string{stats[0].ID, stats[1].ID, stats[2].ID})
+	assert.EqualValues(t, 1, stats[0].Failures)
+	assert.Equal(t, clock.t.Add(40*time.Second), stats[0].BenchedUntil)
+}
+
+func TestFingerprint(t *testing.T) {
+	p := FromValues([]string{"sk-secret-one", "sk-secret-two"})
+	stats := p.Stats()
+	assert.Equal(t, Fingerprint("sk-secret-one"), stats[0].ID)
+	assert.NotEqual(t, stats[0].ID, stats[1].ID)
+	assert.NotContains(t, stats[0].ID, "secret")
+	assert.Equal(t, "sk-secret-one", p.First().Value)
+
+	assert.Panics(t, func() { New(nil) })
+	assert.Panics(t, func() { New([]Key{{ID: "x", Value: "1"}, {ID: "x", Value: "2"}}) })
+}
+
+func TestConcurrentAcquire(t *testing.T) {
+	p := FromValues([]string{"k1", "k2", "k3", "k4"})
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := p.Acquire()
+			if err == nil {
+				p.Report(key.ID, http.StatusOK)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, s := range p.Stats() {
+		assert.EqualValues(t, 10, s.Requests, "round-robin spreads requests evenly")
+	}
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestWithDocumentFile(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "contract.pdf")
+	txtPath := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(pdfPath, []byte("%PDF-1.4"), 0o600))
+	require.NoError(t, os.WriteFile(txtPath, []byte("clause 1"), 0o600))
+
+	p := NewPrompt("Summarize", WithDocumentFile(pdfPath), WithDocumentFile(txtPath))
+	require.NoError(t, p.Validate())
+	require.Len(t, p.Documents, 2)
+
+	pdf := p.Documents[0].Document
+	assert.Equal(t, types.DocumentSourceBase64, pdf.Type)
+	assert.Equal(t, "application/pdf", pdf.MediaType)
+	assert.Equal(t, "contract.pdf", pdf.Filename)
+	assert.Equal(t, "JVBERi0xLjQ=", pdf.Data)
+
+	txt := p.Documents[1].Document
+	assert.Equal(t, types.DocumentSourceText, txt.Type)
+	assert.Equal(t, "clause 1", txt.Data)
+	assert.Equal(t, "notes.txt", txt.Title)
+
+	missing := NewPrompt("Summarize", WithDocumentFile(filepath.Join(dir, "missing.pdf")))
+	assert.Error(t, missing.Validate())
+	assert.False(t, missing.HasDocuments())
+}
+
+func TestPrepareRequestBodyDocuments(t *testing.T) {
+	prompt := NewPrompt("Extract the total",
+		WithDocumentBase64("JVBERi0=", "application/pdf", "invoice.pdf"),
+		WithDocumentCitations(),
+	)
+
+	body, err := PrepareRequestBody(providers.NewAnthropicProvider("key", "claude-sonnet-4-5", nil), prompt, nil, nil)
+	require.NoError(t, err)
+	var req struct {
+		Messages []struct {
+			Role    string                   `json:"role"`
+			Content []map[string]interface{} `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+	require.Len(t, req.Messages, 1)
+	content := req.Messages[0].Content
+	require.Len(t, content, 2)
+	assert.Equal(t, "document", content[0]["type"])
+	assert.Equal(t, map[string]interface{}{"enabled": true}, content[0]["citations"])
+	assert.Equal(t, "text", content[1]["type"])
+	assert.Contains(t, content[1]["text"], "Extract the total")
+
+	// On a conversation the documents ride on the last user turn.
+	conversation := NewPrompt("", WithMessages([]PromptMessage{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+		{Role: "user", Content: "what does it say?"},
+	}), WithDocumentText("clause 1", "Contract"))
+	body, err = PrepareRequestBody(providers.NewOpenAIProvider("key", "gpt-4o", nil), conversation, nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"text":"Contract\n\nclause 1"`)
+	assert.Equal(t, "hi", conversation.Messages[0].Content, "prompt messages must not be mutated")
+	assert.Empty(t, conversation.Messages[2].MultiContent)
+}
+
+func TestDocumentsUnsupported(t *testing.T) {
+	prompt := NewPrompt("Summarize", WithDocumentURL("https://example.com/a.pdf"))
+
+	// OpenAI chat completions cannot take a document by URL.
+	_, err := PrepareRequestBody(providers.NewOpenAIProvider("key", "gpt-4o", nil), prompt, nil, nil)
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+
+	// Generate fails fast instead of retrying a request that can never succeed.
+	l := &LLMImpl{
+		Provider:   providers.NewOllamaProvider("", "llama3", nil),
+		Options:    make(map[string]interface{}),
+		MaxRetries: 3,
+		logger:     utils.NewLogger(utils.LogLevelError),
+	}
+	_, err = l.Generate(context.Background(), NewPrompt("Summarize", WithDocumentText("clause 1", "")))
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestAudioInput(t *testing.T) {
+	prompt := NewPrompt("Transcribe and summarize", WithInputAudio("UklGRg==", "wav"))
+	require.True(t, prompt.HasAudio())
+
+	body, err := PrepareRequestBody(providers.NewOpenAIProvider("key", "gpt-4o-audio-preview", nil), prompt, nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"type":"input_audio"`)
+
+	_, err = PrepareRequestBody(providers.NewAnthropicProvider("key", "claude-sonnet-4-5", nil), prompt, nil, nil)
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memo.MP3")
+	require.NoError(t, os.WriteFile(path, []byte("ID3"), 0o600))
+	fromFile := NewPrompt("Summarize", WithAudioFile(path))
+	require.Len(t, fromFile.Audio, 1)
+	assert.Equal(t, "mp3", fromFile.Audio[0].InputAudio.Format)
+	assert.Equal(t, "SUQz", fromFile.Audio[0].InputAudio.Data)
+}
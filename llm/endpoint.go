@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// parseBaseURL checks a configured base URL, which must be absolute.
+func parseBaseURL(raw string) (*url.URL, error) {
+	base, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", raw)
+	}
+	return base, nil
+}
+
+// endpoint returns the URL generation requests are sent to.
+func (l *LLMImpl) endpoint() string {
+	return l.rebase(l.Provider.Endpoint())
+}
+
+// streamEndpoint returns the URL streaming requests are sent to, which is the generation
+// endpoint unless the provider streams from its own.
+func (l *LLMImpl) streamEndpoint() string {
+	if se, ok := l.Provider.(providers.StreamEndpointer); ok {
+		return l.rebase(se.StreamEndpoint())
+	}
+	return l.endpoint()
+}
+
+// rebase moves endpoint to the configured base URL: its scheme and host become the base URL's,
+// and the base URL's path is prefixed to its own. Without a base URL, or for an endpoint that
+// does not parse, endpoint is returned unchanged.
+func (l *LLMImpl) rebase(endpoint string) string {
+	if l.baseURL == nil {
+		return endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	rebased := *u
+	rebased.Scheme = l.baseURL.Scheme
+	rebased.Host = l.baseURL.Host
+	rebased.User = l.baseURL.User
+	rebased.Path = strings.TrimSuffix(l.baseURL.Path, "/") + u.Path
+	rebased.RawPath = ""
+	return rebased.String()
+}
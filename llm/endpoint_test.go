@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebaseEndpoint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baseURL  string
+		endpoint string
+		expected string
+	}{
+		{"no base URL", "", "https://api.openai.com/v1/chat/completions", "https://api.openai.com/v1/chat/completions"},
+		{"host only", "http://127.0.0.1:8080", "https://api.openai.com/v1/chat/completions", "http://127.0.0.1:8080/v1/chat/completions"},
+		{"path prefix", "https://gateway.example.com/anthropic/", "https://api.anthropic.com/v1/messages", "https://gateway.example.com/anthropic/v1/messages"},
+		{"query kept", "http://localhost:9000", "https://example.com/v1/models/m:generateContent?alt=sse", "http://localhost:9000/v1/models/m:generateContent?alt=sse"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &LLMImpl{}
+			if tc.baseURL != "" {
+				base, err := parseBaseURL(tc.baseURL)
+				require.NoError(t, err)
+				l.baseURL = base
+			}
+			assert.Equal(t, tc.expected, l.rebase(tc.endpoint))
+		})
+	}
+}
+
+func TestParseBaseURLRejectsRelative(t *testing.T) {
+	for _, raw := range []string{"localhost:8080", "/v1", "://bad"} {
+		_, err := parseBaseURL(raw)
+		assert.Error(t, err, raw)
+	}
+}
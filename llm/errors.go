@@ -1,10 +1,12 @@
 package llm
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"unicode/utf8"
 
+	"github.com/teilomillet/gollm/providers"
 	"github.com/teilomillet/gollm/utils"
 )
 
@@ -148,6 +150,26 @@ func classifyHTTPStatus(statusCode int) ErrorType {
 	}
 }
 
+// NewHTTPStatusError builds the LLMError for a non-200 provider response the same way the
+// generation paths do: the type comes from classifyHTTPStatus and the message carries the status
+// code and a truncated copy of the body. Packages that talk to provider endpoints other than
+// chat completions (rerank, batch, audio) use it so callers can branch on the same error types.
+// A body rejecting an expired previous_response_id gets providers.ErrPreviousResponseNotFound as
+// its cause.
+func NewHTTPStatusError(statusCode int, body []byte) *LLMError {
+	var cause error
+	if providers.PreviousResponseNotFound(body) {
+		cause = providers.ErrPreviousResponseNotFound
+	}
+	return NewLLMError(classifyHTTPStatus(statusCode), fmt.Sprintf("API error: status code %d: %s", statusCode, truncateBytes(body, 500)), cause)
+}
+
+// retryable reports whether another attempt could succeed where err failed. A request chaining
+// from an expired response fails the same way every time.
+func retryable(err error) bool {
+	return !errors.Is(err, providers.ErrPreviousResponseNotFound)
+}
+
 // truncateBytes caps b at max runes (appending an ellipsis when truncated) and
 // returns it as a string, so large provider error payloads can be carried in an
 // error/log line without dumping the full body. It walks to a UTF-8 rune
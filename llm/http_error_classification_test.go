@@ -67,3 +67,15 @@ func TestTruncateBytes(t *testing.T) {
 	require.Len(t, []rune(gotMB), 501) // 500 runes + ellipsis
 	require.Equal(t, strings.Repeat("é", 500)+"…", gotMB)
 }
+
+// TestNewHTTPStatusError verifies the exported constructor classifies and formats a status the
+// same way the generation paths always have, so errors from rerank and batch endpoints read alike.
+func TestNewHTTPStatusError(t *testing.T) {
+	err := NewHTTPStatusError(http.StatusTooManyRequests, []byte(`{"error":"slow down"}`))
+	require.Equal(t, ErrorTypeRateLimit, err.Type)
+	require.Equal(t, `API error: status code 429: {"error":"slow down"}`, err.Message)
+
+	err = NewHTTPStatusError(http.StatusInternalServerError, []byte(strings.Repeat("x", 600)))
+	require.Equal(t, ErrorTypeAPI, err.Type)
+	require.True(t, strings.HasSuffix(err.Message, "…"))
+}
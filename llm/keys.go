@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+// credentialPlaceholder is the API key a provider is constructed with when a credential source
+// authenticates it. send replaces the authentication header that carries it with the real
+// credential.
+const credentialPlaceholder = "gollm-credential-source-placeholder"
+
+// keyIDContextKey carries the ID of the pooled key a request was sent with, from send to the
+// usage reports made for that request.
+type keyIDContextKey struct{}
+
+// keyIDFromContext returns the pooled key ID stored by send, or "" when no pool is in use.
+func keyIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyIDContextKey{}).(string)
+	return id
+}
+
+// send performs req with the credential that is current for it. The provider's headers carry the
+// key it was constructed with; when the config has a credential source or a key pool for the
+// provider, that key is replaced with the source's credential or a key taken from the pool. The
+// response status is then reported back, so a 401 invalidates a cached credential and a 401 or
+// 429 benches a pooled key. The returned context carries the pooled key's ID for the usage
+// observer.
+func (l *LLMImpl) send(ctx context.Context, req *http.Request) (context.Context, *http.Response, error) {
+	switch {
+	case l.credentials != nil:
+		cred, err := l.credentials.Credential(ctx)
+		if err != nil {
+			return ctx, nil, fmt.Errorf("obtaining credential: %w", err)
+		}
+		l.applyCredential(req, cred)
+		resp, err := l.client.Do(req)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			if invalidator, ok := l.credentials.(config.CredentialInvalidator); ok {
+				l.logger.Warn("Credential rejected, refreshing on next request", "provider", l.Provider.Name())
+				invalidator.Invalidate()
+			}
+		}
+		return ctx, resp, err
+
+	case l.keyPool != nil:
+		key, err := l.keyPool.Acquire()
+		if err != nil {
+			return ctx, nil, err
+		}
+		l.applyCredential(req, config.Credential{Value: key.Value})
+		l.logger.Debug("Using pooled API key", "provider", l.Provider.Name(), "key_id", key.ID)
+
+		resp, err := l.client.Do(req)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		l.keyPool.Report(key.ID, status)
+		if status == http.StatusUnauthorized || status == http.StatusTooManyRequests {
+			l.logger.Warn("Pooled API key benched", "provider", l.Provider.Name(), "key_id", key.ID, "status", status)
+		}
+		if err != nil {
+			err = fmt.Errorf("key %s: %w", key.ID, err)
+		}
+		return context.WithValue(ctx, keyIDContextKey{}, key.ID), resp, err
+
+	default:
+		resp, err := l.client.Do(req)
+		return ctx, resp, err
+	}
+}
+
+// applyCredential sets cred in the provider's authentication header, l.authHeader, in place of
+// the key the provider was constructed with. A credential that names its own header is sent in
+// that header instead and the provider's is removed, which is how a bearer token reaches a
+// provider that normally takes an api-key header. A provider with no authentication header gets
+// the credential as a bearer token. No other header is touched.
+func (l *LLMImpl) applyCredential(req *http.Request, cred config.Credential) {
+	header, prefix := l.authHeader, l.authPrefix
+	if cred.Header != "" {
+		if l.authHeader != "" && !strings.EqualFold(l.authHeader, cred.Header) {
+			req.Header.Del(l.authHeader)
+		}
+		header, prefix = cred.Header, cred.Prefix
+	}
+	if header == "" {
+		header, prefix = "Authorization", "Bearer "
+	}
+	req.Header.Set(header, prefix+cred.Value)
+}
@@ -0,0 +1,339 @@
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/keypool"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// authRecorder stands in for the OpenAI API: it records the Authorization header of every request
+// and rate-limits one key.
+type authRecorder struct {
+	mu          sync.Mutex
+	seen        []string
+	rateLimited string
+}
+
+func (a *authRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	a.mu.Lock()
+	a.seen = append(a.seen, key)
+	a.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	if key == a.rateLimited {
+		rec.WriteHeader(http.StatusTooManyRequests)
+		_, _ = rec.WriteString(`{"error":{"message":"rate limited"}}`)
+		return rec.Result(), nil
+	}
+	_, _ = rec.WriteString(`{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	return rec.Result(), nil
+}
+
+func TestAPIKeyPoolRotation(t *testing.T) {
+	pool := keypool.New([]keypool.Key{
+		{ID: "a", Value: "sk-test-aaaaaaaaaaaaaaaaaaaa"},
+		{ID: "b", Value: "sk-test-bbbbbbbbbbbbbbbbbbbb"},
+		{ID: "c", Value: "sk-test-cccccccccccccccccccc"},
+	}, keypool.WithCooldown(time.Hour))
+	transport := &authRecorder{rateLimited: "sk-test-bbbbbbbbbbbbbbbbbbbb"}
+
+	var mu sync.Mutex
+	var keyIDs []string
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetAPIKeyPool("openai", pool),
+		config.SetHTTPClient(&http.Client{Transport: transport}),
+		config.SetMaxRetries(1),
+		config.SetRetryDelay(time.Millisecond),
+		config.WithUsageObserver(func(_ context.Context, e UsageEvent) {
+			mu.Lock()
+			keyIDs = append(keyIDs, e.KeyID)
+			mu.Unlock()
+		}),
+	)
+	if cfg.APIKeys["openai"] != "sk-test-aaaaaaaaaaaaaaaaaaaa" {
+		t.Fatalf("SetAPIKeyPool should fill the missing key with the pool's first, got %q", cfg.APIKeys["openai"])
+	}
+
+	// Two clients built from the same config share the pool's rotation and benches.
+	first, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+	second, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+
+	for i, client := range []LLM{first, second, first, second} {
+		if _, err := client.Generate(context.Background(), NewPrompt("hi")); err != nil {
+			t.Fatalf("Generate %d: %v", i, err)
+		}
+	}
+
+	wantSeen := []string{
+		"sk-test-aaaaaaaaaaaaaaaaaaaa",
+		"sk-test-bbbbbbbbbbbbbbbbbbbb", // 429: benched, and the retry moves on
+		"sk-test-cccccccccccccccccccc",
+		"sk-test-aaaaaaaaaaaaaaaaaaaa",
+		"sk-test-cccccccccccccccccccc",
+	}
+	if strings.Join(transport.seen, ",") != strings.Join(wantSeen, ",") {
+		t.Errorf("keys sent = %v, want %v", transport.seen, wantSeen)
+	}
+	if got := strings.Join(keyIDs, ","); got != "a,c,a,c" {
+		t.Errorf("usage event key IDs = %s, want a,c,a,c", got)
+	}
+
+	stats := pool.Stats()
+	if stats[1].Failures != 1 || stats[1].BenchedUntil.IsZero() {
+		t.Errorf("key b should be benched after its 429, got %+v", stats[1])
+	}
+}
+
+func TestAPIKeyPoolExhausted(t *testing.T) {
+	pool := keypool.FromValues([]string{"sk-test-only-key-0000000000"}, keypool.WithCooldown(time.Hour))
+	pool.Report(pool.First().ID, http.StatusUnauthorized)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetAPIKeyPool("openai", pool),
+		config.SetHTTPClient(&http.Client{Transport: &authRecorder{}}),
+		config.SetMaxRetries(0),
+	)
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+	_, _, err = client.GenerateWithUsage(context.Background(), NewPrompt("hi"))
+	if !errors.Is(err, keypool.ErrNoKeyAvailable) {
+		t.Fatalf("expected an exhausted-pool error, got %v", err)
+	}
+}
+
+func TestPooledKeyReplacesOnlyAuthHeader(t *testing.T) {
+	pool := keypool.FromValues([]string{"sk-test-aaaaaaaaaaaaaaaaaaaa", "sk-test-bbbbbbbbbbbbbbbbbbbb"})
+	transport := &headerRecorder{}
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetAPIKeyPool("openai", pool),
+		// A header that happens to hold the construction key is not the authentication header.
+		config.SetExtraHeaders(map[string]string{"X-Audit": "key=sk-test-aaaaaaaaaaaaaaaaaaaa"}),
+		config.SetHTTPClient(&http.Client{Transport: transport}),
+		config.SetMaxRetries(0),
+	)
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Generate(context.Background(), NewPrompt("hi")); err != nil {
+			t.Fatalf("Generate %d: %v", i, err)
+		}
+	}
+
+	if len(transport.headers) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(transport.headers))
+	}
+	second := transport.headers[1]
+	if got := second.Get("Authorization"); got != "Bearer sk-test-bbbbbbbbbbbbbbbbbbbb" {
+		t.Errorf("Authorization = %q, want the second pooled key", got)
+	}
+	if got := second.Get("X-Audit"); got != "key=sk-test-aaaaaaaaaaaaaaaaaaaa" {
+		t.Errorf("X-Audit = %q, want it left as configured", got)
+	}
+}
+
+// headerRecorder records each request's headers and answers with a chat completion, or with a 401
+// for the first unauthorized requests.
+type headerRecorder struct {
+	mu           sync.Mutex
+	headers      []http.Header
+	unauthorized int
+}
+
+func (h *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.mu.Lock()
+	h.headers = append(h.headers, req.Header.Clone())
+	reject := h.unauthorized > 0
+	if reject {
+		h.unauthorized--
+	}
+	h.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	if reject {
+		rec.WriteHeader(http.StatusUnauthorized)
+		return rec.Result(), nil
+	}
+	_, _ = rec.WriteString(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	return rec.Result(), nil
+}
+
+func TestCredentialSourceReplacesProviderKey(t *testing.T) {
+	var calls int
+	source := config.CacheCredential(config.CredentialSourceFunc(func(context.Context) (config.Credential, error) {
+		calls++
+		return config.Credential{Value: fmt.Sprintf("sk-ant-rotated-%d", calls), ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}))
+	transport := &headerRecorder{unauthorized: 1}
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("anthropic"),
+		config.SetModel("claude-test"),
+		config.SetCredentialSource("anthropic", source),
+		config.SetHTTPClient(&http.Client{Transport: transport}),
+		config.SetMaxRetries(1),
+		config.SetRetryDelay(time.Millisecond),
+	)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("a provider with a credential source needs no API key: %v", err)
+	}
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+
+	// The first attempt is rejected, which invalidates the cached credential; the retry fetches
+	// a new one.
+	_, _ = client.Generate(context.Background(), NewPrompt("hi"))
+
+	if len(transport.headers) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(transport.headers))
+	}
+	if got := transport.headers[0].Get("x-api-key"); got != "sk-ant-rotated-1" {
+		t.Errorf("first request x-api-key = %q", got)
+	}
+	if got := transport.headers[1].Get("x-api-key"); got != "sk-ant-rotated-2" {
+		t.Errorf("retry x-api-key = %q, want the refreshed credential", got)
+	}
+}
+
+func TestCredentialSourceBearerTokenForAzure(t *testing.T) {
+	transport := &headerRecorder{}
+	source := config.CredentialSourceFunc(func(context.Context) (config.Credential, error) {
+		return config.Credential{Value: "entra-token", Header: "Authorization", Prefix: "Bearer "}, nil
+	})
+	provider := providers.NewAzureOpenAIProvider(credentialPlaceholder, "deployment", map[string]string{
+		"azure_endpoint": "https://resource.openai.azure.com/openai/deployments/deployment/chat/completions?api-version=2024-10-21",
+	})
+	l := &LLMImpl{
+		Provider:    provider,
+		client:      &http.Client{Transport: transport},
+		logger:      utils.NewLogger(utils.LogLevelOff),
+		Options:     make(map[string]interface{}),
+		credentials: source,
+		authHeader:  "api-key",
+	}
+
+	if _, err := l.Generate(context.Background(), NewPrompt("hi")); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := transport.headers[0]
+	if got.Get("Authorization") != "Bearer entra-token" {
+		t.Errorf("Authorization = %q, want the Entra token", got.Get("Authorization"))
+	}
+	if got.Get("api-key") != "" {
+		t.Errorf("api-key header should be replaced, got %q", got.Get("api-key"))
+	}
+}
+
+// vertexRecorder stands in for Vertex AI: it records each request's URL and bearer token and
+// answers as Claude does.
+type vertexRecorder struct {
+	mu    sync.Mutex
+	urls  []string
+	auths []string
+}
+
+func (v *vertexRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	v.mu.Lock()
+	v.urls = append(v.urls, req.URL.String())
+	v.auths = append(v.auths, req.Header.Get("Authorization"))
+	v.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	_, _ = rec.WriteString(`{"type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`)
+	return rec.Result(), nil
+}
+
+func TestVertexServiceAccountSignIn(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"ya29.vertex","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "gollm@acme-prod.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenServer.URL,
+	})
+	keyFile := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(keyFile, keyJSON, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &vertexRecorder{}
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("vertex"),
+		config.SetModel("claude-sonnet-4@20250514"),
+		config.SetVertex("acme-prod", "us-east5"),
+		config.SetGoogleCredentialsFile(keyFile),
+		config.SetHTTPClient(&http.Client{Transport: transport}),
+	)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("a service account key file stands in for the API key: %v", err)
+	}
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	if err != nil {
+		t.Fatalf("NewLLM: %v", err)
+	}
+	if _, err := client.Generate(context.Background(), NewPrompt("hi")); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	wantURL := "https://us-east5-aiplatform.googleapis.com/v1/projects/acme-prod/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict"
+	if len(transport.urls) != 1 || transport.urls[0] != wantURL {
+		t.Errorf("requests = %v, want one to %s", transport.urls, wantURL)
+	}
+	if len(transport.auths) == 1 && transport.auths[0] != "Bearer ya29.vertex" {
+		t.Errorf("Authorization = %q, want the exchanged access token", transport.auths[0])
+	}
+
+	config.ApplyOptions(cfg, config.SetGoogleCredentialsFile(""))
+	if err := Validate(cfg); err == nil {
+		t.Error("vertex without an access token or key file should not validate")
+	}
+}
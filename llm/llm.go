@@ -12,10 +12,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/keypool"
 	"github.com/teilomillet/gollm/providers"
 	"github.com/teilomillet/gollm/types"
 	"github.com/teilomillet/gollm/utils"
@@ -89,6 +91,18 @@ type LLMImpl struct {
 	// generation reads it from whatever goroutines the caller uses, so the access must be safe.
 	usageObserver UsageObserver
 	usageMutex    sync.RWMutex
+
+	// credentials or keyPool, when the config has one for this provider, supplies the key for
+	// each request in place of the key the provider was constructed with, in the provider's
+	// authentication header: authHeader: authPrefix+key. See send.
+	credentials config.CredentialSource
+	keyPool     *keypool.Pool
+	authHeader  string
+	authPrefix  string
+
+	// baseURL, when the config sets one, replaces the scheme and host of the provider's
+	// endpoints. See endpoint.
+	baseURL *url.URL
 }
 
 // GenerateOption is a function type for configuring generation behavior.
@@ -97,6 +111,28 @@ type GenerateOption func(*GenerateConfig)
 // GenerateConfig holds configuration options for text generation.
 type GenerateConfig struct {
 	UseJSONSchema bool // Whether to use JSON schema validation
+	// Reasoning, when set, requests (true) or suppresses (false) the model's reasoning in
+	// ResponseDetails.Reasoning; nil leaves it to the provider. See WithReasoning.
+	Reasoning *bool
+}
+
+// requestReasoning lets the provider translate WithReasoning into its own request options.
+func (c *GenerateConfig) requestReasoning(provider providers.Provider, options map[string]interface{}) {
+	if c == nil || c.Reasoning == nil {
+		return
+	}
+	if requester, ok := provider.(providers.ReasoningRequester); ok {
+		requester.RequestReasoning(*c.Reasoning, options)
+	}
+}
+
+// filterReasoning drops the reasoning from details when WithReasoning(false) was given, since
+// not every provider can be told to withhold it.
+func (c *GenerateConfig) filterReasoning(details *types.ResponseDetails) {
+	if c == nil || c.Reasoning == nil || *c.Reasoning || details == nil {
+		return
+	}
+	details.Reasoning = nil
 }
 
 // NewLLM creates a new LLM instance with the specified configuration.
@@ -107,15 +143,28 @@ type GenerateConfig struct {
 //   - ErrorTypeProvider if provider initialization fails
 //   - ErrorTypeAuthentication if API key validation fails
 func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.ProviderRegistry) (LLM, error) {
-	extraHeaders := make(map[string]string)
+	extraHeaders := make(map[string]string, len(cfg.ExtraHeaders)+1)
+	for key, value := range cfg.ExtraHeaders {
+		extraHeaders[key] = value
+	}
 	if cfg.Provider == "anthropic" && cfg.EnableCaching {
 		extraHeaders["anthropic-beta"] = "prompt-caching-2024-07-31"
 	}
 
 	// Check if API key is empty (skip for local providers that don't require auth)
 	apiKey := cfg.APIKeys[cfg.Provider]
+	keyPool := cfg.APIKeyPools[cfg.Provider]
+	if apiKey == "" && keyPool != nil {
+		apiKey = keyPool.First().Value
+	}
+	credentials := cfg.CredentialSources[cfg.Provider]
+	if credentials != nil {
+		apiKey = credentialPlaceholder
+	}
 	isLocalProvider := cfg.Provider == "ollama" || cfg.Provider == "lmstudio" || cfg.Provider == "vllm"
-	if apiKey == "" && !isLocalProvider {
+	// Vertex AI signs in with a service account when it has no access token; see below.
+	signsInItself := cfg.Provider == "vertex"
+	if apiKey == "" && !isLocalProvider && !signsInItself {
 		return nil, NewLLMError(ErrorTypeAuthentication, "empty API key", nil)
 	}
 
@@ -127,6 +176,23 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.Provide
 
 	provider.SetDefaultOptions(cfg)
 
+	if apiKey == "" && credentials == nil {
+		if supplier, ok := provider.(providers.CredentialSupplier); ok {
+			credentials, err = supplier.CredentialSource()
+			if err != nil {
+				return nil, NewLLMError(ErrorTypeAuthentication, "failed to obtain credentials", err)
+			}
+		}
+	}
+
+	var baseURL *url.URL
+	if cfg.BaseURL != "" {
+		baseURL, err = parseBaseURL(cfg.BaseURL)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "invalid base URL", err)
+		}
+	}
+
 	// A caller-supplied client is used verbatim (its own Timeout applies), so a custom
 	// RoundTripper can observe every provider request — including response headers, which body
 	// parsing cannot see.
@@ -146,6 +212,12 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.Provide
 		// Carried from the config so accounting reaches clients the caller never holds — the
 		// per-model and aggregator clients inside MOA, and the per-case clients in assess.
 		usageObserver: cfg.UsageObserver,
+		credentials:   credentials,
+		keyPool:       keyPool,
+		baseURL:       baseURL,
+	}
+	if providerConfig, ok := registry.GetProviderConfig(cfg.Provider); ok {
+		llmClient.authHeader, llmClient.authPrefix = providerConfig.AuthHeader, providerConfig.AuthPrefix
 	}
 
 	return llmClient, nil
@@ -228,6 +300,7 @@ func (l *LLMImpl) reportUsage(ctx context.Context, attempt int, outcome UsageOut
 		Attempt:     attempt,
 		Usage:       usage,
 		ServiceTier: tier,
+		KeyID:       keyIDFromContext(ctx),
 		Details:     details,
 	})
 }
@@ -304,18 +377,26 @@ func (l *LLMImpl) Generate(ctx context.Context, prompt *Prompt, opts ...Generate
 	for _, opt := range opts {
 		opt(config)
 	}
+	if err := l.checkAttachments(prompt); err != nil {
+		return "", err
+	}
 	// Set the system prompt in the LLM's options
 	if prompt.SystemPrompt != "" {
 		l.SetOption("system_prompt", prompt.SystemPrompt)
 	}
+	var lastErr error
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
 		l.logger.Debug("Generating text", "provider", l.Provider.Name(), "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt+1)
 		// Pass the entire Prompt struct to attemptGenerate
-		result, err := l.attemptGenerate(ctx, prompt, attempt)
+		result, err := l.attemptGenerate(ctx, prompt, config, attempt)
 		if err == nil {
 			return result, nil
 		}
+		lastErr = err
 		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt+1)
+		if !retryable(err) {
+			return "", err
+		}
 		if attempt < l.MaxRetries {
 			l.logger.Debug("Retrying", "delay", l.RetryDelay)
 			if err := l.wait(ctx); err != nil {
@@ -323,7 +404,7 @@ func (l *LLMImpl) Generate(ctx context.Context, prompt *Prompt, opts ...Generate
 			}
 		}
 	}
-	return "", fmt.Errorf("failed to generate after %d attempts", l.MaxRetries+1)
+	return "", fmt.Errorf("failed to generate after %d attempts: %w", l.MaxRetries+1, lastErr)
 }
 
 // wait implements a cancellable delay between retry attempts.
@@ -343,6 +424,63 @@ func (l *LLMImpl) waitFor(ctx context.Context, d time.Duration) error {
 	}
 }
 
+// checkAttachments is checkRequestAttachments for the request the client would send, with its
+// options.
+func (l *LLMImpl) checkAttachments(prompt *Prompt) error {
+	l.optionsMutex.RLock()
+	messages := l.Options["structured_messages"]
+	l.optionsMutex.RUnlock()
+	return checkRequestAttachments(l.Provider, prompt, map[string]interface{}{"structured_messages": messages})
+}
+
+// checkRequestAttachments is checkAttachments for a request built with options, which carries the
+// attachments of the structured messages set by memory as well as the prompt's own.
+func checkRequestAttachments(provider providers.Provider, prompt *Prompt, options map[string]interface{}) error {
+	messages, _ := options["structured_messages"].([]types.MemoryMessage)
+	if len(messages) == 0 {
+		return checkAttachments(provider, prompt)
+	}
+	withMessages := &Prompt{
+		Documents: prompt.Documents,
+		Audio:     prompt.Audio,
+		Messages:  append([]PromptMessage(nil), prompt.Messages...),
+		optionErr: prompt.optionErr,
+	}
+	for _, msg := range messages {
+		withMessages.Messages = append(withMessages.Messages, PromptMessage{Role: msg.Role, MultiContent: msg.MultiContent})
+	}
+	return checkAttachments(provider, withMessages)
+}
+
+// checkAttachments rejects a prompt whose documents or audio cannot be sent, before any attempt
+// is made: ErrorTypeInvalidInput when an attachment option failed (such as an unreadable file),
+// and ErrorTypeUnsupported when the provider does not accept audio or one of the document source
+// types. Without this a provider would silently drop the attachments and answer without them.
+func checkAttachments(provider providers.Provider, prompt *Prompt) error {
+	if prompt.optionErr != nil {
+		return NewLLMError(ErrorTypeInvalidInput, "invalid prompt attachment", prompt.optionErr)
+	}
+	if prompt.HasAudio() {
+		if supporter, ok := provider.(providers.AudioSupporter); !ok || !supporter.SupportsAudioInput() {
+			return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s does not support audio input", provider.Name()), nil)
+		}
+	}
+	docs := prompt.documentParts()
+	if len(docs) == 0 {
+		return nil
+	}
+	supporter, ok := provider.(providers.DocumentSupporter)
+	if !ok {
+		return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s does not support document content", provider.Name()), nil)
+	}
+	for _, doc := range docs {
+		if !supporter.SupportsDocumentSource(doc.Document.Type) {
+			return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s does not support %q document sources", provider.Name(), doc.Document.Type), nil)
+		}
+	}
+	return nil
+}
+
 // attemptGenerate makes a single attempt to generate text using the provider.
 // It handles request preparation, API communication, and response processing.
 //
@@ -355,62 +493,14 @@ func (l *LLMImpl) waitFor(ctx context.Context, d time.Duration) error {
 //
 // attempt is the zero-based retry index, reported to the usage observer so a recorder can
 // distinguish a first-try success from the tokens burned on a third paid attempt.
-func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, attempt int) (string, error) {
-	// Create a new options map that includes both l.Options and prompt-specific options
-	options := make(map[string]interface{})
-
-	// Safely read from the Options map
-	l.optionsMutex.RLock()
-	for k, v := range l.Options {
-		options[k] = v
-	}
-	l.optionsMutex.RUnlock()
-
-	// Add Tools and ToolChoice to options
-	if len(prompt.Tools) > 0 {
-		options["tools"] = prompt.Tools
-	}
-	if tc, ok := toolChoiceValue(prompt.ToolChoice); ok {
-		options["tool_choice"] = tc
-	}
-
-	// Add Images to options for vision-capable models
-	if prompt.HasImages() {
-		options["images"] = prompt.Images
-	}
-
-	var reqBody []byte
-	var err error
-
-	// Check if we have structured messages from options (memory system)
-	l.optionsMutex.RLock()
-	structuredMessages, hasStructuredMessages := l.Options["structured_messages"]
-	l.optionsMutex.RUnlock()
-
-	if hasStructuredMessages {
-		messages, ok := structuredMessages.([]types.MemoryMessage)
-		if ok {
-			l.logger.Debug("Using structured messages API", "message_count", len(messages))
-			reqBody, err = l.Provider.PrepareRequestWithMessages(messages, options)
-		} else {
-			l.logger.Warn("Invalid structured_messages format", "type", fmt.Sprintf("%T", structuredMessages))
-			reqBody, err = l.Provider.PrepareRequest(prompt.String(), options)
-		}
-	} else if prompt.hasStructuredMessages() {
-		messages := promptMessagesToMemoryMessages(prompt.Messages)
-		l.logger.Debug("Using prompt structured messages", "message_count", len(messages))
-		reqBody, err = l.Provider.PrepareRequestWithMessages(messages, options)
-	} else {
-		// Standard request preparation
-		reqBody, err = l.Provider.PrepareRequest(prompt.String(), options)
-	}
-
+func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, config *GenerateConfig, attempt int) (string, error) {
+	reqBody, _, err := l.requestBody(prompt, nil, config)
 	if err != nil {
 		return "", NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
 
 	l.logger.Debug("Full request body", "body", string(reqBody))
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", l.endpoint(), bytes.NewReader(reqBody))
 	if err != nil {
 		return "", NewLLMError(ErrorTypeRequest, "failed to create request", err)
 	}
@@ -422,7 +512,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, attempt i
 	l.logger.Debug("Request headers", "provider", l.Provider.Name(), "headers", utils.RedactHeaders(headers))
 
 	l.logger.Wire("Full API request", "method", req.Method, "url", req.URL.String(), "headers", utils.RedactHTTPHeaders(req.Header), "body", string(reqBody))
-	resp, err := l.client.Do(req)
+	ctx, resp, err := l.send(ctx, req)
 	if err != nil {
 		return "", NewLLMError(ErrorTypeRequest, "failed to send request", err)
 	}
@@ -437,7 +527,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, attempt i
 
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Warn("API error", "provider", l.Provider.Name(), slog.Int("status", resp.StatusCode), "body", string(body))
-		return "", NewLLMError(classifyHTTPStatus(resp.StatusCode), fmt.Sprintf("API error: status code %d: %s", resp.StatusCode, truncateBytes(body, 500)), nil)
+		return "", NewHTTPStatusError(resp.StatusCode, body)
 	}
 
 	// Parse through the usage-bearing path even though this entrypoint discards the details: the
@@ -450,6 +540,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, attempt i
 		l.reportUsage(ctx, attempt, UsageOutcomeParseFail, nil, body)
 		return "", NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	config.filterReasoning(details)
 	l.logUsage(details)
 	l.reportUsage(ctx, attempt, UsageOutcomeSuccess, details, body)
 
@@ -470,18 +561,25 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 		opt(config)
 	}
 
+	if err := l.checkAttachments(prompt); err != nil {
+		return "", err
+	}
+
 	var result string
 	var lastErr error
 
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
 		l.logger.Debug("Generating text with schema", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt+1)
 
-		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt, schema, attempt)
+		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt, schema, config, attempt)
 		if lastErr == nil {
 			return result, nil
 		}
 
 		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt+1)
+		if !retryable(lastErr) {
+			return "", lastErr
+		}
 
 		if attempt < l.MaxRetries {
 			l.logger.Debug("Retrying", "delay", l.RetryDelay)
@@ -517,6 +615,10 @@ func (l *LLMImpl) GenerateWithUsage(ctx context.Context, prompt *Prompt, opts ..
 		opt(config)
 	}
 
+	if err := l.checkAttachments(prompt); err != nil {
+		return "", nil, err
+	}
+
 	var result string
 	var details *types.ResponseDetails
 	var lastErr error
@@ -524,12 +626,15 @@ func (l *LLMImpl) GenerateWithUsage(ctx context.Context, prompt *Prompt, opts ..
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
 		l.logger.Debug("Generating text with usage tracking", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt+1)
 
-		result, details, lastErr = l.attemptGenerateWithUsage(ctx, prompt, attempt)
+		result, details, lastErr = l.attemptGenerateWithUsage(ctx, prompt, config, attempt)
 		if lastErr == nil {
 			return result, details, nil
 		}
 
 		l.logger.Warn("Generation attempt failed", "error", lastErr, "attempt", attempt+1)
+		if !retryable(lastErr) {
+			return "", nil, lastErr
+		}
 		if attempt < l.MaxRetries {
 			if err := l.wait(ctx); err != nil {
 				return "", nil, err
@@ -557,6 +662,10 @@ func (l *LLMImpl) GenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt
 		opt(config)
 	}
 
+	if err := l.checkAttachments(prompt); err != nil {
+		return "", nil, err
+	}
+
 	var result string
 	var details *types.ResponseDetails
 	var lastErr error
@@ -564,12 +673,15 @@ func (l *LLMImpl) GenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
 		l.logger.Debug("Generating text with schema and usage tracking", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt+1)
 
-		result, details, _, lastErr = l.attemptGenerateWithSchemaAndUsage(ctx, prompt, schema, attempt)
+		result, details, _, lastErr = l.attemptGenerateWithSchemaAndUsage(ctx, prompt, schema, config, attempt)
 		if lastErr == nil {
 			return result, details, nil
 		}
 
 		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt+1)
+		if !retryable(lastErr) {
+			return "", nil, lastErr
+		}
 		if attempt < l.MaxRetries {
 			if err := l.wait(ctx); err != nil {
 				return "", nil, err
@@ -589,62 +701,14 @@ func (l *LLMImpl) GenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt
 //   - Any error encountered during the attempt
 //
 // attempt is the zero-based retry index, reported to the usage observer.
-func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt, attempt int) (string, *types.ResponseDetails, error) {
-	// Create a new options map that includes both l.Options and prompt-specific options
-	options := make(map[string]interface{})
-
-	// Safely read from the Options map
-	l.optionsMutex.RLock()
-	for k, v := range l.Options {
-		options[k] = v
-	}
-	l.optionsMutex.RUnlock()
-
-	// Set system prompt from the prompt object into local options (not l.Options)
-	if prompt.SystemPrompt != "" {
-		options["system_prompt"] = prompt.SystemPrompt
-	}
-
-	// Add Tools and ToolChoice to options
-	if len(prompt.Tools) > 0 {
-		options["tools"] = prompt.Tools
-	}
-	if tc, ok := toolChoiceValue(prompt.ToolChoice); ok {
-		options["tool_choice"] = tc
-	}
-
-	var reqBody []byte
-	var err error
-
-	// Check if we have structured messages
-	l.optionsMutex.RLock()
-	structuredMessages, hasStructuredMessages := l.Options["structured_messages"]
-	l.optionsMutex.RUnlock()
-
-	if hasStructuredMessages {
-		messages, ok := structuredMessages.([]types.MemoryMessage)
-		if ok {
-			l.logger.Debug("Using structured messages API", "message_count", len(messages))
-			reqBody, err = l.Provider.PrepareRequestWithMessages(messages, options)
-		} else {
-			l.logger.Warn("Invalid structured_messages format", "type", fmt.Sprintf("%T", structuredMessages))
-			reqBody, err = l.Provider.PrepareRequest(prompt.String(), options)
-		}
-	} else if prompt.hasStructuredMessages() {
-		messages := promptMessagesToMemoryMessages(prompt.Messages)
-		l.logger.Debug("Using prompt structured messages", "message_count", len(messages))
-		reqBody, err = l.Provider.PrepareRequestWithMessages(messages, options)
-	} else {
-		// Standard request preparation
-		reqBody, err = l.Provider.PrepareRequest(prompt.String(), options)
-	}
-
+func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt, config *GenerateConfig, attempt int) (string, *types.ResponseDetails, error) {
+	reqBody, _, err := l.requestBody(prompt, nil, config)
 	if err != nil {
 		return "", nil, NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
 
 	l.logger.Debug("Full request body", "body", string(reqBody))
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", l.endpoint(), bytes.NewReader(reqBody))
 	if err != nil {
 		return "", nil, NewLLMError(ErrorTypeRequest, "failed to create request", err)
 	}
@@ -654,7 +718,7 @@ func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt,
 	}
 
 	l.logger.Wire("Full API request", "method", req.Method, "url", req.URL.String(), "headers", utils.RedactHTTPHeaders(req.Header), "body", string(reqBody))
-	resp, err := l.client.Do(req)
+	ctx, resp, err := l.send(ctx, req)
 	if err != nil {
 		return "", nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
 	}
@@ -669,7 +733,7 @@ func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt,
 
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Warn("API error", "provider", l.Provider.Name(), slog.Int("status", resp.StatusCode), "body", string(body))
-		return "", nil, NewLLMError(classifyHTTPStatus(resp.StatusCode), fmt.Sprintf("API error: status code %d: %s", resp.StatusCode, truncateBytes(body, 500)), nil)
+		return "", nil, NewHTTPStatusError(resp.StatusCode, body)
 	}
 
 	// Try to use ParseResponseWithUsage if available
@@ -681,6 +745,7 @@ func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt,
 		l.reportUsage(ctx, attempt, UsageOutcomeParseFail, nil, body)
 		return "", nil, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	config.filterReasoning(details)
 
 	l.logUsage(details)
 	l.reportUsage(ctx, attempt, UsageOutcomeSuccess, details, body)
@@ -688,51 +753,6 @@ func (l *LLMImpl) attemptGenerateWithUsage(ctx context.Context, prompt *Prompt,
 	return result, details, nil
 }
 
-// prepareSchemaRequestBody builds the options map from the prompt and prepares
-// the request body for schema-based generation. It centralises the logic shared
-// by attemptGenerateWithSchema and attemptGenerateWithSchemaAndUsage.
-func (l *LLMImpl) prepareSchemaRequestBody(prompt *Prompt, schema interface{}) (reqBody []byte, fullPrompt string, err error) {
-	l.optionsMutex.RLock()
-	options := make(map[string]interface{})
-	for k, v := range l.Options {
-		options[k] = v
-	}
-	l.optionsMutex.RUnlock()
-
-	// Set system prompt from the prompt object into local options (not l.Options)
-	if prompt.SystemPrompt != "" {
-		options["system_prompt"] = prompt.SystemPrompt
-	}
-
-	// Add Tools and ToolChoice to options
-	if len(prompt.Tools) > 0 {
-		options["tools"] = prompt.Tools
-	}
-	if tc, ok := toolChoiceValue(prompt.ToolChoice); ok {
-		options["tool_choice"] = tc
-	}
-
-	// Add Images to options for vision-capable models
-	if prompt.HasImages() {
-		options["images"] = prompt.Images
-	}
-
-	if prompt.hasStructuredMessages() {
-		messages := promptMessagesToMemoryMessages(prompt.Messages)
-		l.logger.Debug("Using prompt structured messages with schema", "message_count", len(messages))
-		reqBody, err = l.Provider.PrepareRequestWithMessagesAndSchema(messages, options, schema)
-		fullPrompt = prompt.String()
-	} else if l.SupportsJSONSchema() {
-		reqBody, err = l.Provider.PrepareRequestWithSchema(prompt.String(), options, schema)
-		fullPrompt = prompt.String()
-	} else {
-		fullPrompt = l.preparePromptWithSchema(prompt.String(), schema)
-		reqBody, err = l.Provider.PrepareRequest(fullPrompt, options)
-	}
-
-	return reqBody, fullPrompt, err
-}
-
 // attemptGenerateWithSchemaAndUsage makes a single attempt to generate text with schema validation and response details.
 // It combines schema validation with response details extraction.
 //
@@ -743,15 +763,15 @@ func (l *LLMImpl) prepareSchemaRequestBody(prompt *Prompt, schema interface{}) (
 //   - Any error encountered during the attempt
 //
 // attempt is the zero-based retry index, reported to the usage observer.
-func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt, schema interface{}, attempt int) (string, *types.ResponseDetails, string, error) {
-	reqBody, fullPrompt, err := l.prepareSchemaRequestBody(prompt, schema)
+func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt, schema interface{}, config *GenerateConfig, attempt int) (string, *types.ResponseDetails, string, error) {
+	reqBody, fullPrompt, err := l.requestBody(prompt, schema, config)
 	if err != nil {
 		return "", nil, fullPrompt, NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
 
 	l.logger.Debug("Request body", "provider", l.Provider.Name(), "body", string(reqBody))
 
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", l.endpoint(), bytes.NewReader(reqBody))
 	if err != nil {
 		return "", nil, fullPrompt, NewLLMError(ErrorTypeRequest, "failed to create request", err)
 	}
@@ -761,7 +781,7 @@ func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt
 	}
 
 	l.logger.Wire("Full API request", "method", req.Method, "url", req.URL.String(), "headers", utils.RedactHTTPHeaders(req.Header), "body", string(reqBody))
-	resp, err := l.client.Do(req)
+	ctx, resp, err := l.send(ctx, req)
 	if err != nil {
 		return "", nil, fullPrompt, NewLLMError(ErrorTypeRequest, "failed to send request", err)
 	}
@@ -776,7 +796,7 @@ func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt
 
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Warn("API error", "provider", l.Provider.Name(), slog.Int("status", resp.StatusCode), "body", string(body))
-		return "", nil, fullPrompt, NewLLMError(classifyHTTPStatus(resp.StatusCode), fmt.Sprintf("API error: status code %d: %s", resp.StatusCode, truncateBytes(body, 500)), nil)
+		return "", nil, fullPrompt, NewHTTPStatusError(resp.StatusCode, body)
 	}
 
 	// Try to use ParseResponseWithUsage
@@ -785,6 +805,7 @@ func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt
 		l.reportUsage(ctx, attempt, UsageOutcomeParseFail, nil, body)
 		return "", nil, fullPrompt, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	config.filterReasoning(details)
 
 	// Validate the result against the schema
 	if err := ValidateAgainstSchema(result, schema); err != nil {
@@ -811,15 +832,15 @@ func (l *LLMImpl) attemptGenerateWithSchemaAndUsage(ctx context.Context, prompt
 //   - Other error types as per attemptGenerate
 //
 // attempt is the zero-based retry index, reported to the usage observer.
-func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, attempt int) (string, string, error) {
-	reqBody, fullPrompt, err := l.prepareSchemaRequestBody(prompt, schema)
+func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, config *GenerateConfig, attempt int) (string, string, error) {
+	reqBody, fullPrompt, err := l.requestBody(prompt, schema, config)
 	if err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
 
 	l.logger.Debug("Request body", "provider", l.Provider.Name(), "body", string(reqBody))
 
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", l.endpoint(), bytes.NewReader(reqBody))
 	if err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeRequest, "failed to create request", err)
 	}
@@ -829,7 +850,7 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt,
 	}
 
 	l.logger.Wire("Full API request", "method", req.Method, "url", req.URL.String(), "headers", utils.RedactHTTPHeaders(req.Header), "body", string(reqBody))
-	resp, err := l.client.Do(req)
+	ctx, resp, err := l.send(ctx, req)
 	if err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeRequest, "failed to send request", err)
 	}
@@ -845,7 +866,7 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt,
 
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Warn("API error", "provider", l.Provider.Name(), slog.Int("status", resp.StatusCode), "body", string(body))
-		return "", fullPrompt, NewLLMError(classifyHTTPStatus(resp.StatusCode), fmt.Sprintf("API error: status code %d: %s", resp.StatusCode, truncateBytes(body, 500)), nil)
+		return "", fullPrompt, NewHTTPStatusError(resp.StatusCode, body)
 	}
 
 	// Parse through the usage-bearing path even though this entrypoint discards the details, so
@@ -856,6 +877,7 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt,
 		l.reportUsage(ctx, attempt, UsageOutcomeParseFail, nil, body)
 		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	config.filterReasoning(details)
 
 	// Validate the result against the schema
 	if err := ValidateAgainstSchema(result, schema); err != nil {
@@ -869,16 +891,106 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt,
 	return result, fullPrompt, nil
 }
 
-// preparePromptWithSchema prepares a prompt with a JSON schema for providers that do not support JSON schema validation.
-// Returns the original prompt if schema marshaling fails (with a warning log).
-func (l *LLMImpl) preparePromptWithSchema(prompt string, schema interface{}) string {
+// promptWithSchema appends the schema instructions used for providers without native JSON schema
+// support.
+func promptWithSchema(prompt string, schema interface{}) (string, error) {
 	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
-		l.logger.Warn("Failed to marshal schema", "error", err)
-		return prompt
+		return "", err
+	}
+
+	return fmt.Sprintf("%s\n\nPlease provide your response in JSON format according to this schema:\n%s", prompt, string(schemaJSON)), nil
+}
+
+// options returns a copy of the client's options.
+func (l *LLMImpl) options() map[string]interface{} {
+	l.optionsMutex.RLock()
+	defer l.optionsMutex.RUnlock()
+	options := make(map[string]interface{}, len(l.Options))
+	for k, v := range l.Options {
+		options[k] = v
 	}
+	return options
+}
 
-	return fmt.Sprintf("%s\n\nPlease provide your response in JSON format according to this schema:\n%s", prompt, string(schemaJSON))
+// requestBody builds the body of one attempt from the client's options, with the reasoning
+// config asks for, and returns the prompt text sent. See buildRequestBody.
+func (l *LLMImpl) requestBody(prompt *Prompt, schema interface{}, config *GenerateConfig) (reqBody []byte, fullPrompt string, err error) {
+	options := l.options()
+	config.requestReasoning(l.Provider, options)
+	return buildRequestBody(l.Provider, prompt, options, schema)
+}
+
+// PrepareRequestBody builds the request body the client would send for prompt, with its options
+// and the structured messages memory has set, without sending it. schema may be nil.
+func (l *LLMImpl) PrepareRequestBody(prompt *Prompt, schema interface{}) ([]byte, error) {
+	if err := l.checkAttachments(prompt); err != nil {
+		return nil, err
+	}
+	body, _, err := l.requestBody(prompt, schema, nil)
+	return body, err
+}
+
+// PrepareRequestBody builds the provider request body for a prompt the way the generation paths
+// do, without sending it. It exists for callers that deliver the body some other way, such as the
+// batch package, which writes it into a provider's batch file instead of posting it.
+//
+// options holds per-request provider options (temperature, max_tokens, …) and is not modified;
+// structured messages among them are sent as the conversation. schema may be nil.
+func PrepareRequestBody(provider providers.Provider, prompt *Prompt, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	if err := checkRequestAttachments(provider, prompt, options); err != nil {
+		return nil, err
+	}
+	body, _, err := buildRequestBody(provider, prompt, options, schema)
+	return body, err
+}
+
+// buildRequestBody builds the provider request body for prompt, and returns it with the prompt
+// text sent. A copy of options carries the prompt's system prompt, tools, tool choice and
+// images. The conversation is the structured messages among options when memory has set them,
+// otherwise the prompt's own messages and attachments, otherwise the prompt as text. A schema
+// goes to the provider's native support or, without it, is folded into the prompt text.
+func buildRequestBody(provider providers.Provider, prompt *Prompt, options map[string]interface{}, schema interface{}) (reqBody []byte, fullPrompt string, err error) {
+	opts := make(map[string]interface{}, len(options)+4)
+	for k, v := range options {
+		opts[k] = v
+	}
+	if prompt.SystemPrompt != "" {
+		opts["system_prompt"] = prompt.SystemPrompt
+	}
+	if len(prompt.Tools) > 0 {
+		opts["tools"] = prompt.Tools
+	}
+	if tc, ok := toolChoiceValue(prompt.ToolChoice); ok {
+		opts["tool_choice"] = tc
+	}
+	if prompt.HasImages() {
+		opts["images"] = prompt.Images
+	}
+
+	fullPrompt = prompt.String()
+	messages, ok := opts["structured_messages"].([]types.MemoryMessage)
+	if !ok || len(messages) == 0 {
+		messages, ok = prompt.requestMessages()
+	}
+	switch {
+	case ok && schema != nil:
+		reqBody, err = provider.PrepareRequestWithMessagesAndSchema(messages, opts, schema)
+	case ok:
+		reqBody, err = provider.PrepareRequestWithMessages(messages, opts)
+	case schema == nil:
+		reqBody, err = provider.PrepareRequest(fullPrompt, opts)
+	case provider.SupportsJSONSchema():
+		reqBody, err = provider.PrepareRequestWithSchema(fullPrompt, opts, schema)
+	default:
+		withSchema, schemaErr := promptWithSchema(fullPrompt, schema)
+		if schemaErr != nil {
+			return nil, fullPrompt, fmt.Errorf("failed to marshal schema: %w", schemaErr)
+		}
+		fullPrompt = withSchema
+		reqBody, err = provider.PrepareRequest(fullPrompt, opts)
+	}
+	return reqBody, fullPrompt, err
 }
 
 // Stream initiates a streaming response from the LLM.
@@ -899,6 +1011,9 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	if !l.SupportsStreaming() {
 		return nil, NewLLMError(ErrorTypeUnsupported, "streaming not supported by provider", nil)
 	}
+	if err := l.checkAttachments(prompt); err != nil {
+		return nil, err
+	}
 
 	// Apply stream options
 	config := &StreamConfig{
@@ -949,11 +1064,14 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 
 	var body []byte
 	var err error
-	if smp, ok := l.Provider.(streamMessagesPreparer); ok && prompt.hasStructuredMessages() {
+	smp, canStreamMessages := l.Provider.(streamMessagesPreparer)
+	if (prompt.HasDocuments() || prompt.HasAudio()) && !canStreamMessages {
+		return nil, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s does not support streaming attachments", l.Provider.Name()), nil)
+	}
+	if messages, ok := prompt.requestMessages(); ok && canStreamMessages {
 		if prompt.SystemPrompt != "" {
 			options["system_prompt"] = prompt.SystemPrompt
 		}
-		messages := promptMessagesToMemoryMessages(prompt.Messages)
 		body, err = smp.PrepareStreamRequestWithMessages(messages, options)
 	} else {
 		body, err = l.Provider.PrepareStreamRequest(prompt.String(), options)
@@ -964,10 +1082,13 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 
 	// Retry establishment only (no tokens produced yet, so re-issuing is safe).
 	// Once data flows, errors are surfaced by Next — chat streams can't resume.
+	endpoint := l.streamEndpoint()
+
 	retry := config.RetryStrategy
 	var resp *http.Response
+	usageCtx := ctx
 	for {
-		req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
 		if err != nil {
 			return nil, NewLLMError(ErrorTypeRequest, "failed to create stream request", err)
 		}
@@ -976,7 +1097,7 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 		}
 
 		l.logger.Wire("Full API request", "method", req.Method, "url", req.URL.String(), "headers", utils.RedactHTTPHeaders(req.Header), "body", string(body))
-		resp, err = l.client.Do(req)
+		usageCtx, resp, err = l.send(ctx, req)
 		if err == nil && resp.StatusCode == http.StatusOK {
 			break
 		}
@@ -999,7 +1120,7 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 				errBody = nil
 			}
 			l.logger.Warn("API error", "provider", l.Provider.Name(), slog.Int("status", code), "body", string(errBody))
-			streamErr = NewLLMError(classifyHTTPStatus(code), fmt.Sprintf("API error: status code %d: %s", code, truncateBytes(errBody, 500)), nil)
+			streamErr = NewHTTPStatusError(code, errBody)
 			transient = code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
 		}
 
@@ -1015,7 +1136,7 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	// Create and return stream. The stream reports its accumulated usage when it ends — the
 	// request is billed for whatever it generated even if the consumer abandons it mid-flight.
 	report := func(outcome UsageOutcome, model, serviceTier string, usage types.TokenUsage) {
-		l.reportStreamUsage(ctx, outcome, model, serviceTier, usage)
+		l.reportStreamUsage(usageCtx, outcome, model, serviceTier, usage)
 	}
 	return newProviderStream(resp.Body, l.Provider, config, report), nil
 }
@@ -1041,6 +1162,7 @@ func (l *LLMImpl) reportStreamUsage(ctx context.Context, outcome UsageOutcome, m
 		Outcome:     outcome,
 		Usage:       usage,
 		ServiceTier: serviceTier,
+		KeyID:       keyIDFromContext(ctx),
 	})
 }
 
@@ -1264,6 +1386,9 @@ func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 				if chunk.ToolCallDelta != nil {
 					token.ToolCallDelta = chunk.ToolCallDelta
 				}
+				token.Reasoning = chunk.Reasoning
+				token.Thinking = chunk.Thinking
+				token.ToolOutputs = chunk.ToolOutputs
 				if chunk.Usage != nil || chunk.Model != "" || chunk.ServiceTier != "" {
 					s.usageMutex.Lock()
 					if chunk.Usage != nil {
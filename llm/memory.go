@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/pkoukk/tiktoken-go"
+	"github.com/teilomillet/gollm/providers"
 	"github.com/teilomillet/gollm/types"
 	"github.com/teilomillet/gollm/utils"
 )
@@ -134,9 +135,8 @@ func (m *Memory) AddStructured(message types.MemoryMessage) {
 	defer m.mutex.Unlock()
 
 	// If tokens aren't already calculated, calculate them
-	if message.Tokens == 0 && message.Content != "" {
-		tokens := m.encoding.Encode(message.Content, nil, nil)
-		message.Tokens = len(tokens)
+	if message.Tokens == 0 {
+		message.Tokens = m.countTokens(message)
 	}
 
 	m.messages = append(m.messages, message)
@@ -152,6 +152,31 @@ func (m *Memory) AddStructured(message types.MemoryMessage) {
 		"total_tokens", m.totalTokens)
 }
 
+// attachmentTokens is the estimated token count of an image, audio clip or non-text document
+// held in memory. Providers count these by size, page or duration, which memory does not know,
+// so each is counted at this fixed estimate: truncation starts roughly, not exactly, when the
+// attachments fill the budget.
+const attachmentTokens = 1000
+
+// countTokens returns the token count of a message: its content, the text of its MultiContent
+// that is not a copy of the content, and an estimate for each attachment.
+func (m *Memory) countTokens(message types.MemoryMessage) int {
+	count := len(m.encoding.Encode(message.Content, nil, nil))
+	for _, part := range message.MultiContent {
+		switch {
+		case part.Type == types.ContentTypeText:
+			if part.Text != message.Content {
+				count += len(m.encoding.Encode(part.Text, nil, nil))
+			}
+		case part.IsDocument() && part.Document.Type == types.DocumentSourceText:
+			count += len(m.encoding.Encode(part.Document.Data, nil, nil))
+		default:
+			count += attachmentTokens
+		}
+	}
+	return count
+}
+
 // truncateIfNeeded removes oldest messages until the total token count is within limits.
 // This is called automatically by Add and AddStructured when necessary.
 func (m *Memory) truncateIfNeeded() {
@@ -219,10 +244,59 @@ func (m *Memory) GetMessages() []types.MemoryMessage {
 				}
 			}
 		}
+		if msg.MultiContent != nil {
+			messages[i].MultiContent = copyContentParts(msg.MultiContent)
+		}
+		if msg.ThinkingBlocks != nil {
+			messages[i].ThinkingBlocks = append([]types.ThinkingBlock(nil), msg.ThinkingBlocks...)
+		}
+		messages[i].ResponseID = msg.ResponseID
 	}
 	return messages
 }
 
+// copyContentParts returns a copy of parts that shares nothing with it.
+func copyContentParts(parts []types.ContentPart) []types.ContentPart {
+	copied := make([]types.ContentPart, len(parts))
+	for i, part := range parts {
+		copied[i] = part
+		if part.ImageURL != nil {
+			imageURL := *part.ImageURL
+			copied[i].ImageURL = &imageURL
+		}
+		if part.Source != nil {
+			source := *part.Source
+			copied[i].Source = &source
+		}
+		if part.Document != nil {
+			document := *part.Document
+			copied[i].Document = &document
+		}
+		if part.InputAudio != nil {
+			audio := *part.InputAudio
+			copied[i].InputAudio = &audio
+		}
+	}
+	return copied
+}
+
+// forgetResponseIDs clears the ResponseID of every message, so a stateful provider sends the
+// full history again instead of chaining from a response it no longer has. It reports whether
+// any ID was cleared.
+func (m *Memory) forgetResponseIDs() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cleared := false
+	for i := range m.messages {
+		if m.messages[i].ResponseID != "" {
+			m.messages[i].ResponseID = ""
+			cleared = true
+		}
+	}
+	return cleared
+}
+
 // Clear removes all messages from memory and resets the token count.
 // This operation is thread-safe.
 func (m *Memory) Clear() {
@@ -345,6 +419,8 @@ func NewLLMWithMemory(llm LLM, maxTokens int, model string) (LLM, error) {
 
 // Generate produces text based on the given prompt and conversation history.
 // It automatically adds the prompt and response to memory for future context.
+// It goes through GenerateWithUsage, so a stateful provider gets the response ID
+// it chains the next call from.
 //
 // Parameters:
 //   - ctx: Context for the request
@@ -355,76 +431,8 @@ func NewLLMWithMemory(llm LLM, maxTokens int, model string) (LLM, error) {
 //   - Generated text response
 //   - Error types as per the base LLM's Generate method
 func (l *LLMWithMemory) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
-	// Add user message to memory
-	l.memory.Add("user", prompt.Input)
-
-	var response string
-	var err error
-
-	if l.useStructuredMessages {
-		// Get structured messages from memory
-		messages := l.memory.GetMessages()
-
-		// Make a copy of the original prompt with empty input
-		// (since content will be in structured messages)
-		emptyPrompt := &Prompt{
-			Input:           "", // Empty as content is in messages
-			Output:          prompt.Output,
-			Directives:      prompt.Directives,
-			Context:         prompt.Context,
-			MaxLength:       prompt.MaxLength,
-			Examples:        prompt.Examples,
-			SystemPrompt:    prompt.SystemPrompt,
-			SystemCacheType: prompt.SystemCacheType,
-			Tools:           prompt.Tools,
-			ToolChoice:      prompt.ToolChoice,
-			// Messages intentionally omitted - using structured_messages option instead
-		}
-
-		// This is an intentional no-op GenerateOption. The structured messages are
-		// passed via SetOption("structured_messages", messages) above, not through
-		// the GenerateOption mechanism. This placeholder is included only to satisfy
-		// the append(opts, withMessages) pattern, ensuring we don't modify the
-		// caller's original opts slice while still passing all options through.
-		withMessages := func(config *GenerateConfig) {}
-
-		// Set structured messages option
-		l.LLM.SetOption("structured_messages", messages)
-
-		// Generate with structured messages
-		response, err = l.LLM.Generate(ctx, emptyPrompt, append(opts, withMessages)...)
-
-		// Remove the structured messages option after use
-		l.LLM.SetOption("structured_messages", nil)
-	} else {
-		// Fallback to traditional flattened prompt approach
-		fullPrompt := l.memory.GetPrompt()
-
-		// Create a new Prompt with the full memory context
-		memoryPrompt := &Prompt{
-			Input:           fullPrompt,
-			Output:          prompt.Output,
-			Directives:      prompt.Directives,
-			Context:         prompt.Context,
-			MaxLength:       prompt.MaxLength,
-			Examples:        prompt.Examples,
-			SystemPrompt:    prompt.SystemPrompt,
-			SystemCacheType: prompt.SystemCacheType,
-			Tools:           prompt.Tools,
-			ToolChoice:      prompt.ToolChoice,
-			// Messages intentionally omitted - using flattened prompt in Input
-		}
-
-		response, err = l.LLM.Generate(ctx, memoryPrompt, opts...)
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	// Add assistant response to memory
-	l.memory.Add("assistant", response)
-	return response, nil
+	response, _, err := l.GenerateWithUsage(ctx, prompt, opts...)
+	return response, err
 }
 
 // SetUseStructuredMessages configures whether to use structured messages.
@@ -451,8 +459,7 @@ func (l *LLMWithMemory) GetMemory() []types.MemoryMessage {
 }
 
 // GenerateWithSchema generates text conforming to a schema, with conversation history.
-// It automatically adds the prompt and response to memory.
-// Respects the useStructuredMessages setting for consistent behavior with Generate.
+// It automatically adds the prompt and response to memory, as GenerateWithSchemaAndUsage does.
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeout
@@ -464,107 +471,108 @@ func (l *LLMWithMemory) GetMemory() []types.MemoryMessage {
 //   - Generated text response
 //   - Error types as per the base LLM's GenerateWithSchema method
 func (l *LLMWithMemory) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, error) {
-	// Add user message to memory
-	l.memory.Add("user", prompt.Input)
-
-	var response string
-	var err error
-
-	if l.useStructuredMessages {
-		// Get structured messages from memory
-		messages := l.memory.GetMessages()
-
-		// Make a copy of the original prompt with empty input
-		// (since content will be in structured messages)
-		emptyPrompt := &Prompt{
-			Input:           "", // Empty as content is in messages
-			Output:          prompt.Output,
-			Directives:      prompt.Directives,
-			Context:         prompt.Context,
-			MaxLength:       prompt.MaxLength,
-			Examples:        prompt.Examples,
-			SystemPrompt:    prompt.SystemPrompt,
-			SystemCacheType: prompt.SystemCacheType,
-			Tools:           prompt.Tools,
-			ToolChoice:      prompt.ToolChoice,
-			// Messages intentionally omitted - using structured_messages option instead
-		}
-
-		// Set structured messages option
-		l.LLM.SetOption("structured_messages", messages)
-
-		// Generate with structured messages and schema
-		response, err = l.LLM.GenerateWithSchema(ctx, emptyPrompt, schema, opts...)
-
-		// Remove the structured messages option after use
-		l.LLM.SetOption("structured_messages", nil)
-	} else {
-		// Fallback to traditional flattened prompt approach
-		fullPrompt := l.memory.GetPrompt()
-
-		memoryPrompt := &Prompt{
-			Input:           fullPrompt,
-			Output:          prompt.Output,
-			Directives:      prompt.Directives,
-			Context:         prompt.Context,
-			MaxLength:       prompt.MaxLength,
-			Examples:        prompt.Examples,
-			SystemPrompt:    prompt.SystemPrompt,
-			SystemCacheType: prompt.SystemCacheType,
-			Tools:           prompt.Tools,
-			ToolChoice:      prompt.ToolChoice,
-			// Messages intentionally omitted - using flattened prompt in Input
-		}
-
-		response, err = l.LLM.GenerateWithSchema(ctx, memoryPrompt, schema, opts...)
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	l.memory.Add("assistant", response)
-	return response, nil
+	response, _, err := l.GenerateWithSchemaAndUsage(ctx, prompt, schema, opts...)
+	return response, err
 }
 
 // GenerateWithUsage produces text and returns response details while maintaining memory.
-// The prompt and response are added to the conversation memory.
+// The prompt and response are added to the conversation memory, the response together with its
+// ID, tool calls and thinking blocks. With a provider in stateful mode (the "stateful" option
+// of openai-responses) the stored ID lets the next call send only the new messages.
 func (l *LLMWithMemory) GenerateWithUsage(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, *types.ResponseDetails, error) {
-	l.memory.Add("user", prompt.Input)
-	fullPrompt := l.memory.GetPrompt()
+	l.addUserMessage(prompt)
 
-	memoryPrompt := &Prompt{
-		Input: fullPrompt,
-	}
-
-	response, details, err := l.LLM.GenerateWithUsage(ctx, memoryPrompt, opts...)
+	response, details, err := l.generateWithDetails(prompt, func(p *Prompt) (string, *types.ResponseDetails, error) {
+		return l.LLM.GenerateWithUsage(ctx, p, opts...)
+	})
 	if err != nil {
 		return "", nil, err
 	}
 
-	l.memory.Add("assistant", response)
+	l.AddAssistantResponse(response, details)
 	return response, details, nil
 }
 
 // GenerateWithSchemaAndUsage generates text conforming to a schema and returns response details while maintaining memory.
 // The prompt and response are added to the conversation memory.
 func (l *LLMWithMemory) GenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, *types.ResponseDetails, error) {
-	l.memory.Add("user", prompt.Input)
-	fullPrompt := l.memory.GetPrompt()
-
-	memoryPrompt := &Prompt{
-		Input: fullPrompt,
-	}
+	l.addUserMessage(prompt)
 
-	response, details, err := l.LLM.GenerateWithSchemaAndUsage(ctx, memoryPrompt, schema, opts...)
+	response, details, err := l.generateWithDetails(prompt, func(p *Prompt) (string, *types.ResponseDetails, error) {
+		return l.LLM.GenerateWithSchemaAndUsage(ctx, p, schema, opts...)
+	})
 	if err != nil {
 		return "", nil, err
 	}
 
-	l.memory.Add("assistant", response)
+	l.AddAssistantResponse(response, details)
 	return response, details, nil
 }
 
+// addUserMessage adds the prompt's input to memory as the new user message. Its images,
+// documents and audio go into the message's MultiContent, ahead of the text as in a prompt sent
+// without memory, so they are sent with this turn and the turns after it.
+func (l *LLMWithMemory) addUserMessage(prompt *Prompt) {
+	attachments := make([]types.ContentPart, 0, len(prompt.Images)+len(prompt.Documents)+len(prompt.Audio)+1)
+	attachments = append(attachments, prompt.Images...)
+	attachments = append(attachments, prompt.Documents...)
+	attachments = append(attachments, prompt.Audio...)
+	if len(attachments) == 0 {
+		l.memory.Add("user", prompt.Input)
+		return
+	}
+	l.memory.AddStructured(types.MemoryMessage{
+		Role:         "user",
+		Content:      prompt.Input,
+		MultiContent: append(copyContentParts(attachments), types.NewTextContent(prompt.Input)),
+	})
+}
+
+// generateWithDetails runs generate against the conversation in memory, which must already hold
+// the new user message. It follows useStructuredMessages like Generate does. When a stateful
+// provider reports that the response it was chaining from has expired, the stored response IDs
+// are dropped and the request is sent once more with the full history.
+//
+// A flattened history is text only, so there the prompt's own attachments are sent with it and
+// those of earlier turns are not.
+func (l *LLMWithMemory) generateWithDetails(prompt *Prompt, generate func(*Prompt) (string, *types.ResponseDetails, error)) (string, *types.ResponseDetails, error) {
+	if !l.useStructuredMessages {
+		flat := memoryPrompt(prompt, l.memory.GetPrompt())
+		flat.Images, flat.Documents, flat.Audio = prompt.Images, prompt.Documents, prompt.Audio
+		return generate(flat)
+	}
+
+	send := func() (string, *types.ResponseDetails, error) {
+		l.LLM.SetOption("structured_messages", l.memory.GetMessages())
+		defer l.LLM.SetOption("structured_messages", nil)
+		return generate(memoryPrompt(prompt, ""))
+	}
+	response, details, err := send()
+	if err != nil && providers.IsPreviousResponseNotFound(err) && l.memory.forgetResponseIDs() {
+		l.GetLogger().Warn("Stored response expired, resending the full conversation history")
+		response, details, err = send()
+	}
+	return response, details, err
+}
+
+// memoryPrompt copies prompt with its input replaced, since the conversation itself comes
+// from memory. Attachments are left out: they are part of the user message in memory.
+func memoryPrompt(prompt *Prompt, input string) *Prompt {
+	return &Prompt{
+		Input:           input,
+		Output:          prompt.Output,
+		Directives:      prompt.Directives,
+		Context:         prompt.Context,
+		MaxLength:       prompt.MaxLength,
+		Examples:        prompt.Examples,
+		SystemPrompt:    prompt.SystemPrompt,
+		SystemCacheType: prompt.SystemCacheType,
+		Tools:           prompt.Tools,
+		ToolChoice:      prompt.ToolChoice,
+		optionErr:       prompt.optionErr,
+	}
+}
+
 // AddToMemory adds a message to memory with the default role format.
 // This is a convenience method that wraps memory.Add.
 func (l *LLMWithMemory) AddToMemory(role, content string) {
@@ -636,3 +644,22 @@ func (l *LLMWithMemory) AddAssistantMessageWithToolCalls(content string, toolCal
 	}
 	l.memory.AddStructured(message)
 }
+
+// AddAssistantResponse adds an assistant reply to memory together with the tool calls,
+// extended-thinking blocks and response ID reported in its details, so the next request can
+// replay the turn exactly, or chain from it on a stateful provider. Anthropic rejects a tool-use
+// continuation whose thinking blocks were dropped, which plain AddToMemory would do. A nil
+// details stores the text alone.
+func (l *LLMWithMemory) AddAssistantResponse(content string, details *types.ResponseDetails) {
+	if details == nil || (len(details.ToolCalls) == 0 && len(details.ThinkingBlocks) == 0 && details.ID == "") {
+		l.memory.Add("assistant", content)
+		return
+	}
+	l.memory.AddStructured(types.MemoryMessage{
+		Role:           "assistant",
+		Content:        content,
+		ToolCalls:      details.ToolCalls,
+		ThinkingBlocks: details.ThinkingBlocks,
+		ResponseID:     details.ID,
+	})
+}
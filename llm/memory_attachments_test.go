@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// newMemoryTestClient returns a memory client for OpenAI chat completions served by a test
+// server, and the bodies of the requests the server received.
+func newMemoryTestClient(t *testing.T) (*LLMWithMemory, func() []string) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o-mini"),
+		config.SetAPIKey("sk-test-key-0123456789abcdef"),
+		config.SetBaseURL(server.URL),
+		config.SetMaxRetries(0),
+	)
+	base, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	require.NoError(t, err)
+	client, err := NewLLMWithMemory(base, 10000, "gpt-4o-mini")
+	require.NoError(t, err)
+	return client.(*LLMWithMemory), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+func TestLLMWithMemorySendsAttachments(t *testing.T) {
+	ctx := context.Background()
+	client, bodies := newMemoryTestClient(t)
+
+	_, err := client.Generate(ctx, NewPrompt("Summarize the contract",
+		WithDocumentBase64("JVBERi0xLjQgY29udHJhY3Q=", "application/pdf", "contract.pdf"),
+		WithImageBase64("aW1hZ2U=", "image/png"),
+	))
+	require.NoError(t, err)
+	_, err = client.Generate(ctx, NewPrompt("Who signed it?"))
+	require.NoError(t, err)
+
+	require.Len(t, bodies(), 2)
+	for i, body := range bodies() {
+		assert.Contains(t, body, "JVBERi0xLjQgY29udHJhY3Q=", "request %d carries the document", i+1)
+		assert.Contains(t, body, "contract.pdf", "request %d carries the document", i+1)
+		assert.Contains(t, body, "aW1hZ2U=", "request %d carries the image", i+1)
+	}
+	assert.Contains(t, bodies()[1], "Who signed it?")
+
+	messages := client.GetMemory()
+	require.Len(t, messages, 4)
+	assert.Equal(t, "Summarize the contract", messages[0].GetTextContent())
+	require.Len(t, messages[0].MultiContent, 3)
+}
+
+func TestLLMWithMemoryFlattenedAttachments(t *testing.T) {
+	client, bodies := newMemoryTestClient(t)
+	client.SetUseStructuredMessages(false)
+
+	_, err := client.Generate(context.Background(), NewPrompt("Summarize the contract",
+		WithDocumentBase64("JVBERi0xLjQgY29udHJhY3Q=", "application/pdf", "contract.pdf"),
+	))
+	require.NoError(t, err)
+	require.Len(t, bodies(), 1)
+	assert.Contains(t, bodies()[0], "JVBERi0xLjQgY29udHJhY3Q=")
+}
+
+func TestLLMWithMemoryRejectsUnsupportedAttachments(t *testing.T) {
+	client, bodies := newMemoryTestClient(t)
+
+	// OpenAI chat completions takes no document URLs.
+	_, err := client.Generate(context.Background(), NewPrompt("Summarize", WithDocumentURL("https://example.com/contract.pdf")))
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr), "got %v", err)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+	assert.Empty(t, bodies())
+}
+
+func TestMemoryGetMessagesCopiesMultiContent(t *testing.T) {
+	memory, err := NewMemory(1000, "gpt-4o-mini", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	memory.AddStructured(types.MemoryMessage{
+		Role:         "user",
+		MultiContent: []types.ContentPart{types.NewDocumentBase64Content("JVBERi0=", "application/pdf", "a.pdf")},
+	})
+
+	copied := memory.GetMessages()
+	copied[0].MultiContent[0].Document.Filename = "changed.pdf"
+	copied[0].MultiContent[0].Type = types.ContentTypeText
+
+	part := memory.GetMessages()[0].MultiContent[0]
+	assert.Equal(t, "a.pdf", part.Document.Filename)
+	assert.True(t, part.IsDocument())
+}
+
+func TestMemoryCountsAttachments(t *testing.T) {
+	memory, err := NewMemory(2500, "gpt-4o-mini", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	addImage := func(text string) {
+		memory.AddStructured(types.MemoryMessage{
+			Role:         "user",
+			Content:      text,
+			MultiContent: []types.ContentPart{types.NewImageURLContent("https://example.com/cat.png", ""), types.NewTextContent(text)},
+		})
+	}
+
+	addImage("first")
+	messages := memory.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, attachmentTokens+len(memory.encoding.Encode("first", nil, nil)), messages[0].Tokens, "the image is estimated and the text counted once")
+
+	memory.AddStructured(types.MemoryMessage{
+		Role:         "user",
+		MultiContent: []types.ContentPart{types.NewDocumentTextContent("hello world", "notes.txt")},
+	})
+	assert.Equal(t, len(memory.encoding.Encode("hello world", nil, nil)), memory.GetMessages()[1].Tokens, "a text document is counted by its text")
+
+	addImage("second")
+	addImage("third")
+	var contents []string
+	for _, message := range memory.GetMessages() {
+		contents = append(contents, message.Content)
+	}
+	assert.Equal(t, []string{"", "second", "third"}, contents, "attachments push older turns out")
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+const previousResponseNotFound = `{"error":{"message":"Previous response with id 'resp_1' not found.","type":"invalid_request_error","param":"previous_response_id","code":"previous_response_not_found"}}`
+
+// statefulStubLLM answers GenerateWithUsage with numbered response IDs and records the
+// structured messages each call was given. With expired set, any request chaining from a
+// stored response fails the way the Responses API does once that response is gone.
+type statefulStubLLM struct {
+	*MockLLM
+	current []types.MemoryMessage
+	sent    [][]types.MemoryMessage
+	expired bool
+}
+
+func (s *statefulStubLLM) SetOption(key string, value interface{}) {
+	if key == "structured_messages" {
+		s.current, _ = value.([]types.MemoryMessage)
+	}
+}
+
+func (s *statefulStubLLM) GenerateWithUsage(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, *types.ResponseDetails, error) {
+	s.sent = append(s.sent, s.current)
+	for _, msg := range s.current {
+		if s.expired && msg.ResponseID != "" {
+			return "", nil, NewHTTPStatusError(http.StatusNotFound, []byte(previousResponseNotFound))
+		}
+	}
+	n := len(s.sent)
+	return fmt.Sprintf("reply %d", n), &types.ResponseDetails{ID: fmt.Sprintf("resp_%d", n)}, nil
+}
+
+func TestLLMWithMemoryStatefulResponses(t *testing.T) {
+	ctx := context.Background()
+	stub := &statefulStubLLM{MockLLM: NewMockLLM(NewMockProvider(), utils.NewLogger(utils.LogLevelOff))}
+	llmWithMem, err := NewLLMWithMemory(stub, 1000, "gpt-4")
+	require.NoError(t, err)
+	mem := llmWithMem.(*LLMWithMemory)
+
+	_, _, err = mem.GenerateWithUsage(ctx, NewPrompt("Hello"))
+	require.NoError(t, err)
+
+	messages := mem.GetMemory()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "resp_1", messages[1].ResponseID, "the response ID is kept for chaining")
+
+	// The stored response has expired: the retry must go out without any ID to chain from.
+	stub.expired = true
+	response, details, err := mem.GenerateWithUsage(ctx, NewPrompt("Again"))
+	require.NoError(t, err)
+	assert.Equal(t, "reply 3", response)
+	assert.Equal(t, "resp_3", details.ID)
+
+	require.Len(t, stub.sent, 3)
+	retried := stub.sent[2]
+	require.Len(t, retried, 3, "the fallback resends the full history")
+	for _, msg := range retried {
+		assert.Empty(t, msg.ResponseID)
+	}
+
+	messages = mem.GetMemory()
+	require.Len(t, messages, 4)
+	assert.Equal(t, "user", messages[2].Role)
+	assert.Equal(t, "resp_3", messages[3].ResponseID)
+}
+
+func TestLLMWithMemoryGenerateChainsResponses(t *testing.T) {
+	ctx := context.Background()
+	stub := &statefulStubLLM{MockLLM: NewMockLLM(NewMockProvider(), utils.NewLogger(utils.LogLevelOff))}
+	llmWithMem, err := NewLLMWithMemory(stub, 1000, "gpt-4")
+	require.NoError(t, err)
+
+	_, err = llmWithMem.Generate(ctx, NewPrompt("Hello"))
+	require.NoError(t, err)
+	messages := llmWithMem.(*LLMWithMemory).GetMemory()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "resp_1", messages[1].ResponseID, "plain Generate keeps the response ID too")
+
+	stub.expired = true
+	response, err := llmWithMem.Generate(ctx, NewPrompt("Again"))
+	require.NoError(t, err)
+	assert.Equal(t, "reply 3", response, "plain Generate falls back to the full history")
+}
+
+// TestPreviousResponseNotFoundIsNotRetried checks that an expired response fails on the first
+// attempt, so the memory fallback runs without waiting out the retries.
+func TestPreviousResponseNotFoundIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(previousResponseNotFound))
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai-responses"),
+		config.SetModel("gpt-4o-mini"),
+		config.SetAPIKey("sk-test-key-0123456789abcdef"),
+		config.SetBaseURL(server.URL),
+		config.SetMaxRetries(3),
+		config.SetRetryDelay(time.Minute),
+	)
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	require.NoError(t, err)
+
+	_, err = client.Generate(context.Background(), NewPrompt("hi"))
+	require.Error(t, err)
+	assert.True(t, providers.IsPreviousResponseNotFound(err))
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, ErrorTypeAPI, llmErr.Type)
+	assert.Equal(t, int32(1), requests.Load())
+
+	_, _, err = client.GenerateWithUsage(context.Background(), NewPrompt("hi"))
+	assert.True(t, providers.IsPreviousResponseNotFound(err))
+	assert.Equal(t, int32(2), requests.Load())
+}
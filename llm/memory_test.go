@@ -123,10 +123,12 @@ func (l *MockLLM) NewPrompt(input string) *Prompt   { return &Prompt{Input: inpu
 func (l *MockLLM) GetLogger() utils.Logger          { return l.logger }
 func (l *MockLLM) SupportsJSONSchema() bool { return false }
 func (l *MockLLM) GenerateWithUsage(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, *types.ResponseDetails, error) {
-	return "mock response", nil, nil
+	response, err := l.Generate(ctx, prompt, opts...)
+	return response, nil, err
 }
 func (l *MockLLM) GenerateWithSchemaAndUsage(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, *types.ResponseDetails, error) {
-	return "mock response", nil, nil
+	response, err := l.GenerateWithSchema(ctx, prompt, schema, opts...)
+	return response, nil, err
 }
 
 // TestStructuredMessageStorage tests that structured messages are properly stored
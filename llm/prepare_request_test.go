@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// TestPrepareRequestBody checks that the exported builder routes a prompt the same way the
+// generation paths do: system prompt and schema for a plain prompt, messages for a conversation.
+func TestPrepareRequestBody(t *testing.T) {
+	provider := providers.NewOpenAIProvider("key", "gpt-4o-mini", nil)
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}}}
+
+	body, err := PrepareRequestBody(provider, NewPrompt("hello", WithSystemPrompt("be terse", CacheTypeEphemeral)), map[string]interface{}{"temperature": 0.2}, schema)
+	require.NoError(t, err)
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, 0.2, req["temperature"])
+	assert.Contains(t, string(body), "be terse")
+	assert.Contains(t, req, "response_format")
+
+	conversation := NewPrompt("", WithMessages([]PromptMessage{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+		{Role: "user", Content: "again"},
+	}))
+	body, err = PrepareRequestBody(provider, conversation, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Len(t, req["messages"], 3)
+}
+
+// TestPrepareRequestBodyStructuredMessages checks that structured messages among the options are
+// sent as the conversation, with or without a schema, as memory's are when generating.
+func TestPrepareRequestBodyStructuredMessages(t *testing.T) {
+	provider := providers.NewOpenAIProvider("key", "gpt-4o-mini", nil)
+	options := map[string]interface{}{"structured_messages": []types.MemoryMessage{
+		{Role: "user", Content: "my name is Ada"},
+		{Role: "assistant", Content: "hello Ada"},
+		{Role: "user", Content: "what is my name?"},
+	}}
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}}
+
+	for _, s := range []interface{}{nil, schema} {
+		body, err := PrepareRequestBody(provider, NewPrompt(""), options, s)
+		require.NoError(t, err)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &req))
+		assert.Len(t, req["messages"], 3)
+		assert.Contains(t, string(body), "my name is Ada")
+		assert.Equal(t, s != nil, req["response_format"] != nil)
+	}
+}
+
+// TestLLMPrepareRequestBody checks that a client builds its request bodies with its own options.
+func TestLLMPrepareRequestBody(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o-mini"),
+		config.SetAPIKey("sk-test-key-0123456789abcdef"),
+	)
+	client, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	require.NoError(t, err)
+	impl := client.(*LLMImpl)
+	impl.SetOption("seed", 42)
+
+	body, err := impl.PrepareRequestBody(NewPrompt("hello"), nil)
+	require.NoError(t, err)
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, float64(42), req["seed"])
+}
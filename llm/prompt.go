@@ -1,7 +1,11 @@
 package llm
 
 import (
+	"encoding/base64"
 	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/invopop/jsonschema"
@@ -29,6 +33,9 @@ type PromptMessage struct {
 	Name         string              `json:"name,omitempty"`          // Optional name identifier for the message
 	ToolCalls    []ToolCall          `json:"tool_calls,omitempty"`    // Optional tool calls requested by the LLM
 	ToolCallID   string              `json:"tool_call_id,omitempty"`  // ID of the tool call this message responds to
+	// ThinkingBlocks replays an assistant turn's extended thinking (from
+	// ResponseDetails.ThinkingBlocks); required by Anthropic alongside its tool calls.
+	ThinkingBlocks []types.ThinkingBlock `json:"thinking_blocks,omitempty"`
 }
 
 // ToolCall represents a request from the LLM to use a specific tool.
@@ -53,6 +60,12 @@ type Prompt struct {
 	Tools           []utils.Tool           `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
 	ToolChoice      map[string]interface{} `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
 	Images          []types.ContentPart    `json:"images,omitempty" jsonschema:"description=Images to include with the prompt"`
+	Documents       []types.ContentPart    `json:"documents,omitempty" jsonschema:"description=Documents and files to include with the prompt"`
+	Audio           []types.ContentPart    `json:"audio,omitempty" jsonschema:"description=Audio clips to include with the prompt"`
+
+	// optionErr records an attachment option that failed (e.g. an unreadable file), so the
+	// failure surfaces from Validate and from generation instead of a silently missing attachment.
+	optionErr error
 }
 
 // PromptOption is a function type that modifies a Prompt.
@@ -199,6 +212,19 @@ func WithJSONSchemaValidation() GenerateOption {
 	}
 }
 
+// WithReasoning decides whether the model's reasoning is requested and returned in
+// ResponseDetails.Reasoning. With include true, providers that only return reasoning when asked
+// are asked: Anthropic enables extended thinking (at medium effort unless reasoning_effort is
+// set), Gemini sends include_thoughts, OpenRouter and Groq include_reasoning, Ollama think, and
+// the Responses API a reasoning summary. With include false, providers that can are told to
+// withhold it and any reasoning still returned is dropped. Without this option each provider
+// returns whatever reasoning its models emit by default.
+func WithReasoning(include bool) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Reasoning = &include
+	}
+}
+
 // WithExamples adds example conversations or outputs to guide the LLM.
 // If a single example ends with .txt or .jsonl, it's treated as a file path.
 //
@@ -270,6 +296,207 @@ func (p *Prompt) HasImages() bool {
 	return len(p.Images) > 0
 }
 
+// WithDocumentBase64 adds a base64-encoded document, such as a PDF, to the prompt.
+// Supported by Anthropic, OpenAI (chat and Responses), and Gemini.
+//
+// Parameters:
+//   - base64Data: Base64-encoded document data (without the data URI prefix)
+//   - mediaType: MIME type of the document, typically "application/pdf"
+//   - filename: Optional filename; OpenAI requires one and a default is used when empty
+//
+// Example:
+//
+//	pdf := base64.StdEncoding.EncodeToString(pdfBytes)
+//	prompt := NewPrompt("Extract the invoice total",
+//	    WithDocumentBase64(pdf, "application/pdf", "invoice.pdf"),
+//	)
+func WithDocumentBase64(base64Data, mediaType, filename string) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, types.NewDocumentBase64Content(base64Data, mediaType, filename))
+	}
+}
+
+// WithDocumentURL adds a document referenced by URL to the prompt.
+// Supported by Anthropic and the OpenAI Responses API.
+//
+// Parameters:
+//   - url: Publicly reachable URL of the document
+func WithDocumentURL(url string) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, types.NewDocumentURLContent(url))
+	}
+}
+
+// WithDocumentText adds a plain-text document to the prompt. Anthropic receives it as a
+// text-sourced document block; other providers receive it as a text part.
+//
+// Parameters:
+//   - text: Document content
+//   - title: Optional document title
+func WithDocumentText(text, title string) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, types.NewDocumentTextContent(text, title))
+	}
+}
+
+// WithDocumentFile reads a document from disk and adds it to the prompt. The media type is
+// inferred from the file extension: text files are added as plain-text documents titled with
+// the file name, everything else as base64 data. If the file cannot be read, the error is
+// returned by Validate and by any generation call made with the prompt.
+//
+// Parameters:
+//   - path: Path to the document
+//
+// Example:
+//
+//	prompt := NewPrompt("Summarize the termination clauses",
+//	    WithDocumentFile("contracts/msa.pdf"),
+//	)
+func WithDocumentFile(path string) PromptOption {
+	return func(p *Prompt) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if p.optionErr == nil {
+				p.optionErr = fmt.Errorf("failed to read document %s: %w", path, err)
+			}
+			return
+		}
+		name := filepath.Base(path)
+		mediaType := documentMediaType(name)
+		if strings.HasPrefix(mediaType, "text/") {
+			p.Documents = append(p.Documents, types.NewDocumentTextContent(string(data), name))
+			return
+		}
+		p.Documents = append(p.Documents, types.NewDocumentBase64Content(base64.StdEncoding.EncodeToString(data), mediaType, name))
+	}
+}
+
+// WithFileID adds a file previously uploaded to the provider's Files API.
+// Supported by Anthropic (with the Files API beta header) and OpenAI.
+//
+// Parameters:
+//   - fileID: Provider-side file identifier
+//   - filename: Optional filename
+func WithFileID(fileID, filename string) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, types.NewFileContent(fileID, filename))
+	}
+}
+
+// WithDocuments adds multiple document or file content parts to the prompt.
+//
+// Parameters:
+//   - documents: Slice of ContentPart representing documents
+func WithDocuments(documents []types.ContentPart) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, documents...)
+	}
+}
+
+// WithDocumentCitations enables citations on the documents added so far.
+// Citations are an Anthropic feature; other providers ignore the flag.
+func WithDocumentCitations() PromptOption {
+	return func(p *Prompt) {
+		for i := range p.Documents {
+			if p.Documents[i].Document != nil {
+				doc := *p.Documents[i].Document
+				doc.Citations = true
+				p.Documents[i].Document = &doc
+			}
+		}
+	}
+}
+
+// WithInputAudio adds a base64-encoded audio clip to the prompt for audio-capable models
+// (e.g. gpt-4o-audio-preview, Gemini).
+//
+// Parameters:
+//   - base64Data: Base64-encoded audio data
+//   - format: Audio format, "wav" or "mp3"
+func WithInputAudio(base64Data, format string) PromptOption {
+	return func(p *Prompt) {
+		p.Audio = append(p.Audio, types.NewInputAudioContent(base64Data, format))
+	}
+}
+
+// WithAudioFile reads an audio clip from disk and adds it to the prompt, taking the format
+// from the file extension. If the file cannot be read, the error is returned by Validate
+// and by any generation call made with the prompt.
+//
+// Parameters:
+//   - path: Path to a .wav or .mp3 file
+func WithAudioFile(path string) PromptOption {
+	return func(p *Prompt) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if p.optionErr == nil {
+				p.optionErr = fmt.Errorf("failed to read audio %s: %w", path, err)
+			}
+			return
+		}
+		format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		p.Audio = append(p.Audio, types.NewInputAudioContent(base64.StdEncoding.EncodeToString(data), format))
+	}
+}
+
+// HasAudio returns true if the prompt contains any audio, either directly or inside its messages.
+func (p *Prompt) HasAudio() bool {
+	for _, part := range p.Audio {
+		if part.IsAudio() {
+			return true
+		}
+	}
+	for _, msg := range p.Messages {
+		for _, part := range msg.MultiContent {
+			if part.IsAudio() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasDocuments returns true if the prompt contains any documents or files,
+// either directly or inside its messages.
+func (p *Prompt) HasDocuments() bool {
+	return len(p.documentParts()) > 0
+}
+
+// documentParts returns every document part the prompt would send.
+func (p *Prompt) documentParts() []types.ContentPart {
+	var docs []types.ContentPart
+	for _, part := range p.Documents {
+		if part.IsDocument() {
+			docs = append(docs, part)
+		}
+	}
+	for _, msg := range p.Messages {
+		for _, part := range msg.MultiContent {
+			if part.IsDocument() {
+				docs = append(docs, part)
+			}
+		}
+	}
+	return docs
+}
+
+// documentMediaType infers a document's MIME type from its file name.
+func documentMediaType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".md", ".markdown":
+		return "text/markdown"
+	case ".txt", ".text", ".log":
+		return "text/plain"
+	}
+	if mediaType := mime.TypeByExtension(ext); mediaType != "" {
+		if base, _, err := mime.ParseMediaType(mediaType); err == nil {
+			return base
+		}
+	}
+	return "application/octet-stream"
+}
+
 // Apply applies the given options to modify the prompt's configuration.
 //
 // Parameters:
@@ -362,6 +589,43 @@ func (p *Prompt) hasStructuredMessages() bool {
 	return msg.Role != "user" || msg.ToolCallID != "" || len(msg.ToolCalls) > 0
 }
 
+// requestMessages returns the messages to send when the prompt must take the structured
+// messages path, and whether it must. Besides multi-turn prompts, that includes prompts with
+// documents or audio: no provider's flat PrepareRequest knows about them, so the prompt is sent
+// as a single user message whose content is the images, the attachments, and the rendered
+// prompt text. Attachments on a multi-turn prompt go on its last user message.
+func (p *Prompt) requestMessages() ([]types.MemoryMessage, bool) {
+	attachments := append(append([]types.ContentPart{}, p.Documents...), p.Audio...)
+	if p.hasStructuredMessages() {
+		messages := promptMessagesToMemoryMessages(p.Messages)
+		if len(attachments) > 0 {
+			for i := len(messages) - 1; i >= 0; i-- {
+				if messages[i].Role != "user" {
+					continue
+				}
+				parts := attachments
+				if messages[i].HasMultiContent() {
+					parts = append(parts, messages[i].MultiContent...)
+				} else {
+					parts = append(parts, types.NewTextContent(messages[i].Content))
+				}
+				messages[i].MultiContent = parts
+				break
+			}
+		}
+		return messages, true
+	}
+	if len(attachments) == 0 {
+		return nil, false
+	}
+
+	parts := make([]types.ContentPart, 0, len(p.Images)+len(attachments)+1)
+	parts = append(parts, p.Images...)
+	parts = append(parts, attachments...)
+	parts = append(parts, types.NewTextContent(p.String()))
+	return []types.MemoryMessage{{Role: "user", MultiContent: parts}}, true
+}
+
 // toolChoiceValue extracts the strategy string from a ToolChoice map
 // (WithToolChoice stores {"type": <strategy>}). Providers read tool_choice as a
 // string — OpenAI uses it directly, Anthropic re-wraps it — so passing the raw
@@ -388,12 +652,13 @@ func promptMessagesToMemoryMessages(msgs []PromptMessage) []types.MemoryMessage
 			toolCalls = append(toolCalls, types.NewToolCall(tc.ID, tc.Function.Name, tc.Function.Arguments))
 		}
 		out[i] = types.MemoryMessage{
-			Role:         m.Role,
-			Content:      m.Content,
-			MultiContent: m.MultiContent,
-			CacheControl: string(m.CacheType),
-			ToolCalls:    toolCalls,
-			ToolCallID:   m.ToolCallID,
+			Role:           m.Role,
+			Content:        m.Content,
+			MultiContent:   m.MultiContent,
+			CacheControl:   string(m.CacheType),
+			ToolCalls:      toolCalls,
+			ToolCallID:     m.ToolCallID,
+			ThinkingBlocks: m.ThinkingBlocks,
 		}
 	}
 	return out
@@ -405,6 +670,9 @@ func promptMessagesToMemoryMessages(msgs []PromptMessage) []types.MemoryMessage
 // Returns:
 //   - Error if validation fails, nil otherwise
 func (p *Prompt) Validate() error {
+	if p.optionErr != nil {
+		return p.optionErr
+	}
 	return Validate(p)
 }
 
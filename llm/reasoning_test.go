@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/teilomillet/gollm/types"
+)
+
+// reasoningStubProvider records how WithReasoning was translated into request options.
+type reasoningStubProvider struct {
+	stubUsageProvider
+	requested []bool
+}
+
+func (p *reasoningStubProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	p.requested = append(p.requested, include)
+	options["stub_reasoning"] = include
+}
+
+func TestWithReasoning(t *testing.T) {
+	srv := okServer(t, usageBody)
+	newDetails := func() *types.ResponseDetails {
+		return &types.ResponseDetails{Model: "m", Reasoning: &types.Reasoning{Text: "because"}}
+	}
+
+	t.Run("default leaves reasoning to the provider", func(t *testing.T) {
+		p := &reasoningStubProvider{stubUsageProvider: stubUsageProvider{endpoint: srv.URL, result: "ok", details: newDetails()}}
+		_, details, err := newUsageStubLLM(&p.stubUsageProvider).GenerateWithUsage(context.Background(), NewPrompt("hi"))
+		if err != nil {
+			t.Fatalf("GenerateWithUsage: %v", err)
+		}
+		if details.Reasoning == nil || details.Reasoning.Text != "because" {
+			t.Fatalf("Reasoning = %+v, want provider reasoning", details.Reasoning)
+		}
+	})
+
+	t.Run("true asks the provider", func(t *testing.T) {
+		p := &reasoningStubProvider{stubUsageProvider: stubUsageProvider{endpoint: srv.URL, result: "ok", details: newDetails()}}
+		l := newUsageStubLLM(&p.stubUsageProvider)
+		l.Provider = p
+		_, details, err := l.GenerateWithUsage(context.Background(), NewPrompt("hi"), WithReasoning(true))
+		if err != nil {
+			t.Fatalf("GenerateWithUsage: %v", err)
+		}
+		if len(p.requested) != 1 || !p.requested[0] {
+			t.Fatalf("requested = %v, want [true]", p.requested)
+		}
+		if details.Reasoning == nil {
+			t.Fatal("Reasoning dropped despite WithReasoning(true)")
+		}
+	})
+
+	t.Run("false drops returned reasoning", func(t *testing.T) {
+		p := &reasoningStubProvider{stubUsageProvider: stubUsageProvider{endpoint: srv.URL, result: "ok", details: newDetails()}}
+		l := newUsageStubLLM(&p.stubUsageProvider)
+		l.Provider = p
+		_, details, err := l.GenerateWithUsage(context.Background(), NewPrompt("hi"), WithReasoning(false))
+		if err != nil {
+			t.Fatalf("GenerateWithUsage: %v", err)
+		}
+		if len(p.requested) != 1 || p.requested[0] {
+			t.Fatalf("requested = %v, want [false]", p.requested)
+		}
+		if details.Reasoning != nil {
+			t.Fatalf("Reasoning = %+v, want nil", details.Reasoning)
+		}
+	})
+}
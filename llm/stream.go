@@ -16,7 +16,7 @@ type StreamToken struct {
 	Text string
 
 	// Type is the normalized chunk kind on the rich path ("text", "usage",
-	// "finish", "tool_call_delta"). On the text-only fallback path it mirrors the
+	// "finish", "tool_call_delta", "thinking", "reasoning", "tool_output"). On the text-only fallback path it mirrors the
 	// SSE event name, which is usually empty (treat as "text").
 	Type string
 
@@ -29,6 +29,21 @@ type StreamToken struct {
 	// ToolCallDelta carries an incremental tool-call fragment when
 	// Type == "tool_call_delta"; nil otherwise.
 	ToolCallDelta *types.ToolCallDelta
+
+	// Reasoning carries incremental reasoning text when Type == "reasoning", and
+	// may accompany a "text" token on providers that send both in one chunk, or
+	// a "thinking" token on Anthropic.
+	// It is never part of Text.
+	Reasoning string
+
+	// Thinking carries the Anthropic thinking-block fragment (signature,
+	// redacted data, or text alongside Reasoning) needed to replay the block;
+	// nil otherwise.
+	Thinking *types.ThinkingDelta
+
+	// ToolOutputs carries a completed built-in tool item when
+	// Type == "tool_output"; nil otherwise.
+	ToolOutputs *types.ToolOutputs
 }
 
 // TokenStream represents a stream of tokens from the LLM.
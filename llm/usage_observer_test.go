@@ -100,7 +100,7 @@ func TestUsageObserverFiresPerOutcome(t *testing.T) {
 			var events []UsageEvent
 			l.SetUsageObserver(func(_ context.Context, e UsageEvent) { events = append(events, e) })
 
-			_, _, _, _ = l.attemptGenerateWithSchemaAndUsage(context.Background(), NewPrompt("hi"), schema, 0)
+			_, _, _, _ = l.attemptGenerateWithSchemaAndUsage(context.Background(), NewPrompt("hi"), schema, nil, 0)
 
 			if len(events) != 1 {
 				t.Fatalf("expected exactly one usage event, got %d", len(events))
@@ -89,7 +89,7 @@ func TestParseFailureRecoversUsageFromBody(t *testing.T) {
 	})
 	events := collectUsage(l)
 
-	if _, _, err := l.attemptGenerateWithUsage(context.Background(), NewPrompt("hi"), 0); err == nil {
+	if _, _, err := l.attemptGenerateWithUsage(context.Background(), NewPrompt("hi"), nil, 0); err == nil {
 		t.Fatal("expected a parse error")
 	}
 
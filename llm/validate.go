@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/teilomillet/gollm/config"
 )
 
 // validate is the shared validator instance used across the package.
@@ -50,14 +51,26 @@ func validateAPIKey(fl validator.FieldLevel) bool {
 	parent := fl.Parent()
 	provider := parent.FieldByName("Provider").String()
 
+	// A provider authenticated by a credential source has no static key to check.
+	if sources, ok := parent.FieldByName("CredentialSources").Interface().(map[string]config.CredentialSource); ok && sources[provider] != nil {
+		return true
+	}
+
 	// For local LLM servers, we don't require an API key.
 	// Just check if the endpoint is accessible (or return true for vLLM).
 	switch provider {
 	case "vllm":
 		// vLLM uses OpenAI-compatible API without authentication
 		return true
+	case "vertex":
+		// Vertex AI takes a short-lived access token, or signs in with a service account key.
+		return apiKeys[provider] != "" || parent.FieldByName("GoogleCredentialsFile").String() != ""
 	case "ollama":
 		endpoint := parent.FieldByName("OllamaEndpoint").String()
+		// Requests go to the base URL when one is set, so that is the server to check.
+		if baseURL := parent.FieldByName("BaseURL").String(); baseURL != "" {
+			endpoint = strings.TrimSuffix(baseURL, "/")
+		}
 		if endpoint == "" {
 			endpoint = "http://localhost:11434"
 		}
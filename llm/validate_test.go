@@ -2,8 +2,11 @@ package llm
 
 import (
 	"errors"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/teilomillet/gollm/config"
 )
 
 // testStruct is a simple struct for validation testing
@@ -170,3 +173,27 @@ func TestDefaultValidate(t *testing.T) {
 		t.Error("expected invalid struct to fail")
 	}
 }
+
+func TestValidateVertexProject(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("vertex"),
+		config.SetModel("gemini-2.5-flash"),
+		config.SetAPIKey("ya29.access-token"),
+		config.SetVertex("", "us-central1"),
+	)
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "VertexProject") {
+		t.Fatalf("expected a missing project to fail on VertexProject, got %v", err)
+	}
+
+	config.ApplyOptions(cfg, config.SetVertex("acme-prod", "us-central1"))
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected a config with a project to pass, got %v", err)
+	}
+
+	config.ApplyOptions(cfg, config.SetProvider("openai"), config.SetAPIKey("sk-test-key-0123456789abcdef"), config.SetVertex("", ""))
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected other providers to need no project, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "MCP-Protocol-Version"
+)
+
+// ConnectHTTP connects to an MCP server over the streamable HTTP transport at url (the
+// server's single MCP endpoint, e.g. "https://example.com/mcp") and initializes a session.
+func ConnectHTTP(ctx context.Context, url string, opts ...Option) (*Client, error) {
+	o := applyOptions(opts)
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newClient(ctx, &httpTransport{url: url, client: client, headers: o.Headers}, o)
+}
+
+// httpTransport POSTs each message to the endpoint. The server answers a request either with
+// a JSON body or with an event stream that carries the response (possibly after notifications
+// of its own). The session ID assigned at initialization is sent on every later request.
+type httpTransport struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(headerSessionID, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(headerProtocolVersion, t.protocolVersion)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+// post sends msg and returns the response once its status is checked and the session ID, if
+// the server assigned one, recorded.
+func (t *httpTransport) post(ctx context.Context, msg *message) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: encoding message: %w", err)
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: sending request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp: %s: status %d: %s", t.url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if id := resp.Header.Get(headerSessionID); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, req *message) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return t.readEventStream(ctx, resp.Body, req.ID)
+	}
+	var msg message
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("mcp: decoding response: %w", err)
+	}
+	return &msg, nil
+}
+
+// readEventStream reads server-sent events until the response to id arrives. Requests the
+// server sends on the stream are answered with a separate POST.
+func (t *httpTransport) readEventStream(ctx context.Context, body io.Reader, id json.RawMessage) (*message, error) {
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			var msg message
+			if json.Unmarshal([]byte(data.String()), &msg) == nil {
+				if msg.isResponse() && string(msg.ID) == string(id) {
+					return &msg, nil
+				}
+				if !msg.isResponse() && len(msg.ID) > 0 {
+					_ = t.notify(ctx, replyTo(&msg))
+				}
+			}
+			data.Reset()
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("mcp: event stream ended without a response")
+			}
+			return nil, fmt.Errorf("mcp: reading event stream: %w", err)
+		}
+	}
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg *message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// close ends the session with a DELETE. Servers that do not support it answer 405, which is
+// fine: the session then simply expires.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: closing session: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,445 @@
+// Package mcp is a Model Context Protocol client. It connects to MCP servers over stdio or
+// streamable HTTP, lists their tools as utils.Tool definitions that can be put on a Prompt, and
+// executes the model's tool calls against them.
+//
+// A typical tool loop:
+//
+//	client, err := mcp.ConnectStdio(ctx, "npx", []string{"-y", "@modelcontextprotocol/server-everything"})
+//	if err != nil { ... }
+//	defer client.Close()
+//
+//	tools, err := client.Tools(ctx)
+//	prompt := gollm.NewPrompt("...", gollm.WithTools(tools))
+//	_, details, err := llm.GenerateWithUsage(ctx, prompt)
+//	for _, result := range client.ExecuteAll(ctx, details.ToolCalls) {
+//		llm.AddToolResult(result.ToolCallID, result.Content)
+//	}
+//
+// This is separate from the hosted MCP tool of the Responses API (utils.Tool with Type "mcp"),
+// where OpenAI connects to the server itself.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// ProtocolVersion is the MCP revision the client asks for. Servers answer with the revision
+// they speak, which the client then uses.
+const ProtocolVersion = "2025-06-18"
+
+// Tool is a tool as an MCP server describes it.
+type Tool struct {
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema,omitempty"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// Content is one item of a tool result. Type "text" carries Text; "image" and "audio" carry
+// base64 Data with its MimeType; "resource_link" and "resource" refer to server resources.
+type Content struct {
+	Type     string                 `json:"type"`
+	Text     string                 `json:"text,omitempty"`
+	Data     string                 `json:"data,omitempty"`
+	MimeType string                 `json:"mimeType,omitempty"`
+	URI      string                 `json:"uri,omitempty"`
+	Name     string                 `json:"name,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
+
+// CallToolResult is the outcome of a tools/call request. IsError marks a failure inside the
+// tool, which is reported to the model rather than returned as a Go error.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Text renders the result for the model: text items joined by newlines, other items as their
+// JSON, and the structured content when there is nothing else.
+func (r *CallToolResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+			continue
+		}
+		if encoded, err := json.Marshal(c); err == nil {
+			parts = append(parts, string(encoded))
+		}
+	}
+	if len(parts) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ServerInfo is what the server reported about itself during initialization.
+type ServerInfo struct {
+	Name            string                 `json:"name"`
+	Version         string                 `json:"version"`
+	ProtocolVersion string                 `json:"-"`
+	Capabilities    map[string]interface{} `json:"-"`
+	Instructions    string                 `json:"-"`
+}
+
+// Error is a JSON-RPC error returned by the server.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mcp: server error %d: %s", e.Code, e.Message)
+}
+
+// JSON-RPC error codes the client sends back for server-initiated requests.
+const (
+	codeMethodNotFound = -32601
+)
+
+// message is a JSON-RPC 2.0 message: a request (Method and ID), a notification (Method only)
+// or a response (ID with Result or Error).
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+func (m *message) isResponse() bool { return m.Method == "" && len(m.ID) > 0 }
+
+// transport carries JSON-RPC messages to one server. call sends a request and waits for the
+// response with the same ID; notify sends a message that expects none.
+type transport interface {
+	call(ctx context.Context, req *message) (*message, error)
+	notify(ctx context.Context, msg *message) error
+	setProtocolVersion(version string)
+	close() error
+}
+
+// Options configure a Client.
+type Options struct {
+	// ClientName and ClientVersion identify the client to the server. Default "gollm" and "1".
+	ClientName    string
+	ClientVersion string
+	// ToolPrefix is prepended to every tool name the client reports and stripped again when
+	// executing, keeping tools from different servers apart in one prompt.
+	ToolPrefix string
+	// Headers are sent with every HTTP request (streamable HTTP only).
+	Headers map[string]string
+	// HTTPClient sends the HTTP requests (streamable HTTP only). Defaults to http.DefaultClient;
+	// per-call deadlines come from the context.
+	HTTPClient *http.Client
+	// Env is added to the environment of a stdio server process.
+	Env []string
+	// Stderr receives the stdio server's standard error. Discarded when nil.
+	Stderr io.Writer
+}
+
+// Option configures a Client.
+type Option func(*Options)
+
+// WithClientInfo sets the name and version the client reports to the server.
+func WithClientInfo(name, version string) Option {
+	return func(o *Options) {
+		o.ClientName = name
+		o.ClientVersion = version
+	}
+}
+
+// WithToolPrefix prefixes the names of the server's tools, e.g. "github_".
+func WithToolPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.ToolPrefix = prefix
+	}
+}
+
+// WithHeaders adds headers to every HTTP request, typically Authorization.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *Options) {
+		o.Headers = headers
+	}
+}
+
+// WithHTTPClient sets the client used for streamable HTTP requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithEnv adds KEY=value entries to a stdio server's environment.
+func WithEnv(env ...string) Option {
+	return func(o *Options) {
+		o.Env = append(o.Env, env...)
+	}
+}
+
+// WithStderr forwards a stdio server's standard error to w.
+func WithStderr(w io.Writer) Option {
+	return func(o *Options) {
+		o.Stderr = w
+	}
+}
+
+func applyOptions(opts []Option) Options {
+	o := Options{ClientName: "gollm", ClientVersion: "1"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	transport transport
+	opts      Options
+	nextID    atomic.Int64
+	server    ServerInfo
+
+	mu    sync.Mutex
+	tools map[string]Tool // by server-side name, filled by ListTools
+}
+
+// newClient runs the initialize handshake over t.
+func newClient(ctx context.Context, t transport, opts Options) (*Client, error) {
+	c := &Client{transport: t, opts: opts}
+	if err := c.initialize(ctx); err != nil {
+		_ = t.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	var result struct {
+		ProtocolVersion string                 `json:"protocolVersion"`
+		Capabilities    map[string]interface{} `json:"capabilities"`
+		ServerInfo      ServerInfo             `json:"serverInfo"`
+		Instructions    string                 `json:"instructions"`
+	}
+	err := c.request(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": c.opts.ClientName, "version": c.opts.ClientVersion},
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("mcp: initialize: %w", err)
+	}
+	c.server = result.ServerInfo
+	c.server.ProtocolVersion = result.ProtocolVersion
+	c.server.Capabilities = result.Capabilities
+	c.server.Instructions = result.Instructions
+	c.transport.setProtocolVersion(result.ProtocolVersion)
+
+	if err := c.transport.notify(ctx, &message{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("mcp: initialized notification: %w", err)
+	}
+	return nil
+}
+
+// Server returns what the server reported about itself during initialization.
+func (c *Client) Server() ServerInfo {
+	return c.server
+}
+
+// request sends method with params and decodes the result into out (when non-nil).
+func (c *Client) request(ctx context.Context, method string, params interface{}, out interface{}) error {
+	id := c.nextID.Add(1)
+	req := &message{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(id)), Method: method, Params: params}
+	resp, err := c.transport.call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("mcp: decoding %s result: %w", method, err)
+	}
+	return nil
+}
+
+// Ping checks that the server is responsive.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.request(ctx, "ping", nil, nil)
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("mcp: tools/list: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	c.mu.Lock()
+	c.tools = make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		c.tools[tool.Name] = tool
+	}
+	c.mu.Unlock()
+	return tools, nil
+}
+
+// Tools lists the server's tools as function tools for a Prompt, with names prefixed as set by
+// WithToolPrefix.
+func (c *Client) Tools(ctx context.Context) ([]utils.Tool, error) {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]utils.Tool, len(tools))
+	for i, tool := range tools {
+		converted[i] = ToUtilsTool(tool, c.opts.ToolPrefix)
+	}
+	return converted, nil
+}
+
+// ToUtilsTool converts an MCP tool to a function tool named prefix+tool.Name. A tool without an
+// input schema gets an empty object schema, which every provider accepts.
+func ToUtilsTool(tool Tool, prefix string) utils.Tool {
+	parameters := tool.InputSchema
+	if parameters == nil {
+		parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	description := tool.Description
+	if description == "" {
+		description = tool.Title
+	}
+	return utils.Tool{
+		Type: "function",
+		Function: utils.Function{
+			Name:        prefix + tool.Name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// HasTool reports whether name (as reported by Tools, prefix included) is one of this server's
+// tools. It only knows the tools from the last ListTools or Tools call.
+func (c *Client) HasTool(name string) bool {
+	serverName, ok := strings.CutPrefix(name, c.opts.ToolPrefix)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, found := c.tools[serverName]
+	return found
+}
+
+// CallTool runs the named tool (the server's own name, without prefix) with arguments, a JSON
+// object. Nil arguments send an empty object.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result CallToolResult
+	err := c.request(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": arguments}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: tools/call %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Execute runs a tool call from the model and returns the result to send back. Failures, from
+// a protocol error or inside the tool, come back as an error result so the model can react to
+// them; Execute never drops a call.
+func (c *Client) Execute(ctx context.Context, call types.ToolCall) types.ToolResult {
+	name, ok := strings.CutPrefix(call.Function.Name, c.opts.ToolPrefix)
+	if !ok {
+		return types.NewToolError(call.ID, fmt.Sprintf("unknown tool %q", call.Function.Name))
+	}
+	arguments, err := toolArguments(call.Function.Arguments)
+	if err != nil {
+		return types.NewToolError(call.ID, err.Error())
+	}
+	result, err := c.CallTool(ctx, name, arguments)
+	if err != nil {
+		return types.NewToolError(call.ID, err.Error())
+	}
+	return types.ToolResult{ToolCallID: call.ID, Content: result.Text(), IsError: result.IsError}
+}
+
+// ExecuteAll runs calls concurrently and returns their results in the same order.
+func (c *Client) ExecuteAll(ctx context.Context, calls []types.ToolCall) []types.ToolResult {
+	return executeAll(ctx, calls, c.Execute)
+}
+
+// Close ends the session and, for stdio, stops the server process.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// toolArguments normalizes the arguments of a tool call to a JSON object. Some providers
+// deliver them as a JSON-encoded string, which is unwrapped.
+func toolArguments(raw json.RawMessage) (json.RawMessage, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return json.RawMessage("{}"), nil
+	}
+	if strings.HasPrefix(trimmed, `"`) {
+		var inner string
+		if err := json.Unmarshal([]byte(trimmed), &inner); err != nil {
+			return nil, fmt.Errorf("invalid tool arguments: %w", err)
+		}
+		trimmed = strings.TrimSpace(inner)
+		if trimmed == "" {
+			return json.RawMessage("{}"), nil
+		}
+	}
+	if !json.Valid([]byte(trimmed)) {
+		return nil, fmt.Errorf("invalid tool arguments: %s", trimmed)
+	}
+	return json.RawMessage(trimmed), nil
+}
+
+func executeAll(ctx context.Context, calls []types.ToolCall, execute func(context.Context, types.ToolCall) types.ToolResult) []types.ToolResult {
+	results := make([]types.ToolResult, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = execute(ctx, call)
+		}()
+	}
+	wg.Wait()
+	return results
+}
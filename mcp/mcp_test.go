@@ -0,0 +1,312 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/types"
+)
+
+// TestMain doubles as a small MCP server: the stdio tests start the test binary again with
+// GOLLM_MCP_TEST_SERVER set, and it then serves on stdin/stdout instead of running tests.
+func TestMain(m *testing.M) {
+	switch os.Getenv("GOLLM_MCP_TEST_SERVER") {
+	case "1":
+		serveStdio(os.Stdin, os.Stdout)
+		os.Exit(0)
+	case "stubborn":
+		// Keeps running, with stdout open, after its stdin is closed.
+		serveStdio(os.Stdin, os.Stdout)
+		select {}
+	}
+	os.Exit(m.Run())
+}
+
+// testServer answers the MCP methods the client uses. Its tools are listed over two pages.
+type testServer struct {
+	name string
+}
+
+func (s *testServer) handle(msg *message) *message {
+	reply := func(result interface{}) *message {
+		data, _ := json.Marshal(result)
+		return &message{JSONRPC: "2.0", ID: msg.ID, Result: data}
+	}
+	params, _ := msg.Params.(map[string]interface{})
+	switch msg.Method {
+	case "initialize":
+		return reply(map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": "0.1"},
+			"instructions":    "test server",
+		})
+	case "ping":
+		return reply(map[string]interface{}{})
+	case "tools/list":
+		if params["cursor"] == "page2" {
+			return reply(map[string]interface{}{"tools": []map[string]interface{}{
+				{"name": "fail", "description": "Always fails"},
+			}})
+		}
+		return reply(map[string]interface{}{
+			"tools": []map[string]interface{}{
+				{"name": "echo", "description": "Echoes text", "inputSchema": map[string]interface{}{
+					"type": "object", "properties": map[string]interface{}{"text": map[string]string{"type": "string"}}}},
+				{"name": "add", "title": "Add numbers", "inputSchema": map[string]interface{}{"type": "object"}},
+			},
+			"nextCursor": "page2",
+		})
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]interface{})
+		switch params["name"] {
+		case "echo":
+			return reply(map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": fmt.Sprint(args["text"])}}})
+		case "add":
+			a, _ := args["a"].(float64)
+			b, _ := args["b"].(float64)
+			return reply(map[string]interface{}{
+				"content":           []map[string]interface{}{{"type": "text", "text": fmt.Sprint(a + b)}},
+				"structuredContent": map[string]float64{"sum": a + b},
+			})
+		case "fail":
+			return reply(map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": "boom"}}, "isError": true})
+		}
+		return &message{JSONRPC: "2.0", ID: msg.ID, Error: &Error{Code: -32602, Message: fmt.Sprintf("unknown tool %v", params["name"])}}
+	}
+	return &message{JSONRPC: "2.0", ID: msg.ID, Error: &Error{Code: codeMethodNotFound, Message: "method not found"}}
+}
+
+// serveStdio runs testServer over newline-delimited JSON. Before each tools/call result it
+// sends a log notification and a ping request, which the client must skip and answer.
+func serveStdio(in io.Reader, out io.Writer) {
+	server := &testServer{name: "stdio-test"}
+	scanner := bufio.NewScanner(in)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		var msg message
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || msg.isResponse() || len(msg.ID) == 0 {
+			continue
+		}
+		if msg.Method == "tools/call" {
+			_ = encoder.Encode(&message{JSONRPC: "2.0", Method: "notifications/message", Params: map[string]string{"level": "info"}})
+			_ = encoder.Encode(&message{JSONRPC: "2.0", ID: json.RawMessage(`"srv-1"`), Method: "ping"})
+		}
+		_ = encoder.Encode(server.handle(&msg))
+	}
+}
+
+// httpTestServer serves testServer over streamable HTTP, answering tools/call with an event
+// stream and everything else with JSON. It requires the session ID after initialization.
+type httpTestServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	headers []http.Header
+	deleted bool
+}
+
+func newHTTPTestServer(t *testing.T, name string) *httpTestServer {
+	t.Helper()
+	s := &httpTestServer{}
+	server := &testServer{name: name}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mu.Unlock()
+		if r.Method == http.MethodDelete {
+			s.mu.Lock()
+			s.deleted = true
+			s.mu.Unlock()
+			return
+		}
+		var msg message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if msg.Method != "initialize" && r.Header.Get(headerSessionID) != "sess-"+name {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if msg.Method == "initialize" {
+			w.Header().Set(headerSessionID, "sess-"+name)
+		}
+		resp, _ := json.Marshal(server.handle(&msg))
+		if msg.Method == "tools/call" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{}}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func toolCall(id, name, arguments string) types.ToolCall {
+	return types.NewToolCall(id, name, json.RawMessage(arguments))
+}
+
+func TestStdioClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := ConnectCommand(ctx, exec.Command(os.Args[0]), WithEnv("GOLLM_MCP_TEST_SERVER=1"), WithToolPrefix("t_"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "stdio-test", client.Server().Name)
+	assert.Equal(t, "2025-03-26", client.Server().ProtocolVersion)
+	require.NoError(t, client.Ping(ctx))
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 3, "both pages are listed")
+	assert.Equal(t, "function", tools[0].Type)
+	assert.Equal(t, "t_echo", tools[0].Function.Name)
+	assert.Equal(t, "Echoes text", tools[0].Function.Description)
+	assert.Equal(t, "object", tools[0].Function.Parameters["type"])
+	assert.Equal(t, "Add numbers", tools[1].Function.Description, "the title stands in for a missing description")
+	assert.Equal(t, map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, tools[2].Function.Parameters)
+
+	results := client.ExecuteAll(ctx, []types.ToolCall{
+		toolCall("c1", "t_echo", `{"text":"hello"}`),
+		toolCall("c2", "t_add", `"{\"a\":2,\"b\":3}"`),
+		toolCall("c3", "t_fail", ``),
+		toolCall("c4", "other_echo", `{}`),
+	})
+	require.Len(t, results, 4)
+	assert.Equal(t, types.ToolResult{ToolCallID: "c1", Content: "hello"}, results[0])
+	assert.Equal(t, types.ToolResult{ToolCallID: "c2", Content: "5"}, results[1])
+	assert.Equal(t, types.ToolResult{ToolCallID: "c3", Content: "boom", IsError: true}, results[2])
+	assert.True(t, results[3].IsError)
+	assert.Equal(t, "c4", results[3].ToolCallID)
+
+	require.NoError(t, client.Close())
+	_, err = client.ListTools(ctx)
+	assert.Error(t, err, "calls after Close fail instead of hanging")
+}
+
+func TestStdioCloseKillsStubbornServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.Command(os.Args[0])
+	client, err := ConnectCommand(ctx, cmd, WithEnv("GOLLM_MCP_TEST_SERVER=stubborn"))
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, client.Close())
+	assert.GreaterOrEqual(t, time.Since(start), stdioShutdownGrace, "the server is given the grace period")
+	assert.NotNil(t, cmd.ProcessState, "the server has been reaped")
+	assert.False(t, cmd.ProcessState.Success(), "the server was killed")
+}
+
+func TestStdioServerMissing(t *testing.T) {
+	_, err := ConnectStdio(context.Background(), "/nonexistent/mcp-server", nil)
+	assert.Error(t, err)
+}
+
+func TestHTTPClient(t *testing.T) {
+	ctx := context.Background()
+	server := newHTTPTestServer(t, "remote")
+
+	client, err := ConnectHTTP(ctx, server.URL, WithHeaders(map[string]string{"Authorization": "Bearer tok"}))
+	require.NoError(t, err)
+	assert.Equal(t, "remote", client.Server().Name)
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tools, 3)
+
+	result := client.Execute(ctx, toolCall("c1", "echo", `{"text":"over http"}`))
+	assert.Equal(t, types.ToolResult{ToolCallID: "c1", Content: "over http"}, result)
+
+	callResult, err := client.CallTool(ctx, "add", json.RawMessage(`{"a":1,"b":1}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sum":2}`, string(callResult.StructuredContent))
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.Code)
+
+	require.NoError(t, client.Close())
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.True(t, server.deleted, "Close ends the session")
+	last := server.headers[len(server.headers)-1]
+	assert.Equal(t, "Bearer tok", last.Get("Authorization"))
+	assert.Equal(t, "2025-03-26", last.Get(headerProtocolVersion))
+	assert.Equal(t, "sess-remote", last.Get(headerSessionID))
+}
+
+func TestHTTPClientStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := ConnectHTTP(context.Background(), server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestToolset(t *testing.T) {
+	ctx := context.Background()
+	first, err := ConnectHTTP(ctx, newHTTPTestServer(t, "first").URL, WithToolPrefix("a_"))
+	require.NoError(t, err)
+	second, err := ConnectHTTP(ctx, newHTTPTestServer(t, "second").URL, WithToolPrefix("b_"))
+	require.NoError(t, err)
+
+	toolset := NewToolset(first, second)
+	tools, err := toolset.Tools(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tools, 6)
+
+	results := toolset.ExecuteAll(ctx, []types.ToolCall{
+		toolCall("c1", "b_echo", `{"text":"second"}`),
+		toolCall("c2", "nope", `{}`),
+	})
+	assert.Equal(t, "second", results[0].Content)
+	assert.False(t, results[0].IsError)
+	assert.True(t, results[1].IsError)
+
+	unprefixed, err := ConnectHTTP(ctx, newHTTPTestServer(t, "third").URL)
+	require.NoError(t, err)
+	duplicate, err := ConnectHTTP(ctx, newHTTPTestServer(t, "fourth").URL)
+	require.NoError(t, err)
+	_, err = NewToolset(unprefixed, duplicate).Tools(ctx)
+	assert.ErrorContains(t, err, "WithToolPrefix")
+}
+
+func TestCallToolResultText(t *testing.T) {
+	structured := &CallToolResult{StructuredContent: json.RawMessage(`{"ok":true}`)}
+	assert.Equal(t, `{"ok":true}`, structured.Text())
+
+	mixed := &CallToolResult{Content: []Content{
+		{Type: "text", Text: "chart:"},
+		{Type: "image", Data: "aGk=", MimeType: "image/png"},
+	}}
+	assert.Equal(t, "chart:\n"+`{"type":"image","data":"aGk=","mimeType":"image/png"}`, mixed.Text())
+}
@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioShutdownGrace is how long Close waits for a server to exit after its stdin is closed
+// before killing it.
+const stdioShutdownGrace = 2 * time.Second
+
+// ConnectStdio starts command as an MCP server speaking over its stdin and stdout, and
+// initializes a session with it. The process runs until Close.
+func ConnectStdio(ctx context.Context, command string, args []string, opts ...Option) (*Client, error) {
+	return ConnectCommand(ctx, exec.Command(command, args...), opts...)
+}
+
+// ConnectCommand is ConnectStdio for a prepared command, for servers that need a working
+// directory or a specific environment. cmd must not have been started, and its Stdin and
+// Stdout must be unset.
+func ConnectCommand(ctx context.Context, cmd *exec.Cmd, opts ...Option) (*Client, error) {
+	o := applyOptions(opts)
+	if len(o.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, o.Env...)
+	}
+	if o.Stderr != nil {
+		cmd.Stderr = o.Stderr
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp: starting %s: %w", cmd.Path, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	return newClient(ctx, t, o)
+}
+
+// stdioTransport exchanges newline-delimited JSON-RPC messages with a child process. A single
+// reader goroutine routes responses to the calls waiting on their IDs.
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	readErr error         // why the read loop stopped; set before done is closed
+	done    chan struct{} // closed when the read loop stops
+
+	closeOnce sync.Once
+}
+
+func (t *stdioTransport) setProtocolVersion(string) {}
+
+func (t *stdioTransport) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("mcp: encoding message: %w", err)
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("mcp: writing to server: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, req *message) (*message, error) {
+	ch := make(chan *message, 1)
+	key := string(req.ID)
+	t.mu.Lock()
+	if t.readErr != nil {
+		err := t.readErr
+		t.mu.Unlock()
+		return nil, err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, t.readErr
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, msg *message) error {
+	return t.write(msg)
+}
+
+// readLoop reads messages until the server closes stdout. Responses go to their callers,
+// server requests get a reply, and notifications are dropped.
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	var err error
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if len(line) > 0 {
+			t.handle(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = errors.New("mcp: server closed the connection")
+	}
+	t.mu.Lock()
+	t.readErr = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) handle(line []byte) {
+	var msg message
+	if json.Unmarshal(line, &msg) != nil {
+		// Servers sometimes log to stdout; anything that is not JSON-RPC is ignored.
+		return
+	}
+	if msg.isResponse() {
+		t.mu.Lock()
+		ch := t.pending[string(msg.ID)]
+		t.mu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+		return
+	}
+	if len(msg.ID) > 0 {
+		_ = t.write(replyTo(&msg))
+	}
+}
+
+// close shuts down the server: closing stdin asks it to exit, and it is killed if it has not
+// within stdioShutdownGrace. cmd.Wait closes stdout, so it runs only once the read loop has
+// reached the end of the output or the server has been killed.
+func (t *stdioTransport) close() error {
+	t.closeOnce.Do(func() {
+		_ = t.stdin.Close()
+		grace := time.NewTimer(stdioShutdownGrace)
+		defer grace.Stop()
+
+		killed := false
+		select {
+		case <-t.done:
+		case <-grace.C:
+			_ = t.cmd.Process.Kill()
+			killed = true
+		}
+		exited := make(chan error, 1)
+		go func() { exited <- t.cmd.Wait() }()
+		if !killed {
+			// The server closed stdout but may still be running.
+			select {
+			case <-exited:
+				return
+			case <-grace.C:
+				_ = t.cmd.Process.Kill()
+			}
+		}
+		<-exited
+		<-t.done
+	})
+	return nil
+}
+
+// replyTo answers a request from the server. The client offers no capabilities, so only ping
+// succeeds.
+func replyTo(req *message) *message {
+	if req.Method == "ping" {
+		return &message{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage("{}")}
+	}
+	return &message{JSONRPC: "2.0", ID: req.ID, Error: &Error{Code: codeMethodNotFound, Message: "method not found: " + req.Method}}
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/teilomillet/gollm/types"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// Toolset combines the tools of several servers into one list for a Prompt and routes each tool
+// call to the server that offers the tool. Give the clients distinct prefixes (WithToolPrefix)
+// when their tool names may collide.
+type Toolset struct {
+	clients []*Client
+}
+
+// NewToolset combines clients. The Toolset does not own them; close them separately.
+func NewToolset(clients ...*Client) *Toolset {
+	return &Toolset{clients: clients}
+}
+
+// Tools lists the tools of every server. Two servers reporting the same name is an error,
+// since a call to that name could not be routed.
+func (s *Toolset) Tools(ctx context.Context) ([]utils.Tool, error) {
+	var all []utils.Tool
+	owner := make(map[string]string)
+	for _, client := range s.clients {
+		tools, err := client.Tools(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", client.Server().Name, err)
+		}
+		for _, tool := range tools {
+			name := tool.Function.Name
+			if other, dup := owner[name]; dup {
+				return nil, fmt.Errorf("mcp: tool %q is offered by both %s and %s; use WithToolPrefix", name, other, client.Server().Name)
+			}
+			owner[name] = client.Server().Name
+			all = append(all, tool)
+		}
+	}
+	return all, nil
+}
+
+// Execute runs a tool call on the server that offers the tool. Tools must have been listed
+// first; a call to an unknown tool returns an error result.
+func (s *Toolset) Execute(ctx context.Context, call types.ToolCall) types.ToolResult {
+	for _, client := range s.clients {
+		if client.HasTool(call.Function.Name) {
+			return client.Execute(ctx, call)
+		}
+	}
+	return types.NewToolError(call.ID, fmt.Sprintf("unknown tool %q", call.Function.Name))
+}
+
+// ExecuteAll runs calls concurrently and returns their results in the same order.
+func (s *Toolset) ExecuteAll(ctx context.Context, calls []types.ToolCall) []types.ToolResult {
+	return executeAll(ctx, calls, s.Execute)
+}
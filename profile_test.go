@@ -0,0 +1,55 @@
+package gollm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/gollmtest"
+)
+
+// TestProfileHeadersAreSent checks that the headers of a profile, and those set with
+// SetExtraHeaders, reach the provider.
+func TestProfileHeadersAreSent(t *testing.T) {
+	var mu sync.Mutex
+	var requests []gollmtest.Request
+	server := gollmtest.NewServer(gollmtest.ServeFunc(func(r gollmtest.Request) gollmtest.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		return gollmtest.Text("ok")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  work:
+    provider: openai
+    model: gpt-4o-mini
+    max_retries: 0
+    headers:
+      X-Team: ${WORK_TEAM}
+`), 0o600))
+	t.Setenv("LLM_CONFIG_FILE", path)
+	t.Setenv("WORK_TEAM", "search")
+	t.Setenv("LLM_BASE_URL", server.URL())
+	t.Setenv("OPENAI_API_KEY", "sk-test-key-0123456789abcdef")
+
+	profile, err := gollm.LoadProfile("work")
+	require.NoError(t, err)
+	client, err := gollm.NewLLM(profile, gollm.SetExtraHeaders(map[string]string{"X-Request-Source": "tests"}))
+	require.NoError(t, err)
+	_, err = client.Generate(context.Background(), gollm.NewPrompt("Hello"))
+	require.NoError(t, err)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "search", requests[0].Header.Get("X-Team"))
+	assert.Equal(t, "tests", requests[0].Header.Get("X-Request-Source"))
+}
@@ -65,6 +65,9 @@ type (
 
 	// ImageSource represents a base64-encoded image for vision models.
 	ImageSource = types.ImageSource
+
+	// DocumentSource describes a document (PDF, text, uploaded file) sent as a content part.
+	DocumentSource = types.DocumentSource
 )
 
 // Cache type constants define the available caching strategies.
@@ -125,6 +128,9 @@ var (
 	// WithJSONSchemaValidation enables JSON schema validation.
 	WithJSONSchemaValidation = llm.WithJSONSchemaValidation
 
+	// WithReasoning decides whether the model's reasoning is requested and returned.
+	WithReasoning = llm.WithReasoning
+
 	// WithStream enables or disables streaming responses.
 	WithStream = config.WithStream
 
@@ -138,6 +144,33 @@ var (
 
 	// WithImages adds multiple images to the prompt for vision-capable models.
 	WithImages = llm.WithImages
+
+	// WithDocumentBase64 adds a base64-encoded document (e.g. a PDF) to the prompt.
+	WithDocumentBase64 = llm.WithDocumentBase64
+
+	// WithDocumentURL adds a document referenced by URL to the prompt.
+	WithDocumentURL = llm.WithDocumentURL
+
+	// WithDocumentText adds a plain-text document to the prompt.
+	WithDocumentText = llm.WithDocumentText
+
+	// WithDocumentFile reads a document from disk and adds it to the prompt.
+	WithDocumentFile = llm.WithDocumentFile
+
+	// WithFileID adds a file already uploaded to the provider's Files API.
+	WithFileID = llm.WithFileID
+
+	// WithDocuments adds multiple document content parts to the prompt.
+	WithDocuments = llm.WithDocuments
+
+	// WithDocumentCitations enables citations on the documents added so far (Anthropic).
+	WithDocumentCitations = llm.WithDocumentCitations
+
+	// WithInputAudio adds a base64-encoded audio clip to the prompt for audio-capable models.
+	WithInputAudio = llm.WithInputAudio
+
+	// WithAudioFile reads an audio clip (.wav, .mp3) from disk and adds it to the prompt.
+	WithAudioFile = llm.WithAudioFile
 )
 
 // CleanResponse processes and cleans up LLM responses by removing markdown formatting
@@ -100,6 +100,18 @@ func (p *AnthropicProvider) SupportsJSONSchema() bool {
 	return true
 }
 
+// SupportsDocumentSource implements DocumentSupporter: document blocks accept base64, URL,
+// plain-text and uploaded-file sources. File IDs also need the Files API beta header,
+// which can be added through extra headers.
+func (p *AnthropicProvider) SupportsDocumentSource(sourceType string) bool {
+	switch sourceType {
+	case types.DocumentSourceBase64, types.DocumentSourceURL, types.DocumentSourceText, types.DocumentSourceFile:
+		return true
+	default:
+		return false
+	}
+}
+
 // Headers returns the required HTTP headers for Anthropic API requests.
 // This includes:
 //   - x-api-key: API key for authentication
@@ -634,6 +646,9 @@ func (p *AnthropicProvider) ParseResponseWithUsage(body []byte) (string, *types.
 			Input     json.RawMessage `json:"input,omitempty"`
 			ToolUseID string          `json:"tool_use_id,omitempty"` // For web_search_tool_result
 			Content   json.RawMessage `json:"content,omitempty"`     // For web_search_tool_result content
+			Thinking  string          `json:"thinking,omitempty"`    // For thinking blocks
+			Signature string          `json:"signature,omitempty"`   // For thinking blocks
+			Data      string          `json:"data,omitempty"`        // For redacted_thinking blocks
 			Citations []struct {
 				Type           string `json:"type"`
 				URL            string `json:"url"`
@@ -744,6 +759,18 @@ func (p *AnthropicProvider) ParseResponseWithUsage(body []byte) (string, *types.
 		p.logger.Debug("Processing content block %d: type=%s", i, content.Type)
 
 		switch content.Type {
+		case "thinking", "redacted_thinking":
+			// Kept verbatim: the signed blocks must be sent back unchanged with the tool
+			// results of this turn, so they are not folded into the text.
+			details.ThinkingBlocks = append(details.ThinkingBlocks, types.ThinkingBlock{
+				Type:      content.Type,
+				Thinking:  content.Thinking,
+				Signature: content.Signature,
+				Data:      content.Data,
+			})
+			details.Reasoning = appendThinkingReasoning(details.Reasoning, content.Type, content.Thinking, content.Signature, content.Data)
+			continue
+
 		case "text":
 			// If we have pending text and this is also text, add a space
 			if lastType == "text" && pendingText.Len() > 0 {
@@ -1067,11 +1094,14 @@ func parseAnthropicStreamChunk(chunk []byte) (types.StreamChunk, error) {
 			Type string `json:"type"`
 			ID   string `json:"id"`
 			Name string `json:"name"`
+			Data string `json:"data"` // redacted_thinking arrives whole at block start
 		} `json:"content_block"`
 		Delta struct {
 			Type        string `json:"type"`
 			Text        string `json:"text"`
 			PartialJSON string `json:"partial_json"`
+			Thinking    string `json:"thinking"`
+			Signature   string `json:"signature"`
 			StopReason  string `json:"stop_reason"`
 		} `json:"delta"`
 		Usage anthropicStreamUsage `json:"usage"`
@@ -1100,6 +1130,12 @@ func parseAnthropicStreamChunk(chunk []byte) (types.StreamChunk, error) {
 				Name:  event.ContentBlock.Name,
 			}}, nil
 		}
+		if event.ContentBlock.Type == "redacted_thinking" {
+			return types.StreamChunk{Kind: "thinking", Thinking: &types.ThinkingDelta{
+				Index:    event.Index,
+				Redacted: event.ContentBlock.Data,
+			}}, nil
+		}
 		return types.StreamChunk{}, types.ErrStreamSkip
 	case "content_block_delta":
 		if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
@@ -1111,6 +1147,20 @@ func parseAnthropicStreamChunk(chunk []byte) (types.StreamChunk, error) {
 				ArgsFragment: event.Delta.PartialJSON,
 			}}, nil
 		}
+		if event.Delta.Type == "thinking_delta" {
+			// The text also goes in Reasoning, where every provider's reasoning is read; the
+			// chunk stays a "thinking" one so the block can be reassembled for replay.
+			return types.StreamChunk{Kind: "thinking", Reasoning: event.Delta.Thinking, Thinking: &types.ThinkingDelta{
+				Index: event.Index,
+				Text:  event.Delta.Thinking,
+			}}, nil
+		}
+		if event.Delta.Type == "signature_delta" {
+			return types.StreamChunk{Kind: "thinking", Thinking: &types.ThinkingDelta{
+				Index:     event.Index,
+				Signature: event.Delta.Signature,
+			}}, nil
+		}
 		return types.StreamChunk{}, types.ErrStreamSkip
 	case "message_delta":
 		// Final event: stop reason, cumulative output tokens, and — when extended
@@ -1129,6 +1179,62 @@ func parseAnthropicStreamChunk(chunk []byte) (types.StreamChunk, error) {
 	}
 }
 
+// appendThinkingReasoning folds one thinking or redacted_thinking block into the normalized
+// reasoning: texts are joined, and the signature and encrypted data are those of the last block
+// carrying them. ThinkingBlocks keeps each block intact for replay.
+func appendThinkingReasoning(r *types.Reasoning, blockType, thinking, signature, data string) *types.Reasoning {
+	if r == nil {
+		r = &types.Reasoning{}
+	}
+	if blockType == "redacted_thinking" {
+		r.Encrypted = data
+		return r
+	}
+	if thinking != "" {
+		if r.Text != "" {
+			r.Text += "\n\n"
+		}
+		r.Text += thinking
+	}
+	if signature != "" {
+		r.Signature = signature
+	}
+	return r
+}
+
+// RequestReasoning enables extended thinking at medium effort when reasoning is wanted and no
+// reasoning_effort was set; an explicit effort is left alone. Anthropic has no way to run
+// thinking without returning it, so include false sends nothing.
+func (p *AnthropicProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	if !include {
+		return
+	}
+	if effort, ok := optionString(options["reasoning_effort"]); ok && effort != "" {
+		return
+	}
+	if effort, ok := optionString(p.options["reasoning_effort"]); ok && effort != "" {
+		return
+	}
+	options["reasoning_effort"] = string(types.ReasoningEffortMedium)
+}
+
+// anthropicThinkingContent converts preserved thinking blocks back to request content blocks.
+func anthropicThinkingContent(blocks []types.ThinkingBlock) []map[string]interface{} {
+	content := make([]map[string]interface{}, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Type == "redacted_thinking" {
+			content = append(content, map[string]interface{}{"type": "redacted_thinking", "data": b.Data})
+			continue
+		}
+		content = append(content, map[string]interface{}{
+			"type":      "thinking",
+			"thinking":  b.Thinking,
+			"signature": b.Signature,
+		})
+	}
+	return content
+}
+
 // PrepareRequestWithMessages creates a request body using structured message objects
 // rather than a flattened prompt string. This enables more efficient caching and
 // better preserves conversation structure for the Claude API.
@@ -1281,6 +1387,9 @@ func (p *AnthropicProvider) PrepareRequestWithMessages(messages []types.MemoryMe
 
 		// Handle assistant messages with tool calls
 		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			// Thinking blocks go first, unchanged: Anthropic verifies their signatures
+			// and rejects a tool-use continuation that drops or edits them.
+			content = append(content, anthropicThinkingContent(msg.ThinkingBlocks)...)
 			// Add text content if present
 			if msg.Content != "" {
 				content = append(content, map[string]interface{}{
@@ -1322,6 +1431,9 @@ func (p *AnthropicProvider) PrepareRequestWithMessages(messages []types.MemoryMe
 				},
 			}
 		}
+		if msg.Role == "assistant" && len(msg.ThinkingBlocks) > 0 {
+			content = append(anthropicThinkingContent(msg.ThinkingBlocks), content...)
+		}
 
 		// Add cache_control if specified (to the last content block)
 		if len(content) > 0 {
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/teilomillet/gollm/types"
@@ -384,3 +385,105 @@ func TestAnthropicThinkingOffByDefault(t *testing.T) {
 		t.Errorf("thinking must be absent when reasoning_effort is not set")
 	}
 }
+
+// TestAnthropicThinkingBlocksCaptured verifies thinking and redacted_thinking blocks are kept
+// verbatim on ResponseDetails and never leak into the response text.
+func TestAnthropicThinkingBlocksCaptured(t *testing.T) {
+	p := NewAnthropicProvider("fake-key", "claude-sonnet-4-5", nil).(*AnthropicProvider)
+	body := []byte(`{"content":[
+		{"type":"thinking","thinking":"Need the weather.","signature":"sig-1"},
+		{"type":"redacted_thinking","data":"enc-1"},
+		{"type":"text","text":"Checking."},
+		{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}
+	],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`)
+
+	text, details, err := p.ParseResponseWithUsage(body)
+	if err != nil {
+		t.Fatalf("ParseResponseWithUsage failed: %v", err)
+	}
+	if details == nil || len(details.ThinkingBlocks) != 2 {
+		t.Fatalf("expected 2 thinking blocks, got %+v", details)
+	}
+	want := []types.ThinkingBlock{
+		{Type: "thinking", Thinking: "Need the weather.", Signature: "sig-1"},
+		{Type: "redacted_thinking", Data: "enc-1"},
+	}
+	for i, b := range want {
+		if details.ThinkingBlocks[i] != b {
+			t.Errorf("block %d: expected %+v, got %+v", i, b, details.ThinkingBlocks[i])
+		}
+	}
+	if len(details.ToolCalls) != 1 {
+		t.Errorf("expected the tool call alongside the thinking, got %+v", details.ToolCalls)
+	}
+	for _, leaked := range []string{"Need the weather.", "enc-1", "sig-1"} {
+		if strings.Contains(text, leaked) {
+			t.Errorf("thinking content %q leaked into text %q", leaked, text)
+		}
+	}
+}
+
+// TestAnthropicThinkingBlocksReplayed verifies an assistant turn's thinking blocks are sent back
+// first and unchanged, before its text and tool_use blocks.
+func TestAnthropicThinkingBlocksReplayed(t *testing.T) {
+	p := NewAnthropicProvider("fake-key", "claude-sonnet-4-5", nil).(*AnthropicProvider)
+	messages := []types.MemoryMessage{
+		{Role: "user", Content: "Weather in Paris?"},
+		{
+			Role:    "assistant",
+			Content: "Checking.",
+			ThinkingBlocks: []types.ThinkingBlock{
+				{Type: "thinking", Thinking: "Need the weather.", Signature: "sig-1"},
+				{Type: "redacted_thinking", Data: "enc-1"},
+			},
+			ToolCalls: []types.ToolCall{types.NewToolCall("toolu_1", "get_weather", []byte(`{"city":"Paris"}`))},
+		},
+		{Role: "tool", Content: "Sunny", ToolCallID: "toolu_1"},
+	}
+
+	body, err := p.PrepareRequestWithMessages(messages, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("PrepareRequestWithMessages failed: %v", err)
+	}
+	req := decodeAnthropicRequest(t, body)
+	msgs, ok := req["messages"].([]interface{})
+	if !ok || len(msgs) < 2 {
+		t.Fatalf("expected messages, got %v", req["messages"])
+	}
+	assistant := mustObject(t, msgs[1], "assistant message")
+	content, ok := assistant["content"].([]interface{})
+	if !ok || len(content) != 4 {
+		t.Fatalf("expected thinking, redacted, text and tool_use blocks, got %v", assistant["content"])
+	}
+	first := mustObject(t, content[0], "first block")
+	if first["type"] != "thinking" || first["thinking"] != "Need the weather." || first["signature"] != "sig-1" {
+		t.Errorf("unexpected thinking block: %v", first)
+	}
+	second := mustObject(t, content[1], "second block")
+	if second["type"] != "redacted_thinking" || second["data"] != "enc-1" {
+		t.Errorf("unexpected redacted block: %v", second)
+	}
+	if mustObject(t, content[2], "third block")["type"] != "text" || mustObject(t, content[3], "fourth block")["type"] != "tool_use" {
+		t.Errorf("text and tool_use must follow the thinking blocks, got %v", content)
+	}
+}
+
+// TestAnthropicThinkingStreamChunks verifies streamed thinking text, signatures and redacted
+// blocks arrive as thinking chunks carrying the delta needed for replay, the text also as
+// reasoning.
+func TestAnthropicThinkingStreamChunks(t *testing.T) {
+	p := NewAnthropicProvider("fake-key", "claude-sonnet-4-5", nil).(*AnthropicProvider)
+
+	c, err := p.ParseStreamResponseRich([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me"}}`))
+	if err != nil || c.Kind != "thinking" || c.Reasoning != "Let me" || c.Thinking == nil || c.Thinking.Text != "Let me" || c.Text != "" {
+		t.Fatalf("thinking_delta: %+v err=%v", c, err)
+	}
+	c, err = p.ParseStreamResponseRich([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}`))
+	if err != nil || c.Kind != "thinking" || c.Thinking.Signature != "sig-1" {
+		t.Fatalf("signature_delta: %+v err=%v", c, err)
+	}
+	c, err = p.ParseStreamResponseRich([]byte(`{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"enc-1"}}`))
+	if err != nil || c.Kind != "thinking" || c.Thinking.Index != 1 || c.Thinking.Redacted != "enc-1" {
+		t.Fatalf("redacted_thinking start: %+v err=%v", c, err)
+	}
+}
@@ -27,6 +27,10 @@ func NewDeepSeekProvider(apiKey, model string, extraHeaders map[string]string) P
 	}
 	// DeepSeek uses the "system" role, not OpenAI's "developer" role.
 	provider.systemRole = "system"
+	// DeepSeek's chat endpoint accepts text only.
+	provider.documents = documentsNone
+	provider.audioInput = false
+	provider.audioOutput = false
 	// Override the endpoint
 	return provider
 }
@@ -121,7 +121,7 @@ func (p *GenericProvider) Headers() map[string]string {
 	}
 
 	// Add authentication header
-	if p.apiKey != "" {
+	if p.apiKey != "" && p.config.AuthHeader != "" {
 		headers[p.config.AuthHeader] = p.config.AuthPrefix + p.apiKey
 	}
 
@@ -399,6 +399,8 @@ func (p *GenericProvider) parseOpenAIResponseWithUsage(body []byte) (string, *ty
 				FunctionCall struct {
 					Arguments string `json:"arguments"`
 				} `json:"function_call"`
+				ReasoningContent compatReasoningText `json:"reasoning_content"`
+				Reasoning        compatReasoningText `json:"reasoning"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -424,6 +426,7 @@ func (p *GenericProvider) parseOpenAIResponseWithUsage(body []byte) (string, *ty
 		ID:         response.ID,
 		Model:      response.Model,
 		TokenUsage: *response.Usage.normalize(),
+		Reasoning:  compatReasoning(response.Choices[0].Message.ReasoningContent, response.Choices[0].Message.Reasoning),
 	}
 
 	// Check for function calling response
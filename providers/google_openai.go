@@ -34,6 +34,10 @@ func NewGoogleProvider(apiKey, model string, extraHeaders map[string]string) Pro
 	// Gemini maps a "system"-role message to system_instruction and doesn't
 	// recognize OpenAI's "developer" role.
 	provider.systemRole = "system"
+	// Gemini reads documents from data URIs (as inline_data), not OpenAI's file parts.
+	provider.documents = documentsInline
+	// The OpenAI-compatible endpoint has no audio output.
+	provider.audioOutput = false
 
 	return provider
 }
@@ -144,6 +148,54 @@ func (p *GoogleProvider) withThinking(body []byte, err error, options map[string
 	return p.applyThinking(body, options)
 }
 
+// RequestReasoning sets include_thoughts, which asks a thinking model for summaries of its
+// thoughts alongside the answer.
+func (p *GoogleProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	options["include_thoughts"] = include
+}
+
+// ParseResponse wraps OpenAIProvider.ParseResponse to drop the thought summaries that
+// include_thoughts adds to the front of the content.
+func (p *GoogleProvider) ParseResponse(body []byte) (string, error) {
+	text, err := p.OpenAIProvider.ParseResponse(body)
+	if err != nil {
+		return "", err
+	}
+	_, answer := splitGeminiThoughts(text)
+	return answer, nil
+}
+
+// ParseResponseWithUsage wraps OpenAIProvider.ParseResponseWithUsage to move the thought
+// summaries out of the content and into details.Reasoning. Streams are not split: the tags
+// can straddle chunks, so a streamed thought summary arrives as text.
+func (p *GoogleProvider) ParseResponseWithUsage(body []byte) (string, *types.ResponseDetails, error) {
+	text, details, err := p.OpenAIProvider.ParseResponseWithUsage(body)
+	if err != nil {
+		return "", nil, err
+	}
+	if thoughts, answer := splitGeminiThoughts(text); thoughts != "" {
+		details.Reasoning = &types.Reasoning{Text: thoughts}
+		text = answer
+	}
+	return text, details, nil
+}
+
+// splitGeminiThoughts separates a leading <thought>…</thought> section, which is how the
+// OpenAI-compatible endpoint returns thought summaries, from the answer that follows it.
+// Text without one is returned unchanged as the answer.
+func splitGeminiThoughts(text string) (thoughts, answer string) {
+	const openTag, closeTag = "<thought>", "</thought>"
+	trimmed := strings.TrimLeft(text, " \t\r\n")
+	if !strings.HasPrefix(trimmed, openTag) {
+		return "", text
+	}
+	end := strings.Index(trimmed, closeTag)
+	if end < 0 {
+		return "", text
+	}
+	return strings.TrimSpace(trimmed[len(openTag):end]), strings.TrimLeft(trimmed[end+len(closeTag):], " \t\r\n")
+}
+
 // geminiMajorVersion returns the integer major version parsed from a Gemini
 // model id ("gemini-2.5-flash" -> 2, "gemini-3-pro-preview" -> 3), or 0 when no
 // numeric version is present ("gemini-flash-latest", "gemini-future-x").
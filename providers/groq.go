@@ -72,6 +72,15 @@ func (p *GroqProvider) SetOption(key string, value interface{}) {
 	p.options[key] = value
 }
 
+// RequestReasoning asks Groq's reasoning models to return their reasoning in the message's
+// reasoning field. Nothing is sent when include is false: models without reasoning reject the
+// parameter, and the reasoning of those that have it is dropped from the result anyway.
+func (p *GroqProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	if include {
+		options["include_reasoning"] = true
+	}
+}
+
 // SetDefaultOptions configures standard options from the global configuration.
 // This includes temperature, max tokens, and sampling parameters.
 func (p *GroqProvider) SetDefaultOptions(config *config.Config) {
@@ -259,6 +268,7 @@ func (p *GroqProvider) ParseResponseWithUsage(body []byte) (string, *types.Respo
 						Arguments json.RawMessage `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				Reasoning compatReasoningText `json:"reasoning"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -290,6 +300,7 @@ func (p *GroqProvider) ParseResponseWithUsage(body []byte) (string, *types.Respo
 	}
 
 	message := response.Choices[0].Message
+	details.Reasoning = compatReasoning("", message.Reasoning)
 	var parts []string
 	if message.Content != "" {
 		parts = append(parts, message.Content)
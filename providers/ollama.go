@@ -171,6 +171,12 @@ func (p *OllamaProvider) PrepareRequestWithSchema(prompt string, options map[str
 	return p.PrepareRequest(prompt, options)
 }
 
+// RequestReasoning sets Ollama's think parameter, which switches a thinking model's reasoning on
+// or off and returns it in the thinking field rather than inline in the response.
+func (p *OllamaProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	options["think"] = include
+}
+
 // ParseResponse extracts the generated text from the Ollama API response.
 // It handles Ollama's streaming response format and concatenates the results.
 //
@@ -215,7 +221,7 @@ func (p *OllamaProvider) ParseResponse(body []byte) (string, error) {
 //   - Response details carrying the normalized token usage
 //   - Any error encountered during parsing
 func (p *OllamaProvider) ParseResponseWithUsage(body []byte) (string, *types.ResponseDetails, error) {
-	var fullResponse strings.Builder
+	var fullResponse, thinking strings.Builder
 	details := &types.ResponseDetails{Model: p.model}
 	decoder := json.NewDecoder(bytes.NewReader(body))
 
@@ -223,6 +229,7 @@ func (p *OllamaProvider) ParseResponseWithUsage(body []byte) (string, *types.Res
 		var response struct {
 			Model    string `json:"model"`
 			Response string `json:"response"`
+			Thinking string `json:"thinking"` // set when thinking is enabled ("think")
 			Done     bool   `json:"done"`
 			// Counts arrive only on the terminal object.
 			PromptEvalCount int `json:"prompt_eval_count"`
@@ -232,6 +239,7 @@ func (p *OllamaProvider) ParseResponseWithUsage(body []byte) (string, *types.Res
 			return "", nil, fmt.Errorf("error parsing Ollama response: %w", err)
 		}
 		fullResponse.WriteString(response.Response)
+		thinking.WriteString(response.Thinking)
 		if response.Model != "" {
 			details.Model = response.Model
 		}
@@ -247,6 +255,9 @@ func (p *OllamaProvider) ParseResponseWithUsage(body []byte) (string, *types.Res
 	}
 
 	details.TokenUsage.TotalTokens = details.TokenUsage.PromptTokens + details.TokenUsage.CompletionTokens
+	if thinking.Len() > 0 {
+		details.Reasoning = &types.Reasoning{Text: thinking.String()}
+	}
 	return fullResponse.String(), details, nil
 }
 
@@ -256,6 +267,7 @@ func (p *OllamaProvider) ParseStreamResponseRich(chunk []byte) (types.StreamChun
 	var response struct {
 		Model           string `json:"model"`
 		Response        string `json:"response"`
+		Thinking        string `json:"thinking"`
 		Done            bool   `json:"done"`
 		DoneReason      string `json:"done_reason"`
 		PromptEvalCount int    `json:"prompt_eval_count"`
@@ -279,9 +291,12 @@ func (p *OllamaProvider) ParseStreamResponseRich(chunk []byte) (types.StreamChun
 	}
 
 	if response.Response == "" {
+		if response.Thinking != "" {
+			return types.StreamChunk{Kind: "reasoning", Reasoning: response.Thinking, Model: response.Model}, nil
+		}
 		return types.StreamChunk{}, types.ErrStreamSkip
 	}
-	return types.StreamChunk{Kind: "text", Text: response.Response, Model: response.Model}, nil
+	return types.StreamChunk{Kind: "text", Text: response.Response, Reasoning: response.Thinking, Model: response.Model}, nil
 }
 
 // HandleFunctionCalls processes function calling capabilities.
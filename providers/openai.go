@@ -25,6 +25,49 @@ type OpenAIProvider struct {
 	// systemRole is the role for the system prompt message: "developer" for
 	// OpenAI; compat endpoints that reject it (Google, DeepSeek) set "system".
 	systemRole string
+	// documents selects how document parts are encoded: documentsFile for OpenAI's
+	// chat "file" parts, documentsInline for compat endpoints that only take data
+	// URIs (Google), and documentsNone where documents are rejected (DeepSeek).
+	documents documentEncoding
+	// audioInput reports whether input_audio parts are accepted (OpenAI and Google).
+	audioInput bool
+	// audioOutput reports whether the endpoint can answer with speech (OpenAI only).
+	audioOutput bool
+}
+
+// documentEncoding is how an OpenAI-compatible endpoint accepts document content parts.
+type documentEncoding int
+
+const (
+	documentsNone documentEncoding = iota
+	documentsFile
+	documentsInline
+)
+
+// SupportsDocumentSource implements DocumentSupporter. Chat completions takes inline
+// and uploaded files; URL sources exist only on the Responses API.
+func (p *OpenAIProvider) SupportsDocumentSource(sourceType string) bool {
+	switch p.documents {
+	case documentsFile:
+		return sourceType == types.DocumentSourceBase64 || sourceType == types.DocumentSourceFile || sourceType == types.DocumentSourceText
+	case documentsInline:
+		return sourceType == types.DocumentSourceBase64 || sourceType == types.DocumentSourceText
+	default:
+		return false
+	}
+}
+
+// SupportsAudioInput implements AudioSupporter.
+func (p *OpenAIProvider) SupportsAudioInput() bool {
+	return p.audioInput
+}
+
+// buildContent converts multimodal parts to message content using this endpoint's document encoding.
+func (p *OpenAIProvider) buildContent(parts []types.ContentPart) []map[string]interface{} {
+	if p.documents == documentsInline {
+		return buildOpenAIContent(parts, contentPartToInlineData)
+	}
+	return BuildOpenAIContentFromParts(parts)
 }
 
 // systemMessageRole returns the role to use for the system prompt message,
@@ -57,6 +100,9 @@ func NewOpenAIProvider(apiKey, model string, extraHeaders map[string]string) Pro
 		options:      make(map[string]interface{}),
 		logger:       utils.NewLogger(utils.LogLevelInfo),
 		systemRole:   "developer",
+		documents:    documentsFile,
+		audioInput:   true,
+		audioOutput:  true,
 	}
 }
 
@@ -206,9 +252,24 @@ func (p *OpenAIProvider) SetDefaultOptions(config *config.Config) {
 	if config.Seed != nil {
 		p.SetOption("seed", *config.Seed)
 	}
+	if config.AudioOutput != nil {
+		if p.supportsAudioOutput() {
+			// Audio-capable models (gpt-4o-audio-preview, …) speak only when asked for both modalities.
+			p.SetOption("modalities", []string{"text", "audio"})
+			p.SetOption("audio", *config.AudioOutput)
+		} else {
+			p.logger.Warn("Audio output is not supported by this model; ignoring it", "model", p.model)
+		}
+	}
 	p.logger.Debug("Default options set", "temperature", config.Temperature, "max_tokens", config.MaxTokens, "seed", config.Seed)
 }
 
+// supportsAudioOutput reports whether modalities and audio may be requested: only OpenAI's own
+// endpoint speaks, and only its audio models (gpt-4o-audio-preview, gpt-audio, …) accept them.
+func (p *OpenAIProvider) supportsAudioOutput() bool {
+	return p.audioOutput && strings.Contains(strings.ToLower(p.model), "audio")
+}
+
 // Name returns "openai" as the provider identifier.
 func (p *OpenAIProvider) Name() string {
 	return "openai"
@@ -580,6 +641,8 @@ func (p *OpenAIProvider) ParseResponse(body []byte) (string, error) {
 						Arguments json.RawMessage `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				// Audio is set when audio output was requested; content is then null.
+				Audio *types.AudioOutput `json:"audio"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -601,6 +664,8 @@ func (p *OpenAIProvider) ParseResponse(body []byte) (string, error) {
 	var parts []string
 	if message.Content != "" {
 		parts = append(parts, message.Content)
+	} else if message.Audio != nil && message.Audio.Transcript != "" {
+		parts = append(parts, message.Audio.Transcript)
 	}
 
 	if len(message.ToolCalls) > 0 {
@@ -653,6 +718,11 @@ func (p *OpenAIProvider) ParseResponseWithUsage(body []byte) (string, *types.Res
 						Arguments json.RawMessage `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				// Audio is set when audio output was requested; content is then null.
+				Audio *types.AudioOutput `json:"audio"`
+				// Reasoning from OpenAI-compatible backends reached through this provider.
+				ReasoningContent compatReasoningText `json:"reasoning_content"`
+				Reasoning        compatReasoningText `json:"reasoning"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -781,11 +851,19 @@ func (p *OpenAIProvider) ParseResponseWithUsage(body []byte) (string, *types.Res
 	}
 
 	message := response.Choices[0].Message
+	details.Reasoning = compatReasoning(message.ReasoningContent, message.Reasoning)
 
 	var parts []string
 	if message.Content != "" {
 		parts = append(parts, message.Content)
 	}
+	if message.Audio != nil {
+		// With audio output the transcript stands in for the (null) text content.
+		details.Audio = message.Audio
+		if message.Content == "" && message.Audio.Transcript != "" {
+			parts = append(parts, message.Audio.Transcript)
+		}
+	}
 
 	if len(message.ToolCalls) > 0 {
 		for _, call := range message.ToolCalls {
@@ -1145,8 +1223,8 @@ func (p *OpenAIProvider) buildOpenAIMessages(messages []types.MemoryMessage, opt
 				message["content"] = msg.Content
 			}
 		} else if msg.HasMultiContent() {
-			// Handle multimodal content (text + images)
-			message["content"] = BuildOpenAIContentFromParts(msg.MultiContent)
+			// Handle multimodal content (text, images, documents)
+			message["content"] = p.buildContent(msg.MultiContent)
 		} else {
 			// Regular text message
 			message["content"] = msg.Content
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/types"
+)
+
+func TestOpenAIAudioInput(t *testing.T) {
+	messages := []types.MemoryMessage{{Role: "user", MultiContent: []types.ContentPart{
+		types.NewInputAudioContent("UklGRg==", "wav"),
+		types.NewTextContent("What is said here?"),
+	}}}
+
+	for _, p := range []Provider{
+		NewOpenAIProvider("key", "gpt-4o-audio-preview", nil),
+		NewGoogleProvider("key", "gemini-2.5-flash", nil),
+	} {
+		body, err := p.PrepareRequestWithMessages(messages, nil)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `{"input_audio":{"data":"UklGRg==","format":"wav"},"type":"input_audio"}`, p.Name())
+		assert.True(t, p.(AudioSupporter).SupportsAudioInput(), p.Name())
+	}
+
+	assert.False(t, NewDeepSeekProvider("key", "deepseek-chat", nil).(AudioSupporter).SupportsAudioInput())
+	_, ok := NewAnthropicProvider("key", "claude", nil).(AudioSupporter)
+	assert.False(t, ok)
+}
+
+func TestOpenAIAudioOutput(t *testing.T) {
+	p := NewOpenAIProvider("key", "gpt-4o-audio-preview", nil)
+	cfg := config.NewConfig()
+	config.SetAudioOutput("verse", "mp3")(cfg)
+	p.SetDefaultOptions(cfg)
+
+	body, err := p.PrepareRequest("Say hi", nil)
+	require.NoError(t, err)
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, []interface{}{"text", "audio"}, req["modalities"])
+	assert.Equal(t, map[string]interface{}{"voice": "verse", "format": "mp3"}, req["audio"])
+
+	resp := []byte(`{"id":"chatcmpl-1","model":"gpt-4o-audio-preview","choices":[{"index":0,"finish_reason":"stop",
+		"message":{"role":"assistant","content":null,"audio":{"id":"audio_1","data":"SUQz","expires_at":1729000000,"transcript":"Hi!"}}}],
+		"usage":{"prompt_tokens":10,"completion_tokens":30,"total_tokens":40,"completion_tokens_details":{"audio_tokens":25}}}`)
+
+	text, details, err := p.ParseResponseWithUsage(resp)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", text)
+	require.NotNil(t, details.Audio)
+	assert.Equal(t, types.AudioOutput{ID: "audio_1", Data: "SUQz", Transcript: "Hi!", ExpiresAt: 1729000000}, *details.Audio)
+	assert.Equal(t, 25, details.TokenUsage.AudioCompletionTokens)
+
+	text, err = p.ParseResponse(resp)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", text)
+}
+
+func TestAudioOutputOnlyForOpenAIAudioModels(t *testing.T) {
+	cfg := config.NewConfig()
+	config.SetAudioOutput("verse", "mp3")(cfg)
+
+	google := NewGoogleProvider("key", "gemini-2.5-flash-audio", nil).(*GoogleProvider)
+	google.OpenAIProvider.SetDefaultOptions(cfg)
+	deepseek := NewDeepSeekProvider("key", "deepseek-chat", nil).(*DeepSeekProvider)
+	deepseek.OpenAIProvider.SetDefaultOptions(cfg)
+	text := NewOpenAIProvider("key", "gpt-4o-mini", nil)
+	text.SetDefaultOptions(cfg)
+	groq := NewGroqProvider("key", "llama-3.3-70b-versatile", nil)
+	groq.SetDefaultOptions(cfg)
+
+	for _, p := range []Provider{google, deepseek, text, groq} {
+		body, err := p.PrepareRequest("Say hi", nil)
+		require.NoError(t, err)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &req))
+		assert.NotContains(t, req, "modalities", p.Name())
+		assert.NotContains(t, req, "audio", p.Name())
+	}
+}
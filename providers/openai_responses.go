@@ -3,6 +3,7 @@ package providers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -46,6 +47,17 @@ func (p *OpenAIResponsesProvider) Endpoint() string         { return "https://ap
 func (p *OpenAIResponsesProvider) SupportsJSONSchema() bool { return true }
 func (p *OpenAIResponsesProvider) SupportsStreaming() bool  { return true }
 
+// SupportsDocumentSource implements DocumentSupporter: input_file takes inline data,
+// URLs and uploaded file IDs, and plain-text documents are sent as input_text.
+func (p *OpenAIResponsesProvider) SupportsDocumentSource(sourceType string) bool {
+	switch sourceType {
+	case types.DocumentSourceBase64, types.DocumentSourceURL, types.DocumentSourceText, types.DocumentSourceFile:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *OpenAIResponsesProvider) Headers() map[string]string {
 	headers := map[string]string{
 		"Content-Type":  "application/json",
@@ -101,6 +113,29 @@ func (p *OpenAIResponsesProvider) SetOption(key string, value interface{}) {
 	p.options[key] = value
 }
 
+// RequestReasoning asks reasoning models for a summary of their reasoning (reasoning.summary
+// "auto"); OpenAI does not return the reasoning itself. Other models reject the reasoning
+// parameter, and include false needs nothing since summaries are off by default.
+func (p *OpenAIResponsesProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	if !include || !modelNeedsReasoningEffort(p.model) {
+		return
+	}
+	// A per-request reasoning object replaces the provider-level one when the options are
+	// merged, so start from both rather than dropping a configured effort or summary.
+	reasoning := map[string]interface{}{}
+	for _, m := range []map[string]interface{}{p.options, options} {
+		if existing, ok := m["reasoning"].(map[string]interface{}); ok {
+			for k, v := range existing {
+				reasoning[k] = v
+			}
+		}
+	}
+	if _, ok := reasoning["summary"]; !ok {
+		reasoning["summary"] = "auto"
+	}
+	options["reasoning"] = reasoning
+}
+
 func (p *OpenAIResponsesProvider) SetDefaultOptions(cfg *config.Config) {
 	p.SetOption("temperature", cfg.Temperature)
 	p.SetOption("max_tokens", cfg.MaxTokens)
@@ -117,7 +152,7 @@ func (p *OpenAIResponsesProvider) SetDefaultOptions(cfg *config.Config) {
 // should not be blindly copied into the top-level request body.
 var responsesExcludeKeys = []string{
 	"tools", "tool_choice", "strict_tools", "system_prompt",
-	"structured_messages", "images", "stream",
+	"structured_messages", "images", "stream", "stateful",
 }
 
 // buildTools converts utils.Tool slice into the Responses API tools format.
@@ -235,11 +270,8 @@ func (p *OpenAIResponsesProvider) PrepareRequest(prompt string, options map[stri
 	// Check for images
 	images, hasImages := options["images"].([]types.ContentPart)
 	if hasImages && len(images) > 0 {
-		content := []map[string]interface{}{
-			{"type": "input_text", "text": prompt},
-		}
-		// ConvertImagesToOpenAIContent is defined in vision_helpers.go
-		content = append(content, ConvertImagesToOpenAIContent(images)...)
+		// BuildResponsesContentFromParts is defined in vision_helpers.go
+		content := BuildResponsesContentFromParts(append([]types.ContentPart{types.NewTextContent(prompt)}, images...))
 		request["input"] = []map[string]interface{}{
 			{"role": "user", "content": content},
 		}
@@ -255,6 +287,7 @@ func (p *OpenAIResponsesProvider) PrepareRequest(prompt string, options map[stri
 	for k, v := range merged {
 		request[k] = v
 	}
+	p.applyConversationState(request, options, "")
 	applyResponsesVerbosity(request)
 	applyResponsesReasoning(request)
 
@@ -296,6 +329,7 @@ func (p *OpenAIResponsesProvider) PrepareRequestWithSchema(prompt string, option
 	for k, v := range merged {
 		request[k] = v
 	}
+	p.applyConversationState(request, options, "")
 	applyResponsesVerbosity(request)
 	applyResponsesReasoning(request)
 
@@ -315,6 +349,7 @@ func (p *OpenAIResponsesProvider) PrepareRequestWithMessages(messages []types.Me
 		request["instructions"] = systemPrompt
 	}
 
+	messages, previousResponseID := p.statefulMessages(messages, options)
 	input := p.convertMessagesToInput(messages)
 	request["input"] = input
 
@@ -324,6 +359,7 @@ func (p *OpenAIResponsesProvider) PrepareRequestWithMessages(messages []types.Me
 	for k, v := range merged {
 		request[k] = v
 	}
+	p.applyConversationState(request, options, previousResponseID)
 	applyResponsesVerbosity(request)
 	applyResponsesReasoning(request)
 
@@ -358,6 +394,7 @@ func (p *OpenAIResponsesProvider) PrepareRequestWithMessagesAndSchema(messages [
 		request["instructions"] = systemPrompt
 	}
 
+	messages, previousResponseID := p.statefulMessages(messages, options)
 	input := p.convertMessagesToInput(messages)
 	request["input"] = input
 
@@ -367,6 +404,7 @@ func (p *OpenAIResponsesProvider) PrepareRequestWithMessagesAndSchema(messages [
 	for k, v := range merged {
 		request[k] = v
 	}
+	p.applyConversationState(request, options, previousResponseID)
 	applyResponsesVerbosity(request)
 	applyResponsesReasoning(request)
 
@@ -414,8 +452,8 @@ func (p *OpenAIResponsesProvider) convertMessagesToInput(messages []types.Memory
 
 		// Multimodal content
 		if msg.HasMultiContent() {
-			// BuildOpenAIContentFromParts is defined in vision_helpers.go
-			item["content"] = BuildOpenAIContentFromParts(msg.MultiContent)
+			// BuildResponsesContentFromParts is defined in vision_helpers.go
+			item["content"] = BuildResponsesContentFromParts(msg.MultiContent)
 		} else {
 			item["content"] = msg.Content
 		}
@@ -426,6 +464,70 @@ func (p *OpenAIResponsesProvider) convertMessagesToInput(messages []types.Memory
 	return input
 }
 
+// ---------------------------------------------------------------------------
+// Conversation state — previous_response_id chaining
+// ---------------------------------------------------------------------------
+
+// stateful reports whether the "stateful" option is on for this request. In that mode responses
+// are stored server-side and a conversation continues from the last one by ID instead of
+// resending its history.
+func (p *OpenAIResponsesProvider) stateful(options map[string]interface{}) bool {
+	if v, ok := options["stateful"].(bool); ok {
+		return v
+	}
+	v, _ := p.options["stateful"].(bool)
+	return v
+}
+
+// statefulMessages trims a conversation to what the server has not seen yet: in stateful mode
+// it finds the newest assistant message carrying a ResponseID and returns the messages after it
+// together with that ID. Without such a message, outside stateful mode, or when the caller set
+// previous_response_id explicitly, the full history is returned with no ID.
+func (p *OpenAIResponsesProvider) statefulMessages(messages []types.MemoryMessage, options map[string]interface{}) ([]types.MemoryMessage, string) {
+	if !p.stateful(options) || options["previous_response_id"] != nil || p.options["previous_response_id"] != nil {
+		return messages, ""
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && messages[i].ResponseID != "" {
+			return messages[i+1:], messages[i].ResponseID
+		}
+	}
+	return messages, ""
+}
+
+// applyConversationState sets store and previous_response_id for stateful requests.
+func (p *OpenAIResponsesProvider) applyConversationState(request map[string]interface{}, options map[string]interface{}, previousResponseID string) {
+	if !p.stateful(options) {
+		return
+	}
+	request["store"] = true
+	if previousResponseID != "" {
+		request["previous_response_id"] = previousResponseID
+	}
+}
+
+// ErrPreviousResponseNotFound is the cause of the error a request fails with when the Responses
+// API no longer has the response named by its previous_response_id (the stored response expired
+// or was deleted). The conversation can then only continue by resending its full history, so
+// the request is not retried as it is.
+var ErrPreviousResponseNotFound = errors.New("previous response not found")
+
+// PreviousResponseNotFound reports whether body, the body of a failed request, is the Responses
+// API rejecting a previous_response_id it no longer has.
+func PreviousResponseNotFound(body []byte) bool {
+	var response struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	return json.Unmarshal(body, &response) == nil && response.Error.Code == "previous_response_not_found"
+}
+
+// IsPreviousResponseNotFound reports whether err is caused by ErrPreviousResponseNotFound.
+func IsPreviousResponseNotFound(err error) bool {
+	return errors.Is(err, ErrPreviousResponseNotFound)
+}
+
 // ---------------------------------------------------------------------------
 // Response parsing
 // ---------------------------------------------------------------------------
@@ -463,6 +565,13 @@ func (p *OpenAIResponsesProvider) parseResponseInternal(body []byte) (string, *t
 				} `json:"annotations,omitempty"`
 			} `json:"content,omitempty"`
 
+			// reasoning fields
+			Summary []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"summary,omitempty"`
+			EncryptedContent string `json:"encrypted_content,omitempty"`
+
 			// function_call fields
 			Name      string `json:"name,omitempty"`
 			CallID    string `json:"call_id,omitempty"`
@@ -519,6 +628,7 @@ func (p *OpenAIResponsesProvider) parseResponseInternal(body []byte) (string, *t
 		},
 		ServiceTier: response.ServiceTier,
 	}
+	details.ToolOutputs = parseResponsesToolOutputs(body)
 
 	// Collect text, function calls, and web search data
 	var textContent strings.Builder
@@ -535,6 +645,33 @@ func (p *OpenAIResponsesProvider) parseResponseInternal(body []byte) (string, *t
 				}
 			}
 
+		case "reasoning":
+			// OpenAI models expose only a summary (when reasoning.summary is set); open-weight
+			// models served through this API return the full text as reasoning_text content.
+			var texts []string
+			for _, s := range item.Summary {
+				texts = append(texts, s.Text)
+			}
+			for _, c := range item.Content {
+				if c.Type == "reasoning_text" {
+					texts = append(texts, c.Text)
+				}
+			}
+			if len(texts) > 0 || item.EncryptedContent != "" {
+				if details.Reasoning == nil {
+					details.Reasoning = &types.Reasoning{}
+				}
+				if text := strings.Join(texts, "\n\n"); text != "" {
+					if details.Reasoning.Text != "" {
+						details.Reasoning.Text += "\n\n"
+					}
+					details.Reasoning.Text += text
+				}
+				if item.EncryptedContent != "" {
+					details.Reasoning.Encrypted = item.EncryptedContent
+				}
+			}
+
 		case "function_call":
 			// Preserve structured tool call data on ResponseDetails
 			details.ToolCalls = append(details.ToolCalls, types.NewToolCall(item.CallID, item.Name, json.RawMessage(item.Arguments)))
@@ -629,9 +766,109 @@ func (p *OpenAIResponsesProvider) parseResponseInternal(body []byte) (string, *t
 		return strings.Join(functionCalls, "\n"), details, nil
 	}
 
+	// A turn can end on built-in tool items alone, e.g. a computer_call waiting for its
+	// screenshot or an MCP approval request; those are the result.
+	if details.ToolOutputs != nil {
+		return "", details, nil
+	}
+
 	return "", details, fmt.Errorf("no content in response")
 }
 
+// parseResponsesToolOutputs collects the built-in tool items of a response body, plus the
+// file citations on its messages. It returns nil when there are none.
+func parseResponsesToolOutputs(body []byte) *types.ToolOutputs {
+	var response struct {
+		Output []json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+	out := &types.ToolOutputs{}
+	for _, item := range response.Output {
+		if addResponsesToolItem(out, item) {
+			continue
+		}
+		var message struct {
+			Type    string `json:"type"`
+			Content []struct {
+				Annotations []json.RawMessage `json:"annotations"`
+			} `json:"content"`
+		}
+		if json.Unmarshal(item, &message) != nil || message.Type != "message" {
+			continue
+		}
+		for _, c := range message.Content {
+			for _, annotation := range c.Annotations {
+				addResponsesFileCitation(out, annotation)
+			}
+		}
+	}
+	if out.IsEmpty() {
+		return nil
+	}
+	return out
+}
+
+// addResponsesToolItem appends item to out when it is a built-in tool item (other than
+// web_search_call, which has its own path), reporting whether it was one. Items that fail to
+// decode are skipped rather than failing the response.
+func addResponsesToolItem(out *types.ToolOutputs, item json.RawMessage) bool {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(item, &head) != nil {
+		return false
+	}
+	switch head.Type {
+	case "file_search_call":
+		var call types.FileSearchCall
+		if json.Unmarshal(item, &call) == nil {
+			out.FileSearchCalls = append(out.FileSearchCalls, call)
+		}
+	case "code_interpreter_call":
+		var call types.CodeInterpreterCall
+		if json.Unmarshal(item, &call) == nil {
+			out.CodeInterpreterCalls = append(out.CodeInterpreterCalls, call)
+		}
+	case "mcp_call":
+		var call types.MCPCall
+		if json.Unmarshal(item, &call) == nil {
+			out.MCPCalls = append(out.MCPCalls, call)
+		}
+	case "mcp_list_tools":
+		var list types.MCPListTools
+		if json.Unmarshal(item, &list) == nil {
+			out.MCPListTools = append(out.MCPListTools, list)
+		}
+	case "mcp_approval_request":
+		var request types.MCPApprovalRequest
+		if json.Unmarshal(item, &request) == nil {
+			out.MCPApprovalRequests = append(out.MCPApprovalRequests, request)
+		}
+	case "computer_call":
+		var call types.ComputerCall
+		if json.Unmarshal(item, &call) == nil {
+			out.ComputerCalls = append(out.ComputerCalls, call)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// addResponsesFileCitation appends a file_citation or container_file_citation annotation to
+// out; other annotation types (url_citation) are left to the web search path.
+func addResponsesFileCitation(out *types.ToolOutputs, annotation json.RawMessage) {
+	var citation types.FileCitation
+	if json.Unmarshal(annotation, &citation) != nil {
+		return
+	}
+	if citation.Type == "file_citation" || citation.Type == "container_file_citation" {
+		out.FileCitations = append(out.FileCitations, citation)
+	}
+}
+
 // parseResponsesWebSearchOutput handles parsing output[] items containing
 // web_search_call and message items from the Responses API format.
 // This is separate from OpenAIProvider.parseWebSearchResponse (which handles
@@ -765,6 +1002,7 @@ func (p *OpenAIResponsesProvider) PrepareStreamRequest(prompt string, options ma
 	for k, v := range merged {
 		request[k] = v
 	}
+	p.applyConversationState(request, options, "")
 	applyResponsesVerbosity(request)
 	applyResponsesReasoning(request)
 
@@ -885,6 +1123,11 @@ func (p *OpenAIResponsesProvider) ParseStreamResponseRich(chunk []byte) (types.S
 			return types.StreamChunk{}, types.ErrStreamSkip
 		}
 		return types.StreamChunk{Kind: "text", Text: event.Delta}, nil
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		if event.Delta == "" {
+			return types.StreamChunk{}, types.ErrStreamSkip
+		}
+		return types.StreamChunk{Kind: "reasoning", Reasoning: event.Delta}, nil
 	case "response.output_item.added":
 		// A function_call output item opens with its call id + name; arguments
 		// stream in afterward as response.function_call_arguments.delta events.
@@ -906,6 +1149,26 @@ func (p *OpenAIResponsesProvider) ParseStreamResponseRich(chunk []byte) (types.S
 			Index:        event.OutputIndex,
 			ArgsFragment: event.Delta,
 		}}, nil
+	case "response.output_item.done", "response.output_text.annotation.added":
+		// Built-in tool items are reported once complete; file citations as they are added
+		// to the text.
+		var raw struct {
+			Item       json.RawMessage `json:"item"`
+			Annotation json.RawMessage `json:"annotation"`
+		}
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return types.StreamChunk{}, fmt.Errorf("malformed response: %w", err)
+		}
+		out := &types.ToolOutputs{}
+		if event.Type == "response.output_item.done" {
+			addResponsesToolItem(out, raw.Item)
+		} else {
+			addResponsesFileCitation(out, raw.Annotation)
+		}
+		if out.IsEmpty() {
+			return types.StreamChunk{}, types.ErrStreamSkip
+		}
+		return types.StreamChunk{Kind: "tool_output", ToolOutputs: out}, nil
 	case "response.completed", "response.incomplete":
 		// Terminal success events carry status + usage together. "incomplete"
 		// (e.g. max_output_tokens) is a truncated-but-valid response, the analog of
@@ -2,6 +2,8 @@ package providers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"testing"
 
@@ -360,6 +362,124 @@ func TestResponsesParseWebSearch(t *testing.T) {
 	assert.Equal(t, "https://weather.com", citations[0].URL)
 }
 
+func TestResponsesParseBuiltinToolOutputs(t *testing.T) {
+	responseJSON := `{
+		"id": "resp_tools",
+		"status": "completed",
+		"model": "gpt-4.1",
+		"output": [
+			{"type": "file_search_call", "id": "fs_1", "status": "completed", "queries": ["refund policy"],
+			 "results": [{"file_id": "file_1", "filename": "policy.pdf", "score": 0.92, "text": "Refunds within 30 days"}]},
+			{"type": "code_interpreter_call", "id": "ci_1", "status": "completed", "container_id": "cntr_1", "code": "print(2+2)",
+			 "outputs": [{"type": "logs", "logs": "4"}, {"type": "image", "url": "https://files/plot.png"}]},
+			{"type": "mcp_list_tools", "id": "mcpl_1", "server_label": "deepwiki",
+			 "tools": [{"name": "ask_question", "input_schema": {"type": "object"}}]},
+			{"type": "mcp_call", "id": "mcp_1", "server_label": "deepwiki", "name": "ask_question",
+			 "arguments": "{\"q\":\"x\"}", "output": "answer", "error": null},
+			{"type": "mcp_call", "id": "mcp_2", "server_label": "deepwiki", "name": "ask_question",
+			 "error": {"type": "tool_execution_error", "message": "timeout"}},
+			{"type": "message", "role": "assistant", "content": [{"type": "output_text", "text": "Done.",
+			 "annotations": [
+				{"type": "file_citation", "file_id": "file_1", "filename": "policy.pdf", "index": 4},
+				{"type": "container_file_citation", "container_id": "cntr_1", "file_id": "cfile_1", "filename": "plot.png", "start_index": 0, "end_index": 5}]}]}
+		],
+		"usage": {"input_tokens": 10, "output_tokens": 5, "total_tokens": 15}
+	}`
+
+	p := newTestResponsesProvider("gpt-4.1")
+	text, details, err := p.ParseResponseWithUsage([]byte(responseJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "Done.", text)
+	out := details.ToolOutputs
+	require.NotNil(t, out)
+
+	require.Len(t, out.FileSearchCalls, 1)
+	assert.Equal(t, []string{"refund policy"}, out.FileSearchCalls[0].Queries)
+	require.Len(t, out.FileSearchCalls[0].Results, 1)
+	assert.Equal(t, "policy.pdf", out.FileSearchCalls[0].Results[0].Filename)
+	assert.InDelta(t, 0.92, out.FileSearchCalls[0].Results[0].Score, 1e-9)
+
+	require.Len(t, out.CodeInterpreterCalls, 1)
+	ci := out.CodeInterpreterCalls[0]
+	assert.Equal(t, "print(2+2)", ci.Code)
+	assert.Equal(t, "cntr_1", ci.ContainerID)
+	require.Len(t, ci.Outputs, 2)
+	assert.Equal(t, "4", ci.Outputs[0].Logs)
+	assert.Equal(t, "https://files/plot.png", ci.Outputs[1].URL)
+
+	require.Len(t, out.MCPListTools, 1)
+	assert.Equal(t, "ask_question", out.MCPListTools[0].Tools[0].Name)
+	require.Len(t, out.MCPCalls, 2)
+	assert.Equal(t, "answer", out.MCPCalls[0].Output)
+	assert.Empty(t, out.MCPCalls[0].Error)
+	assert.Equal(t, "timeout", out.MCPCalls[1].Error)
+
+	require.Len(t, out.FileCitations, 2)
+	assert.Equal(t, "file_citation", out.FileCitations[0].Type)
+	assert.Equal(t, "cntr_1", out.FileCitations[1].ContainerID)
+	assert.Equal(t, "cfile_1", out.FileCitations[1].FileID)
+}
+
+func TestResponsesParseComputerCallOnly(t *testing.T) {
+	responseJSON := `{
+		"id": "resp_cu",
+		"status": "completed",
+		"model": "computer-use-preview",
+		"output": [
+			{"type": "computer_call", "id": "cu_1", "call_id": "call_1", "status": "completed",
+			 "action": {"type": "click", "x": 120, "y": 48, "button": "left"},
+			 "pending_safety_checks": [{"id": "sc_1", "code": "malicious_instructions", "message": "check"}]},
+			{"type": "mcp_approval_request", "id": "mcpr_1", "server_label": "deepwiki", "name": "ask_question", "arguments": "{}"}
+		]
+	}`
+
+	p := newTestResponsesProvider("computer-use-preview")
+	text, details, err := p.ParseResponseWithUsage([]byte(responseJSON))
+	require.NoError(t, err, "a turn ending on tool items is not an empty response")
+	assert.Empty(t, text)
+	require.NotNil(t, details.ToolOutputs)
+	require.Len(t, details.ToolOutputs.ComputerCalls, 1)
+	call := details.ToolOutputs.ComputerCalls[0]
+	assert.Equal(t, "call_1", call.CallID)
+	assert.Equal(t, types.ComputerAction{Type: "click", X: 120, Y: 48, Button: "left"}, call.Action)
+	require.Len(t, call.PendingSafetyChecks, 1)
+	assert.Equal(t, "sc_1", call.PendingSafetyChecks[0].ID)
+	require.Len(t, details.ToolOutputs.MCPApprovalRequests, 1)
+	assert.Equal(t, "mcpr_1", details.ToolOutputs.MCPApprovalRequests[0].ID)
+}
+
+func TestResponsesParseNoToolOutputs(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4o")
+	_, details, err := p.ParseResponseWithUsage([]byte(`{"output":[{"type":"message","content":[{"type":"output_text","text":"hi"}]}]}`))
+	require.NoError(t, err)
+	assert.Nil(t, details.ToolOutputs)
+}
+
+func TestResponsesStreamToolOutputs(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4.1")
+
+	chunk, err := p.ParseStreamResponseRich([]byte(`{"type":"response.output_item.done","output_index":0,
+		"item":{"type":"code_interpreter_call","id":"ci_1","status":"completed","code":"1+1","outputs":[{"type":"logs","logs":"2"}]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "tool_output", chunk.Kind)
+	require.NotNil(t, chunk.ToolOutputs)
+	require.Len(t, chunk.ToolOutputs.CodeInterpreterCalls, 1)
+	assert.Equal(t, "2", chunk.ToolOutputs.CodeInterpreterCalls[0].Outputs[0].Logs)
+
+	chunk, err = p.ParseStreamResponseRich([]byte(`{"type":"response.output_text.annotation.added",
+		"annotation":{"type":"container_file_citation","container_id":"cntr_1","file_id":"cfile_1","filename":"out.csv"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "tool_output", chunk.Kind)
+	require.Len(t, chunk.ToolOutputs.FileCitations, 1)
+	assert.Equal(t, "out.csv", chunk.ToolOutputs.FileCitations[0].Filename)
+
+	// Completed messages and function calls have their own paths.
+	_, err = p.ParseStreamResponseRich([]byte(`{"type":"response.output_item.done","item":{"type":"message","content":[]}}`))
+	assert.ErrorIs(t, err, types.ErrStreamSkip)
+	_, err = p.ParseStreamResponseRich([]byte(`{"type":"response.output_text.annotation.added","annotation":{"type":"url_citation","url":"https://x"}}`))
+	assert.ErrorIs(t, err, types.ErrStreamSkip)
+}
+
 // ---------------------------------------------------------------------------
 // Option merging
 // ---------------------------------------------------------------------------
@@ -609,6 +729,100 @@ func TestResponsesStreamExcludesStreamKey(t *testing.T) {
 	assert.Nil(t, req["stream"])
 }
 
+// ---------------------------------------------------------------------------
+// Stateful conversations
+// ---------------------------------------------------------------------------
+
+func TestResponsesStatefulChainsFromLastResponse(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4o")
+	p.SetOption("stateful", true)
+
+	messages := []types.MemoryMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi", ResponseID: "resp_1"},
+		{Role: "user", Content: "Weather?"},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "call_1", Type: "function"}}, ResponseID: "resp_2"},
+		{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+	}
+	body, err := p.PrepareRequestWithMessages(messages, map[string]interface{}{"system_prompt": "Be brief"})
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, "resp_2", req["previous_response_id"])
+	assert.Equal(t, true, req["store"])
+	assert.Nil(t, req["stateful"], "the mode switch is not an API parameter")
+	assert.Equal(t, "Be brief", req["instructions"], "instructions are not carried over by previous_response_id")
+
+	input := req["input"].([]interface{})
+	require.Len(t, input, 1, "only messages after the chained response are sent")
+	assert.Equal(t, "function_call_output", input[0].(map[string]interface{})["type"])
+}
+
+func TestResponsesStatefulFirstTurnSendsHistory(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4o")
+
+	messages := []types.MemoryMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi"},
+		{Role: "user", Content: "Again"},
+	}
+	body, err := p.PrepareRequestWithMessages(messages, map[string]interface{}{"stateful": true})
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Nil(t, req["previous_response_id"])
+	assert.Equal(t, true, req["store"])
+	assert.Len(t, req["input"], 3)
+}
+
+func TestResponsesStatelessIgnoresResponseIDs(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4o")
+
+	messages := []types.MemoryMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi", ResponseID: "resp_1"},
+		{Role: "user", Content: "Again"},
+	}
+	body, err := p.PrepareRequestWithMessages(messages, nil)
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Nil(t, req["previous_response_id"])
+	assert.Nil(t, req["store"])
+	assert.Len(t, req["input"], 3)
+}
+
+func TestResponsesStatefulExplicitPreviousResponseID(t *testing.T) {
+	p := newTestResponsesProvider("gpt-4o")
+
+	messages := []types.MemoryMessage{
+		{Role: "assistant", Content: "Hi", ResponseID: "resp_1"},
+		{Role: "user", Content: "Again"},
+	}
+	body, err := p.PrepareRequestWithMessages(messages, map[string]interface{}{"stateful": true, "previous_response_id": "resp_0"})
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, "resp_0", req["previous_response_id"])
+	assert.Len(t, req["input"], 2)
+}
+
+func TestPreviousResponseNotFound(t *testing.T) {
+	body := `{"error":{"message":"Previous response with id 'resp_1' not found.","type":"invalid_request_error","param":"previous_response_id","code":"previous_response_not_found"}}`
+	assert.True(t, PreviousResponseNotFound([]byte(body)))
+	assert.False(t, PreviousResponseNotFound([]byte(`{"error":{"message":"Previous response with id 'resp_1' not found.","code":"rate_limit_exceeded"}}`)),
+		"only the error code identifies the error")
+	assert.False(t, PreviousResponseNotFound([]byte("not json")))
+
+	assert.True(t, IsPreviousResponseNotFound(fmt.Errorf("failed: %w", ErrPreviousResponseNotFound)))
+	assert.False(t, IsPreviousResponseNotFound(errors.New("API error: status code 404: "+body)))
+	assert.False(t, IsPreviousResponseNotFound(nil))
+}
+
 // ---------------------------------------------------------------------------
 // Model detection helpers
 // ---------------------------------------------------------------------------
@@ -41,6 +41,8 @@ func parseOpenAICompatStreamChunk(chunk []byte) (types.StreamChunk, error) {
 						Arguments string `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				ReasoningContent compatReasoningText `json:"reasoning_content"`
+				Reasoning        compatReasoningText `json:"reasoning"`
 			} `json:"delta"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -89,10 +91,45 @@ func parseOpenAICompatStreamChunk(chunk []byte) (types.StreamChunk, error) {
 		}
 		return finish, nil
 	}
+	reasoning := compatReasoning(choice.Delta.ReasoningContent, choice.Delta.Reasoning)
 	if choice.Delta.Content == "" {
-		return types.StreamChunk{}, types.ErrStreamSkip
+		if reasoning == nil {
+			return types.StreamChunk{}, types.ErrStreamSkip
+		}
+		return types.StreamChunk{Kind: "reasoning", Reasoning: reasoning.Text, Model: response.Model, ServiceTier: response.ServiceTier}, nil
+	}
+	out := types.StreamChunk{Kind: "text", Text: choice.Delta.Content, Model: response.Model, ServiceTier: response.ServiceTier}
+	if reasoning != nil {
+		out.Reasoning = reasoning.Text
+	}
+	return out, nil
+}
+
+// compatReasoningText decodes the reasoning field OpenAI-compatible backends add to a message or
+// delta. It is a string everywhere this package reads it; any other shape is ignored rather than
+// failing the whole response over an optional field.
+type compatReasoningText string
+
+func (t *compatReasoningText) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*t = compatReasoningText(s)
+	}
+	return nil
+}
+
+// compatReasoning normalizes an OpenAI-compatible reasoning field pair: reasoning_content
+// (DeepSeek, vLLM, Qwen, LM Studio) or reasoning (OpenRouter, Groq's parsed format, newer vLLM).
+// Backends send one or the other; nil when both are empty.
+func compatReasoning(reasoningContent, reasoning compatReasoningText) *types.Reasoning {
+	text := string(reasoningContent)
+	if text == "" {
+		text = string(reasoning)
+	}
+	if text == "" {
+		return nil
 	}
-	return types.StreamChunk{Kind: "text", Text: choice.Delta.Content, Model: response.Model, ServiceTier: response.ServiceTier}, nil
+	return &types.Reasoning{Text: text}
 }
 
 // openAICompatUsage is the usage object shared by the OpenAI-shaped APIs, including the cached-input
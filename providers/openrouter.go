@@ -101,6 +101,50 @@ func (p *OpenRouterProvider) SetDefaultOptions(config *config.Config) {
 	}
 }
 
+// RequestReasoning sets OpenRouter's include_reasoning, which returns (or withholds) the
+// upstream model's reasoning in the message's reasoning field whatever the model.
+func (p *OpenRouterProvider) RequestReasoning(include bool, options map[string]interface{}) {
+	options["include_reasoning"] = include
+}
+
+// openRouterReasoningDetail is one entry of OpenRouter's reasoning_details, which carries the
+// upstream reasoning in its original form: plain or signed text, a summary, or encrypted data.
+type openRouterReasoningDetail struct {
+	Type      string `json:"type"` // reasoning.text, reasoning.summary or reasoning.encrypted
+	Text      string `json:"text"`
+	Summary   string `json:"summary"`
+	Signature string `json:"signature"`
+	Data      string `json:"data"`
+}
+
+// openRouterReasoning normalizes the reasoning string and its details. The text comes from the
+// reasoning field when set, else from the text and summary details; signature and encrypted
+// data only exist in the details.
+func openRouterReasoning(reasoning compatReasoningText, details []openRouterReasoningDetail) *types.Reasoning {
+	r := &types.Reasoning{Text: string(reasoning)}
+	var texts []string
+	for _, d := range details {
+		switch d.Type {
+		case "reasoning.text":
+			texts = append(texts, d.Text)
+			if d.Signature != "" {
+				r.Signature = d.Signature
+			}
+		case "reasoning.summary":
+			texts = append(texts, d.Summary)
+		case "reasoning.encrypted":
+			r.Encrypted = d.Data
+		}
+	}
+	if r.Text == "" {
+		r.Text = strings.Join(texts, "")
+	}
+	if r.IsEmpty() {
+		return nil
+	}
+	return r
+}
+
 // SupportsJSONSchema indicates whether this provider supports JSON schema validation.
 // OpenRouter supports JSON schema validation when using supported models.
 func (p *OpenRouterProvider) SupportsJSONSchema() bool {
@@ -528,6 +572,8 @@ func (p *OpenRouterProvider) ParseResponseWithUsage(body []byte) (string, *types
 						Arguments json.RawMessage `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				Reasoning        compatReasoningText         `json:"reasoning"`
+				ReasoningDetails []openRouterReasoningDetail `json:"reasoning_details"`
 			} `json:"message"`
 			FinishReason       string `json:"finish_reason"`
 			NativeFinishReason string `json:"native_finish_reason"`
@@ -562,13 +608,14 @@ func (p *OpenRouterProvider) ParseResponseWithUsage(body []byte) (string, *types
 			p.logger.Info("Model used", "requested", p.model, "actual", chatResp.Model)
 		}
 
+		message := chatResp.Choices[0].Message
 		details := &types.ResponseDetails{
 			ID:         chatResp.ID,
 			Model:      chatResp.Model,
 			TokenUsage: *chatResp.Usage.normalize(),
+			Reasoning:  openRouterReasoning(message.Reasoning, message.ReasoningDetails),
 		}
 
-		message := chatResp.Choices[0].Message
 		var parts []string
 		if message.Content != "" {
 			parts = append(parts, message.Content)
@@ -771,6 +818,7 @@ func (p *OpenRouterProvider) ParseStreamResponseRich(chunk []byte) (types.Stream
 						Arguments string `json:"arguments"`
 					} `json:"function"`
 				} `json:"tool_calls"`
+				Reasoning compatReasoningText `json:"reasoning"`
 			} `json:"delta"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -809,7 +857,10 @@ func (p *OpenRouterProvider) ParseStreamResponseRich(chunk []byte) (types.Stream
 			return types.StreamChunk{Kind: "tool_call_delta", ToolCallDelta: deltas[0], ExtraToolCallDeltas: deltas[1:], Model: resp.Model}, nil
 		}
 		if choice.Delta.Content != "" {
-			return types.StreamChunk{Kind: "text", Text: choice.Delta.Content, Model: resp.Model}, nil
+			return types.StreamChunk{Kind: "text", Text: choice.Delta.Content, Reasoning: string(choice.Delta.Reasoning), Model: resp.Model}, nil
+		}
+		if choice.Delta.Reasoning != "" {
+			return types.StreamChunk{Kind: "reasoning", Reasoning: string(choice.Delta.Reasoning), Model: resp.Model}, nil
 		}
 		// Finish reason does not end the stream — a usage chunk and [DONE] follow.
 		// Attach usage if this same chunk also carries it.
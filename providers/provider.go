@@ -96,6 +96,47 @@ type Provider interface {
 	ParseStreamResponse(chunk []byte) (string, error)
 }
 
+// DocumentSupporter is an optional interface implemented by providers that accept document and
+// file content parts (types.ContentTypeDocument and types.ContentTypeFile). Providers that do not
+// implement it are treated as accepting no documents at all.
+type DocumentSupporter interface {
+	// SupportsDocumentSource reports whether a document with the given source type
+	// ("base64", "url", "text", or "file") can be sent to this provider.
+	SupportsDocumentSource(sourceType string) bool
+}
+
+// AudioSupporter is an optional interface implemented by providers that accept audio input
+// content parts (types.ContentTypeInputAudio). Providers that do not implement it are treated
+// as text-only.
+type AudioSupporter interface {
+	// SupportsAudioInput reports whether audio content parts can be sent to this provider.
+	SupportsAudioInput() bool
+}
+
+// ReasoningRequester is an optional interface implemented by providers whose API returns the
+// model's reasoning only when asked (or can be told to withhold it). Providers that do not
+// implement it return whatever reasoning their models emit unprompted, as DeepSeek's reasoner
+// and vLLM with a reasoning parser do.
+type ReasoningRequester interface {
+	// RequestReasoning sets the provider's own option keys on a request's options so that the
+	// response includes (include true) or omits the model's reasoning.
+	RequestReasoning(include bool, options map[string]interface{})
+}
+
+// StreamEndpointer is an optional interface implemented by providers that stream from a different
+// URL than they generate from, as Anthropic models on Vertex AI do (:streamRawPredict). Providers
+// that do not implement it stream from Endpoint.
+type StreamEndpointer interface {
+	StreamEndpoint() string
+}
+
+// CredentialSupplier is an optional interface implemented by providers that can obtain their own
+// credentials, such as Vertex AI's service-account tokens. It is consulted only when the config
+// has neither an API key nor a credential source for the provider.
+type CredentialSupplier interface {
+	CredentialSource() (config.CredentialSource, error)
+}
+
 // ProviderType represents the general type of LLM API
 type ProviderType string
 
@@ -182,6 +223,30 @@ func normalizeSchema(schema interface{}) (interface{}, error) {
 // Each provider implementation must provide a constructor function of this type.
 type ProviderConstructor func(apiKey, model string, extraHeaders map[string]string) Provider
 
+// builtinProviders maps the name of each provider implemented in this package to its constructor.
+// It is a function because the constructors themselves reach the default registry.
+func builtinProviders() map[string]ProviderConstructor {
+	return map[string]ProviderConstructor{
+		"openai":           NewOpenAIProvider,
+		"anthropic":        NewAnthropicProvider,
+		"groq":             NewGroqProvider,
+		"ollama":           NewOllamaProvider,
+		"mistral":          NewMistralProvider,
+		"cohere":           NewCohereProvider,
+		"deepseek":         NewDeepSeekProvider,
+		"google-openai":    NewGoogleProvider,
+		"azure-openai":     NewAzureOpenAIProvider,
+		"aliyun":           NewAliyunProvider,
+		"lmstudio":         NewLMStudioProvider,
+		"openrouter":       NewOpenRouterProvider,
+		"lambda":           NewLambdaProvider,
+		"bedrock":          NewBedrockProvider,
+		"vllm":             NewVLLMProvider,
+		"openai-responses": NewOpenAIResponsesProvider,
+		"vertex":           NewVertexProvider,
+	}
+}
+
 // ProviderRegistry manages the registration and retrieval of LLM providers.
 // It provides thread-safe access to provider constructors and supports
 // dynamic provider registration.
@@ -203,6 +268,7 @@ type ProviderRegistry struct {
 //   - "cohere": Cohere's models
 //   - "deepseek": DeepSeek's models
 //   - "google-openai": Google's Gemini models using OpenAI compatible API
+//   - "vertex": Gemini and Claude models on Google Cloud Vertex AI
 //
 // Example usage:
 //
@@ -218,24 +284,7 @@ func NewProviderRegistry(providerNames ...string) *ProviderRegistry {
 	}
 
 	// Register all known providers
-	knownProviders := map[string]ProviderConstructor{
-		"openai":           NewOpenAIProvider,
-		"anthropic":        NewAnthropicProvider,
-		"groq":             NewGroqProvider,
-		"ollama":           NewOllamaProvider,
-		"mistral":          NewMistralProvider,
-		"cohere":           NewCohereProvider,
-		"deepseek":         NewDeepSeekProvider,
-		"google-openai":    NewGoogleProvider,
-		"azure-openai":     NewAzureOpenAIProvider,
-		"aliyun":           NewAliyunProvider,
-		"lmstudio":         NewLMStudioProvider,
-		"openrouter":       NewOpenRouterProvider,
-		"lambda":           NewLambdaProvider,
-		"bedrock":          NewBedrockProvider,
-		"vllm":             NewVLLMProvider,
-		"openai-responses": NewOpenAIResponsesProvider,
-	}
+	knownProviders := builtinProviders()
 
 	// Standard provider configurations
 	standardConfigs := map[string]ProviderConfig{
@@ -379,6 +428,36 @@ func NewProviderRegistry(providerNames ...string) *ProviderRegistry {
 			SupportsSchema:    true,
 			SupportsStreaming: true,
 		},
+		"mistral": {
+			Name:              "mistral",
+			Type:              TypeOpenAI,
+			Endpoint:          "https://api.mistral.ai/v1/chat/completions",
+			AuthHeader:        "Authorization",
+			AuthPrefix:        "Bearer ",
+			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
+			SupportsSchema:    true,
+			SupportsStreaming: true,
+		},
+		"cohere": {
+			Name:              "cohere",
+			Type:              TypeCustom,
+			Endpoint:          "https://api.cohere.com/v2/chat",
+			AuthHeader:        "Authorization",
+			AuthPrefix:        "Bearer ",
+			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
+			SupportsSchema:    true,
+			SupportsStreaming: true,
+		},
+		"vertex": {
+			Name:              "vertex",
+			Type:              TypeCustom,
+			Endpoint:          "", // Built from the project, location and model
+			AuthHeader:        "Authorization",
+			AuthPrefix:        "Bearer ",
+			RequiredHeaders:   map[string]string{"Content-Type": "application/json"},
+			SupportsSchema:    true,
+			SupportsStreaming: true,
+		},
 	}
 
 	// Store standard configs
@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+func init() {
+	config.SetProvidersFileLoader(RegisterProvidersFile)
+}
+
+// ProvidersFile is the document read from a providers file (see LoadProvidersFile):
+//
+//	providers:
+//	  - name: my-gateway
+//	    type: openai
+//	    endpoint: https://gateway.example.com/v1/chat/completions
+//	    auth_header: Authorization
+//	    auth_prefix: "Bearer "
+//	    api_key: ${GATEWAY_TOKEN}
+//	    headers:
+//	      X-Team: research
+//	    supports_schema: true
+//	    supports_streaming: true
+type ProvidersFile struct {
+	Providers []ProviderDefinition `json:"providers" yaml:"providers"`
+}
+
+// ProviderDefinition describes one provider in a providers file. It is the declarative form of
+// the ProviderConfig passed to RegisterGenericProvider, plus an optional API key.
+type ProviderDefinition struct {
+	// Name is the name used with SetProvider. It may not be a built-in provider's name.
+	Name string `json:"name" yaml:"name"`
+
+	// Type is the API format: "openai", "anthropic" or "claude".
+	Type ProviderType `json:"type" yaml:"type"`
+
+	// Endpoint is the full request URL. A "{model}" placeholder is replaced with the model.
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+
+	// AuthHeader and AuthPrefix form the authentication header: AuthHeader: AuthPrefix+key.
+	// Leave AuthHeader empty for servers that need no authentication.
+	AuthHeader string `json:"auth_header" yaml:"auth_header"`
+	AuthPrefix string `json:"auth_prefix" yaml:"auth_prefix"`
+
+	// APIKey is used when the environment supplies no <NAME>_API_KEY for this provider.
+	// Write it as a reference such as ${GATEWAY_TOKEN} rather than a literal secret.
+	APIKey string `json:"api_key" yaml:"api_key"`
+
+	// Headers are sent with every request. Content-Type defaults to application/json.
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	// EndpointParams are added to the endpoint's query string.
+	EndpointParams map[string]string `json:"endpoint_params" yaml:"endpoint_params"`
+
+	SupportsSchema    bool `json:"supports_schema" yaml:"supports_schema"`
+	SupportsStreaming bool `json:"supports_streaming" yaml:"supports_streaming"`
+}
+
+// ProviderConfig returns the registry configuration for the definition.
+func (d ProviderDefinition) ProviderConfig() ProviderConfig {
+	headers := make(map[string]string, len(d.Headers)+1)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	if _, ok := headers["Content-Type"]; !ok {
+		headers["Content-Type"] = "application/json"
+	}
+	return ProviderConfig{
+		Name:              d.Name,
+		Type:              d.Type,
+		Endpoint:          d.Endpoint,
+		AuthHeader:        d.AuthHeader,
+		AuthPrefix:        d.AuthPrefix,
+		RequiredHeaders:   headers,
+		EndpointParams:    d.EndpointParams,
+		SupportsSchema:    d.SupportsSchema,
+		SupportsStreaming: d.SupportsStreaming,
+	}
+}
+
+// LoadProvidersFile reads provider definitions from a YAML file, or a JSON file when the name
+// ends in ".json". Environment variables written as $VAR or ${VAR} are expanded in every string
+// value ("$$" is a literal "$"). Unknown fields, unset variables and invalid definitions are
+// errors; all problems in the file are reported together.
+func LoadProvidersFile(path string) ([]ProviderDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers file: %w", err)
+	}
+
+	var file ProvidersFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse providers file %s: %w", path, err)
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for i := range file.Providers {
+		def := &file.Providers[i]
+		defProblems := append(expandDefinition(def), validateDefinition(def)...)
+		label := fmt.Sprintf("providers[%d]", i)
+		if def.Name != "" {
+			label = fmt.Sprintf("providers[%d] (%s)", i, def.Name)
+		}
+		for _, p := range defProblems {
+			problems = append(problems, label+": "+p)
+		}
+		if def.Name != "" {
+			if seen[def.Name] {
+				problems = append(problems, label+": duplicate name")
+			}
+			seen[def.Name] = true
+		}
+	}
+	if len(file.Providers) == 0 {
+		problems = append(problems, "no providers defined (expected a top-level \"providers\" list)")
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid providers file %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return file.Providers, nil
+}
+
+// RegisterProvidersFile loads a providers file and registers each provider in the default
+// registry with RegisterGenericProvider. It returns the API keys the file supplies, keyed by
+// provider name; a provider without auth_header gets a placeholder key, as local servers do in
+// NewLLM, so that client construction does not reject it. Nothing is registered if the file has
+// any problem.
+//
+// LoadConfig calls it when LLM_PROVIDERS_FILE is set.
+func RegisterProvidersFile(path string) (map[string]string, error) {
+	defs, err := LoadProvidersFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	for _, def := range defs {
+		RegisterGenericProvider(def.Name, def.ProviderConfig())
+		switch {
+		case def.APIKey != "":
+			keys[def.Name] = def.APIKey
+		case def.AuthHeader == "":
+			keys[def.Name] = def.Name + "-local"
+		}
+	}
+	return keys, nil
+}
+
+// expandDefinition expands environment variables in the definition's string values in place
+// and returns a problem for each unset variable.
+func expandDefinition(def *ProviderDefinition) []string {
+	missing := make(map[string]bool)
+	expand := func(s string) string {
+		return config.ExpandEnv(s, missing)
+	}
+
+	def.Name = expand(def.Name)
+	def.Type = ProviderType(expand(string(def.Type)))
+	def.Endpoint = expand(def.Endpoint)
+	def.AuthHeader = expand(def.AuthHeader)
+	def.AuthPrefix = expand(def.AuthPrefix)
+	def.APIKey = expand(def.APIKey)
+	for k, v := range def.Headers {
+		def.Headers[k] = expand(v)
+	}
+	for k, v := range def.EndpointParams {
+		def.EndpointParams[k] = expand(v)
+	}
+	return config.MissingEnvProblems(missing)
+}
+
+// validateDefinition returns what is wrong with an expanded definition.
+func validateDefinition(def *ProviderDefinition) []string {
+	var problems []string
+	if def.Name == "" {
+		problems = append(problems, "name is required")
+	} else if _, builtin := builtinProviders()[def.Name]; builtin {
+		problems = append(problems, fmt.Sprintf("name %q is a built-in provider", def.Name))
+	}
+
+	switch def.Type {
+	case TypeOpenAI, TypeAnthropic, TypeClaude:
+	case "":
+		problems = append(problems, "type is required (openai, anthropic or claude)")
+	default:
+		problems = append(problems, fmt.Sprintf("type %q is not supported (openai, anthropic or claude)", def.Type))
+	}
+
+	if def.Endpoint == "" {
+		problems = append(problems, "endpoint is required")
+	} else if u, err := url.Parse(def.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("endpoint %q is not an http(s) URL", def.Endpoint))
+	}
+
+	if def.AuthHeader == "" && def.AuthPrefix != "" {
+		problems = append(problems, "auth_prefix is set without auth_header")
+	}
+	if def.AuthHeader == "" && def.APIKey != "" {
+		problems = append(problems, "api_key is set without auth_header")
+	}
+	return problems
+}
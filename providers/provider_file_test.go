@@ -0,0 +1,158 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+func writeProvidersFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadProvidersFileYAML(t *testing.T) {
+	t.Setenv("GATEWAY_HOST", "gateway.example.com")
+	t.Setenv("GATEWAY_TOKEN", "secret")
+	path := writeProvidersFile(t, "providers.yaml", `
+providers:
+  - name: file-gateway
+    type: openai
+    endpoint: https://${GATEWAY_HOST}/v1/chat/completions
+    auth_header: Authorization
+    auth_prefix: "Bearer "
+    api_key: ${GATEWAY_TOKEN}
+    headers:
+      X-Team: research
+      X-Price: "$$5"
+    supports_schema: true
+  - name: file-local
+    type: anthropic
+    endpoint: http://localhost:9000/v1/messages
+`)
+
+	defs, err := LoadProvidersFile(path)
+	require.NoError(t, err)
+	require.Len(t, defs, 2)
+
+	gateway := defs[0].ProviderConfig()
+	assert.Equal(t, "file-gateway", gateway.Name)
+	assert.Equal(t, TypeOpenAI, gateway.Type)
+	assert.Equal(t, "https://gateway.example.com/v1/chat/completions", gateway.Endpoint)
+	assert.Equal(t, map[string]string{"X-Team": "research", "X-Price": "$5", "Content-Type": "application/json"}, gateway.RequiredHeaders)
+	assert.True(t, gateway.SupportsSchema)
+	assert.False(t, gateway.SupportsStreaming)
+	assert.Equal(t, "secret", defs[0].APIKey)
+
+	assert.Equal(t, TypeAnthropic, defs[1].Type)
+	assert.Empty(t, defs[1].AuthHeader)
+}
+
+func TestLoadProvidersFileJSON(t *testing.T) {
+	path := writeProvidersFile(t, "providers.json", `{"providers": [
+		{"name": "file-json", "type": "openai", "endpoint": "https://json.example.com/v1/chat/completions",
+		 "auth_header": "api-key", "supports_streaming": true}
+	]}`)
+
+	defs, err := LoadProvidersFile(path)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	assert.Equal(t, "api-key", defs[0].AuthHeader)
+	assert.True(t, defs[0].SupportsStreaming)
+}
+
+func TestLoadProvidersFileErrors(t *testing.T) {
+	t.Run("all problems are reported", func(t *testing.T) {
+		path := writeProvidersFile(t, "providers.yaml", `
+providers:
+  - name: openai
+    type: openai
+    endpoint: https://api.example.com
+  - type: gemini
+    endpoint: localhost:8080
+    auth_prefix: "Bearer "
+  - name: dup
+    type: openai
+    endpoint: https://a.example.com
+    headers:
+      X-Key: ${GOLLM_TEST_UNSET_VARIABLE}
+  - name: dup
+    type: claude
+    endpoint: https://b.example.com
+    api_key: literal
+`)
+		_, err := LoadProvidersFile(path)
+		require.Error(t, err)
+		msg := err.Error()
+		assert.Contains(t, msg, `providers[0] (openai): name "openai" is a built-in provider`)
+		assert.Contains(t, msg, "providers[1]: name is required")
+		assert.Contains(t, msg, `providers[1]: type "gemini" is not supported`)
+		assert.Contains(t, msg, `providers[1]: endpoint "localhost:8080" is not an http(s) URL`)
+		assert.Contains(t, msg, "providers[1]: auth_prefix is set without auth_header")
+		assert.Contains(t, msg, "providers[2] (dup): environment variable GOLLM_TEST_UNSET_VARIABLE is not set")
+		assert.Contains(t, msg, "providers[3] (dup): duplicate name")
+		assert.Contains(t, msg, "providers[3] (dup): api_key is set without auth_header")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		path := writeProvidersFile(t, "providers.yml", "providers:\n  - name: x\n    endpoint_url: https://x.example.com\n")
+		_, err := LoadProvidersFile(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "endpoint_url")
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		path := writeProvidersFile(t, "providers.json", `{"providers": []}`)
+		_, err := LoadProvidersFile(path)
+		assert.ErrorContains(t, err, "no providers defined")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadProvidersFile(filepath.Join(t.TempDir(), "absent.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadConfigRegistersProvidersFile(t *testing.T) {
+	t.Setenv("FILE_GATEWAY_TOKEN", "from-file")
+	path := writeProvidersFile(t, "providers.yaml", `
+providers:
+  - name: file-registered
+    type: openai
+    endpoint: https://registered.example.com/v1/chat/completions
+    auth_header: Authorization
+    auth_prefix: "Bearer "
+    api_key: ${FILE_GATEWAY_TOKEN}
+    supports_streaming: true
+  - name: file-keyless
+    type: openai
+    endpoint: http://localhost:8123/v1/chat/completions
+`)
+	t.Setenv("LLM_PROVIDERS_FILE", path)
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.APIKeys["file-registered"])
+
+	provider, err := GetDefaultRegistry().Get("file-registered", cfg.APIKeys["file-registered"], "gateway-model", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://registered.example.com/v1/chat/completions", provider.Endpoint())
+	assert.Equal(t, "Bearer from-file", provider.Headers()["Authorization"])
+	assert.True(t, provider.SupportsStreaming())
+
+	assert.Equal(t, "file-keyless-local", cfg.APIKeys["file-keyless"])
+	keyless, err := GetDefaultRegistry().Get("file-keyless", cfg.APIKeys["file-keyless"], "local-model", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, keyless.Headers(), "", "no auth header is sent without auth_header")
+
+	t.Setenv("LLM_PROVIDERS_FILE", writeProvidersFile(t, "bad.yaml", "providers:\n  - name: broken\n"))
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "endpoint is required")
+}
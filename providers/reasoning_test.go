@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompatReasoningParsed covers both names OpenAI-compatible servers use for the reasoning
+// field: DeepSeek and vLLM send reasoning_content, Groq and OpenRouter send reasoning.
+func TestCompatReasoningParsed(t *testing.T) {
+	p := NewOpenAIProvider("k", "deepseek-reasoner", nil)
+	for name, body := range map[string]string{
+		"reasoning_content": `{"choices":[{"message":{"content":"4","reasoning_content":"2+2"}}]}`,
+		"reasoning":         `{"choices":[{"message":{"content":"4","reasoning":"2+2"}}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			text, details, err := p.ParseResponseWithUsage([]byte(body))
+			require.NoError(t, err)
+			assert.Equal(t, "4", text)
+			require.NotNil(t, details.Reasoning)
+			assert.Equal(t, "2+2", details.Reasoning.Text)
+		})
+	}
+
+	_, details, err := p.ParseResponseWithUsage([]byte(`{"choices":[{"message":{"content":"4","reasoning":{"effort":"low"}}}]}`))
+	require.NoError(t, err)
+	assert.Nil(t, details.Reasoning, "a non-string reasoning field is not reasoning text")
+}
+
+func TestCompatReasoningStreamed(t *testing.T) {
+	chunk, err := parseOpenAICompatStreamChunk([]byte(`{"choices":[{"delta":{"reasoning_content":"thinking"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "reasoning", chunk.Kind)
+	assert.Equal(t, "thinking", chunk.Reasoning)
+	assert.Empty(t, chunk.Text)
+
+	chunk, err = parseOpenAICompatStreamChunk([]byte(`{"choices":[{"delta":{"content":"hi"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "hi", chunk.Text)
+	assert.Empty(t, chunk.Reasoning)
+}
+
+func TestOpenRouterReasoningDetails(t *testing.T) {
+	p := NewOpenRouterProvider("k", "anthropic/claude-sonnet-4", nil)
+	body := `{"choices":[{"message":{"content":"ok","reasoning_details":[
+		{"type":"reasoning.text","text":"step one","signature":"sig"},
+		{"type":"reasoning.encrypted","data":"opaque"}]}}]}`
+	_, details, err := p.ParseResponseWithUsage([]byte(body))
+	require.NoError(t, err)
+	require.NotNil(t, details.Reasoning)
+	assert.Equal(t, "step one", details.Reasoning.Text)
+	assert.Equal(t, "sig", details.Reasoning.Signature)
+	assert.Equal(t, "opaque", details.Reasoning.Encrypted)
+}
+
+func TestOllamaThinking(t *testing.T) {
+	p := NewOllamaProvider("", "qwen3", nil).(*OllamaProvider)
+	_, details, err := p.ParseResponseWithUsage([]byte(`{"response":"hi","thinking":"greet","done":true}`))
+	require.NoError(t, err)
+	require.NotNil(t, details.Reasoning)
+	assert.Equal(t, "greet", details.Reasoning.Text)
+
+	chunk, err := p.ParseStreamResponseRich([]byte(`{"response":"","thinking":"hm","done":false}`))
+	require.NoError(t, err)
+	assert.Equal(t, "reasoning", chunk.Kind)
+	assert.Equal(t, "hm", chunk.Reasoning)
+}
+
+func TestGeminiThoughtsSplit(t *testing.T) {
+	p := NewGoogleProvider("k", "gemini-2.5-flash", nil)
+	text, details, err := p.ParseResponseWithUsage([]byte(`{"choices":[{"message":{"content":"<thought>weigh it</thought>\nanswer"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "answer", text)
+	require.NotNil(t, details.Reasoning)
+	assert.Equal(t, "weigh it", details.Reasoning.Text)
+
+	text, err = p.ParseResponse([]byte(`{"choices":[{"message":{"content":"no <thought> here"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "no <thought> here", text)
+}
+
+func TestResponsesReasoning(t *testing.T) {
+	p := NewOpenAIResponsesProvider("k", "o4-mini", nil).(*OpenAIResponsesProvider)
+	body := `{"output":[
+		{"type":"reasoning","summary":[{"type":"summary_text","text":"plan"}],"encrypted_content":"enc"},
+		{"type":"message","content":[{"type":"output_text","text":"done"}]}]}`
+	text, details, err := p.ParseResponseWithUsage([]byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, "done", text)
+	require.NotNil(t, details.Reasoning)
+	assert.Equal(t, "plan", details.Reasoning.Text)
+	assert.Equal(t, "enc", details.Reasoning.Encrypted)
+
+	chunk, err := p.ParseStreamResponseRich([]byte(`{"type":"response.reasoning_summary_text.delta","delta":"pl"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "reasoning", chunk.Kind)
+	assert.Equal(t, "pl", chunk.Reasoning)
+}
+
+func TestAnthropicThinkingReasoning(t *testing.T) {
+	p := NewAnthropicProvider("k", "claude-sonnet-4-5", nil)
+	body := `{"content":[
+		{"type":"thinking","thinking":"first","signature":"s1"},
+		{"type":"redacted_thinking","data":"hidden"},
+		{"type":"text","text":"out"}],"usage":{"input_tokens":1,"output_tokens":1}}`
+	_, details, err := p.ParseResponseWithUsage([]byte(body))
+	require.NoError(t, err)
+	require.NotNil(t, details.Reasoning)
+	assert.Equal(t, "first", details.Reasoning.Text)
+	assert.Equal(t, "s1", details.Reasoning.Signature)
+	assert.Equal(t, "hidden", details.Reasoning.Encrypted)
+}
+
+func TestRequestReasoningOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider Provider
+		include  bool
+		want     map[string]interface{}
+	}{
+		{"anthropic", NewAnthropicProvider("k", "claude-sonnet-4-5", nil), true, map[string]interface{}{"reasoning_effort": "medium"}},
+		{"anthropic off", NewAnthropicProvider("k", "claude-sonnet-4-5", nil), false, map[string]interface{}{}},
+		{"gemini", NewGoogleProvider("k", "gemini-2.5-flash", nil), true, map[string]interface{}{"include_thoughts": true}},
+		{"openrouter", NewOpenRouterProvider("k", "x/y", nil), false, map[string]interface{}{"include_reasoning": false}},
+		{"groq", NewGroqProvider("k", "qwen/qwen3-32b", nil), true, map[string]interface{}{"include_reasoning": true}},
+		{"ollama", NewOllamaProvider("", "qwen3", nil), false, map[string]interface{}{"think": false}},
+		{"responses", NewOpenAIResponsesProvider("k", "o4-mini", nil), true, map[string]interface{}{"reasoning": map[string]interface{}{"summary": "auto"}}},
+		{"responses non-reasoning model", NewOpenAIResponsesProvider("k", "gpt-4o", nil), true, map[string]interface{}{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requester, ok := tc.provider.(ReasoningRequester)
+			require.True(t, ok)
+			options := map[string]interface{}{}
+			requester.RequestReasoning(tc.include, options)
+			assert.Equal(t, tc.want, options)
+		})
+	}
+
+	// An explicit effort wins over the default WithReasoning picks.
+	options := map[string]interface{}{"reasoning_effort": "high"}
+	NewAnthropicProvider("k", "claude-sonnet-4-5", nil).(ReasoningRequester).RequestReasoning(true, options)
+	assert.Equal(t, "high", options["reasoning_effort"])
+}
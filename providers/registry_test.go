@@ -113,6 +113,8 @@ func TestProviderConfigsExist(t *testing.T) {
 		"openrouter",
 		"lambda",
 		"bedrock",
+		"mistral",
+		"cohere",
 	}
 
 	registry := GetDefaultRegistry()
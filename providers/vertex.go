@@ -0,0 +1,237 @@
+// Package providers implements LLM provider interfaces and implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/types"
+)
+
+// vertexAnthropicVersion is the API version Anthropic models on Vertex AI take in the request
+// body, in place of the anthropic-version header.
+const vertexAnthropicVersion = "vertex-2023-10-16"
+
+// vertexTarget addresses a Google Cloud project and location on Vertex AI and signs in to it.
+// Both Vertex providers embed it.
+type vertexTarget struct {
+	project         string
+	location        string
+	credentialsFile string
+
+	once        sync.Once
+	credentials config.CredentialSource
+	err         error
+}
+
+// configure reads the project, location and service account key file from the config.
+func (v *vertexTarget) configure(cfg *config.Config) {
+	v.project = cfg.VertexProject
+	v.location = cfg.VertexLocation
+	v.credentialsFile = cfg.GoogleCredentialsFile
+}
+
+// baseURL is the project's URL in the location, under which models are addressed. The global
+// location has no regional host.
+func (v *vertexTarget) baseURL() string {
+	location := v.location
+	if location == "" {
+		location = "us-central1"
+	}
+	host := location + "-aiplatform.googleapis.com"
+	if location == "global" {
+		host = "aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s", host, v.project, location)
+}
+
+// CredentialSource implements CredentialSupplier: it signs in with the service account key file,
+// exchanging a signed JWT for access tokens that are cached until shortly before they expire.
+func (v *vertexTarget) CredentialSource() (config.CredentialSource, error) {
+	v.once.Do(func() {
+		if v.credentialsFile == "" {
+			v.err = fmt.Errorf("vertex: no credentials: set GOOGLE_APPLICATION_CREDENTIALS to a service account key file, or configure an access token or credential source")
+			return
+		}
+		data, err := os.ReadFile(v.credentialsFile)
+		if err != nil {
+			v.err = fmt.Errorf("vertex: reading service account key: %w", err)
+			return
+		}
+		v.credentials, v.err = config.GoogleServiceAccountCredential(data)
+	})
+	return v.credentials, v.err
+}
+
+// vertexHeaders are the headers of a Vertex AI request, with the configured extra headers. An API
+// key, when given, is an access token obtained elsewhere (for example with
+// "gcloud auth print-access-token").
+func vertexHeaders(apiKey string, extraHeaders map[string]string) map[string]string {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	for key, value := range extraHeaders {
+		headers[key] = value
+	}
+	return headers
+}
+
+// NewVertexProvider creates a provider for models hosted on Google Cloud Vertex AI. Claude models
+// (names starting with "claude", e.g. "claude-sonnet-4@20250514") are served through Anthropic's
+// Messages API; any other model goes through Vertex AI's OpenAI-compatible endpoint, with
+// "google/" prepended to bare Gemini names (e.g. "gemini-2.5-flash").
+//
+// The project and location come from the config (VERTEX_PROJECT and VERTEX_LOCATION). Requests
+// are authenticated with the API key as an access token when one is configured, and otherwise
+// with tokens obtained from the service account key in GOOGLE_APPLICATION_CREDENTIALS.
+//
+// Parameters:
+//   - apiKey: An OAuth2 access token, or empty to sign in with a service account
+//   - model: The model to use (e.g., "gemini-2.5-flash", "claude-sonnet-4@20250514")
+//   - extraHeaders: Additional HTTP headers for requests
+//
+// Returns:
+//   - A configured Vertex AI Provider instance
+func NewVertexProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	if strings.HasPrefix(model, "claude") {
+		provider := &VertexAnthropicProvider{
+			AnthropicProvider: *NewAnthropicProvider(apiKey, model, extraHeaders).(*AnthropicProvider),
+		}
+		// Keep only the caller's headers: the Anthropic API's default beta header is not Vertex AI's.
+		provider.extraHeaders = make(map[string]string, len(extraHeaders))
+		for key, value := range extraHeaders {
+			provider.extraHeaders[key] = value
+		}
+		return provider
+	}
+
+	if !strings.Contains(model, "/") {
+		model = "google/" + model
+	}
+	provider := &VertexGeminiProvider{
+		GoogleProvider: *NewGoogleProvider(apiKey, model, extraHeaders).(*GoogleProvider),
+	}
+	return provider
+}
+
+// VertexGeminiProvider implements the Provider interface for Gemini (and other publishers')
+// models on Vertex AI's OpenAI-compatible endpoint. Accordingly, it inherits from GoogleProvider.
+type VertexGeminiProvider struct {
+	GoogleProvider
+	vertexTarget
+}
+
+// Name returns "vertex" as the provider identifier.
+func (p *VertexGeminiProvider) Name() string {
+	return "vertex"
+}
+
+// Endpoint returns the OpenAI-compatible chat completions URL of the project and location.
+func (p *VertexGeminiProvider) Endpoint() string {
+	return p.baseURL() + "/endpoints/openapi/chat/completions"
+}
+
+// Headers returns the content type, the bearer token when an access token was given, and the
+// extra headers.
+func (p *VertexGeminiProvider) Headers() map[string]string {
+	return vertexHeaders(p.apiKey, p.extraHeaders)
+}
+
+// SetDefaultOptions configures standard options and the Vertex AI target from the global
+// configuration.
+func (p *VertexGeminiProvider) SetDefaultOptions(cfg *config.Config) {
+	p.GoogleProvider.SetDefaultOptions(cfg)
+	p.configure(cfg)
+}
+
+// VertexAnthropicProvider implements the Provider interface for Claude models on Vertex AI.
+// Requests are built by AnthropicProvider and adjusted to Vertex AI's rawPredict format: the
+// model moves from the body to the URL and the API version into the body.
+type VertexAnthropicProvider struct {
+	AnthropicProvider
+	vertexTarget
+}
+
+// Name returns "vertex" as the provider identifier.
+func (p *VertexAnthropicProvider) Name() string {
+	return "vertex"
+}
+
+// Endpoint returns the model's rawPredict URL.
+func (p *VertexAnthropicProvider) Endpoint() string {
+	return p.modelURL() + ":rawPredict"
+}
+
+// StreamEndpoint implements StreamEndpointer: streaming requests go to streamRawPredict.
+func (p *VertexAnthropicProvider) StreamEndpoint() string {
+	return p.modelURL() + ":streamRawPredict"
+}
+
+func (p *VertexAnthropicProvider) modelURL() string {
+	return p.baseURL() + "/publishers/anthropic/models/" + p.model
+}
+
+// Headers returns the content type, the bearer token when an access token was given, and the
+// extra headers. Vertex AI takes the API version in the body rather than the anthropic-version
+// header.
+func (p *VertexAnthropicProvider) Headers() map[string]string {
+	return vertexHeaders(p.apiKey, p.extraHeaders)
+}
+
+// SetDefaultOptions configures standard options and the Vertex AI target from the global
+// configuration.
+func (p *VertexAnthropicProvider) SetDefaultOptions(cfg *config.Config) {
+	p.AnthropicProvider.SetDefaultOptions(cfg)
+	p.configure(cfg)
+}
+
+// PrepareRequest creates the request body for a Claude model on Vertex AI.
+func (p *VertexAnthropicProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareRequest(prompt, options))
+}
+
+// PrepareRequestWithSchema creates a request body that asks for output matching schema.
+func (p *VertexAnthropicProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareRequestWithSchema(prompt, options, schema))
+}
+
+// PrepareRequestWithMessages creates a request body from structured messages.
+func (p *VertexAnthropicProvider) PrepareRequestWithMessages(messages []types.MemoryMessage, options map[string]interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareRequestWithMessages(messages, options))
+}
+
+// PrepareRequestWithMessagesAndSchema creates a request body from structured messages that asks
+// for output matching schema.
+func (p *VertexAnthropicProvider) PrepareRequestWithMessagesAndSchema(messages []types.MemoryMessage, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareRequestWithMessagesAndSchema(messages, options, schema))
+}
+
+// PrepareStreamRequest creates a request body for streaming API calls.
+func (p *VertexAnthropicProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareStreamRequest(prompt, options))
+}
+
+// PrepareStreamRequestWithMessages creates a streaming request body from structured messages.
+func (p *VertexAnthropicProvider) PrepareStreamRequestWithMessages(messages []types.MemoryMessage, options map[string]interface{}) ([]byte, error) {
+	return toVertexAnthropic(p.AnthropicProvider.PrepareStreamRequestWithMessages(messages, options))
+}
+
+// toVertexAnthropic rewrites an Anthropic Messages API body for Vertex AI, which names the model
+// in the URL and takes the API version in the body.
+func toVertexAnthropic(body []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	var requestBody map[string]interface{}
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil, err
+	}
+	delete(requestBody, "model")
+	requestBody["anthropic_version"] = vertexAnthropicVersion
+	return json.Marshal(requestBody)
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/types"
+)
+
+func newVertexProvider(t *testing.T, model, location string) Provider {
+	t.Helper()
+	p := NewVertexProvider("", model, nil)
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetVertex("acme-prod", location))
+	p.SetDefaultOptions(cfg)
+	return p
+}
+
+func TestVertexGeminiProvider(t *testing.T) {
+	p := newVertexProvider(t, "gemini-2.5-flash", "europe-west4")
+	require.IsType(t, &VertexGeminiProvider{}, p)
+	assert.Equal(t, "vertex", p.Name())
+	assert.Equal(t, "https://europe-west4-aiplatform.googleapis.com/v1/projects/acme-prod/locations/europe-west4/endpoints/openapi/chat/completions", p.Endpoint())
+	assert.Equal(t, map[string]string{"Content-Type": "application/json"}, p.Headers(), "no bearer header without an access token")
+
+	body, err := p.PrepareRequest("hello", map[string]interface{}{})
+	require.NoError(t, err)
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, "google/gemini-2.5-flash", req["model"])
+
+	global := newVertexProvider(t, "gemini-2.5-pro", "global")
+	assert.Equal(t, "https://aiplatform.googleapis.com/v1/projects/acme-prod/locations/global/endpoints/openapi/chat/completions", global.Endpoint())
+
+	withToken := NewVertexProvider("ya29.token", "meta/llama-3.3-70b-instruct-maas", nil)
+	assert.Equal(t, "Bearer ya29.token", withToken.Headers()["Authorization"])
+	body, err = withToken.PrepareRequest("hello", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"model":"meta/llama-3.3-70b-instruct-maas"`, "a publisher-qualified model is kept as is")
+
+	withHeaders := NewVertexProvider("ya29.token", "gemini-2.5-flash", map[string]string{"X-Team": "search"})
+	assert.Equal(t, map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer ya29.token",
+		"X-Team":        "search",
+	}, withHeaders.Headers())
+}
+
+func TestVertexAnthropicProvider(t *testing.T) {
+	p := newVertexProvider(t, "claude-sonnet-4@20250514", "us-east5")
+	require.IsType(t, &VertexAnthropicProvider{}, p)
+	assert.Equal(t, "vertex", p.Name())
+	base := "https://us-east5-aiplatform.googleapis.com/v1/projects/acme-prod/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514"
+	assert.Equal(t, base+":rawPredict", p.Endpoint())
+	require.Implements(t, (*StreamEndpointer)(nil), p)
+	assert.Equal(t, base+":streamRawPredict", p.(StreamEndpointer).StreamEndpoint())
+
+	headers := p.Headers()
+	assert.NotContains(t, headers, "x-api-key")
+	assert.NotContains(t, headers, "anthropic-version")
+	assert.NotContains(t, headers, "anthropic-beta")
+
+	withHeaders := NewVertexProvider("", "claude-sonnet-4@20250514", map[string]string{"X-Team": "search"})
+	assert.Equal(t, "search", withHeaders.Headers()["X-Team"])
+
+	messages := []types.MemoryMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hello"},
+	}
+	bodies := map[string]func() ([]byte, error){
+		"PrepareRequest": func() ([]byte, error) { return p.PrepareRequest("hello", map[string]interface{}{}) },
+		"PrepareRequestWithMessages": func() ([]byte, error) {
+			return p.PrepareRequestWithMessages(messages, map[string]interface{}{})
+		},
+		"PrepareStreamRequest": func() ([]byte, error) { return p.PrepareStreamRequest("hello", map[string]interface{}{}) },
+		"PrepareStreamRequestWithMessages": func() ([]byte, error) {
+			return p.(interface {
+				PrepareStreamRequestWithMessages([]types.MemoryMessage, map[string]interface{}) ([]byte, error)
+			}).PrepareStreamRequestWithMessages(messages, map[string]interface{}{})
+		},
+	}
+	for name, prepare := range bodies {
+		body, err := prepare()
+		require.NoError(t, err, name)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &req), name)
+		assert.NotContains(t, req, "model", name)
+		assert.Equal(t, "vertex-2023-10-16", req["anthropic_version"], name)
+		assert.NotEmpty(t, req["messages"], name)
+		if strings.HasPrefix(name, "PrepareStream") {
+			assert.Equal(t, true, req["stream"], name)
+		}
+	}
+}
+
+// writeServiceAccountKey writes a service account key file whose tokens come from tokenURL.
+func writeServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "gollm@acme-prod.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURL,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestVertexCredentialSource(t *testing.T) {
+	var exchanges atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges.Add(1)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+		assert.NotEmpty(t, r.PostForm.Get("assertion"))
+		_, _ = w.Write([]byte(`{"access_token":"vertex-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	p := NewVertexProvider("", "gemini-2.5-flash", nil)
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetVertex("acme-prod", "us-central1"),
+		config.SetGoogleCredentialsFile(writeServiceAccountKey(t, server.URL)),
+	)
+	p.SetDefaultOptions(cfg)
+
+	require.Implements(t, (*CredentialSupplier)(nil), p)
+	source, err := p.(CredentialSupplier).CredentialSource()
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		cred, err := source.Credential(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "vertex-token", cred.Value)
+		assert.Equal(t, "Authorization", cred.Header)
+		assert.Equal(t, "Bearer ", cred.Prefix)
+	}
+	assert.EqualValues(t, 1, exchanges.Load(), "the access token is cached")
+
+	unconfigured := newVertexProvider(t, "claude-sonnet-4@20250514", "us-east5")
+	_, err = unconfigured.(CredentialSupplier).CredentialSource()
+	assert.ErrorContains(t, err, "GOOGLE_APPLICATION_CREDENTIALS")
+}
@@ -112,6 +112,167 @@ func ContentPartToAnthropicImage(part types.ContentPart) (map[string]interface{}
 	}
 }
 
+// ContentPartToAnthropicDocument converts a document ContentPart to an Anthropic document block.
+// All four source types are supported; title, context and citations are passed through when set.
+// Returns the formatted map and whether conversion was successful.
+func ContentPartToAnthropicDocument(part types.ContentPart) (map[string]interface{}, bool) {
+	if !part.IsDocument() {
+		return nil, false
+	}
+	doc := part.Document
+
+	var source map[string]interface{}
+	switch doc.Type {
+	case types.DocumentSourceBase64:
+		if doc.Data == "" {
+			return nil, false
+		}
+		source = map[string]interface{}{
+			"type":       "base64",
+			"media_type": doc.MediaType,
+			"data":       doc.Data,
+		}
+	case types.DocumentSourceURL:
+		if doc.URL == "" {
+			return nil, false
+		}
+		source = map[string]interface{}{"type": "url", "url": doc.URL}
+	case types.DocumentSourceText:
+		source = map[string]interface{}{
+			"type":       "text",
+			"media_type": "text/plain",
+			"data":       doc.Data,
+		}
+	case types.DocumentSourceFile:
+		if doc.FileID == "" {
+			return nil, false
+		}
+		source = map[string]interface{}{"type": "file", "file_id": doc.FileID}
+	default:
+		return nil, false
+	}
+
+	block := map[string]interface{}{
+		"type":   "document",
+		"source": source,
+	}
+	if doc.Title != "" {
+		block["title"] = doc.Title
+	}
+	if doc.Context != "" {
+		block["context"] = doc.Context
+	}
+	if doc.Citations {
+		block["citations"] = map[string]interface{}{"enabled": true}
+	}
+	return block, true
+}
+
+// ContentPartToOpenAIFile converts a document ContentPart to an OpenAI chat completions "file" part.
+// Inline data is sent as a data URI and uploaded files by ID; plain-text documents become text
+// parts. URL sources have no chat completions equivalent and are not converted.
+// Returns the formatted map and whether conversion was successful.
+func ContentPartToOpenAIFile(part types.ContentPart) (map[string]interface{}, bool) {
+	if !part.IsDocument() {
+		return nil, false
+	}
+	doc := part.Document
+
+	switch doc.Type {
+	case types.DocumentSourceBase64:
+		if doc.Data == "" {
+			return nil, false
+		}
+		return map[string]interface{}{
+			"type": "file",
+			"file": map[string]interface{}{
+				"filename":  documentFilename(doc),
+				"file_data": fmt.Sprintf("data:%s;base64,%s", doc.MediaType, doc.Data),
+			},
+		}, true
+	case types.DocumentSourceFile:
+		if doc.FileID == "" {
+			return nil, false
+		}
+		return map[string]interface{}{
+			"type": "file",
+			"file": map[string]interface{}{"file_id": doc.FileID},
+		}, true
+	case types.DocumentSourceText:
+		return map[string]interface{}{"type": "text", "text": documentText(doc)}, true
+	default:
+		return nil, false
+	}
+}
+
+// contentPartToInlineData converts a document ContentPart for OpenAI-compatible endpoints that
+// accept documents only as data URIs in image_url parts (Gemini turns these into inline_data).
+// Plain-text documents become text parts.
+func contentPartToInlineData(part types.ContentPart) (map[string]interface{}, bool) {
+	if !part.IsDocument() {
+		return nil, false
+	}
+	doc := part.Document
+
+	switch doc.Type {
+	case types.DocumentSourceBase64:
+		if doc.Data == "" {
+			return nil, false
+		}
+		return map[string]interface{}{
+			"type": "image_url",
+			"image_url": map[string]interface{}{
+				"url": fmt.Sprintf("data:%s;base64,%s", doc.MediaType, doc.Data),
+			},
+		}, true
+	case types.DocumentSourceText:
+		return map[string]interface{}{"type": "text", "text": documentText(doc)}, true
+	default:
+		return nil, false
+	}
+}
+
+// ContentPartToOpenAIAudio converts an audio ContentPart to an OpenAI-style input_audio part,
+// which OpenAI chat completions and Gemini's OpenAI-compatible endpoint both accept.
+// Returns the formatted map and whether conversion was successful.
+func ContentPartToOpenAIAudio(part types.ContentPart) (map[string]interface{}, bool) {
+	if !part.IsAudio() || part.InputAudio.Data == "" {
+		return nil, false
+	}
+	return map[string]interface{}{
+		"type": "input_audio",
+		"input_audio": map[string]interface{}{
+			"data":   part.InputAudio.Data,
+			"format": part.InputAudio.Format,
+		},
+	}, true
+}
+
+// documentFilename returns the document's filename, inventing one from the media type when unset
+// because OpenAI rejects inline files without a name.
+func documentFilename(doc *types.DocumentSource) string {
+	if doc.Filename != "" {
+		return doc.Filename
+	}
+	switch doc.MediaType {
+	case "application/pdf":
+		return "document.pdf"
+	case "text/plain":
+		return "document.txt"
+	default:
+		return "document"
+	}
+}
+
+// documentText renders a plain-text document for providers without a native text source,
+// prefixing the title so the model can still tell documents apart.
+func documentText(doc *types.DocumentSource) string {
+	if doc.Title == "" {
+		return doc.Data
+	}
+	return doc.Title + "\n\n" + doc.Data
+}
+
 // ConvertImagesToOpenAIContent converts a slice of ContentPart images to OpenAI format.
 // Returns a slice of formatted image objects.
 func ConvertImagesToOpenAIContent(images []types.ContentPart) []map[string]interface{} {
@@ -136,9 +297,14 @@ func ConvertImagesToAnthropicContent(images []types.ContentPart) []map[string]in
 	return result
 }
 
-// BuildOpenAIContentFromParts converts a slice of ContentPart (text + images) to OpenAI message content.
-// This is the primary helper for building multimodal content arrays.
+// BuildOpenAIContentFromParts converts a slice of ContentPart (text, images, documents, audio) to
+// OpenAI message content. This is the primary helper for building multimodal content arrays.
 func BuildOpenAIContentFromParts(parts []types.ContentPart) []map[string]interface{} {
+	return buildOpenAIContent(parts, ContentPartToOpenAIFile)
+}
+
+// buildOpenAIContent converts parts to OpenAI message content, encoding documents with convertDoc.
+func buildOpenAIContent(parts []types.ContentPart, convertDoc func(types.ContentPart) (map[string]interface{}, bool)) []map[string]interface{} {
 	content := make([]map[string]interface{}, 0, len(parts))
 	for _, part := range parts {
 		switch part.Type {
@@ -147,6 +313,14 @@ func BuildOpenAIContentFromParts(parts []types.ContentPart) []map[string]interfa
 				"type": "text",
 				"text": part.Text,
 			})
+		case types.ContentTypeDocument, types.ContentTypeFile:
+			if converted, ok := convertDoc(part); ok {
+				content = append(content, converted)
+			}
+		case types.ContentTypeInputAudio:
+			if converted, ok := ContentPartToOpenAIAudio(part); ok {
+				content = append(content, converted)
+			}
 		default:
 			if converted, ok := ContentPartToOpenAIImage(part); ok {
 				content = append(content, converted)
@@ -156,8 +330,8 @@ func BuildOpenAIContentFromParts(parts []types.ContentPart) []map[string]interfa
 	return content
 }
 
-// BuildAnthropicContentFromParts converts a slice of ContentPart (text + images) to Anthropic message content.
-// This is the primary helper for building multimodal content arrays.
+// BuildAnthropicContentFromParts converts a slice of ContentPart (text, images, documents) to
+// Anthropic message content. This is the primary helper for building multimodal content arrays.
 func BuildAnthropicContentFromParts(parts []types.ContentPart) []map[string]interface{} {
 	content := make([]map[string]interface{}, 0, len(parts))
 	for _, part := range parts {
@@ -167,6 +341,10 @@ func BuildAnthropicContentFromParts(parts []types.ContentPart) []map[string]inte
 				"type": "text",
 				"text": part.Text,
 			})
+		case types.ContentTypeDocument, types.ContentTypeFile:
+			if converted, ok := ContentPartToAnthropicDocument(part); ok {
+				content = append(content, converted)
+			}
 		default:
 			if converted, ok := ContentPartToAnthropicImage(part); ok {
 				content = append(content, converted)
@@ -176,6 +354,68 @@ func BuildAnthropicContentFromParts(parts []types.ContentPart) []map[string]inte
 	return content
 }
 
+// BuildResponsesContentFromParts converts a slice of ContentPart to OpenAI Responses API input
+// content: input_text, input_image, and input_file items.
+func BuildResponsesContentFromParts(parts []types.ContentPart) []map[string]interface{} {
+	content := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		if converted, ok := contentPartToResponsesInput(part); ok {
+			content = append(content, converted)
+		}
+	}
+	return content
+}
+
+// contentPartToResponsesInput converts a single ContentPart to a Responses API input item.
+func contentPartToResponsesInput(part types.ContentPart) (map[string]interface{}, bool) {
+	switch part.Type {
+	case types.ContentTypeText:
+		return map[string]interface{}{"type": "input_text", "text": part.Text}, true
+
+	case types.ContentTypeImageURL, types.ContentTypeImage:
+		img, ok := ContentPartToOpenAIImage(part)
+		if !ok {
+			return nil, false
+		}
+		imageURL := img["image_url"].(map[string]interface{})
+		item := map[string]interface{}{"type": "input_image", "image_url": imageURL["url"]}
+		if detail, ok := imageURL["detail"]; ok {
+			item["detail"] = detail
+		}
+		return item, true
+
+	case types.ContentTypeDocument, types.ContentTypeFile:
+		if !part.IsDocument() {
+			return nil, false
+		}
+		doc := part.Document
+		switch doc.Type {
+		case types.DocumentSourceBase64:
+			if doc.Data == "" {
+				return nil, false
+			}
+			return map[string]interface{}{
+				"type":      "input_file",
+				"filename":  documentFilename(doc),
+				"file_data": fmt.Sprintf("data:%s;base64,%s", doc.MediaType, doc.Data),
+			}, true
+		case types.DocumentSourceURL:
+			if doc.URL == "" {
+				return nil, false
+			}
+			return map[string]interface{}{"type": "input_file", "file_url": doc.URL}, true
+		case types.DocumentSourceFile:
+			if doc.FileID == "" {
+				return nil, false
+			}
+			return map[string]interface{}{"type": "input_file", "file_id": doc.FileID}, true
+		case types.DocumentSourceText:
+			return map[string]interface{}{"type": "input_text", "text": documentText(doc)}, true
+		}
+	}
+	return nil, false
+}
+
 // NormalizeContentArray safely converts various content representations to []map[string]interface{}.
 // Handles: string, []map[string]interface{}, []interface{}, and nil.
 func NormalizeContentArray(content interface{}) []map[string]interface{} {
@@ -286,3 +286,104 @@ func TestBuildAnthropicContentFromParts(t *testing.T) {
 		assert.Equal(t, "image", result[1]["type"])
 	})
 }
+
+func TestContentPartToAnthropicDocument(t *testing.T) {
+	pdf := types.NewDocumentBase64Content("JVBERi0=", "application/pdf", "invoice.pdf")
+	pdf.Document.Title = "Invoice"
+	pdf.Document.Citations = true
+
+	tests := []struct {
+		name       string
+		part       types.ContentPart
+		wantSource map[string]interface{}
+		wantOK     bool
+	}{
+		{"base64", pdf, map[string]interface{}{"type": "base64", "media_type": "application/pdf", "data": "JVBERi0="}, true},
+		{"url", types.NewDocumentURLContent("https://example.com/a.pdf"), map[string]interface{}{"type": "url", "url": "https://example.com/a.pdf"}, true},
+		{"text", types.NewDocumentTextContent("clause 1", ""), map[string]interface{}{"type": "text", "media_type": "text/plain", "data": "clause 1"}, true},
+		{"file", types.NewFileContent("file_011", ""), map[string]interface{}{"type": "file", "file_id": "file_011"}, true},
+		{"image is not a document", types.NewImageBase64Content("abc", "image/png"), nil, false},
+		{"empty base64", types.NewDocumentBase64Content("", "application/pdf", ""), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ContentPartToAnthropicDocument(tt.part)
+			require.Equal(t, tt.wantOK, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, "document", got["type"])
+			assert.Equal(t, tt.wantSource, got["source"])
+		})
+	}
+
+	got, _ := ContentPartToAnthropicDocument(pdf)
+	assert.Equal(t, "Invoice", got["title"])
+	assert.Equal(t, map[string]interface{}{"enabled": true}, got["citations"])
+}
+
+func TestContentPartToOpenAIFile(t *testing.T) {
+	got, ok := ContentPartToOpenAIFile(types.NewDocumentBase64Content("JVBERi0=", "application/pdf", ""))
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"type": "file",
+		"file": map[string]interface{}{"filename": "document.pdf", "file_data": "data:application/pdf;base64,JVBERi0="},
+	}, got)
+
+	got, ok = ContentPartToOpenAIFile(types.NewFileContent("file-abc", "x.pdf"))
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"file_id": "file-abc"}, got["file"])
+
+	got, ok = ContentPartToOpenAIFile(types.NewDocumentTextContent("body", "Title"))
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "Title\n\nbody"}, got)
+
+	_, ok = ContentPartToOpenAIFile(types.NewDocumentURLContent("https://example.com/a.pdf"))
+	assert.False(t, ok, "chat completions has no URL file source")
+}
+
+func TestBuildResponsesContentFromParts(t *testing.T) {
+	content := BuildResponsesContentFromParts([]types.ContentPart{
+		types.NewTextContent("Summarize"),
+		types.NewImageURLContent("https://example.com/a.png", "low"),
+		types.NewDocumentBase64Content("JVBERi0=", "application/pdf", "a.pdf"),
+		types.NewDocumentURLContent("https://example.com/b.pdf"),
+		types.NewFileContent("file-abc", ""),
+	})
+
+	require.Len(t, content, 5)
+	assert.Equal(t, map[string]interface{}{"type": "input_text", "text": "Summarize"}, content[0])
+	assert.Equal(t, map[string]interface{}{"type": "input_image", "image_url": "https://example.com/a.png", "detail": "low"}, content[1])
+	assert.Equal(t, map[string]interface{}{"type": "input_file", "filename": "a.pdf", "file_data": "data:application/pdf;base64,JVBERi0="}, content[2])
+	assert.Equal(t, map[string]interface{}{"type": "input_file", "file_url": "https://example.com/b.pdf"}, content[3])
+	assert.Equal(t, map[string]interface{}{"type": "input_file", "file_id": "file-abc"}, content[4])
+}
+
+func TestProviderDocumentEncoding(t *testing.T) {
+	pdf := types.NewDocumentBase64Content("JVBERi0=", "application/pdf", "a.pdf")
+	messages := []types.MemoryMessage{{Role: "user", MultiContent: []types.ContentPart{pdf, types.NewTextContent("Summarize")}}}
+
+	// Gemini's compat endpoint takes documents as data URIs, which it maps to inline_data.
+	google := NewGoogleProvider("key", "gemini-2.5-flash", nil)
+	body, err := google.PrepareRequestWithMessages(messages, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"image_url":{"url":"data:application/pdf;base64,JVBERi0="}`)
+
+	openai := NewOpenAIProvider("key", "gpt-4o", nil)
+	body, err = openai.PrepareRequestWithMessages(messages, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"type":"file"`)
+
+	supports := func(p Provider, source string) bool {
+		ds, ok := p.(DocumentSupporter)
+		return ok && ds.SupportsDocumentSource(source)
+	}
+	assert.True(t, supports(NewAnthropicProvider("key", "claude", nil), types.DocumentSourceURL))
+	assert.True(t, supports(NewOpenAIResponsesProvider("key", "gpt-4o", nil), types.DocumentSourceURL))
+	assert.False(t, supports(openai, types.DocumentSourceURL))
+	assert.True(t, supports(openai, types.DocumentSourceFile))
+	assert.False(t, supports(google, types.DocumentSourceFile))
+	assert.False(t, supports(NewDeepSeekProvider("key", "deepseek-chat", nil), types.DocumentSourceBase64))
+	assert.False(t, supports(NewOllamaProvider("", "llama3", nil), types.DocumentSourceBase64))
+}
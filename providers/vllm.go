@@ -257,6 +257,9 @@ func (p *VLLMProvider) ParseResponseWithUsage(body []byte) (string, *types.Respo
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
+				// Set when the server runs with --reasoning-parser.
+				ReasoningContent compatReasoningText `json:"reasoning_content"`
+				Reasoning        compatReasoningText `json:"reasoning"`
 			} `json:"message"`
 		} `json:"choices"`
 		Usage struct {
@@ -284,7 +287,9 @@ func (p *VLLMProvider) ParseResponseWithUsage(body []byte) (string, *types.Respo
 		return "", details, fmt.Errorf("empty response from API")
 	}
 
-	return response.Choices[0].Message.Content, details, nil
+	message := response.Choices[0].Message
+	details.Reasoning = compatReasoning(message.ReasoningContent, message.Reasoning)
+	return message.Content, details, nil
 }
 
 // HandleFunctionCalls processes function calling in the response.
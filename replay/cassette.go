@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// Cassette is the content of a cassette file: the interactions in the order they were recorded.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Credentials in its headers and URL are redacted.
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+// Response is a recorded response. A streamed (SSE) body is stored whole, events and all.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is a request or response body. It is stored as text so that cassettes can be read and
+// edited, and as base64 when it is not valid UTF-8.
+type Body []byte
+
+// MarshalJSON stores the body as a string, or as {"base64": ...} for binary content.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON reads either form written by MarshalJSON.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+	var binary struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &binary); err != nil {
+		return fmt.Errorf("body is neither a string nor {\"base64\": ...}")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(binary.Base64)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("replay: parsing cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating its directory, by way of a temporary file so that
+// an interrupted write never leaves a truncated cassette behind.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("replay: encoding cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("replay: writing cassette: %w", err)
+	}
+	return nil
+}
+
+// sensitiveQueryParams are URL query parameters that carry credentials, such as the key Gemini's
+// native API takes in the URL.
+var sensitiveQueryParams = []string{"key", "api_key", "api-key", "access_token"}
+
+// redactedValue replaces a sensitive query parameter's value.
+const redactedValue = "REDACTED"
+
+// newRequest captures req and its body for the cassette, redacting credentials.
+func newRequest(req *http.Request, body []byte) Request {
+	return Request{
+		Method:  req.Method,
+		URL:     redactURL(req.URL),
+		Headers: utils.RedactHTTPHeaders(req.Header),
+		Body:    body,
+	}
+}
+
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, name := range sensitiveQueryParams {
+		if query.Has(name) {
+			query.Set(name, redactedValue)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	clone := *u
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// Matcher reports whether a recorded request answers a live one. The live request is captured
+// like a recorded one, with its credentials redacted.
+type Matcher func(live, recorded Request) bool
+
+// DefaultMatcher matches requests with the same method, URL and body. Query parameters named in
+// ignoreQuery and body fields named in ignoreFields are left out of the comparison; a field is a
+// dotted path into a JSON body, such as "seed" or "metadata.user_id". Bodies that are not JSON
+// must be identical.
+func DefaultMatcher(ignoreFields, ignoreQuery []string) Matcher {
+	return func(live, recorded Request) bool {
+		if live.Method != recorded.Method {
+			return false
+		}
+		if stripQuery(live.URL, ignoreQuery) != stripQuery(recorded.URL, ignoreQuery) {
+			return false
+		}
+		return equalBodies(live.Body, recorded.Body, ignoreFields)
+	}
+}
+
+func stripQuery(rawURL string, params []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || len(params) == 0 || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	for _, name := range params {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func equalBodies(a, b []byte, ignoreFields []string) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return string(a) == string(b)
+	}
+	for _, field := range ignoreFields {
+		path := strings.Split(field, ".")
+		deleteField(av, path)
+		deleteField(bv, path)
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// deleteField removes the field at path from a decoded JSON value.
+func deleteField(v interface{}, path []string) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+	deleteField(obj[path[0]], path[1:])
+}
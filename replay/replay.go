@@ -0,0 +1,280 @@
+// Package replay records provider HTTP traffic to cassette files and serves it back, so that
+// tests exercise the real request builders and response parsers without calling a provider.
+//
+// A Recorder is an http.RoundTripper. In ModeRecord it sends each request to the real transport
+// and appends the request and response, including streamed (SSE) bodies, to its cassette, with
+// authentication headers redacted by utils.RedactHTTPHeaders. In ModeReplay it answers each
+// request with the recorded response that matches it and never touches the network. Install it
+// with config.SetHTTPClient:
+//
+//	rec, err := replay.New("testdata/cassettes/summarize.json")
+//	...
+//	llm, err := gollm.NewLLM(gollm.SetProvider("openai"), gollm.SetHTTPClient(rec.Client()), ...)
+//
+// New picks the mode from the GOLLM_RECORD environment variable, so a suite replays in CI and
+// is re-recorded with GOLLM_RECORD=1 go test ./... on a machine that has the API keys.
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves recorded responses and fails requests that match none.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the real transport and records them, replacing the cassette.
+	ModeRecord
+)
+
+// String returns "replay" or "record".
+func (m Mode) String() string {
+	if m == ModeRecord {
+		return "record"
+	}
+	return "replay"
+}
+
+// RecordEnv is the environment variable that switches recorders created without WithMode to
+// ModeRecord when it is set to a true value ("1", "true", ...).
+const RecordEnv = "GOLLM_RECORD"
+
+// ModeFromEnv returns ModeRecord when RecordEnv is set to a true value, and ModeReplay otherwise.
+func ModeFromEnv() Mode {
+	if record, err := strconv.ParseBool(os.Getenv(RecordEnv)); err == nil && record {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// ModeOf returns the mode a Recorder created with opts runs in.
+func ModeOf(opts ...Option) Mode {
+	r := &Recorder{mode: ModeFromEnv()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r.mode
+}
+
+// ErrNoInteraction is returned (wrapped) in replay mode for a request that no recorded
+// interaction matches.
+var ErrNoInteraction = errors.New("replay: no recorded interaction matches the request")
+
+// Recorder is an http.RoundTripper that records to or replays from one cassette file. It is safe
+// for concurrent use.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matcher   Matcher
+
+	ignoreFields []string
+	ignoreQuery  []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMode sets the mode instead of taking it from RecordEnv.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport sets the transport that record mode sends requests to; http.DefaultTransport
+// when not set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// IgnoreFields leaves the given JSON body fields, as dotted paths, out of request matching. Use
+// it for fields that change from run to run, such as a random seed or a request ID.
+func IgnoreFields(paths ...string) Option {
+	return func(r *Recorder) {
+		r.ignoreFields = append(r.ignoreFields, paths...)
+	}
+}
+
+// IgnoreQueryParams leaves the given URL query parameters out of request matching.
+func IgnoreQueryParams(names ...string) Option {
+	return func(r *Recorder) {
+		r.ignoreQuery = append(r.ignoreQuery, names...)
+	}
+}
+
+// WithMatcher replaces the default matching on method, URL and body. IgnoreFields and
+// IgnoreQueryParams have no effect with a custom matcher.
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// New creates a Recorder for the cassette at path. In replay mode the cassette must exist; in
+// record mode it is created, or replaced, when the first interaction is recorded.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      ModeFromEnv(),
+		transport: http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.matcher == nil {
+		r.matcher = DefaultMatcher(r.ignoreFields, r.ignoreQuery)
+	}
+
+	if r.mode == ModeRecord {
+		r.cassette = &Cassette{}
+		return r, nil
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	r.cassette = cassette
+	r.used = make([]bool, len(cassette.Interactions))
+	return r, nil
+}
+
+// Mode returns the mode the recorder runs in.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Path returns the cassette file's path.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Client returns an HTTP client that sends its requests through the recorder, for
+// config.SetHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays req.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("replay: reading request body: %w", err)
+		}
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	// The whole body is read before it is handed on, so a streamed response reaches the caller
+	// at once rather than event by event; its content is unchanged.
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("replay: reading response body: %w", err)
+	}
+
+	interaction := Interaction{
+		Request: newRequest(req, body),
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    utils.RedactHTTPHeaders(resp.Header),
+			Body:       respBody,
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	err = r.cassette.Save(r.path)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+// replay answers with the first unused interaction that matches. When every match has been used,
+// the last one is served again, so a request repeated more often than it was recorded (a retry,
+// a cache check) still gets an answer.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	live := newRequest(req, body)
+
+	r.mu.Lock()
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.matcher(live, interaction.Request) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found >= 0 {
+		r.used[found] = true
+	}
+	r.mu.Unlock()
+
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s in %s", ErrNoInteraction, req.Method, live.URL, r.path)
+	}
+	recorded := r.cassette.Interactions[found].Response
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.StatusCode) + " " + http.StatusText(recorded.StatusCode),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the recorded requests that replay mode has not served, as "METHOD URL", so a
+// test can check that the code under test made every call it used to.
+func (r *Recorder) Unused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []string
+	for i, interaction := range r.cassette.Interactions {
+		if r.mode == ModeReplay && !r.used[i] {
+			unused = append(unused, interaction.Request.Method+" "+interaction.Request.URL)
+		}
+	}
+	return unused
+}
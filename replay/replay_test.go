@@ -0,0 +1,187 @@
+package replay_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/replay"
+	"github.com/teilomillet/gollm/utils"
+)
+
+const testKey = "sk-test-secret-0123456789abcdef"
+
+// fakeOpenAI answers chat completions, streamed when the request asks for it, and counts calls.
+type fakeOpenAI struct {
+	calls atomic.Int32
+}
+
+func (f *fakeOpenAI) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls.Add(1)
+	body, _ := io.ReadAll(req.Body)
+	rec := httptest.NewRecorder()
+	if strings.Contains(string(body), `"stream":true`) {
+		rec.Header().Set("Content-Type", "text/event-stream")
+		_, _ = rec.WriteString("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+			"data: [DONE]\n\n")
+		return rec.Result(), nil
+	}
+	rec.Header().Set("Content-Type", "application/json")
+	_, _ = rec.WriteString(`{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"4"}}]}`)
+	return rec.Result(), nil
+}
+
+func newClient(t *testing.T, recorder *replay.Recorder) llm.LLM {
+	t.Helper()
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o-mini"),
+		config.SetAPIKey(testKey),
+		config.SetHTTPClient(recorder.Client()),
+		config.SetMaxRetries(0),
+	)
+	client, err := llm.NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.GetDefaultRegistry())
+	require.NoError(t, err)
+	return client
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "math.json")
+	upstream := &fakeOpenAI{}
+
+	recorder, err := replay.New(path, replay.WithMode(replay.ModeRecord), replay.WithTransport(upstream))
+	require.NoError(t, err)
+	client := newClient(t, recorder)
+	answer, err := client.Generate(context.Background(), llm.NewPrompt("What is 2+2?"))
+	require.NoError(t, err)
+	assert.Equal(t, "4", answer)
+	streamed := readStream(t, client)
+	assert.Equal(t, "Hello", streamed)
+	assert.EqualValues(t, 2, upstream.calls.Load())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), testKey, "the API key is redacted")
+	assert.Contains(t, string(data), "...89abcdef", "redacted the way the logger redacts")
+	assert.Contains(t, string(data), `data: [DONE]`, "the SSE body is recorded whole")
+
+	replayer, err := replay.New(path, replay.WithMode(replay.ModeReplay))
+	require.NoError(t, err)
+	client = newClient(t, replayer)
+	answer, err = client.Generate(context.Background(), llm.NewPrompt("What is 2+2?"))
+	require.NoError(t, err)
+	assert.Equal(t, "4", answer)
+	assert.Equal(t, "Hello", readStream(t, client))
+	assert.Empty(t, replayer.Unused())
+	assert.EqualValues(t, 2, upstream.calls.Load(), "replay never reaches the provider")
+
+	_, _, err = client.GenerateWithUsage(context.Background(), llm.NewPrompt("What is 3+3?"))
+	assert.ErrorIs(t, err, replay.ErrNoInteraction, "an unrecorded prompt is not answered")
+}
+
+func readStream(t *testing.T, client llm.LLM) string {
+	t.Helper()
+	stream, err := client.Stream(context.Background(), llm.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	defer stream.Close()
+	var text strings.Builder
+	for {
+		token, err := stream.Next(context.Background())
+		if err == io.EOF {
+			return text.String()
+		}
+		require.NoError(t, err)
+		text.WriteString(token.Text)
+	}
+}
+
+func TestMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := &replay.Cassette{Interactions: []replay.Interaction{
+		{
+			Request:  replay.Request{Method: "POST", URL: "https://api.example.com/v1/chat?key=REDACTED&trace=1", Body: replay.Body(`{"model":"m","seed":1,"metadata":{"user_id":"a","team":"x"}}`)},
+			Response: replay.Response{StatusCode: 200, Body: replay.Body("first")},
+		},
+		{
+			Request:  replay.Request{Method: "POST", URL: "https://api.example.com/v1/chat?key=REDACTED&trace=1", Body: replay.Body(`{"model":"m","seed":1,"metadata":{"user_id":"a","team":"x"}}`)},
+			Response: replay.Response{StatusCode: 429, Body: replay.Body("second")},
+		},
+		{
+			Request:  replay.Request{Method: "GET", URL: "https://api.example.com/v1/models"},
+			Response: replay.Response{StatusCode: 200, Body: replay.Body{0xff, 0x00, 0xfe}},
+		},
+	}}
+	require.NoError(t, cassette.Save(path))
+
+	send := func(r *replay.Recorder, method, url, body string) (int, string, error) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := r.RoundTrip(req)
+		if err != nil {
+			return 0, "", err
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), nil
+	}
+
+	strict, err := replay.New(path, replay.WithMode(replay.ModeReplay))
+	require.NoError(t, err)
+	_, _, err = send(strict, "POST", "https://api.example.com/v1/chat?key=live-key&trace=2", `{"model":"m","seed":2,"metadata":{"user_id":"b","team":"x"}}`)
+	assert.ErrorIs(t, err, replay.ErrNoInteraction)
+
+	loose, err := replay.New(path, replay.WithMode(replay.ModeReplay),
+		replay.IgnoreFields("seed", "metadata.user_id"), replay.IgnoreQueryParams("trace"))
+	require.NoError(t, err)
+	body := `{"model":"m","seed":2,"metadata":{"user_id":"b","team":"x"}}`
+	var got []string
+	for i := 0; i < 3; i++ {
+		status, text, err := send(loose, "POST", "https://api.example.com/v1/chat?key=live-key&trace=2", body)
+		require.NoError(t, err)
+		got = append(got, text)
+		if i == 1 {
+			assert.Equal(t, 429, status)
+		}
+	}
+	assert.Equal(t, []string{"first", "second", "second"}, got, "matches are served in order, then the last repeats")
+	assert.Equal(t, []string{"GET https://api.example.com/v1/models"}, loose.Unused())
+
+	_, _, err = send(loose, "POST", "https://api.example.com/v1/chat?key=live-key", `{"model":"other"}`)
+	assert.ErrorIs(t, err, replay.ErrNoInteraction, "fields that are not ignored still count")
+
+	_, text, err := send(loose, "GET", "https://api.example.com/v1/models", "")
+	require.NoError(t, err)
+	assert.Equal(t, string([]byte{0xff, 0x00, 0xfe}), text, "binary bodies survive the cassette")
+
+	custom, err := replay.New(path, replay.WithMode(replay.ModeReplay), replay.WithMatcher(func(live, recorded replay.Request) bool {
+		return live.Method == recorded.Method
+	}))
+	require.NoError(t, err)
+	_, text, err = send(custom, "GET", "https://elsewhere.example.com/", "")
+	require.NoError(t, err)
+	assert.Equal(t, string([]byte{0xff, 0x00, 0xfe}), text)
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(replay.RecordEnv, "")
+	assert.Equal(t, replay.ModeReplay, replay.ModeFromEnv())
+	t.Setenv(replay.RecordEnv, "1")
+	assert.Equal(t, replay.ModeRecord, replay.ModeFromEnv())
+	assert.Equal(t, replay.ModeReplay, replay.ModeOf(replay.WithMode(replay.ModeReplay)))
+
+	_, err := replay.New(filepath.Join(t.TempDir(), "absent.json"), replay.WithMode(replay.ModeReplay))
+	assert.Error(t, err, "replaying needs a cassette")
+}
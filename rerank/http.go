@@ -0,0 +1,266 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Format selects the wire format an HTTP reranker speaks.
+type Format string
+
+const (
+	// FormatCohere is the {model, query, documents, top_n} request answered by
+	// {results: [{index, relevance_score}]}. Cohere v2, Jina, vLLM and most hosted rerankers
+	// speak it.
+	FormatCohere Format = "cohere"
+
+	// FormatTEI is Hugging Face Text Embeddings Inference: {query, texts} answered by a bare
+	// [{index, score}] array.
+	FormatTEI Format = "tei"
+)
+
+// EndpointConfig describes an HTTP rerank endpoint. It mirrors providers.ProviderConfig so a
+// server that is not built in can be added from configuration alone.
+type EndpointConfig struct {
+	// Name identifies the reranker in errors.
+	Name string
+	// URL is the full rerank URL, e.g. "https://api.jina.ai/v1/rerank".
+	URL string
+	// Model is sent with every request unless overridden by WithModel. TEI ignores it.
+	Model string
+	// APIKey is sent in AuthHeader. An empty key sends no auth header, which suits local servers.
+	APIKey string
+	// AuthHeader is the header carrying the key. Defaults to "Authorization".
+	AuthHeader string
+	// AuthPrefix is prepended to the key. Defaults to "Bearer " when AuthHeader is defaulted too.
+	AuthPrefix string
+	// Headers are added to every request.
+	Headers map[string]string
+	// Format selects the wire format. Defaults to FormatCohere.
+	Format Format
+	// HTTPClient sends the requests. Defaults to a client with a 60 second timeout.
+	HTTPClient *http.Client
+}
+
+// HTTPReranker is a Reranker backed by a rerank endpoint.
+type HTTPReranker struct {
+	cfg    EndpointConfig
+	client *http.Client
+}
+
+// NewReranker creates a reranker for the endpoint described by cfg. Use it for Jina and other
+// Cohere-compatible services that have no dedicated constructor.
+func NewReranker(cfg EndpointConfig) (*HTTPReranker, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("rerank: endpoint URL is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatCohere
+	}
+	if cfg.Format != FormatCohere && cfg.Format != FormatTEI {
+		return nil, fmt.Errorf("rerank: unknown format %q", cfg.Format)
+	}
+	if cfg.AuthHeader == "" {
+		cfg.AuthHeader = "Authorization"
+		if cfg.AuthPrefix == "" {
+			cfg.AuthPrefix = "Bearer "
+		}
+	}
+	if cfg.Name == "" {
+		cfg.Name = string(cfg.Format)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &HTTPReranker{cfg: cfg, client: client}, nil
+}
+
+// NewCohereReranker creates a reranker for Cohere's /v2/rerank endpoint, e.g. with model
+// "rerank-v3.5".
+func NewCohereReranker(apiKey, model string) *HTTPReranker {
+	return NewCohereRerankerWithURL(apiKey, model, "https://api.cohere.com")
+}
+
+// NewCohereRerankerWithURL creates a Cohere reranker against a custom base URL, matching
+// providers.NewCohereProviderWithURL.
+func NewCohereRerankerWithURL(apiKey, model, baseURL string) *HTTPReranker {
+	r, _ := NewReranker(EndpointConfig{
+		Name:   "cohere",
+		URL:    strings.TrimSuffix(baseURL, "/") + "/v2/rerank",
+		Model:  model,
+		APIKey: apiKey,
+		Format: FormatCohere,
+	})
+	return r
+}
+
+// NewVLLMReranker creates a reranker for a vLLM server running a cross-encoder model. The
+// endpoint is normalized the way providers.VLLMProvider normalizes its own: a bare host gets
+// "/rerank", a ".../v1" base gets "/v1/rerank", and a full ".../rerank" URL is used as is.
+func NewVLLMReranker(endpoint, model string) *HTTPReranker {
+	r, _ := NewReranker(EndpointConfig{
+		Name:   "vllm",
+		URL:    rerankURL(endpoint),
+		Model:  model,
+		Format: FormatCohere,
+	})
+	return r
+}
+
+// NewTEIReranker creates a reranker for a Hugging Face Text Embeddings Inference server. TEI
+// serves a single model, so there is no model parameter.
+func NewTEIReranker(endpoint string) *HTTPReranker {
+	r, _ := NewReranker(EndpointConfig{
+		Name:   "tei",
+		URL:    rerankURL(endpoint),
+		Format: FormatTEI,
+	})
+	return r
+}
+
+// rerankURL appends "/rerank" to a server base URL unless it is already there.
+func rerankURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/rerank") {
+		return endpoint
+	}
+	return endpoint + "/rerank"
+}
+
+// Rerank sends one request to the endpoint and returns the results ordered by relevance.
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, documents []string, opts ...Option) ([]Result, error) {
+	o := applyOptions(opts)
+	if len(documents) == 0 {
+		return []Result{}, nil
+	}
+
+	body, err := r.prepareRequest(query, documents, o)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to prepare rerank request", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, "failed to create rerank request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.cfg.APIKey != "" {
+		req.Header.Set(r.cfg.AuthHeader, r.cfg.AuthPrefix+r.cfg.APIKey)
+	}
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeRequest, fmt.Sprintf("failed to send rerank request to %s", r.cfg.Name), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to read rerank response", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, llm.NewHTTPStatusError(resp.StatusCode, respBody)
+	}
+
+	results, err := r.parseResponse(respBody, len(documents))
+	if err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, fmt.Sprintf("failed to parse %s rerank response", r.cfg.Name), err)
+	}
+	return finalize(results, documents, o), nil
+}
+
+func (r *HTTPReranker) prepareRequest(query string, documents []string, o Options) ([]byte, error) {
+	if r.cfg.Format == FormatTEI {
+		return json.Marshal(map[string]interface{}{
+			"query":       query,
+			"texts":       documents,
+			"return_text": false,
+		})
+	}
+
+	requestBody := map[string]interface{}{
+		"query":     query,
+		"documents": documents,
+	}
+	model := r.cfg.Model
+	if o.Model != "" {
+		model = o.Model
+	}
+	if model != "" {
+		requestBody["model"] = model
+	}
+	if o.TopN > 0 {
+		requestBody["top_n"] = o.TopN
+	}
+	return json.Marshal(requestBody)
+}
+
+func (r *HTTPReranker) parseResponse(body []byte, count int) ([]Result, error) {
+	var raw []struct {
+		Index          int             `json:"index"`
+		RelevanceScore *float64        `json:"relevance_score"`
+		Score          *float64        `json:"score"`
+		Document       json.RawMessage `json:"document"`
+		Text           string          `json:"text"`
+	}
+	if r.cfg.Format == FormatTEI {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var envelope struct {
+			Results *json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, err
+		}
+		if envelope.Results == nil {
+			return nil, fmt.Errorf("response has no results field")
+		}
+		if err := json.Unmarshal(*envelope.Results, &raw); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]Result, 0, len(raw))
+	for _, item := range raw {
+		if item.Index < 0 || item.Index >= count {
+			return nil, fmt.Errorf("result index %d out of range for %d documents", item.Index, count)
+		}
+		result := Result{Index: item.Index, Document: item.Text}
+		switch {
+		case item.RelevanceScore != nil:
+			result.Score = *item.RelevanceScore
+		case item.Score != nil:
+			result.Score = *item.Score
+		}
+		// Jina and vLLM echo documents as {"text": ...}; some servers echo the bare string.
+		if len(item.Document) > 0 && result.Document == "" {
+			var doc struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(item.Document, &doc); err == nil {
+				result.Document = doc.Text
+			} else {
+				var text string
+				if json.Unmarshal(item.Document, &text) == nil {
+					result.Document = text
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
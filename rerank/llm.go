@@ -0,0 +1,113 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+)
+
+// llmRankingSchema is the structured output requested from the model. Only indices and scores
+// are asked for, so the answer stays short regardless of document length.
+var llmRankingSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"rankings": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"index": map[string]interface{}{"type": "integer"},
+					"score": map[string]interface{}{"type": "number"},
+				},
+				"required": []string{"index", "score"},
+			},
+		},
+	},
+	"required": []string{"rankings"},
+}
+
+type llmRanking struct {
+	Rankings []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	} `json:"rankings"`
+}
+
+// LLMReranker is a Reranker for providers without a rerank endpoint. It shows the model every
+// document in one prompt and asks, through GenerateWithSchema, for a relevance score per index.
+// It costs a full generation per call and is far less calibrated than a cross-encoder, so prefer
+// an HTTPReranker wherever one is available.
+type LLMReranker struct {
+	client llm.LLM
+
+	// MaxDocumentChars truncates each document in the prompt. Zero leaves documents whole.
+	MaxDocumentChars int
+}
+
+// NewLLMReranker creates a reranker that scores documents with client.
+func NewLLMReranker(client llm.LLM) *LLMReranker {
+	return &LLMReranker{client: client}
+}
+
+// Rerank asks the model to score every document and returns the results ordered by relevance.
+// Documents the model leaves out are returned with a zero score rather than dropped, so the
+// result always covers the input; indices the model invents are ignored. WithModel has no effect:
+// the model is whatever the client was built with.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string, opts ...Option) ([]Result, error) {
+	o := applyOptions(opts)
+	if len(documents) == 0 {
+		return []Result{}, nil
+	}
+
+	response, err := r.client.GenerateWithSchema(ctx, r.buildPrompt(query, documents), llmRankingSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranking llmRanking
+	if err := json.Unmarshal([]byte(gollm.CleanResponse(response)), &ranking); err != nil {
+		return nil, llm.NewLLMError(llm.ErrorTypeResponse, "failed to parse LLM rerank response", err)
+	}
+
+	scored := make(map[int]float64, len(documents))
+	for _, item := range ranking.Rankings {
+		if item.Index < 0 || item.Index >= len(documents) {
+			continue
+		}
+		if _, seen := scored[item.Index]; !seen {
+			scored[item.Index] = item.Score
+		}
+	}
+
+	results := make([]Result, len(documents))
+	for i := range documents {
+		results[i] = Result{Index: i, Score: scored[i]}
+	}
+	return finalize(results, documents, o), nil
+}
+
+func (r *LLMReranker) buildPrompt(query string, documents []string) *llm.Prompt {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query: %s\n\nDocuments:\n", query)
+	for i, doc := range documents {
+		if r.MaxDocumentChars > 0 {
+			if runes := []rune(doc); len(runes) > r.MaxDocumentChars {
+				doc = string(runes[:r.MaxDocumentChars]) + "…"
+			}
+		}
+		fmt.Fprintf(&sb, "[%d] %s\n", i, doc)
+	}
+
+	return llm.NewPrompt(sb.String(),
+		llm.WithSystemPrompt("You are a search relevance judge. Score how well each document answers the query.", llm.CacheTypeEphemeral),
+		llm.WithDirectives(
+			"Return one entry per document, identified by its zero-based index in brackets",
+			"Scores range from 0 (irrelevant) to 1 (directly answers the query)",
+			"Judge relevance to the query only, not document quality or length",
+		),
+	)
+}
@@ -0,0 +1,97 @@
+// Package rerank scores a set of candidate documents against a query and returns them reordered
+// by relevance. Retrieval pipelines use it between vector search and generation: a cheap first
+// stage over-fetches candidates, and a cross-encoder (or an LLM, where no rerank endpoint exists)
+// decides which of them are worth spending prompt tokens on.
+//
+// Implementations talk to Cohere's /v2/rerank, vLLM and Hugging Face TEI /rerank servers, any
+// Jina-style endpoint described by an EndpointConfig, or fall back to an llm.LLM through
+// GenerateWithSchema.
+package rerank
+
+import (
+	"context"
+	"sort"
+)
+
+// Result is one scored document. Index refers to the position of the document in the slice
+// passed to Rerank, so callers can map the result back to their own records without relying on
+// the document text being echoed.
+type Result struct {
+	Index    int     `json:"index"`
+	Score    float64 `json:"score"`
+	Document string  `json:"document,omitempty"`
+}
+
+// Reranker scores documents against a query. Results are ordered by descending Score and, when
+// TopN is set, truncated to that many entries. Scores are only comparable within one call: Cohere
+// and Jina return a normalized relevance in [0, 1], TEI returns raw logits unless normalized, and
+// the LLM fallback returns whatever scale the model was asked for.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string, opts ...Option) ([]Result, error)
+}
+
+// Options are the per-call settings shared by every Reranker.
+type Options struct {
+	// TopN limits the number of results returned. Zero returns every document.
+	TopN int
+	// ReturnDocuments copies the document text into Result.Document.
+	ReturnDocuments bool
+	// Model overrides the reranker's configured model for this call.
+	Model string
+}
+
+// Option configures a single Rerank call.
+type Option func(*Options)
+
+// WithTopN limits the number of results returned to the n most relevant documents.
+func WithTopN(n int) Option {
+	return func(o *Options) {
+		o.TopN = n
+	}
+}
+
+// WithReturnDocuments fills Result.Document with the text of each returned document.
+func WithReturnDocuments() Option {
+	return func(o *Options) {
+		o.ReturnDocuments = true
+	}
+}
+
+// WithModel overrides the reranker's configured model for a single call.
+func WithModel(model string) Option {
+	return func(o *Options) {
+		o.Model = model
+	}
+}
+
+func applyOptions(opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// finalize orders results by descending score (ties keep document order), applies TopN, and
+// fills in document text when requested. Every implementation funnels its results through here so
+// the ordering contract does not depend on what a particular server happens to return.
+func finalize(results []Result, documents []string, o Options) []Result {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Index < results[j].Index
+		}
+		return results[i].Score > results[j].Score
+	})
+	if o.TopN > 0 && len(results) > o.TopN {
+		results = results[:o.TopN]
+	}
+	for i := range results {
+		if o.ReturnDocuments && results[i].Document == "" && results[i].Index >= 0 && results[i].Index < len(documents) {
+			results[i].Document = documents[results[i].Index]
+		}
+		if !o.ReturnDocuments {
+			results[i].Document = ""
+		}
+	}
+	return results
+}
@@ -0,0 +1,230 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+)
+
+var testDocuments = []string{
+	"Paris is the capital of France.",
+	"Bananas are rich in potassium.",
+	"France's capital city hosts the Louvre.",
+}
+
+func TestCohereReranker(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/rerank", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		// Deliberately out of order: the reranker must sort regardless.
+		_, _ = w.Write([]byte(`{"id":"r1","results":[
+			{"index":2,"relevance_score":0.81},
+			{"index":0,"relevance_score":0.97}
+		],"meta":{"billed_units":{"search_units":1}}}`))
+	}))
+	defer server.Close()
+
+	r := NewCohereRerankerWithURL("test-key", "rerank-v3.5", server.URL+"/")
+	results, err := r.Rerank(context.Background(), "capital of France", testDocuments, WithTopN(2), WithReturnDocuments())
+	require.NoError(t, err)
+
+	assert.Equal(t, "rerank-v3.5", gotBody["model"])
+	assert.Equal(t, "capital of France", gotBody["query"])
+	assert.Equal(t, float64(2), gotBody["top_n"])
+	assert.Len(t, gotBody["documents"], 3)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, Result{Index: 0, Score: 0.97, Document: testDocuments[0]}, results[0])
+	assert.Equal(t, Result{Index: 2, Score: 0.81, Document: testDocuments[2]}, results[1])
+}
+
+func TestVLLMReranker(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantPath string
+	}{
+		{"bare host", "", "/rerank"},
+		{"v1 base", "/v1", "/v1/rerank"},
+		{"full path", "/v2/rerank", "/v2/rerank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.wantPath, r.URL.Path)
+				assert.Empty(t, r.Header.Get("Authorization"))
+				_, _ = w.Write([]byte(`{"model":"BAAI/bge-reranker-v2-m3","results":[
+					{"index":1,"document":{"text":"Bananas are rich in potassium."},"relevance_score":0.02},
+					{"index":0,"document":{"text":"Paris is the capital of France."},"relevance_score":0.99},
+					{"index":2,"document":{"text":"France's capital city hosts the Louvre."},"relevance_score":0.75}
+				],"usage":{"total_tokens":42}}`))
+			}))
+			defer server.Close()
+
+			r := NewVLLMReranker(server.URL+tt.endpoint, "BAAI/bge-reranker-v2-m3")
+			results, err := r.Rerank(context.Background(), "capital of France", testDocuments)
+			require.NoError(t, err)
+			require.Len(t, results, 3)
+			assert.Equal(t, []int{0, 2, 1}, indices(results))
+			// Echoed documents are dropped unless asked for.
+			assert.Empty(t, results[0].Document)
+		})
+	}
+}
+
+func TestTEIReranker(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rerank", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		_, _ = w.Write([]byte(`[{"index":2,"score":3.1},{"index":0,"score":4.5},{"index":1,"score":-7.2}]`))
+	}))
+	defer server.Close()
+
+	r := NewTEIReranker(server.URL)
+	results, err := r.Rerank(context.Background(), "capital of France", testDocuments, WithTopN(1))
+	require.NoError(t, err)
+
+	assert.Len(t, gotBody["texts"], 3)
+	assert.NotContains(t, gotBody, "model")
+	require.Len(t, results, 1)
+	assert.Equal(t, 0, results[0].Index)
+	assert.Equal(t, 4.5, results[0].Score)
+}
+
+func TestGenericReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "jina_test", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "yes", r.Header.Get("X-Extra"))
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "jina-reranker-v2-base-multilingual", body["model"])
+		_, _ = w.Write([]byte(`{"results":[{"index":0,"relevance_score":0.5,"document":"Paris is the capital of France."}]}`))
+	}))
+	defer server.Close()
+
+	r, err := NewReranker(EndpointConfig{
+		Name:       "jina",
+		URL:        server.URL + "/v1/rerank",
+		Model:      "jina-reranker-v1",
+		APIKey:     "jina_test",
+		AuthHeader: "X-Api-Key",
+		Headers:    map[string]string{"X-Extra": "yes"},
+	})
+	require.NoError(t, err)
+
+	results, err := r.Rerank(context.Background(), "q", testDocuments, WithModel("jina-reranker-v2-base-multilingual"), WithReturnDocuments())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, testDocuments[0], results[0].Document)
+}
+
+func TestNewRerankerValidation(t *testing.T) {
+	_, err := NewReranker(EndpointConfig{})
+	assert.Error(t, err)
+
+	_, err = NewReranker(EndpointConfig{URL: "http://localhost", Format: "soap"})
+	assert.Error(t, err)
+}
+
+func TestHTTPRerankerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantType llm.ErrorType
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"message":"too many requests"}`, llm.ErrorTypeRateLimit},
+		{"unauthorized", http.StatusUnauthorized, `{"message":"invalid api token"}`, llm.ErrorTypeAuthentication},
+		{"bad request", http.StatusBadRequest, `{"message":"documents must not be empty"}`, llm.ErrorTypeInvalidInput},
+		{"index out of range", http.StatusOK, `{"results":[{"index":7,"relevance_score":0.1}]}`, llm.ErrorTypeResponse},
+		{"missing results", http.StatusOK, `{"id":"x"}`, llm.ErrorTypeResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewCohereRerankerWithURL("k", "m", server.URL).Rerank(context.Background(), "q", testDocuments)
+			var llmErr *llm.LLMError
+			require.True(t, errors.As(err, &llmErr), "got %v", err)
+			assert.Equal(t, tt.wantType, llmErr.Type)
+		})
+	}
+}
+
+func TestRerankEmptyDocuments(t *testing.T) {
+	results, err := NewCohereReranker("k", "m").Rerank(context.Background(), "q", nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+// TestLLMReranker drives the fallback through a real client pointed at a local OpenAI-compatible
+// server, so the request goes through GenerateWithSchema exactly as it would in production.
+func TestLLMReranker(t *testing.T) {
+	var gotRequest map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotRequest))
+		// Index 1 is omitted and index 9 does not exist; both must be handled.
+		content := `{"rankings":[{"index":2,"score":0.8},{"index":0,"score":0.95},{"index":9,"score":1}]}`
+		resp := map[string]interface{}{
+			"id":    "chatcmpl-1",
+			"model": "test-model",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": content},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 50, "completion_tokens": 20, "total_tokens": 70},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client, err := gollm.NewLLM(
+		gollm.SetProvider("vllm"),
+		gollm.SetModel("test-model"),
+		gollm.SetVLLMEndpoint(server.URL),
+		gollm.SetMaxRetries(0),
+		gollm.SetLogLevel(gollm.LogLevelError),
+	)
+	require.NoError(t, err)
+
+	r := NewLLMReranker(client)
+	r.MaxDocumentChars = 10
+	results, err := r.Rerank(context.Background(), "capital of France", testDocuments)
+	require.NoError(t, err)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, []int{0, 2, 1}, indices(results))
+	assert.Equal(t, 0.0, results[2].Score)
+
+	encoded, _ := json.Marshal(gotRequest["messages"])
+	assert.Contains(t, string(encoded), "[0] Paris is t…")
+	assert.True(t, strings.Contains(string(encoded), "capital of France"))
+}
+
+func indices(results []Result) []int {
+	out := make([]int, len(results))
+	for i, r := range results {
+		out[i] = r.Index
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+package types
+
+// InputAudio is base64-encoded audio sent as part of a message (OpenAI "input_audio").
+type InputAudio struct {
+	Data   string `json:"data"`   // Base64-encoded audio data
+	Format string `json:"format"` // Audio format: "wav" or "mp3" (OpenAI); Gemini also accepts others
+}
+
+// NewInputAudioContent creates an audio content part from base64-encoded data.
+// format is the audio container, typically "wav" or "mp3".
+func NewInputAudioContent(base64Data, format string) ContentPart {
+	return ContentPart{
+		Type: ContentTypeInputAudio,
+		InputAudio: &InputAudio{
+			Data:   base64Data,
+			Format: format,
+		},
+	}
+}
+
+// AudioOutputConfig requests spoken output from models that can produce it (e.g.
+// gpt-4o-audio-preview). It is sent as the "audio" request parameter alongside
+// modalities ["text", "audio"]; see config.SetAudioOutput.
+type AudioOutputConfig struct {
+	Voice  string `json:"voice"`  // Voice name, e.g. "alloy", "verse"
+	Format string `json:"format"` // Output format: "wav", "mp3", "flac", "opus", or "pcm16"
+}
+
+// AudioOutput is the audio a model returned, exposed on ResponseDetails.Audio.
+// The generated text of such a response is the transcript.
+type AudioOutput struct {
+	ID         string `json:"id,omitempty"`         // Audio ID, used to refer to it in a follow-up turn
+	Data       string `json:"data,omitempty"`       // Base64-encoded audio in the requested format
+	Transcript string `json:"transcript,omitempty"` // Transcript of the audio
+	ExpiresAt  int64  `json:"expires_at,omitempty"` // Unix time after which the ID can no longer be referenced
+}
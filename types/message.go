@@ -12,6 +12,20 @@ const (
 	ContentTypeImageURL ContentPartType = "image_url"
 	// ContentTypeImage represents an image with embedded data (base64).
 	ContentTypeImage ContentPartType = "image"
+	// ContentTypeDocument represents a document (PDF, plain text, ...) sent inline, by URL, or as text.
+	ContentTypeDocument ContentPartType = "document"
+	// ContentTypeFile represents a file previously uploaded to the provider, referenced by ID.
+	ContentTypeFile ContentPartType = "file"
+	// ContentTypeInputAudio represents base64-encoded audio input.
+	ContentTypeInputAudio ContentPartType = "input_audio"
+)
+
+// Document source types.
+const (
+	DocumentSourceBase64 = "base64" // Inline base64-encoded data
+	DocumentSourceURL    = "url"    // Publicly reachable URL
+	DocumentSourceText   = "text"   // Plain text supplied directly
+	DocumentSourceFile   = "file"   // Provider-side file ID from a prior upload
 )
 
 // ImageURL represents an image referenced by URL.
@@ -29,13 +43,49 @@ type ImageSource struct {
 	Data      string `json:"data"`       // Base64-encoded image data
 }
 
+// DocumentSource describes where a document's content comes from.
+// Which fields are used depends on Type; providers reject source types they cannot send.
+type DocumentSource struct {
+	Type      string `json:"type"`                 // Source type: "base64", "url", "text", or "file"
+	MediaType string `json:"media_type,omitempty"` // MIME type, e.g. "application/pdf" or "text/plain"
+	Data      string `json:"data,omitempty"`       // Base64 data (Type "base64") or plain text (Type "text")
+	URL       string `json:"url,omitempty"`        // Document URL (Type "url")
+	FileID    string `json:"file_id,omitempty"`    // Uploaded file ID (Type "file")
+	Filename  string `json:"filename,omitempty"`   // Optional filename; OpenAI requires one for inline files
+	Title     string `json:"title,omitempty"`      // Optional title shown to the model (Anthropic)
+	Context   string `json:"context,omitempty"`    // Optional context about the document (Anthropic)
+	Citations bool   `json:"citations,omitempty"`  // Enable citations for this document (Anthropic)
+}
+
 // ContentPart represents a single part of multimodal content.
-// A message can contain multiple parts (e.g., text and images).
+// A message can contain multiple parts (e.g., text, images, and documents).
 type ContentPart struct {
-	Type     ContentPartType `json:"type"`                // Type of content: "text", "image_url", or "image"
-	Text     string          `json:"text,omitempty"`      // Text content (when Type is "text")
-	ImageURL *ImageURL       `json:"image_url,omitempty"` // Image URL (when Type is "image_url")
-	Source   *ImageSource    `json:"source,omitempty"`    // Image source (when Type is "image", used by Anthropic)
+	Type       ContentPartType `json:"type"`                  // Type of content: "text", "image_url", "image", "document", "file", or "input_audio"
+	Text       string          `json:"text,omitempty"`        // Text content (when Type is "text")
+	ImageURL   *ImageURL       `json:"image_url,omitempty"`   // Image URL (when Type is "image_url")
+	Source     *ImageSource    `json:"source,omitempty"`      // Image source (when Type is "image", used by Anthropic)
+	Document   *DocumentSource `json:"document,omitempty"`    // Document source (when Type is "document" or "file")
+	InputAudio *InputAudio     `json:"input_audio,omitempty"` // Audio data (when Type is "input_audio")
+}
+
+// IsDocument returns true if the part carries a document or file.
+func (c ContentPart) IsDocument() bool {
+	return (c.Type == ContentTypeDocument || c.Type == ContentTypeFile) && c.Document != nil
+}
+
+// IsAudio returns true if the part carries audio input.
+func (c ContentPart) IsAudio() bool {
+	return c.Type == ContentTypeInputAudio && c.InputAudio != nil
+}
+
+// HasDocuments returns true if any of the parts carries a document or file.
+func HasDocuments(parts []ContentPart) bool {
+	for _, part := range parts {
+		if part.IsDocument() {
+			return true
+		}
+	}
+	return false
 }
 
 // NewTextContent creates a text content part.
@@ -74,6 +124,57 @@ func NewImageBase64Content(base64Data, mediaType string) ContentPart {
 	}
 }
 
+// NewDocumentBase64Content creates a document content part from base64-encoded data.
+// mediaType is typically "application/pdf"; filename is optional but OpenAI requires one.
+func NewDocumentBase64Content(base64Data, mediaType, filename string) ContentPart {
+	return ContentPart{
+		Type: ContentTypeDocument,
+		Document: &DocumentSource{
+			Type:      DocumentSourceBase64,
+			MediaType: mediaType,
+			Data:      base64Data,
+			Filename:  filename,
+		},
+	}
+}
+
+// NewDocumentURLContent creates a document content part referenced by URL.
+func NewDocumentURLContent(url string) ContentPart {
+	return ContentPart{
+		Type: ContentTypeDocument,
+		Document: &DocumentSource{
+			Type: DocumentSourceURL,
+			URL:  url,
+		},
+	}
+}
+
+// NewDocumentTextContent creates a plain-text document content part.
+// The title is optional.
+func NewDocumentTextContent(text, title string) ContentPart {
+	return ContentPart{
+		Type: ContentTypeDocument,
+		Document: &DocumentSource{
+			Type:      DocumentSourceText,
+			MediaType: "text/plain",
+			Data:      text,
+			Title:     title,
+		},
+	}
+}
+
+// NewFileContent creates a content part referencing a file already uploaded to the provider.
+func NewFileContent(fileID, filename string) ContentPart {
+	return ContentPart{
+		Type: ContentTypeFile,
+		Document: &DocumentSource{
+			Type:     DocumentSourceFile,
+			FileID:   fileID,
+			Filename: filename,
+		},
+	}
+}
+
 // MemoryMessage represents a single message in the conversation history.
 // It includes the role of the speaker, the content of the message,
 // and the number of tokens in the message for efficient memory management.
@@ -94,6 +195,12 @@ type MemoryMessage struct {
 	Metadata     map[string]interface{} // Additional provider-specific metadata
 	ToolCalls    []ToolCall             // Tool calls requested by the assistant (only for role="assistant")
 	ToolCallID   string                 // ID of the tool call this message responds to (only for role="tool")
+	// ThinkingBlocks are the signed thinking blocks of an assistant turn (Anthropic extended
+	// thinking), replayed unchanged ahead of its tool calls.
+	ThinkingBlocks []ThinkingBlock
+	// ResponseID is the provider's ID for the response an assistant message came from
+	// (ResponseDetails.ID). Providers that keep conversation state server-side chain from it.
+	ResponseID string
 }
 
 // HasMultiContent returns true if the message contains multimodal content.
@@ -239,4 +346,17 @@ type ResponseDetails struct {
 	// priority a premium — so a cost record without it can be wrong by 2x on
 	// counts that are perfectly accurate. Empty when the provider reports none.
 	ServiceTier string `json:"service_tier,omitempty"`
+	// Audio is the spoken output of a model asked for audio (see AudioOutputConfig).
+	// Nil for text-only responses.
+	Audio *AudioOutput `json:"audio,omitempty"`
+	// ThinkingBlocks are the model's extended-thinking blocks, signatures included. Pass them
+	// back on the assistant message (MemoryMessage or PromptMessage) when continuing a tool-use
+	// turn; Anthropic rejects the continuation otherwise.
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+	// Reasoning is the model's reasoning, normalized across providers. Nil when the provider
+	// returned none or it was not requested (see llm.WithReasoning).
+	Reasoning *Reasoning `json:"reasoning,omitempty"`
+	// ToolOutputs holds the results of built-in tools other than web search (file search,
+	// code interpreter, MCP, computer use). Nil when none ran.
+	ToolOutputs *ToolOutputs `json:"tool_outputs,omitempty"`
 }
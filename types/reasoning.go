@@ -30,3 +30,23 @@ const (
 
 // String returns the underlying string value.
 func (e ReasoningEffort) String() string { return string(e) }
+
+// Reasoning is the model's reasoning behind a response, normalized across providers: DeepSeek
+// and vLLM's reasoning_content, OpenRouter's reasoning and reasoning_details, Anthropic's
+// thinking blocks, Gemini's thought summaries, Ollama's thinking and the Responses API's
+// reasoning items all land here. It is for reading and logging; to continue an Anthropic
+// tool-use turn, replay ResponseDetails.ThinkingBlocks instead.
+type Reasoning struct {
+	// Text is the readable reasoning (or its summary, where the provider only exposes one).
+	Text string `json:"text,omitempty"`
+	// Signature is the provider's opaque signature over the reasoning, when it signs it.
+	Signature string `json:"signature,omitempty"`
+	// Encrypted is an opaque payload of reasoning the provider withholds as text
+	// (Anthropic's redacted thinking, OpenAI's encrypted_content).
+	Encrypted string `json:"encrypted,omitempty"`
+}
+
+// IsEmpty reports whether r carries no reasoning at all.
+func (r *Reasoning) IsEmpty() bool {
+	return r == nil || (r.Text == "" && r.Signature == "" && r.Encrypted == "")
+}
@@ -19,7 +19,7 @@ var ErrStreamSkip = errors.New("skip token")
 // alone.
 type StreamChunk struct {
 	Text          string         // incremental text (empty for usage/finish-only chunks)
-	Kind          string         // primary signal: "text" | "usage" | "finish" | "tool_call_delta"
+	Kind          string         // primary signal: "text" | "usage" | "finish" | "tool_call_delta" | "reasoning" | "thinking" | "tool_output"
 	FinishReason  string         // provider stop/finish reason (set on finish chunks)
 	Usage         *TokenUsage    // token usage, when the provider reports it mid/end of stream
 	Model         string         // model the provider says served this chunk; the resolved one, which a gateway or moving alias prices differently from the requested one (empty when unreported)
@@ -28,6 +28,16 @@ type StreamChunk struct {
 	// ExtraToolCallDeltas carries additional fragments when one chunk opens
 	// multiple parallel calls; the loop emits them as subsequent tokens.
 	ExtraToolCallDeltas []*ToolCallDelta
+	// Reasoning is an incremental fragment of the model's reasoning text (set when Kind ==
+	// "reasoning", and alongside Thinking on Anthropic's "thinking" chunks). Like Thinking it
+	// is kept out of Text, so the answer stays clean.
+	Reasoning string
+	// Thinking carries an extended-thinking fragment for replay (set when Kind == "thinking",
+	// Anthropic only). A fragment with readable text also has Reasoning set to the same text.
+	Thinking *ThinkingDelta
+	// ToolOutputs carries a completed built-in tool item (file search, code interpreter, MCP,
+	// computer use) or a file citation (set when Kind == "tool_output").
+	ToolOutputs *ToolOutputs
 }
 
 // ToolCallDelta is one incremental fragment of a streamed tool/function call.
@@ -0,0 +1,23 @@
+package types
+
+// ThinkingBlock is one block of a model's extended thinking, kept verbatim so it can be sent
+// back. Anthropic signs thinking blocks and requires the ones from an assistant turn that used
+// tools to be returned unchanged in that turn when the tool results are sent, so they travel
+// on ResponseDetails, MemoryMessage and PromptMessage rather than being reduced to text.
+type ThinkingBlock struct {
+	Type      string `json:"type"`                // "thinking" or "redacted_thinking"
+	Thinking  string `json:"thinking,omitempty"`  // Thinking text (Type "thinking")
+	Signature string `json:"signature,omitempty"` // Opaque signature verifying the thinking (Type "thinking")
+	Data      string `json:"data,omitempty"`      // Encrypted thinking (Type "redacted_thinking")
+}
+
+// ThinkingDelta is one incremental fragment of a streamed thinking block, carried on a
+// StreamChunk of Kind "thinking". Like ToolCallDelta, fragments belong to the block at Index
+// and the consumer concatenates Text per Index; the Signature arrives in its own fragment just
+// before the block closes, and a redacted block arrives whole in a single fragment.
+type ThinkingDelta struct {
+	Index     int    // content block this fragment belongs to
+	Text      string // thinking text to append
+	Signature string // block signature, set on the fragment that carries it
+	Redacted  string // encrypted data of a redacted_thinking block
+}
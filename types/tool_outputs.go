@@ -0,0 +1,222 @@
+package types
+
+import "encoding/json"
+
+// ToolOutputs collects the output items of OpenAI's built-in tools other than web search
+// (which is reported through WebSearchResponse). Each field holds the items of one kind in
+// response order; the JSON shapes match the Responses API output items.
+type ToolOutputs struct {
+	FileSearchCalls      []FileSearchCall      `json:"file_search_calls,omitempty"`
+	CodeInterpreterCalls []CodeInterpreterCall `json:"code_interpreter_calls,omitempty"`
+	MCPCalls             []MCPCall             `json:"mcp_calls,omitempty"`
+	MCPListTools         []MCPListTools        `json:"mcp_list_tools,omitempty"`
+	MCPApprovalRequests  []MCPApprovalRequest  `json:"mcp_approval_requests,omitempty"`
+	ComputerCalls        []ComputerCall        `json:"computer_calls,omitempty"`
+	// FileCitations are the file annotations on the message text: files found by file_search
+	// and files written by code_interpreter.
+	FileCitations []FileCitation `json:"file_citations,omitempty"`
+}
+
+// IsEmpty reports whether no built-in tool output was collected.
+func (o *ToolOutputs) IsEmpty() bool {
+	return o == nil || len(o.FileSearchCalls)+len(o.CodeInterpreterCalls)+len(o.MCPCalls)+
+		len(o.MCPListTools)+len(o.MCPApprovalRequests)+len(o.ComputerCalls)+len(o.FileCitations) == 0
+}
+
+// Merge appends the items of other to o.
+func (o *ToolOutputs) Merge(other *ToolOutputs) {
+	if other == nil {
+		return
+	}
+	o.FileSearchCalls = append(o.FileSearchCalls, other.FileSearchCalls...)
+	o.CodeInterpreterCalls = append(o.CodeInterpreterCalls, other.CodeInterpreterCalls...)
+	o.MCPCalls = append(o.MCPCalls, other.MCPCalls...)
+	o.MCPListTools = append(o.MCPListTools, other.MCPListTools...)
+	o.MCPApprovalRequests = append(o.MCPApprovalRequests, other.MCPApprovalRequests...)
+	o.ComputerCalls = append(o.ComputerCalls, other.ComputerCalls...)
+	o.FileCitations = append(o.FileCitations, other.FileCitations...)
+}
+
+// FileSearchCall is a file_search invocation. Results are only returned when the request
+// asks for them with include: ["file_search_call.results"].
+type FileSearchCall struct {
+	ID      string             `json:"id"`
+	Status  string             `json:"status"`
+	Queries []string           `json:"queries,omitempty"`
+	Results []FileSearchResult `json:"results,omitempty"`
+}
+
+// FileSearchResult is one chunk retrieved from a vector store.
+type FileSearchResult struct {
+	FileID     string                 `json:"file_id"`
+	Filename   string                 `json:"filename,omitempty"`
+	Score      float64                `json:"score,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// CodeInterpreterCall is a code_interpreter run: the code executed and what it produced.
+// Outputs are only returned when the request asks for them with
+// include: ["code_interpreter_call.outputs"].
+type CodeInterpreterCall struct {
+	ID          string                  `json:"id"`
+	Status      string                  `json:"status"`
+	ContainerID string                  `json:"container_id,omitempty"`
+	Code        string                  `json:"code,omitempty"`
+	Outputs     []CodeInterpreterOutput `json:"outputs,omitempty"`
+}
+
+// CodeInterpreterOutput is one output of a code_interpreter run: Type "logs" carries Logs,
+// "image" carries URL, and "files" carries Files.
+type CodeInterpreterOutput struct {
+	Type  string                `json:"type"`
+	Logs  string                `json:"logs,omitempty"`
+	URL   string                `json:"url,omitempty"`
+	Files []CodeInterpreterFile `json:"files,omitempty"`
+}
+
+// CodeInterpreterFile is a file written by a code_interpreter run.
+type CodeInterpreterFile struct {
+	FileID   string `json:"file_id"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// FileCitation is a file annotation on the message text. Type is "file_citation" for a
+// file_search hit and "container_file_citation" for a file in a code_interpreter container;
+// download the latter from the container with ContainerID.
+type FileCitation struct {
+	Type        string `json:"type"`
+	FileID      string `json:"file_id"`
+	Filename    string `json:"filename,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	Index       int    `json:"index,omitempty"`
+	StartIndex  int    `json:"start_index,omitempty"`
+	EndIndex    int    `json:"end_index,omitempty"`
+}
+
+// MCPCall is a call the model made to a tool on a remote MCP server. Error is set instead of
+// Output when the call failed.
+type MCPCall struct {
+	ID                string `json:"id"`
+	ServerLabel       string `json:"server_label"`
+	Name              string `json:"name"`
+	Arguments         string `json:"arguments,omitempty"`
+	Output            string `json:"output,omitempty"`
+	Error             string `json:"error,omitempty"`
+	ApprovalRequestID string `json:"approval_request_id,omitempty"`
+}
+
+// UnmarshalJSON accepts the error either as a string or as an object with a message.
+func (c *MCPCall) UnmarshalJSON(data []byte) error {
+	type plain MCPCall
+	var raw struct {
+		plain
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = MCPCall(raw.plain)
+	c.Error = mcpErrorText(raw.Error)
+	return nil
+}
+
+// MCPListTools is the list of tools an MCP server offered, imported at the start of a
+// response.
+type MCPListTools struct {
+	ID          string    `json:"id"`
+	ServerLabel string    `json:"server_label"`
+	Tools       []MCPTool `json:"tools,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// UnmarshalJSON accepts the error either as a string or as an object with a message.
+func (l *MCPListTools) UnmarshalJSON(data []byte) error {
+	type plain MCPListTools
+	var raw struct {
+		plain
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*l = MCPListTools(raw.plain)
+	l.Error = mcpErrorText(raw.Error)
+	return nil
+}
+
+// MCPTool describes one tool offered by an MCP server.
+type MCPTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// MCPApprovalRequest asks the caller to approve an MCP tool call before it runs. Answer it
+// with an mcp_approval_response input item carrying ID.
+type MCPApprovalRequest struct {
+	ID          string `json:"id"`
+	ServerLabel string `json:"server_label"`
+	Name        string `json:"name"`
+	Arguments   string `json:"arguments,omitempty"`
+}
+
+// ComputerCall is an action the computer-use model wants performed. The caller performs it and
+// replies with a computer_call_output item for CallID, acknowledging any safety checks.
+type ComputerCall struct {
+	ID                  string                `json:"id"`
+	CallID              string                `json:"call_id"`
+	Status              string                `json:"status"`
+	Action              ComputerAction        `json:"action"`
+	PendingSafetyChecks []ComputerSafetyCheck `json:"pending_safety_checks,omitempty"`
+}
+
+// ComputerAction is a single computer-use action. Type is one of "click", "double_click",
+// "drag", "keypress", "move", "screenshot", "scroll", "type" or "wait"; only the fields that
+// action uses are set.
+type ComputerAction struct {
+	Type    string          `json:"type"`
+	X       int             `json:"x,omitempty"`
+	Y       int             `json:"y,omitempty"`
+	Button  string          `json:"button,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Keys    []string        `json:"keys,omitempty"`
+	ScrollX int             `json:"scroll_x,omitempty"`
+	ScrollY int             `json:"scroll_y,omitempty"`
+	Path    []ComputerPoint `json:"path,omitempty"`
+}
+
+// ComputerPoint is a screen coordinate on a drag path.
+type ComputerPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// ComputerSafetyCheck is a safety check the caller must acknowledge before continuing.
+type ComputerSafetyCheck struct {
+	ID      string `json:"id"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// mcpErrorText reads an MCP error that is either a string or an object with a message.
+func mcpErrorText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var obj struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil && obj.Message != "" {
+		return obj.Message
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
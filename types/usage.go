@@ -67,6 +67,10 @@ type UsageEvent struct {
 	// every token in Usage, so a recorder that ignores it can be wrong by 2x on
 	// counts that are exactly right. Empty when the provider reports none.
 	ServiceTier string
+	// KeyID identifies the pooled API key that paid for this round-trip (see
+	// config.SetAPIKeyPool). It is the key's ID, never its value, and is empty when
+	// the provider has no key pool.
+	KeyID string
 	// Details is the parsed provider response detail when one was available (nil
 	// on parse failures, on aborted streams, and for providers that report no
 	// usage). Usage is populated regardless — read Usage, not Details.TokenUsage.
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+	// Concurrent writers each use their own temporary file, so one of them wins whole.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, WriteFileAtomic(path, []byte(fmt.Sprintf("writer-%d", i)), 0o600))
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Regexp(t, `^writer-\d$`, string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "absent", "file"), nil, 0o600))
+}